@@ -0,0 +1,89 @@
+package eazye
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// Append uploads a message to the folder, e.g. to keep a copy of a sent email
+// or to restore one from a backup. The message is given with its headers, bare
+// LF line endings are turned into CRLF as IMAP requires, and a zero date
+// leaves the internal date up to the server. On servers with UIDPLUS
+// (RFC 4315) the UID of the new email is returned, otherwise 0. Messages over
+// the APPENDLIMIT (RFC 7889) the server announced fail with ErrTooLarge
+// without being sent. If the connection drops once the message is sent it is
+// not sent again, as that could leave two copies in the folder, and
+// ErrConnectionLost is returned.
+func (c *Client) Append(folder string, flags []string, date time.Time, raw []byte) (uint32, error) {
+	return c.AppendContext(context.Background(), folder, flags, date, raw)
+}
+
+// AppendContext is Append with a context.
+func (c *Client) AppendContext(ctx context.Context, folder string, flags []string, date time.Time, raw []byte) (uint32, error) {
+	if c.SafeMode {
+		return 0, ErrReadOnlyMode
+	}
+	raw = toCRLF(raw)
+	if limit, ok := c.appendLimit(); ok && uint64(len(raw)) > limit {
+		return 0, fmt.Errorf("%w: %d bytes, the server takes up to %d", ErrTooLarge, len(raw), limit)
+	}
+
+	var idate *time.Time
+	if !date.IsZero() {
+		idate = &date
+	}
+	cmd, err := c.doOnce(ctx, func() (*imap.Command, error) {
+		return c.server().Append(folder, imap.NewFlagSet(flags...), idate, imap.NewLiteral(raw))
+	})
+	if err != nil {
+		return 0, fmt.Errorf("unable to append email: %w", err)
+	}
+
+	uid := appendUID(cmd)
+	c.audit(AuditAppended, uid, folder)
+	return uid, nil
+}
+
+// appendLimit returns the largest message the server takes, if it announced
+// a limit with APPENDLIMIT=<size>.
+func (c *Client) appendLimit() (uint64, bool) {
+	for _, name := range c.Capabilities() {
+		if value, ok := strings.CutPrefix(name, "APPENDLIMIT="); ok {
+			limit, err := strconv.ParseUint(value, 10, 64)
+			return limit, err == nil
+		}
+	}
+	return 0, false
+}
+
+// appendUID returns the UID from the APPENDUID response code of a completed
+// APPEND, or 0 if there is none.
+func appendUID(cmd *imap.Command) uint32 {
+	rsp, err := cmd.Result(imap.OK)
+	if err != nil || rsp == nil || rsp.Label != "APPENDUID" || len(rsp.Fields) < 3 {
+		return 0
+	}
+	return imap.AsNumber(rsp.Fields[2])
+}
+
+// toCRLF turns bare LF line endings into CRLF.
+func toCRLF(raw []byte) []byte {
+	if bytes.Count(raw, []byte("\n")) == bytes.Count(raw, []byte("\r\n")) {
+		return raw
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(raw) + bytes.Count(raw, []byte("\n")))
+	for i, b := range raw {
+		if b == '\n' && (i == 0 || raw[i-1] != '\r') {
+			buf.WriteByte('\r')
+		}
+		buf.WriteByte(b)
+	}
+	return buf.Bytes()
+}
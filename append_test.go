@@ -0,0 +1,36 @@
+package eazye
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestToCRLF(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"Subject: hi\r\n\r\nbody\r\n", "Subject: hi\r\n\r\nbody\r\n"},
+		{"Subject: hi\n\nbody\n", "Subject: hi\r\n\r\nbody\r\n"},
+		{"\nmixed\r\nendings\n", "\r\nmixed\r\nendings\r\n"},
+	}
+	for _, test := range tests {
+		if got := string(toCRLF([]byte(test.raw))); got != test.want {
+			t.Errorf("toCRLF(%q) got %q, want %q", test.raw, got, test.want)
+		}
+	}
+}
+
+func TestAppendLimit(t *testing.T) {
+	c := &Client{imapConn: &fakeConn{caps: map[string]bool{"APPENDLIMIT=10": true}}}
+	_, err := c.Append("Sent", nil, time.Time{}, []byte("Subject: too long\r\n\r\n"))
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Append() got %v, want %s", err, ErrTooLarge)
+	}
+
+	c.SafeMode = true
+	if _, err = c.Append("Sent", nil, time.Time{}, nil); !errors.Is(err, ErrReadOnlyMode) {
+		t.Errorf("Append() in safe mode got %v, want %s", err, ErrReadOnlyMode)
+	}
+}
@@ -0,0 +1,176 @@
+package eazye
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// ArchiveCheckpoint is how far an ArchiveScan got, to resume it later.
+type ArchiveCheckpoint struct {
+	UIDValidity uint32 `json:"uid_validity"`
+	// Below is the UID the scan carries on under, 0 if it has not started.
+	Below uint32 `json:"below"`
+	// Done is set once the oldest email was handled.
+	Done bool `json:"done"`
+}
+
+// ArchiveScan walks through a huge folder, such as Gmail's "All Mail" with
+// millions of emails, from the newest email to the oldest. Rather than one
+// SEARCH for the whole folder it searches and fetches windows of UIDs, sizing
+// them to how fast the server answers, and records a checkpoint after every
+// window so an interrupted scan can be resumed.
+type ArchiveScan struct {
+	Client *Client
+	// Checkpoint, if set, is called after every window, e.g. to write the
+	// checkpoint to a file. An error stops the scan.
+	Checkpoint func(ArchiveCheckpoint) error
+
+	// MinWindow and MaxWindow bound the number of UIDs per window.
+	MinWindow uint32
+	MaxWindow uint32
+	// TargetLatency is how long a window should take. The window is doubled
+	// after faster ones and halved after slower ones.
+	TargetLatency time.Duration
+
+	// HeadersOnly fetches only the headers of the emails, see GetHeaders.
+	HeadersOnly bool
+}
+
+// NewArchiveScan initializes a new ArchiveScan with sensible windows.
+func NewArchiveScan(client *Client) *ArchiveScan {
+	return &ArchiveScan{
+		Client:        client,
+		MinWindow:     100,
+		MaxWindow:     20000,
+		TargetLatency: 10 * time.Second,
+	}
+}
+
+// Run scans the folder from the checkpoint, the zero ArchiveCheckpoint for a
+// fresh scan, passing the emails to handle newest first. Their flags are left
+// alone. An error from handle stops the scan and is returned along with the
+// checkpoint to resume from, which hands the whole window off again. If the
+// folder was reset since the checkpoint was taken ErrStateExpired is
+// returned.
+func (s *ArchiveScan) Run(ctx context.Context, from ArchiveCheckpoint, handle func(Email) error) (ArchiveCheckpoint, error) {
+	c := s.Client
+	checkpoint := ArchiveCheckpoint{UIDValidity: c.uidValidity(), Below: from.Below, Done: from.Done}
+	if from.UIDValidity != 0 && from.UIDValidity != checkpoint.UIDValidity {
+		return from, ErrStateExpired
+	}
+	if checkpoint.Done {
+		return checkpoint, nil
+	}
+
+	if checkpoint.Below == 0 {
+		top, err := s.top(ctx)
+		if err != nil {
+			return checkpoint, err
+		}
+		checkpoint.Below = top
+	}
+
+	window := s.MinWindow
+	if window == 0 {
+		window = 1
+	}
+	for checkpoint.Below > 1 {
+		low := uint32(1)
+		if checkpoint.Below > window {
+			low = checkpoint.Below - window
+		}
+
+		start := time.Now()
+		emails, err := s.window(ctx, low, checkpoint.Below-1)
+		if err != nil {
+			return checkpoint, err
+		}
+		window = nextWindow(window, time.Since(start), s.TargetLatency, s.MinWindow, s.MaxWindow)
+
+		for _, email := range emails {
+			if err = handle(email); err != nil {
+				return checkpoint, err
+			}
+		}
+
+		checkpoint.Below = low
+		checkpoint.Done = low == 1
+		if s.Checkpoint != nil {
+			if err = s.Checkpoint(checkpoint); err != nil {
+				return checkpoint, err
+			}
+		}
+	}
+	checkpoint.Done = true
+	return checkpoint, nil
+}
+
+// top returns the UID above the newest email, or the sequence number for
+// servers without UID commands.
+func (s *ArchiveScan) top(ctx context.Context) (uint32, error) {
+	c := s.Client
+	if c.SequenceNumbers {
+		mailbox := c.mailbox()
+		if mailbox == nil {
+			return 0, nil
+		}
+		return mailbox.Messages + 1, nil
+	}
+	return c.uidNext(ctx)
+}
+
+// window fetches the emails with UIDs from low to high, newest first.
+func (s *ArchiveScan) window(ctx context.Context, low, high uint32) ([]Email, error) {
+	c := s.Client
+	keys := []imap.Field{fmt.Sprintf("%d:%d", low, high)}
+	if !c.SequenceNumbers {
+		keys = append([]imap.Field{"UID"}, keys...)
+	}
+	cmd, err := c.findEmails(ctx, Query{keys: keys})
+	if err != nil {
+		return nil, err
+	}
+
+	uids := searchResults(cmd)
+	var responses chan Response
+	if s.HeadersOnly {
+		responses = make(chan Response, c.bufferSize())
+		go func() {
+			defer close(responses)
+			c.getHeaders(ctx, uids, responses)
+		}()
+	} else {
+		responses = c.generateUIDs(ctx, uids, false, false, true)
+	}
+
+	emails, err := c.collect(ctx, responses)
+	sort.SliceStable(emails, func(i, j int) bool {
+		return imap.AsNumber(emails[i].ID) > imap.AsNumber(emails[j].ID)
+	})
+	return emails, err
+}
+
+// nextWindow doubles the window if the last one took less than half the
+// target and halves it if it took more than the target, within the bounds.
+func nextWindow(window uint32, took, target time.Duration, min, max uint32) uint32 {
+	switch {
+	case took < target/2 && (max == 0 || window < max):
+		window *= 2
+	case took > target && window > min:
+		window /= 2
+	}
+	if window < min {
+		window = min
+	}
+	if max > 0 && window > max {
+		window = max
+	}
+	if window == 0 {
+		window = 1
+	}
+	return window
+}
@@ -0,0 +1,63 @@
+package eazye
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNextWindow(t *testing.T) {
+	target := 10 * time.Second
+	tests := []struct {
+		window uint32
+		took   time.Duration
+		want   uint32
+	}{
+		{100, time.Second, 200},
+		{100, 7 * time.Second, 100},
+		{400, 20 * time.Second, 200},
+		{100, 20 * time.Second, 100},
+		{15000, time.Second, 20000},
+	}
+	for _, test := range tests {
+		if got := nextWindow(test.window, test.took, target, 100, 20000); got != test.want {
+			t.Errorf("nextWindow(%d, %s) got %d, want %d", test.window, test.took, got, test.want)
+		}
+	}
+}
+
+func TestArchiveScanExpired(t *testing.T) {
+	scan := NewArchiveScan(&Client{})
+	_, err := scan.Run(context.Background(), ArchiveCheckpoint{UIDValidity: 7, Below: 100}, func(Email) error {
+		t.Error("Run() handed off an email for an expired checkpoint")
+		return nil
+	})
+	if err != ErrStateExpired {
+		t.Errorf("Run() got %v, want ErrStateExpired", err)
+	}
+}
+
+func TestArchiveScanLeavesFlags(t *testing.T) {
+	srv := testServer(t)
+	srv.AddMessage("INBOX", []byte("Subject: unread\r\n\r\n1\r\n"))
+	srv.AddMessage("INBOX", []byte("Subject: read\r\n\r\n2\r\n"), `\Seen`)
+
+	c := testClient(t, srv)
+	var handled []string
+	checkpoint, err := NewArchiveScan(c).Run(context.Background(), ArchiveCheckpoint{}, func(email Email) error {
+		handled = append(handled, email.Subject)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Run() returned an error: %s", err)
+	}
+	if !checkpoint.Done || len(handled) != 2 || handled[0] != "read" {
+		t.Errorf("Run() got %+v handling %q, want done handling [read unread]", checkpoint, handled)
+	}
+	if serverHasFlag(srv, "INBOX", 1, `\Seen`) || !serverHasFlag(srv, "INBOX", 2, `\Seen`) {
+		t.Errorf("Run() changed the flags: %+v", srv.Messages("INBOX"))
+	}
+	if hasCommand(srv, "UID STORE") {
+		t.Errorf("Run() stored flags: %q", srv.Commands())
+	}
+}
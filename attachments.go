@@ -0,0 +1,118 @@
+package eazye
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// AttachmentFilter decides which attachments GetAttachments will download.
+type AttachmentFilter func(uid uint32, part Part) bool
+
+// AttachmentSink receives the decoded content of each attachment downloaded
+// by GetAttachments.
+type AttachmentSink func(uid uint32, part Part, content io.Reader) error
+
+// MediaTypes is an AttachmentFilter that matches attachments of any of the
+// given media types, e.g. "application/pdf".
+func MediaTypes(types ...string) AttachmentFilter {
+	return func(uid uint32, part Part) bool {
+		for _, t := range types {
+			if part.MediaType() == t {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// GetAttachments will find all emails matching the query and pass along the
+// attachments accepted by the filter to the sink. Only the matching MIME parts
+// are fetched from the server and the emails are not marked as read. A nil
+// filter accepts all attachments.
+func (c *Client) GetAttachments(q Query, filter AttachmentFilter, sink AttachmentSink) error {
+	return c.GetAttachmentsContext(context.Background(), q, filter, sink)
+}
+
+// GetAttachmentsContext is GetAttachments with a context.
+func (c *Client) GetAttachmentsContext(ctx context.Context, q Query, filter AttachmentFilter, sink AttachmentSink) error {
+	cmd, err := c.findEmails(ctx, q)
+	if err != nil {
+		return err
+	}
+
+	// fetch the structures in batches like fetchEmails, so huge folders
+	// neither time out nor have to fit in memory all at once
+	for _, uids := range batches(searchResults(cmd), c.fetchBatchSize()) {
+		seq := &imap.SeqSet{}
+		seq.AddNum(uids...)
+		fCmd, err := c.do(ctx, func() (*imap.Command, error) {
+			return c.uidFetch(seq, "UID", "BODYSTRUCTURE")
+		})
+		if err != nil {
+			return fmt.Errorf("unable to perform uid fetch: %w", err)
+		}
+
+		for _, msgData := range fCmd.Data {
+			info := c.messageInfo(msgData)
+			// skip any unsolicited FETCH responses, same as getEmails
+			if _, ok := info.Attrs["BODYSTRUCTURE"]; !ok {
+				continue
+			}
+
+			var parts []Part
+			for _, part := range parseBodyStructure(info.Attrs["BODYSTRUCTURE"], "") {
+				if part.IsAttachment() && (filter == nil || filter(info.UID, part)) {
+					parts = append(parts, part)
+				}
+			}
+
+			if err = c.fetchParts(ctx, info.UID, parts, sink); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// fetchParts fetches the given MIME parts of a single message and passes them
+// along to the sink.
+func (c *Client) fetchParts(ctx context.Context, uid uint32, parts []Part, sink AttachmentSink) error {
+	if len(parts) == 0 {
+		return nil
+	}
+
+	items := []string{"UID"}
+	for _, part := range parts {
+		items = append(items, "BODY.PEEK["+part.Section+"]")
+	}
+
+	seq := &imap.SeqSet{}
+	seq.AddNum(uid)
+	fCmd, err := c.do(ctx, func() (*imap.Command, error) {
+		return c.uidFetch(seq, items...)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to fetch attachments of %d: %w", uid, err)
+	}
+
+	for _, msgData := range fCmd.Data {
+		attrs := c.messageInfo(msgData).Attrs
+		for _, part := range parts {
+			body, ok := attrs["BODY["+part.Section+"]"]
+			if !ok {
+				continue
+			}
+			content := decodeTransfer(part.Encoding, bytes.NewReader(imap.AsBytes(body)))
+			if err = sink(uid, part, content); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
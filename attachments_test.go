@@ -0,0 +1,61 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// attachmentEmail is a multipart email with a text part and a PDF attached.
+func attachmentEmail(name, content string) []byte {
+	return []byte("Subject: invoice\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=b\r\n" +
+		"\r\n" +
+		"--b\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"see attached\r\n" +
+		"--b\r\n" +
+		"Content-Type: application/pdf; name=" + name + "\r\n" +
+		"Content-Disposition: attachment; filename=" + name + "\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		content + "\r\n" +
+		"--b--\r\n")
+}
+
+func TestGetAttachments(t *testing.T) {
+	srv := testServer(t)
+	srv.AddMessage("INBOX", attachmentEmail("a.pdf", "JVBERi0x"))
+	srv.AddMessage("INBOX", []byte("Subject: plain\r\n\r\nno attachments\r\n"))
+	srv.AddMessage("INBOX", attachmentEmail("b.pdf", "JVBERi0y"))
+
+	c := testClient(t, srv, SetFetchBatchSize(2))
+	got := map[uint32]string{}
+	err := c.GetAttachments(All(), MediaTypes("application/pdf"), func(uid uint32, part Part, content io.Reader) error {
+		data, err := io.ReadAll(content)
+		got[uid] = part.Filename() + ":" + string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("GetAttachments() returned an error: %s", err)
+	}
+	if want := map[uint32]string{1: "a.pdf:%PDF-1", 3: "b.pdf:%PDF-2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAttachments() got %q, want %q", got, want)
+	}
+	if !hasCommand(srv, "UID FETCH 1:2 (UID BODYSTRUCTURE)") || !hasCommand(srv, "UID FETCH 3 (UID BODYSTRUCTURE)") {
+		t.Errorf("GetAttachments() got commands %q, want the structures fetched in batches", srv.Commands())
+	}
+	if serverHasFlag(srv, "INBOX", 1, `\Seen`) {
+		t.Errorf("GetAttachments() marked the email as read")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err = c.GetAttachmentsContext(ctx, All(), nil, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetAttachmentsContext() with a cancelled context got %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,161 @@
+package eazye
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// Snapshot records the state of every email in a folder at some point in
+// time, to check later on that nothing was deleted or tampered with.
+type Snapshot struct {
+	Folder      string          `json:"folder"`
+	UIDValidity uint32          `json:"uid_validity"`
+	TakenAt     time.Time       `json:"taken_at"`
+	Emails      []SnapshotEmail `json:"emails"`
+}
+
+// SnapshotEmail is a single email of a Snapshot. Hash is the hex encoded
+// SHA-256 of the full message.
+type SnapshotEmail struct {
+	UID   uint32   `json:"uid"`
+	Flags []string `json:"flags"`
+	Hash  string   `json:"hash"`
+}
+
+// snapshotBatchSize is the number of emails fetched per FETCH command while
+// taking a snapshot, to keep the memory used in check.
+const snapshotBatchSize = 200
+
+// Snapshot records the UIDs, flags and content hashes of all the emails in
+// the folder. The emails are left unread.
+func (c *Client) Snapshot(ctx context.Context) (Snapshot, error) {
+	snapshot := Snapshot{
+		Folder:      c.Folder,
+		UIDValidity: c.uidValidity(),
+		TakenAt:     time.Now(),
+	}
+
+	cmd, err := c.findEmails(ctx, All())
+	if err != nil {
+		return snapshot, err
+	}
+
+	for _, batch := range batches(searchResults(cmd), snapshotBatchSize) {
+		seq := &imap.SeqSet{}
+		seq.AddNum(batch...)
+
+		fCmd, err := c.do(ctx, func() (*imap.Command, error) {
+			return c.uidFetch(seq, "UID", "FLAGS", "BODY.PEEK[]")
+		})
+		if err != nil {
+			return snapshot, fmt.Errorf("unable to perform uid fetch: %w", err)
+		}
+		for _, msgData := range fCmd.Data {
+			info := c.messageInfo(msgData)
+			if _, ok := info.Attrs["BODY[]"]; !ok {
+				continue
+			}
+			sum := sha256.Sum256(imap.AsBytes(info.Attrs["BODY[]"]))
+			email := SnapshotEmail{UID: info.UID, Hash: hex.EncodeToString(sum[:])}
+			for flag := range info.Flags {
+				email.Flags = append(email.Flags, flag)
+			}
+			sort.Strings(email.Flags)
+			snapshot.Emails = append(snapshot.Emails, email)
+		}
+	}
+
+	sort.Slice(snapshot.Emails, func(i, j int) bool {
+		return snapshot.Emails[i].UID < snapshot.Emails[j].UID
+	})
+	return snapshot, nil
+}
+
+// ReadSnapshot reads a snapshot saved with WriteFile.
+func ReadSnapshot(path string) (Snapshot, error) {
+	var snapshot Snapshot
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot, err
+	}
+	err = json.Unmarshal(data, &snapshot)
+	return snapshot, err
+}
+
+// WriteFile saves the snapshot as JSON in the file at the given path.
+func (s Snapshot) WriteFile(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// AuditReport is what changed in a folder between two snapshots.
+type AuditReport struct {
+	// UIDValidityChanged is set if the folder was reset in between, in which
+	// case the UIDs can not be compared and every email shows up as deleted
+	// and added.
+	UIDValidityChanged bool
+	Deleted            []SnapshotEmail
+	Added              []SnapshotEmail
+	Modified           []AuditChange
+}
+
+// AuditChange is an email whose flags or content changed.
+type AuditChange struct {
+	Old, New SnapshotEmail
+}
+
+// ContentChanged tells whether the message itself changed, which a server
+// never does to an email once it has a UID.
+func (a AuditChange) ContentChanged() bool {
+	return a.Old.Hash != a.New.Hash
+}
+
+// Clean tells whether nothing was deleted or modified. New emails do not
+// count.
+func (r AuditReport) Clean() bool {
+	return !r.UIDValidityChanged && len(r.Deleted) == 0 && len(r.Modified) == 0
+}
+
+// CompareSnapshots reports the emails deleted, added and modified between
+// the old and the new snapshot of a folder.
+func CompareSnapshots(old, new Snapshot) AuditReport {
+	var report AuditReport
+	if old.UIDValidity != new.UIDValidity {
+		report.UIDValidityChanged = true
+		report.Deleted = old.Emails
+		report.Added = new.Emails
+		return report
+	}
+
+	current := make(map[uint32]SnapshotEmail, len(new.Emails))
+	for _, email := range new.Emails {
+		current[email.UID] = email
+	}
+	for _, email := range old.Emails {
+		now, ok := current[email.UID]
+		delete(current, email.UID)
+		switch {
+		case !ok:
+			report.Deleted = append(report.Deleted, email)
+		case now.Hash != email.Hash || !equalStrings(now.Flags, email.Flags):
+			report.Modified = append(report.Modified, AuditChange{Old: email, New: now})
+		}
+	}
+	for _, email := range new.Emails {
+		if _, ok := current[email.UID]; ok {
+			report.Added = append(report.Added, email)
+		}
+	}
+	return report
+}
@@ -0,0 +1,60 @@
+package eazye
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareSnapshots(t *testing.T) {
+	old := Snapshot{UIDValidity: 7, Emails: []SnapshotEmail{
+		{UID: 1, Hash: "a"},
+		{UID: 2, Hash: "b", Flags: []string{`\Seen`}},
+		{UID: 3, Hash: "c"},
+		{UID: 4, Hash: "d"},
+	}}
+	new := Snapshot{UIDValidity: 7, Emails: []SnapshotEmail{
+		{UID: 1, Hash: "a"},
+		{UID: 2, Hash: "b", Flags: []string{`\Flagged`, `\Seen`}},
+		{UID: 4, Hash: "x"},
+		{UID: 5, Hash: "e"},
+	}}
+
+	report := CompareSnapshots(old, new)
+	if report.Clean() {
+		t.Error("CompareSnapshots() got a clean report")
+	}
+	if len(report.Deleted) != 1 || report.Deleted[0].UID != 3 {
+		t.Errorf("CompareSnapshots() got deleted %+v, want uid 3", report.Deleted)
+	}
+	if len(report.Added) != 1 || report.Added[0].UID != 5 {
+		t.Errorf("CompareSnapshots() got added %+v, want uid 5", report.Added)
+	}
+	if len(report.Modified) != 2 || report.Modified[0].ContentChanged() || !report.Modified[1].ContentChanged() {
+		t.Errorf("CompareSnapshots() got modified %+v, want the flags of uid 2 and the content of uid 4", report.Modified)
+	}
+
+	if report = CompareSnapshots(old, old); !report.Clean() {
+		t.Errorf("CompareSnapshots() of a snapshot with itself got %+v", report)
+	}
+
+	new.UIDValidity = 8
+	if report = CompareSnapshots(old, new); !report.UIDValidityChanged || report.Clean() {
+		t.Errorf("CompareSnapshots() across a UIDVALIDITY change got %+v", report)
+	}
+}
+
+func TestSnapshotFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	want := Snapshot{Folder: "INBOX", UIDValidity: 7, Emails: []SnapshotEmail{{UID: 1, Hash: "a", Flags: []string{`\Seen`}}}}
+	if err := want.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadSnapshot(path)
+	if err != nil {
+		t.Fatalf("ReadSnapshot() returned an error: %s", err)
+	}
+	if got.Folder != want.Folder || got.UIDValidity != want.UIDValidity || len(got.Emails) != 1 || got.Emails[0].Hash != "a" {
+		t.Errorf("ReadSnapshot() got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+package eazye
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// AuditAction is something a Client did to an email.
+type AuditAction string
+
+// The actions an AuditRecord can be about.
+const (
+	AuditFetched      AuditAction = "fetched"
+	AuditMarkedRead   AuditAction = "marked read"
+	AuditMarkedUnread AuditAction = "marked unread"
+	AuditDeleted      AuditAction = "deleted"
+	AuditCopied       AuditAction = "copied"
+	AuditMoved        AuditAction = "moved"
+	AuditExported     AuditAction = "exported"
+	AuditAppended     AuditAction = "appended"
+)
+
+// AuditRecord tells what was done to which email and when.
+type AuditRecord struct {
+	Time   time.Time   `json:"time"`
+	Action AuditAction `json:"action"`
+	Folder string      `json:"folder"`
+	UID    uint32      `json:"uid"`
+	// Target is the folder the email was copied or moved to.
+	Target string `json:"target,omitempty"`
+}
+
+// AuditSink receives a record of every action a Client performs on an email,
+// once the server has confirmed it, e.g. to prove what some automation did
+// to a mailbox.
+type AuditSink interface {
+	Audit(record AuditRecord)
+}
+
+// AuditSinkFunc is an adapter to use an ordinary function as an AuditSink.
+type AuditSinkFunc func(record AuditRecord)
+
+// Audit calls f(record).
+func (f AuditSinkFunc) Audit(record AuditRecord) {
+	f(record)
+}
+
+// SetAuditSink is a functional option to set the AuditSink attr.
+func SetAuditSink(sink AuditSink) Option {
+	return func(c *Client) {
+		c.AuditSink = sink
+	}
+}
+
+// JSONAuditSink writes every record as a line of JSON. It is safe to share
+// between Clients.
+type JSONAuditSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewJSONAuditSink initializes a JSONAuditSink writing to w.
+func NewJSONAuditSink(w io.Writer) *JSONAuditSink {
+	return &JSONAuditSink{enc: json.NewEncoder(w)}
+}
+
+// Audit writes the record.
+func (s *JSONAuditSink) Audit(record AuditRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return
+	}
+	s.err = s.enc.Encode(record)
+}
+
+// Err returns the first error writing a record, after which nothing else is
+// written.
+func (s *JSONAuditSink) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// audit passes a record of the action along to the AuditSink, if there is
+// one.
+func (c *Client) audit(action AuditAction, uid uint32, target string) {
+	if c.AuditSink == nil {
+		return
+	}
+	c.AuditSink.Audit(AuditRecord{
+		Time:   time.Now(),
+		Action: action,
+		Folder: c.Folder,
+		UID:    uid,
+		Target: target,
+	})
+}
@@ -0,0 +1,37 @@
+package eazye
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestJSONAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+	c := &Client{Folder: "INBOX", AuditSink: NewJSONAuditSink(&buf)}
+	c.audit(AuditFetched, 3, "")
+	c.audit(AuditMoved, 3, "Archive")
+
+	dec := json.NewDecoder(&buf)
+	var records []AuditRecord
+	for dec.More() {
+		var record AuditRecord
+		if err := dec.Decode(&record); err != nil {
+			t.Fatalf("unable to decode record: %s", err)
+		}
+		records = append(records, record)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("JSONAuditSink wrote %d records, want 2", len(records))
+	}
+	if r := records[0]; r.Action != AuditFetched || r.UID != 3 || r.Folder != "INBOX" || r.Time.IsZero() {
+		t.Errorf("JSONAuditSink got unexpected record: %+v", r)
+	}
+	if r := records[1]; r.Action != AuditMoved || r.Target != "Archive" {
+		t.Errorf("JSONAuditSink got unexpected record: %+v", r)
+	}
+	if err := c.AuditSink.(*JSONAuditSink).Err(); err != nil {
+		t.Errorf("Err() got %s", err)
+	}
+}
@@ -0,0 +1,81 @@
+package eazye
+
+import (
+	"fmt"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// Authenticator logs in to the server once the IMAP session is established.
+type Authenticator interface {
+	Authenticate(c *imap.Client) error
+}
+
+// SetAuth is a functional option to set the Auth attr.
+func SetAuth(auth Authenticator) Option {
+	return func(c *Client) {
+		c.Auth = auth
+	}
+}
+
+// SetXOAuth2 is a functional option to authenticate with an OAuth 2.0 access
+// token using the XOAUTH2 SASL mechanism, as required by Gmail and Office 365.
+// Getting and refreshing the token is left to the caller.
+func SetXOAuth2(user, accessToken string) Option {
+	return SetAuth(XOAuth2(user, accessToken))
+}
+
+// Login returns an Authenticator that uses the plain LOGIN command. This is
+// what a Client uses if Auth is not set.
+func Login(user, pwd string) Authenticator {
+	return loginAuth{user: user, pwd: pwd}
+}
+
+type loginAuth struct {
+	user string
+	pwd  string
+}
+
+func (a loginAuth) Authenticate(c *imap.Client) error {
+	_, err := c.Login(a.user, a.pwd)
+	return err
+}
+
+// XOAuth2 returns an Authenticator that uses the XOAUTH2 SASL mechanism.
+func XOAuth2(user, accessToken string) Authenticator {
+	return saslAuth{&xoauth2{user: user, token: accessToken}}
+}
+
+// saslAuth adapts an imap.SASL mechanism to an Authenticator.
+type saslAuth struct {
+	mech imap.SASL
+}
+
+func (a saslAuth) Authenticate(c *imap.Client) error {
+	_, err := c.Auth(a.mech)
+	return err
+}
+
+type xoauth2 struct {
+	user  string
+	token string
+}
+
+func (a *xoauth2) Start(s *imap.ServerInfo) (string, []byte, error) {
+	ir := "user=" + a.user + "\x01auth=Bearer " + a.token + "\x01\x01"
+	return "XOAUTH2", []byte(ir), nil
+}
+
+// Next is only called when the token is rejected, the challenge then holds
+// the error details as JSON.
+func (a *xoauth2) Next(challenge []byte) ([]byte, error) {
+	return nil, fmt.Errorf("%w: xoauth2 rejected: %s", ErrAuthFailed, challenge)
+}
+
+// authenticator returns the Authenticator to log in with.
+func (c *Client) authenticator() Authenticator {
+	if c.Auth != nil {
+		return c.Auth
+	}
+	return Login(c.user, c.pwd)
+}
@@ -0,0 +1,62 @@
+package eazye
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestXOAuth2Start(t *testing.T) {
+	mech := &xoauth2{user: "someuser@example.com", token: "ya29.vF9dft4qmTc2Nvb3RlckBhdHRhdmlzdGEuY29tCg"}
+
+	name, ir, err := mech.Start(nil)
+	if err != nil {
+		t.Fatalf("Start() returned an error: %s", err)
+	}
+	if name != "XOAUTH2" {
+		t.Errorf("Start() got mechanism %q, want XOAUTH2", name)
+	}
+	want := "user=someuser@example.com\x01auth=Bearer ya29.vF9dft4qmTc2Nvb3RlckBhdHRhdmlzdGEuY29tCg\x01\x01"
+	if string(ir) != want {
+		t.Errorf("Start() got initial response %q, want %q", ir, want)
+	}
+
+	if _, err = mech.Next([]byte(`{"status":"401"}`)); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Next() for a rejected token got %v, want %s", err, ErrAuthFailed)
+	}
+}
+
+func TestAuthenticator(t *testing.T) {
+	c := &Client{user: "user", pwd: "pwd"}
+	if got := c.authenticator(); got != Login("user", "pwd") {
+		t.Errorf("authenticator() got %#v, want plain login", got)
+	}
+
+	SetXOAuth2("user", "token")(c)
+	if _, ok := c.authenticator().(saslAuth); !ok {
+		t.Errorf("authenticator() got %#v, want XOAUTH2", c.authenticator())
+	}
+}
+
+func TestLoginFailed(t *testing.T) {
+	srv := testServer(t)
+	srv.User, srv.Password = "user", "secret"
+
+	tests := []struct {
+		name     string
+		addr     string
+		pwd      string
+		wantAuth bool
+	}{
+		{"wrong password", srv.Addr, "wrong", true},
+		{"dropped", newDropProxy(t, srv.Addr, " LOGIN ").Addr().String(), "secret", false},
+	}
+	for _, tt := range tests {
+		_, err := New(tt.addr, "user", tt.pwd, SetFolder("INBOX"), SetRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+		if err == nil {
+			t.Fatalf("%s: New() returned no error", tt.name)
+		}
+		if got := errors.Is(err, ErrAuthFailed); got != tt.wantAuth {
+			t.Errorf("%s: New() got %v, want ErrAuthFailed %v", tt.name, err, tt.wantAuth)
+		}
+	}
+}
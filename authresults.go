@@ -0,0 +1,339 @@
+package eazye
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// AuthResult is the result of one authentication method in an
+// Authentication-Results header (RFC 8601), e.g. "dkim=pass
+// header.d=example.com".
+type AuthResult struct {
+	// Method is e.g. "spf", "dkim", "dmarc" or "arc", lower case.
+	Method string `json:"method"`
+	// Result is e.g. "pass", "fail", "softfail", "none" or "temperror",
+	// lower case.
+	Result string `json:"result"`
+	Reason string `json:"reason,omitempty"`
+	// Props holds the properties of the result, e.g. "smtp.mailfrom" or
+	// "header.d".
+	Props map[string]string `json:"props,omitempty"`
+}
+
+// AuthResults is an Authentication-Results header.
+type AuthResults struct {
+	// ServID is the authserv-id of the server that added the header, e.g.
+	// "mx.google.com".
+	ServID  string       `json:"serv_id"`
+	Results []AuthResult `json:"results,omitempty"`
+}
+
+// ARCSet is one set of ARC headers (RFC 8617), added by a server that
+// authenticated the email before passing it on, e.g. a mailing list.
+type ARCSet struct {
+	// Instance is the number of the set, 1 for the first server.
+	Instance int `json:"instance"`
+	// Chain is the cv= of the ARC-Seal: "none" for the first set, "pass"
+	// or "fail" for the chain of sets before it.
+	Chain string `json:"chain"`
+	// Domain is the d= of the ARC-Seal, the domain of the server.
+	Domain string `json:"domain"`
+	// Results are the ARC-Authentication-Results, what the server found.
+	Results AuthResults `json:"results"`
+}
+
+// AuthenticationStatus sums up how an email was authenticated by the
+// server that received it.
+type AuthenticationStatus struct {
+	// ServID is that of the Authentication-Results header the results
+	// come from, "" if there is none to trust.
+	ServID string `json:"serv_id,omitempty"`
+	// SPF, DKIM, DMARC and ARC are the results of the methods, "" if the
+	// server did not check it. DKIM is "pass" if any signature passed.
+	SPF   string `json:"spf,omitempty"`
+	DKIM  string `json:"dkim,omitempty"`
+	DMARC string `json:"dmarc,omitempty"`
+	ARC   string `json:"arc,omitempty"`
+	// FromDomain is the domain of the From address.
+	FromDomain string `json:"from_domain,omitempty"`
+	// SPFAligned and DKIMAligned are set if SPF, or a DKIM signature,
+	// passed for a domain of the same organization as FromDomain (relaxed
+	// alignment). Aligned is set if either is, which is what DMARC passes
+	// on, whatever the server said about DMARC.
+	SPFAligned  bool `json:"spf_aligned"`
+	DKIMAligned bool `json:"dkim_aligned"`
+	Aligned     bool `json:"aligned"`
+	// ARCChain are the ARC sets of the email, oldest first.
+	ARCChain []ARCSet `json:"arc_chain,omitempty"`
+}
+
+// resultPattern matches the key=value pairs of a result.
+var resultPattern = regexp.MustCompile(`([A-Za-z0-9_.\-/]+)\s*=\s*("(?:[^"\\]|\\.)*"|[^\s"]+)`)
+
+// AuthenticationResults returns the Authentication-Results headers of the
+// email, the newest, added by the last server, first.
+func (e Email) AuthenticationResults() []AuthResults {
+	if e.Message == nil {
+		return nil
+	}
+	var results []AuthResults
+	for _, value := range e.Message.Header["Authentication-Results"] {
+		results = append(results, parseAuthResults(value))
+	}
+	return results
+}
+
+// ARCChain returns the ARC sets of the email, oldest first. Sets missing
+// their ARC-Seal have no Chain or Domain.
+func (e Email) ARCChain() []ARCSet {
+	if e.Message == nil {
+		return nil
+	}
+
+	sets := map[int]*ARCSet{}
+	set := func(i int) *ARCSet {
+		if sets[i] == nil {
+			sets[i] = &ARCSet{Instance: i}
+		}
+		return sets[i]
+	}
+	for _, value := range e.Message.Header["Arc-Seal"] {
+		tags := parseTagList(value)
+		if i, err := strconv.Atoi(tags["i"]); err == nil {
+			set(i).Chain, set(i).Domain = strings.ToLower(tags["cv"]), strings.ToLower(tags["d"])
+		}
+	}
+	for _, value := range e.Message.Header["Arc-Authentication-Results"] {
+		instance, rest, _ := strings.Cut(value, ";")
+		_, n, _ := strings.Cut(instance, "=")
+		if i, err := strconv.Atoi(strings.TrimSpace(n)); err == nil {
+			set(i).Results = parseAuthResults(rest)
+		}
+	}
+
+	chain := make([]ARCSet, 0, len(sets))
+	for _, s := range sets {
+		chain = append(chain, *s)
+	}
+	sort.Slice(chain, func(i, j int) bool { return chain[i].Instance < chain[j].Instance })
+	return chain
+}
+
+// AuthenticationStatus sums up the Authentication-Results of the email and
+// checks the alignment of SPF and DKIM with the From domain itself. As any
+// server on the way, or the sender, can add Authentication-Results, only the
+// newest header of the trusted authserv-ids counts, e.g. "mx.google.com" for
+// Gmail. Without any the results are unknown: nothing passed and nothing is
+// aligned.
+func (e Email) AuthenticationStatus(trusted ...string) AuthenticationStatus {
+	var status AuthenticationStatus
+	if e.From != nil {
+		if i := strings.LastIndexByte(e.From.Address, '@'); i >= 0 {
+			status.FromDomain = strings.ToLower(e.From.Address[i+1:])
+		}
+	}
+	status.ARCChain = e.ARCChain()
+
+	var header *AuthResults
+	for _, results := range e.AuthenticationResults() {
+		if containsFold(trusted, results.ServID) {
+			header = &results
+			break
+		}
+	}
+	if header == nil {
+		return status
+	}
+	status.ServID = header.ServID
+
+	for _, result := range header.Results {
+		switch result.Method {
+		case "spf":
+			if status.SPF == "" {
+				status.SPF = result.Result
+			}
+			if result.Result == "pass" && alignedDomain(spfDomain(result), status.FromDomain) {
+				status.SPFAligned = true
+			}
+		case "dkim":
+			if status.DKIM == "" || result.Result == "pass" {
+				status.DKIM = result.Result
+			}
+			if result.Result == "pass" && alignedDomain(dkimDomain(result), status.FromDomain) {
+				status.DKIMAligned = true
+			}
+		case "dmarc":
+			if status.DMARC == "" {
+				status.DMARC = result.Result
+			}
+		case "arc":
+			if status.ARC == "" {
+				status.ARC = result.Result
+			}
+		}
+	}
+	status.Aligned = status.SPFAligned || status.DKIMAligned
+	return status
+}
+
+// parseAuthResults parses an Authentication-Results header, leniently.
+func parseAuthResults(value string) AuthResults {
+	segments := splitUnquoted(stripComments(value), ';')
+	fields := strings.Fields(segments[0])
+	if len(fields) == 0 {
+		return AuthResults{}
+	}
+
+	results := AuthResults{ServID: strings.ToLower(fields[0])}
+	for _, segment := range segments[1:] {
+		matches := resultPattern.FindAllStringSubmatch(segment, -1)
+		if len(matches) == 0 {
+			// "none" or garbage
+			continue
+		}
+		method, _, _ := strings.Cut(matches[0][1], "/")
+		result := AuthResult{Method: strings.ToLower(method), Result: strings.ToLower(unquote(matches[0][2]))}
+		for _, m := range matches[1:] {
+			key, val := strings.ToLower(m[1]), unquote(m[2])
+			if key == "reason" {
+				result.Reason = val
+				continue
+			}
+			if result.Props == nil {
+				result.Props = map[string]string{}
+			}
+			result.Props[key] = val
+		}
+		results.Results = append(results.Results, result)
+	}
+	return results
+}
+
+// parseTagList parses a DKIM style tag list, "a=1; b=2", to a map of lower
+// case tags.
+func parseTagList(value string) map[string]string {
+	tags := map[string]string{}
+	for _, tag := range strings.Split(value, ";") {
+		if key, val, ok := strings.Cut(tag, "="); ok {
+			tags[strings.ToLower(strings.TrimSpace(key))] = strings.Join(strings.Fields(val), "")
+		}
+	}
+	return tags
+}
+
+// stripComments removes the (comments) of a header outside of quotes,
+// nested ones included.
+func stripComments(value string) string {
+	var b strings.Builder
+	depth, quoted, escaped := 0, false, false
+	for _, r := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"' && depth == 0:
+			quoted = !quoted
+		case r == '(' && !quoted:
+			depth++
+			continue
+		case r == ')' && !quoted && depth > 0:
+			depth--
+			continue
+		}
+		if depth == 0 {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// splitUnquoted splits s at every sep outside of quotes.
+func splitUnquoted(s string, sep rune) []string {
+	var (
+		parts   []string
+		start   int
+		quoted  bool
+		escaped bool
+	)
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquote returns a value without its quotes, if it has them.
+func unquote(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		if s, err := strconv.Unquote(value); err == nil {
+			return s
+		}
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// spfDomain returns the domain SPF was checked for: that of the MAIL FROM,
+// or the HELO name if it was empty.
+func spfDomain(result AuthResult) string {
+	from := result.Props["smtp.mailfrom"]
+	if i := strings.LastIndexByte(from, '@'); i >= 0 {
+		return from[i+1:]
+	}
+	if from != "" {
+		return from
+	}
+	return result.Props["smtp.helo"]
+}
+
+// dkimDomain returns the domain of the DKIM signature: its d= tag, or the
+// domain of its i= tag.
+func dkimDomain(result AuthResult) string {
+	if d := result.Props["header.d"]; d != "" {
+		return d
+	}
+	i := result.Props["header.i"]
+	return i[strings.LastIndexByte(i, '@')+1:]
+}
+
+// alignedDomain tells whether the domains belong to the same organization,
+// that is have the same registrable domain.
+func alignedDomain(domain, from string) bool {
+	domain, from = strings.ToLower(strings.TrimSuffix(domain, ".")), strings.ToLower(strings.TrimSuffix(from, "."))
+	if domain == "" || from == "" {
+		return false
+	}
+	if domain == from {
+		return true
+	}
+	orgDomain, err := publicsuffix.EffectiveTLDPlusOne(domain)
+	if err != nil {
+		return false
+	}
+	orgFrom, err := publicsuffix.EffectiveTLDPlusOne(from)
+	return err == nil && orgDomain == orgFrom
+}
+
+// containsFold tells whether the list has s, compared case insensitively.
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
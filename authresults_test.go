@@ -0,0 +1,109 @@
+package eazye
+
+import (
+	"reflect"
+	"testing"
+)
+
+const authHeader = "Authentication-Results: mx.example.com;\r\n" +
+	"       dkim=pass (2048-bit key) header.d=mail.example.org header.s=s1 header.b=abc;\r\n" +
+	"       spf=pass (domain of bounces@bounce.example.net designates 192.0.2.1 as permitted sender) smtp.mailfrom=bounces@bounce.example.net;\r\n" +
+	"       dmarc=pass (p=REJECT) header.from=example.org;\r\n" +
+	"       arc=none reason=\"no; seals\"\r\n" +
+	"Authentication-Results: forged.example.com; spf=pass smtp.mailfrom=example.org\r\n" +
+	"ARC-Seal: i=2; a=rsa-sha256; t=1; cv=pass; d=lists.example.com; s=arc; b=x\r\n" +
+	"ARC-Authentication-Results: i=2; lists.example.com; dkim=pass header.d=example.org\r\n" +
+	"ARC-Seal: i=1; a=rsa-sha256; t=1; cv=none; d=Example.org; s=arc; b=x\r\n" +
+	"ARC-Authentication-Results: i=1; mx.example.org; spf=pass smtp.mailfrom=example.org\r\n" +
+	"From: Bob <bob@example.org>\r\n" +
+	"Subject: hi\r\n\r\n"
+
+func TestAuthenticationResults(t *testing.T) {
+	results := rawEmail(t, authHeader).AuthenticationResults()
+
+	want := []AuthResults{
+		{
+			ServID: "mx.example.com",
+			Results: []AuthResult{
+				{Method: "dkim", Result: "pass", Props: map[string]string{"header.d": "mail.example.org", "header.s": "s1", "header.b": "abc"}},
+				{Method: "spf", Result: "pass", Props: map[string]string{"smtp.mailfrom": "bounces@bounce.example.net"}},
+				{Method: "dmarc", Result: "pass", Props: map[string]string{"header.from": "example.org"}},
+				{Method: "arc", Result: "none", Reason: "no; seals"},
+			},
+		},
+		{
+			ServID:  "forged.example.com",
+			Results: []AuthResult{{Method: "spf", Result: "pass", Props: map[string]string{"smtp.mailfrom": "example.org"}}},
+		},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("AuthenticationResults() got %+v, want %+v", results, want)
+	}
+}
+
+func TestARCChain(t *testing.T) {
+	chain := rawEmail(t, authHeader).ARCChain()
+
+	want := []ARCSet{
+		{Instance: 1, Chain: "none", Domain: "example.org", Results: AuthResults{
+			ServID:  "mx.example.org",
+			Results: []AuthResult{{Method: "spf", Result: "pass", Props: map[string]string{"smtp.mailfrom": "example.org"}}},
+		}},
+		{Instance: 2, Chain: "pass", Domain: "lists.example.com", Results: AuthResults{
+			ServID:  "lists.example.com",
+			Results: []AuthResult{{Method: "dkim", Result: "pass", Props: map[string]string{"header.d": "example.org"}}},
+		}},
+	}
+	if !reflect.DeepEqual(chain, want) {
+		t.Errorf("ARCChain() got %+v, want %+v", chain, want)
+	}
+}
+
+func TestAuthenticationStatus(t *testing.T) {
+	email := rawEmail(t, authHeader)
+
+	status := email.AuthenticationStatus()
+	if status.ServID != "" || status.SPF != "" || status.DKIM != "" || status.Aligned || status.FromDomain != "example.org" {
+		t.Errorf("AuthenticationStatus() got %+v, want nothing trusted", status)
+	}
+
+	status = email.AuthenticationStatus("mx.example.com")
+	if status.ServID != "mx.example.com" || status.SPF != "pass" || status.DKIM != "pass" || status.DMARC != "pass" || status.ARC != "none" {
+		t.Errorf("AuthenticationStatus(mx) got %+v", status)
+	}
+	if status.FromDomain != "example.org" || status.SPFAligned || !status.DKIMAligned || !status.Aligned {
+		t.Errorf("AuthenticationStatus(mx) got alignment %+v", status)
+	}
+	if len(status.ARCChain) != 2 {
+		t.Errorf("AuthenticationStatus(mx) got %d ARC sets, want 2", len(status.ARCChain))
+	}
+
+	status = email.AuthenticationStatus("FORGED.example.com")
+	if status.ServID != "forged.example.com" || !status.SPFAligned || status.DKIM != "" {
+		t.Errorf("AuthenticationStatus(forged) got %+v", status)
+	}
+
+	if status = email.AuthenticationStatus("other.example.com"); status.ServID != "" || status.Aligned {
+		t.Errorf("AuthenticationStatus(other) got %+v, want nothing trusted", status)
+	}
+}
+
+func TestAlignedDomain(t *testing.T) {
+	tests := []struct {
+		domain, from string
+		want         bool
+	}{
+		{"example.org", "example.org", true},
+		{"mail.example.org", "example.org", true},
+		{"example.org.", "news.EXAMPLE.org", true},
+		{"example.net", "example.org", false},
+		{"", "example.org", false},
+		{"evil.co.uk", "example.co.uk", false},
+	}
+
+	for _, tt := range tests {
+		if got := alignedDomain(tt.domain, tt.from); got != tt.want {
+			t.Errorf("alignedDomain(%q, %q) got %v, want %v", tt.domain, tt.from, got, tt.want)
+		}
+	}
+}
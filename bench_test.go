@@ -0,0 +1,100 @@
+package eazye
+
+import (
+	"bytes"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// benchFields builds the FETCH attributes of a raw message the way the server
+// would return them.
+func benchFields(uid uint32, raw string) imap.FieldMap {
+	msg, _ := mail.ReadMessage(bytes.NewReader([]byte(raw)))
+	var header bytes.Buffer
+	for key, values := range msg.Header {
+		for _, value := range values {
+			fmt.Fprintf(&header, "%s: %s\r\n", key, value)
+		}
+	}
+	header.WriteString("\r\n")
+
+	return imap.FieldMap{
+		"UID":           uid,
+		"INTERNALDATE":  time.Date(2014, 8, 12, 10, 20, 6, 0, time.UTC),
+		"RFC822.HEADER": header.Bytes(),
+		"BODY[]":        []byte(raw),
+	}
+}
+
+func BenchmarkNewEmail(b *testing.B) {
+	fixtures := map[string]string{
+		"quoted":    quotedEmail,
+		"html":      htmlEmail,
+		"multipart": multipartEmail,
+	}
+	for name, raw := range fixtures {
+		fields := benchFields(1, raw)
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(raw)))
+			for i := 0; i < b.N; i++ {
+				if _, err := newEmail(fields); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkFolder fetches every message of large folders from a fake server
+// with GetAll, in batches of different sizes.
+func BenchmarkFolder(b *testing.B) {
+	raws := []string{quotedEmail, htmlEmail, multipartEmail}
+
+	for _, size := range []int{1000, 10000} {
+		srv := testServer(b)
+		for n := 0; n < size; n++ {
+			srv.AddMessage("INBOX", []byte(raws[n%len(raws)]))
+		}
+
+		for _, batch := range []int{100, DefaultFetchBatchSize, 2000} {
+			b.Run(strconv.Itoa(size)+"/batch-"+strconv.Itoa(batch), func(b *testing.B) {
+				c := testClient(b, srv, SetFetchBatchSize(batch), SetPeek(true))
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					emails, err := c.GetAll(false, false)
+					if err != nil {
+						b.Fatal(err)
+					}
+					if len(emails) != size {
+						b.Fatalf("GetAll() got %d emails, want %d", len(emails), size)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkParseBodyStructure(b *testing.B) {
+	given := []imap.Field{
+		[]imap.Field{
+			[]imap.Field{"text", "plain", []imap.Field{"charset", "utf-8"}, nil, nil, "7bit", uint32(12), uint32(1), nil, nil, nil},
+			[]imap.Field{"text", "html", []imap.Field{"charset", "utf-8"}, nil, nil, "quoted-printable", uint32(120), uint32(4), nil, nil, nil},
+			"alternative", []imap.Field{"boundary", "abc"}, nil, nil,
+		},
+		[]imap.Field{"application", "pdf", []imap.Field{"name", "invoice.pdf"}, nil, nil, "base64", uint32(2048), nil,
+			[]imap.Field{"attachment", []imap.Field{"filename", "invoice.pdf"}}, nil},
+		"mixed", []imap.Field{"boundary", "xyz"}, nil, nil,
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		parseBodyStructure(given, "")
+	}
+}
@@ -0,0 +1,183 @@
+package eazye
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// BlockKind is the kind of element a TextBlock comes from.
+type BlockKind string
+
+const (
+	BlockParagraph BlockKind = "paragraph"
+	BlockHeading   BlockKind = "heading"
+	BlockListItem  BlockKind = "list_item"
+	BlockTableCell BlockKind = "table_cell"
+	BlockQuote     BlockKind = "quote"
+	BlockPre       BlockKind = "pre"
+)
+
+// TextBlock is a block of the visible text of an HTML body.
+type TextBlock struct {
+	Kind BlockKind `json:"kind"`
+	// Tag is the element the block comes from, e.g. "h2" or "td", or "" for
+	// text outside of any.
+	Tag string `json:"tag"`
+	// Text has the whitespace collapsed, but for the line breaks of br
+	// elements. The text of pre blocks is as is.
+	Text string `json:"text"`
+	// Depth is the number of lists and quotes the block is in.
+	Depth int `json:"depth"`
+	// Links are the links in the block, in order.
+	Links []Link `json:"links,omitempty"`
+}
+
+// Link is a link in an HTML body.
+type Link struct {
+	// Text is the visible text of the link, whitespace collapsed.
+	Text string `json:"text"`
+	Href string `json:"href"`
+}
+
+// TextBlocks returns the visible text of an HTML body split into the blocks
+// it is laid out in, in order, for a plain text version with line breaks in
+// the right places. Text in a list item, table cell, heading, quote or pre is
+// of that kind, even if it is in a paragraph or div there, everything else is
+// a paragraph. Blocks without any text are dropped.
+func TextBlocks(body io.Reader) ([]TextBlock, error) {
+	doc, err := html.Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	w := &blockWriter{}
+	w.children(doc)
+	w.flush()
+	return w.blocks, nil
+}
+
+// TextBlocks returns the blocks of the visible text of the HTML body, see
+// TextBlocks, or a paragraph per line of the plain text body if it has no
+// HTML body.
+func (e Email) TextBlocks() ([]TextBlock, error) {
+	html, text, err := e.bodies()
+	if err != nil {
+		return nil, err
+	}
+	if len(html) > 0 {
+		return TextBlocks(bytes.NewReader(html))
+	}
+
+	var blocks []TextBlock
+	for _, line := range bodyLines(string(text)) {
+		if line = strings.TrimSpace(line); line != "" {
+			blocks = append(blocks, TextBlock{Kind: BlockParagraph, Text: line})
+		}
+	}
+	return blocks, nil
+}
+
+// blockWriter collects the blocks of an HTML tree.
+type blockWriter struct {
+	blocks []TextBlock
+	// text and links are those of the block being written
+	text  strings.Builder
+	links []Link
+	// kind and tag are those of the innermost block the writer is in
+	kind  BlockKind
+	tag   string
+	depth int
+}
+
+// flush adds the text written so far as a block, if there is any.
+func (w *blockWriter) flush() {
+	text := w.text.String()
+	w.text.Reset()
+	if w.kind != BlockPre {
+		lines := strings.Split(text, "\n")
+		for i, line := range lines {
+			lines[i] = strings.Join(strings.Fields(line), " ")
+		}
+		text = strings.Trim(strings.Join(lines, "\n"), "\n")
+	}
+	if strings.TrimSpace(text) != "" {
+		kind := w.kind
+		if kind == "" {
+			kind = BlockParagraph
+		}
+		w.blocks = append(w.blocks, TextBlock{Kind: kind, Tag: w.tag, Text: text, Depth: w.depth, Links: w.links})
+	}
+	w.links = nil
+}
+
+func (w *blockWriter) children(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		w.node(child)
+	}
+}
+
+func (w *blockWriter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text.WriteString(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		w.children(n)
+		return
+	}
+	if isNonVisible(n.Data) {
+		return
+	}
+
+	switch tag := n.Data; tag {
+	case "br":
+		w.text.WriteString("\n")
+	case "a":
+		text := strings.Join(strings.Fields(nodeText(n)), " ")
+		if href := strings.TrimSpace(attr(n, "href")); href != "" {
+			w.links = append(w.links, Link{Text: text, Href: href})
+		}
+		w.children(n)
+	case "ul", "ol":
+		w.depth++
+		w.children(n)
+		w.depth--
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		w.block(n, BlockHeading)
+	case "li", "dd", "dt":
+		w.block(n, BlockListItem)
+	case "td", "th":
+		w.block(n, BlockTableCell)
+	case "pre":
+		w.block(n, BlockPre)
+	case "blockquote":
+		w.depth++
+		w.block(n, BlockQuote)
+		w.depth--
+	default:
+		if blockTags[tag] {
+			w.block(n, "")
+			return
+		}
+		w.children(n)
+	}
+}
+
+// block writes the children of n as blocks of their own, of the given kind,
+// or of the kind of the block it is in if kind is "".
+func (w *blockWriter) block(n *html.Node, kind BlockKind) {
+	w.flush()
+	outerKind, outerTag := w.kind, w.tag
+	w.tag = n.Data
+	if kind != "" {
+		w.kind = kind
+	} else if w.kind == "" {
+		w.kind = BlockParagraph
+	}
+	w.children(n)
+	w.flush()
+	w.kind, w.tag = outerKind, outerTag
+}
@@ -0,0 +1,40 @@
+package eazye
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTextBlocks(t *testing.T) {
+	body := `<html><head><title>Newsletter</title></head><body>
+<h1>Weekly   news</h1>
+<p>Hello,<br>read <a href="https://example.com/post">the post</a> today.</p>
+<ul><li>One</li><li><p>Two</p></li></ul>
+<blockquote>Quoted</blockquote>
+<table><tr><td>Cell</td><td><b>Other</b> cell</td></tr></table>
+<pre>  a
+  b</pre>
+Bye
+</body></html>`
+
+	blocks, err := TextBlocks(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("TextBlocks() returned an error: %s", err)
+	}
+
+	want := []TextBlock{
+		{Kind: BlockHeading, Tag: "h1", Text: "Weekly news"},
+		{Kind: BlockParagraph, Tag: "p", Text: "Hello,\nread the post today.", Links: []Link{{Text: "the post", Href: "https://example.com/post"}}},
+		{Kind: BlockListItem, Tag: "li", Text: "One", Depth: 1},
+		{Kind: BlockListItem, Tag: "p", Text: "Two", Depth: 1},
+		{Kind: BlockQuote, Tag: "blockquote", Text: "Quoted", Depth: 1},
+		{Kind: BlockTableCell, Tag: "td", Text: "Cell"},
+		{Kind: BlockTableCell, Tag: "td", Text: "Other cell"},
+		{Kind: BlockPre, Tag: "pre", Text: "  a\n  b"},
+		{Kind: BlockParagraph, Text: "Bye"},
+	}
+	if !reflect.DeepEqual(blocks, want) {
+		t.Errorf("TextBlocks() got %+v, want %+v", blocks, want)
+	}
+}
@@ -0,0 +1,173 @@
+package eazye
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+)
+
+// HTMLBody returns the HTML body of the email decoded to UTF-8, or "" if it
+// has none.
+func (e Email) HTMLBody() (string, error) {
+	html, _, err := e.bodies()
+	return string(html), err
+}
+
+// TextBody returns the plain text body of the email decoded to UTF-8. For
+// emails without one the visible text of the HTML body is returned instead,
+// one line per block of text.
+func (e Email) TextBody() (string, error) {
+	html, text, err := e.bodies()
+	if err != nil || text != nil || html == nil {
+		return string(text), err
+	}
+
+	visible, err := VisibleText(bytes.NewReader(html))
+	return string(bytes.Join(visible, []byte("\n"))), err
+}
+
+// VisibleText returns all of the visible text of the HTML body, see
+// VisibleText, or the plain text body if there is no HTML.
+func (e Email) VisibleText(options ...TextOption) ([][]byte, error) {
+	if len(e.HTML) == 0 {
+		if len(e.Text) == 0 {
+			return nil, nil
+		}
+		return [][]byte{e.Text}, nil
+	}
+	return VisibleText(bytes.NewReader(e.HTML), options...)
+}
+
+// Raw returns the full message, headers included, exactly as fetched from
+// the server. For emails that were not fetched by a Client it is put back
+// together from the Message.
+func (e Email) Raw() ([]byte, error) {
+	return rawMessage(e)
+}
+
+// bodies returns the HTML and Text of the email, parsing the message for
+// emails that were not fetched by a Client.
+func (e Email) bodies() (html, text []byte, err error) {
+	if e.HTML != nil || e.Text != nil || e.raw != nil {
+		return e.HTML, e.Text, nil
+	}
+
+	raw, err := rawMessage(e)
+	if err != nil {
+		return nil, nil, err
+	}
+	html, text, _, err = parseBody(e.Message.Header, raw)
+	return html, text, err
+}
+
+// rawMessage returns the full message of the email. Emails that were not
+// fetched by a Client are put back together from their Message, whose body
+// is read and replaced so it can be read again.
+func rawMessage(email Email) ([]byte, error) {
+	if email.raw != nil {
+		return email.raw, nil
+	}
+	if email.Message == nil {
+		return nil, errors.New("email has no message")
+	}
+
+	body, err := io.ReadAll(email.Message.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read body: %w", err)
+	}
+	email.Message.Body = bytes.NewReader(body)
+
+	var raw bytes.Buffer
+	for key, values := range email.Message.Header {
+		for _, value := range values {
+			fmt.Fprintf(&raw, "%s: %s\r\n", key, value)
+		}
+	}
+	raw.WriteString("\r\n")
+	raw.Write(body)
+	return raw.Bytes(), nil
+}
+
+// parseBody pulls the HTML and plain text bodies out of a message, as fetched
+// with BODY[] so headers included, decoding them to UTF-8. Attachments are
+// skipped. The header tells how the message is encoded, the message's own is
+// used if it has no Content-Type.
+func parseBody(header mail.Header, body []byte) (html, text []byte, multipart bool, err error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("unable to read message: %w", err)
+	}
+	if header.Get("Content-Type") == "" {
+		header = msg.Header
+	}
+
+	contentType := header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	multipart = strings.HasPrefix(mediaType, "multipart/")
+
+	text, html, err = findText(contentType, header.Get("Content-Transfer-Encoding"), msg.Body)
+	return html, text, multipart, err
+}
+
+// findText walks a MIME part, and its children for multiparts, for the first
+// text/plain and text/html bodies that are not attachments.
+func findText(contentType, encoding string, body io.Reader) (plain, htmlBody []byte, err error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if contentType == "" || err != nil {
+		// RFC 2045 says to assume plain text
+		mediaType, params, err = "text/plain", map[string]string{}, nil
+	}
+
+	switch {
+	case mediaType == "text/plain":
+		plain, err = readText(params["charset"], encoding, body)
+		return plain, nil, err
+	case mediaType == "text/html":
+		htmlBody, err = readText(params["charset"], encoding, body)
+		return nil, htmlBody, err
+	case !strings.HasPrefix(mediaType, "multipart/"):
+		return nil, nil, nil
+	}
+
+	mr := multipart.NewReader(body, params["boundary"])
+	for plain == nil || htmlBody == nil {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return plain, htmlBody, fmt.Errorf("unable to read MIME part: %w", err)
+		}
+		if disposition, _, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition")); disposition == "attachment" {
+			continue
+		}
+
+		// quoted-printable parts are decoded by the multipart reader itself
+		p, h, err := findText(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+		if err != nil {
+			return plain, htmlBody, err
+		}
+		if plain == nil {
+			plain = p
+		}
+		if htmlBody == nil {
+			htmlBody = h
+		}
+	}
+	return plain, htmlBody, nil
+}
+
+// readText reads a text part, undoing its transfer encoding and converting it
+// from its charset to UTF-8.
+func readText(charsetName, encoding string, body io.Reader) ([]byte, error) {
+	r, err := decodeCharset(charsetName, decodeTransfer(encoding, body))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
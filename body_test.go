@@ -0,0 +1,64 @@
+package eazye
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+func TestBodies(t *testing.T) {
+	email, err := newEmail(imap.FieldMap{
+		"RFC822.HEADER": []byte(multipartHeader),
+		"BODY[]":        []byte(multipartHeader + multipartBody),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !email.IsMultiPart {
+		t.Error("newEmail() did not set IsMultiPart")
+	}
+
+	text, err := email.TextBody()
+	if err != nil {
+		t.Fatalf("TextBody() returned an error: %s", err)
+	}
+	if text != "Café at 10" {
+		t.Errorf("TextBody() got %q, want %q", text, "Café at 10")
+	}
+
+	html, err := email.HTMLBody()
+	if err != nil {
+		t.Fatalf("HTMLBody() returned an error: %s", err)
+	}
+	if html != "<p>Caf&eacute; at 10</p>" {
+		t.Errorf("HTMLBody() got %q, want %q", html, "<p>Caf&eacute; at 10</p>")
+	}
+}
+
+func TestBodiesFromMessage(t *testing.T) {
+	msg, err := mail.ReadMessage(strings.NewReader("Subject: hi\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n" +
+		"Caf=C3=A9 at 10"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := Email{Message: msg}
+
+	text, err := email.TextBody()
+	if err != nil {
+		t.Fatalf("TextBody() returned an error: %s", err)
+	}
+	if text != "Café at 10" {
+		t.Errorf("TextBody() got %q, want %q", text, "Café at 10")
+	}
+
+	// the body can be read again
+	if again, _ := email.TextBody(); again != text {
+		t.Errorf("TextBody() a second time got %q, want %q", again, text)
+	}
+	if html, err := email.HTMLBody(); html != "" || err != nil {
+		t.Errorf("HTMLBody() got %q, %v, want no HTML", html, err)
+	}
+}
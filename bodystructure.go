@@ -0,0 +1,137 @@
+package eazye
+
+import (
+	"encoding/base64"
+	"io"
+	"mime/quotedprintable"
+	"strconv"
+	"strings"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// Part describes a single (non multipart) MIME part of a message as reported
+// by the server in the BODYSTRUCTURE of the message.
+type Part struct {
+	// Section is the IMAP section specifier of the part, e.g. "1.2".
+	Section string
+	// Type and Subtype are always lower case, e.g. "application" and "pdf".
+	Type        string
+	Subtype     string
+	Params      map[string]string
+	ID          string
+	Description string
+	Encoding    string
+	Size        uint32
+
+	Disposition       string
+	DispositionParams map[string]string
+}
+
+// MediaType returns the full media type of the part, e.g. "application/pdf".
+func (p Part) MediaType() string {
+	return p.Type + "/" + p.Subtype
+}
+
+// Filename returns the decoded file name of the part, if it has one.
+func (p Part) Filename() string {
+	name := p.DispositionParams["filename"]
+	if name == "" {
+		name = p.Params["name"]
+	}
+	return DecodeHeader(name)
+}
+
+// IsAttachment reports whether the part is an attachment rather than a part
+// of the message body.
+func (p Part) IsAttachment() bool {
+	return p.Disposition == "attachment" || p.Filename() != ""
+}
+
+// parseBodyStructure walks a BODYSTRUCTURE response and returns all of the
+// leaf parts it describes. Attached messages are not descended into.
+func parseBodyStructure(f imap.Field, section string) []Part {
+	fields := imap.AsList(f)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	// multipart bodies start with the list of their children
+	if imap.TypeOf(fields[0]) == imap.List {
+		var parts []Part
+		for i, child := range fields {
+			if imap.TypeOf(child) != imap.List {
+				break
+			}
+			parts = append(parts, parseBodyStructure(child, joinSection(section, i+1))...)
+		}
+		return parts
+	}
+
+	if len(fields) < 7 {
+		return nil
+	}
+	if section == "" {
+		section = "1"
+	}
+
+	p := Part{
+		Section:     section,
+		Type:        strings.ToLower(imap.AsString(fields[0])),
+		Subtype:     strings.ToLower(imap.AsString(fields[1])),
+		Params:      parseParams(fields[2]),
+		ID:          imap.AsString(fields[3]),
+		Description: imap.AsString(fields[4]),
+		Encoding:    strings.ToLower(imap.AsString(fields[5])),
+		Size:        imap.AsNumber(fields[6]),
+	}
+
+	// the extension data comes after some type specific fields and the MD5
+	ext := 8
+	switch {
+	case p.Type == "text":
+		ext = 9
+	case p.Type == "message" && p.Subtype == "rfc822":
+		ext = 11
+	}
+	if len(fields) > ext {
+		dsp := imap.AsList(fields[ext])
+		if len(dsp) > 0 {
+			p.Disposition = strings.ToLower(imap.AsString(dsp[0]))
+		}
+		if len(dsp) > 1 {
+			p.DispositionParams = parseParams(dsp[1])
+		}
+	}
+
+	return []Part{p}
+}
+
+// parseParams turns a BODYSTRUCTURE parameter list into a map with lower
+// case keys.
+func parseParams(f imap.Field) map[string]string {
+	list := imap.AsList(f)
+	params := make(map[string]string, len(list)/2)
+	for i := 0; i+1 < len(list); i += 2 {
+		params[strings.ToLower(imap.AsString(list[i]))] = imap.AsString(list[i+1])
+	}
+	return params
+}
+
+func joinSection(parent string, n int) string {
+	if parent == "" {
+		return strconv.Itoa(n)
+	}
+	return parent + "." + strconv.Itoa(n)
+}
+
+// decodeTransfer undoes the content transfer encoding of a MIME part.
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(encoding) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
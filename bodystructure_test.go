@@ -0,0 +1,45 @@
+package eazye
+
+import (
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+func TestParseBodyStructure(t *testing.T) {
+	// (("text" "plain" ("charset" "utf-8") NIL NIL "7bit" 12 1 NIL NIL NIL)
+	//  ("application" "pdf" ("name" "invoice.pdf") NIL NIL "base64" 2048 NIL ("attachment" ("filename" "invoice.pdf")) NIL)
+	//  "mixed" ("boundary" "xyz") NIL NIL)
+	given := []imap.Field{
+		[]imap.Field{"text", "plain", []imap.Field{"charset", "utf-8"}, nil, nil, "7bit", uint32(12), uint32(1), nil, nil, nil},
+		[]imap.Field{"application", "PDF", []imap.Field{"name", "invoice.pdf"}, nil, nil, "BASE64", uint32(2048), nil,
+			[]imap.Field{"attachment", []imap.Field{"FILENAME", "invoice.pdf"}}, nil},
+		"mixed", []imap.Field{"boundary", "xyz"}, nil, nil,
+	}
+
+	parts := parseBodyStructure(given, "")
+	if len(parts) != 2 {
+		t.Fatalf("parseBodyStructure() got %d parts, want 2", len(parts))
+	}
+
+	if parts[0].Section != "1" || parts[0].MediaType() != "text/plain" || parts[0].IsAttachment() {
+		t.Errorf("parseBodyStructure() got unexpected first part: %+v", parts[0])
+	}
+
+	pdf := parts[1]
+	if pdf.Section != "2" || pdf.MediaType() != "application/pdf" || pdf.Encoding != "base64" || pdf.Size != 2048 {
+		t.Errorf("parseBodyStructure() got unexpected second part: %+v", pdf)
+	}
+	if !pdf.IsAttachment() || pdf.Filename() != "invoice.pdf" {
+		t.Errorf("parseBodyStructure() did not find the attachment: %+v", pdf)
+	}
+}
+
+func TestParseBodyStructureSinglePart(t *testing.T) {
+	given := []imap.Field{"text", "html", nil, nil, nil, "quoted-printable", uint32(120), uint32(4)}
+
+	parts := parseBodyStructure(given, "")
+	if len(parts) != 1 || parts[0].Section != "1" || parts[0].MediaType() != "text/html" {
+		t.Errorf("parseBodyStructure() got unexpected parts: %+v", parts)
+	}
+}
@@ -0,0 +1,176 @@
+package eazye
+
+import (
+	"bufio"
+	"bytes"
+	"mime"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+)
+
+// BounceType tells whether delivery failed for good or may still succeed.
+type BounceType string
+
+const (
+	// HardBounce is a permanent failure, e.g. for an unknown address. The
+	// address should not be sent to again.
+	HardBounce BounceType = "hard"
+	// SoftBounce is a temporary failure, e.g. for a full mailbox or a
+	// delayed delivery. Sending again later may work.
+	SoftBounce BounceType = "soft"
+)
+
+// Bounce is a recipient delivery failed to, as reported by a delivery status
+// notification (DSN, RFC 3464).
+type Bounce struct {
+	// Recipient is the address delivery failed to, the original one if the
+	// DSN has it.
+	Recipient string `json:"recipient"`
+	// Action is "failed" or "delayed".
+	Action string `json:"action"`
+	// Status is the enhanced status code (RFC 3463), e.g. "5.1.1".
+	Status string `json:"status"`
+	// Diagnostic is the error the server reported, e.g. "550 5.1.1 User
+	// unknown", without the type of the code.
+	Diagnostic string `json:"diagnostic,omitempty"`
+	// RemoteMTA is the server that reported it, without the type of the
+	// name.
+	RemoteMTA string `json:"remote_mta,omitempty"`
+	// MessageID is that of the email that bounced, if the DSN includes it.
+	MessageID string     `json:"message_id,omitempty"`
+	Type      BounceType `json:"type"`
+}
+
+var (
+	// enhancedStatus matches an enhanced status code in a diagnostic.
+	enhancedStatus = regexp.MustCompile(`\b([245])\.(\d{1,3})\.(\d{1,3})\b`)
+	// replyCode matches the SMTP reply code at the start of a diagnostic.
+	replyCode = regexp.MustCompile(`^([245])\d\d\b`)
+)
+
+// IsBounce tells whether the email is a delivery status notification, a
+// multipart/report with a report-type of delivery-status.
+func (e Email) IsBounce() bool {
+	if e.Message == nil {
+		return false
+	}
+	mediaType, params, err := mime.ParseMediaType(e.Message.Header.Get("Content-Type"))
+	return err == nil && mediaType == "multipart/report" && strings.EqualFold(params["report-type"], "delivery-status")
+}
+
+// Bounces returns the recipients a delivery status notification reports
+// delivery failed or was delayed for, and nil for other emails, see
+// IsBounce. Recipients delivered to are left out.
+func (e Email) Bounces() ([]Bounce, error) {
+	if !e.IsBounce() {
+		return nil, nil
+	}
+	parts, err := e.Parts()
+	if err != nil {
+		return nil, err
+	}
+
+	var status, messageID string
+	for _, part := range parts {
+		switch part.MediaType() {
+		case "message/delivery-status", "message/global-delivery-status":
+			status = string(part.Content)
+		case "message/rfc822", "text/rfc822-headers", "message/global", "message/global-headers":
+			if msg, err := mail.ReadMessage(bytes.NewReader(append(part.Content, "\r\n\r\n"...))); err == nil {
+				messageID = msg.Header.Get("Message-Id")
+			}
+		}
+	}
+
+	blocks := statusBlocks(status)
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+
+	var bounces []Bounce
+	// the first block has the fields of the whole message, the others
+	// those of a recipient each
+	for _, fields := range blocks[1:] {
+		bounce := Bounce{
+			Recipient:  withoutType(fields.Get("Original-Recipient")),
+			Action:     strings.ToLower(fields.Get("Action")),
+			Status:     fields.Get("Status"),
+			Diagnostic: withoutType(fields.Get("Diagnostic-Code")),
+			RemoteMTA:  withoutType(fields.Get("Remote-Mta")),
+			MessageID:  messageID,
+		}
+		if bounce.Recipient == "" {
+			bounce.Recipient = withoutType(fields.Get("Final-Recipient"))
+		}
+		if bounce.Action != "failed" && bounce.Action != "delayed" {
+			continue
+		}
+		if bounce.Status, _, _ = strings.Cut(bounce.Status, " "); bounce.Status == "" {
+			bounce.Status = diagnosticStatus(bounce.Diagnostic)
+		}
+		bounce.Type = bounceType(bounce.Action, bounce.Status)
+		bounces = append(bounces, bounce)
+	}
+	return bounces, nil
+}
+
+// statusBlocks returns the blocks of fields of a delivery-status part, which
+// are separated by blank lines.
+func statusBlocks(status string) []textproto.MIMEHeader {
+	var (
+		blocks []textproto.MIMEHeader
+		block  []string
+	)
+	flush := func() {
+		if len(block) == 0 {
+			return
+		}
+		r := textproto.NewReader(bufio.NewReader(strings.NewReader(strings.Join(block, "\r\n") + "\r\n\r\n")))
+		// keep what could be read of a broken block
+		fields, _ := r.ReadMIMEHeader()
+		blocks = append(blocks, fields)
+		block = nil
+	}
+	for _, line := range bodyLines(status) {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		block = append(block, line)
+	}
+	flush()
+	return blocks
+}
+
+// withoutType returns the value of a field like "rfc822; bob@example.com"
+// without the type before the semicolon.
+func withoutType(value string) string {
+	if _, v, ok := strings.Cut(value, ";"); ok {
+		value = v
+	}
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// diagnosticStatus returns the enhanced status code of a diagnostic, or one
+// made up of the class of its SMTP reply code, or "" if it has neither.
+func diagnosticStatus(diagnostic string) string {
+	if m := enhancedStatus.FindString(diagnostic); m != "" {
+		return m
+	}
+	if m := replyCode.FindStringSubmatch(diagnostic); m != nil {
+		return m[1] + ".0.0"
+	}
+	return ""
+}
+
+// bounceType classifies a bounce by its action and status. Delays and
+// temporary failures are soft, as are full mailboxes (5.2.2), which many
+// servers report as permanent. Failures without a status are hard.
+func bounceType(action, status string) BounceType {
+	if action == "delayed" || strings.HasPrefix(status, "4.") || status == "5.2.2" {
+		return SoftBounce
+	}
+	return HardBounce
+}
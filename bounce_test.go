@@ -0,0 +1,112 @@
+package eazye
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+const bounceHeader = "From: Mail Delivery System <MAILER-DAEMON@mx.example.com>\r\n" +
+	"Subject: Undelivered Mail Returned to Sender\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/report; report-type=delivery-status; boundary=\"dsn\"\r\n\r\n"
+
+const bounceBody = "--dsn\r\nContent-Type: text/plain\r\n\r\nI'm sorry to have to inform you that your message could not be delivered.\r\n" +
+	"--dsn\r\nContent-Type: message/delivery-status\r\n\r\n" +
+	"Reporting-MTA: dns; mx.example.com\r\n" +
+	"Arrival-Date: Mon, 12 Oct 2026 10:00:00 +0000\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; bob@example.org\r\n" +
+	"Original-Recipient: rfc822;Bob@Example.org\r\n" +
+	"Action: failed\r\n" +
+	"Status: 5.1.1\r\n" +
+	"Remote-MTA: dns; mx.example.org\r\n" +
+	"Diagnostic-Code: smtp; 550 5.1.1 <bob@example.org>: Recipient address\r\n" +
+	"    rejected: User unknown\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; carol@example.org\r\n" +
+	"Action: delayed\r\n" +
+	"Status: 4.4.1\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; dave@example.org\r\n" +
+	"Action: failed\r\n" +
+	"Diagnostic-Code: smtp; 552 5.2.2 Mailbox full\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; erin@example.org\r\n" +
+	"Action: delivered\r\n" +
+	"Status: 2.0.0\r\n" +
+	"\r\n" +
+	"--dsn\r\nContent-Type: text/rfc822-headers\r\n\r\n" +
+	"Message-ID: <42@example.com>\r\nSubject: Hello\r\n" +
+	"--dsn--\r\n"
+
+func TestBounces(t *testing.T) {
+	email, err := newEmail(imap.FieldMap{
+		"RFC822.HEADER": []byte(bounceHeader),
+		"BODY[]":        []byte(bounceHeader + bounceBody),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !email.IsBounce() {
+		t.Fatal("IsBounce() got false, want true")
+	}
+	bounces, err := email.Bounces()
+	if err != nil {
+		t.Fatalf("Bounces() returned an error: %s", err)
+	}
+
+	want := []Bounce{
+		{
+			Recipient:  "Bob@Example.org",
+			Action:     "failed",
+			Status:     "5.1.1",
+			Diagnostic: "550 5.1.1 <bob@example.org>: Recipient address rejected: User unknown",
+			RemoteMTA:  "mx.example.org",
+			MessageID:  "<42@example.com>",
+			Type:       HardBounce,
+		},
+		{Recipient: "carol@example.org", Action: "delayed", Status: "4.4.1", MessageID: "<42@example.com>", Type: SoftBounce},
+		{Recipient: "dave@example.org", Action: "failed", Status: "5.2.2", Diagnostic: "552 5.2.2 Mailbox full", MessageID: "<42@example.com>", Type: SoftBounce},
+	}
+	if !reflect.DeepEqual(bounces, want) {
+		t.Errorf("Bounces() got %+v, want %+v", bounces, want)
+	}
+}
+
+func TestBouncesNotDSN(t *testing.T) {
+	email, err := newEmail(imap.FieldMap{
+		"RFC822.HEADER": []byte(multipartHeader),
+		"BODY[]":        []byte(multipartHeader + multipartBody),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if email.IsBounce() {
+		t.Error("IsBounce() got true, want false")
+	}
+	if bounces, err := email.Bounces(); bounces != nil || err != nil {
+		t.Errorf("Bounces() got %v, %v, want nil", bounces, err)
+	}
+}
+
+func TestDiagnosticStatus(t *testing.T) {
+	tests := []struct {
+		diagnostic string
+		want       string
+	}{
+		{"550 5.1.1 User unknown", "5.1.1"},
+		{"421 Try again later", "4.0.0"},
+		{"#5.7.1 smtp;550 Access denied", "5.7.1"},
+		{"Host not found", ""},
+	}
+
+	for _, tt := range tests {
+		if got := diagnosticStatus(tt.diagnostic); got != tt.want {
+			t.Errorf("diagnosticStatus(%q) got %q, want %q", tt.diagnostic, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package eazye
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mxk/go-imap/imap"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// StoreWhere adds the flag to, or removes it from, all of the emails matching
+// the query without fetching any of them, e.g. StoreWhere(Unread(), `\Seen`,
+// true) to mark the whole folder as read. The flags are changed with one
+// STORE per FetchBatchSize emails rather than one per email. It returns the
+// number of emails changed, which falls short of those matched if a STORE
+// fails.
+func (c *Client) StoreWhere(q Query, flag string, add bool) (int, error) {
+	return c.StoreWhereContext(context.Background(), q, flag, add)
+}
+
+// StoreWhereContext is StoreWhere with a context.
+func (c *Client) StoreWhereContext(ctx context.Context, q Query, flag string, add bool) (int, error) {
+	uids, err := c.storeWhere(ctx, q, flag, add)
+	return len(uids), err
+}
+
+// storeWhere changes the flag of the emails matching the query and returns
+// the UIDs of those changed.
+func (c *Client) storeWhere(ctx context.Context, q Query, flag string, add bool) ([]uint32, error) {
+	if c.SafeMode {
+		return nil, ErrReadOnlyMode
+	}
+	cmd, err := c.findEmails(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	var stored []uint32
+	item := "-FLAGS.SILENT"
+	if add {
+		item = "+FLAGS.SILENT"
+	}
+	for _, batchUIDs := range batches(searchResults(cmd), c.fetchBatchSize()) {
+		seq := &imap.SeqSet{}
+		seq.AddNum(batchUIDs...)
+		storeCtx, span := c.startSpan(ctx, "eazye.Store",
+			attribute.String("imap.flags", item+" "+flag), attribute.Int("imap.emails", len(batchUIDs)))
+		_, err := c.do(storeCtx, func() (*imap.Command, error) {
+			return c.uidStore(seq, item, flag)
+		})
+		endSpan(span, err)
+		if err != nil {
+			return stored, fmt.Errorf("unable to store flags: %w", err)
+		}
+		for _, uid := range batchUIDs {
+			c.auditStore(uid, flag, add)
+		}
+		stored = append(stored, batchUIDs...)
+	}
+	return stored, nil
+}
+
+// DeleteWhere deletes all of the emails matching the query without fetching
+// any of them, e.g. DeleteWhere(Before(cutoff), true) for a retention job.
+// With expunge set they are removed from the folder right away, which without
+// UIDPLUS (RFC 4315) on the server removes any other email flagged as deleted
+// as well. It returns the number of emails deleted.
+func (c *Client) DeleteWhere(q Query, expunge bool) (int, error) {
+	return c.DeleteWhereContext(context.Background(), q, expunge)
+}
+
+// DeleteWhereContext is DeleteWhere with a context.
+func (c *Client) DeleteWhereContext(ctx context.Context, q Query, expunge bool) (int, error) {
+	uids, err := c.storeWhere(ctx, q, `\Deleted`, true)
+	if err != nil || !expunge || len(uids) == 0 {
+		return len(uids), err
+	}
+	seq := &imap.SeqSet{}
+	seq.AddNum(uids...)
+	return len(uids), c.expunge(ctx, seq)
+}
@@ -0,0 +1,108 @@
+package eazye
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStoreWhere(t *testing.T) {
+	tests := []struct {
+		name       string
+		q          Query
+		flag       string
+		add        bool
+		want       int
+		wantStores int
+		// wantFlagged says which of the emails "a1", "a2" and "b", which
+		// is read, end up with the flag
+		wantFlagged []bool
+	}{
+		{"mark read", Subject("a"), `\Seen`, true, 2, 1, []bool{true, true, true}},
+		{"flag all in batches", All(), `\Flagged`, true, 3, 2, []bool{true, true, true}},
+		{"mark unread", Subject("b"), `\Seen`, false, 1, 1, []bool{false, false, false}},
+		{"no match", Subject("none"), `\Seen`, true, 0, 0, []bool{false, false, true}},
+	}
+
+	for _, tt := range tests {
+		srv := testServer(t)
+		uids := []uint32{
+			srv.AddMessage("INBOX", []byte("Subject: a1\r\n\r\nx\r\n")),
+			srv.AddMessage("INBOX", []byte("Subject: a2\r\n\r\nx\r\n")),
+			srv.AddMessage("INBOX", []byte("Subject: b\r\n\r\nx\r\n"), `\Seen`),
+		}
+		c := testClient(t, srv, SetFetchBatchSize(2))
+		srv.ResetCommands()
+
+		n, err := c.StoreWhere(tt.q, tt.flag, tt.add)
+		if err != nil {
+			t.Fatalf("%s: StoreWhere() returned an error: %s", tt.name, err)
+		}
+		if n != tt.want {
+			t.Errorf("%s: StoreWhere() got %d, want %d", tt.name, n, tt.want)
+		}
+		stores := 0
+		for _, command := range srv.Commands() {
+			if strings.HasPrefix(command, "UID STORE") {
+				stores++
+			}
+		}
+		if stores != tt.wantStores {
+			t.Errorf("%s: StoreWhere() sent %d STOREs, want %d", tt.name, stores, tt.wantStores)
+		}
+		for i, uid := range uids {
+			if got := serverHasFlag(srv, "INBOX", uid, tt.flag); got != tt.wantFlagged[i] {
+				t.Errorf("%s: StoreWhere() left email %d with %s %v, want %v", tt.name, uid, tt.flag, got, tt.wantFlagged[i])
+			}
+		}
+		if hasCommand(srv, "UID FETCH") {
+			t.Errorf("%s: StoreWhere() fetched emails", tt.name)
+		}
+	}
+}
+
+func TestDeleteWhere(t *testing.T) {
+	tests := []struct {
+		name    string
+		expunge bool
+		want    int
+		// wantLeft is the subjects left in the folder
+		wantLeft string
+	}{
+		{"flag only", false, 2, "a1 a2 b c"},
+		{"expunge", true, 2, "b c"},
+	}
+
+	for _, tt := range tests {
+		srv := testServer(t)
+		srv.AddMessage("INBOX", []byte("Subject: a1\r\n\r\nx\r\n"))
+		srv.AddMessage("INBOX", []byte("Subject: a2\r\n\r\nx\r\n"))
+		srv.AddMessage("INBOX", []byte("Subject: b\r\n\r\nx\r\n"))
+		// flagged by someone else, not to be expunged along
+		srv.AddMessage("INBOX", []byte("Subject: c\r\n\r\nx\r\n"), `\Deleted`)
+		c := testClient(t, srv)
+
+		n, err := c.DeleteWhere(Subject("a"), tt.expunge)
+		if err != nil {
+			t.Fatalf("%s: DeleteWhere() returned an error: %s", tt.name, err)
+		}
+		if n != tt.want {
+			t.Errorf("%s: DeleteWhere() got %d, want %d", tt.name, n, tt.want)
+		}
+		var left []string
+		for _, msg := range srv.Messages("INBOX") {
+			subject, _, _ := strings.Cut(strings.TrimPrefix(string(msg.Raw), "Subject: "), "\r\n")
+			left = append(left, subject)
+			if strings.HasPrefix(subject, "a") && !serverHasFlag(srv, "INBOX", msg.UID, `\Deleted`) {
+				t.Errorf("%s: DeleteWhere() did not flag %q as deleted", tt.name, subject)
+			}
+		}
+		if got := strings.Join(left, " "); got != tt.wantLeft {
+			t.Errorf("%s: DeleteWhere() left %q, want %q", tt.name, got, tt.wantLeft)
+		}
+	}
+
+	c := testClient(t, testServer(t), SetSafeMode(true))
+	if _, err := c.DeleteWhere(All(), true); err != ErrReadOnlyMode {
+		t.Errorf("DeleteWhere() in safe mode got %v, want %s", err, ErrReadOnlyMode)
+	}
+}
@@ -0,0 +1,126 @@
+package eazye
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// CacheKey identifies an email in a Cache. As UIDs are only meaningful
+// within a folder and UIDVALIDITY, both are part of the key.
+type CacheKey struct {
+	Folder      string
+	UIDValidity uint32
+	UID         uint32
+}
+
+// CachedEmail is what a Cache keeps of an email: the full message as
+// fetched, headers included, and its internal date. Its flags and Gmail
+// labels change over time, so they are fetched again whenever it comes out
+// of the cache.
+type CachedEmail struct {
+	InternalDate time.Time
+	Raw          []byte
+}
+
+// Cache keeps emails around so they do not have to be fetched again. The
+// content of an email never changes once it has a UID, so entries never go
+// stale, they can only be evicted.
+type Cache interface {
+	// Get returns the cached email, and false if there is none.
+	Get(key CacheKey) (CachedEmail, bool, error)
+	Put(key CacheKey, email CachedEmail) error
+}
+
+// SetCache is a functional option to set the Cache attr.
+func SetCache(cache Cache) Option {
+	return func(c *Client) {
+		c.Cache = cache
+	}
+}
+
+func (c *Client) cacheKey(uid uint32) CacheKey {
+	return CacheKey{Folder: c.Folder, UIDValidity: c.uidValidity(), UID: uid}
+}
+
+// fromCache splits the UIDs into the emails found in the cache and the UIDs
+// that still need fetching. A failing cache only means the emails get
+// fetched.
+func (c *Client) fromCache(uids []uint32) (missing []uint32, cached []Email) {
+	for _, uid := range uids {
+		entry, ok, err := c.Cache.Get(c.cacheKey(uid))
+		if err != nil || !ok {
+			missing = append(missing, uid)
+			continue
+		}
+		email, err := newCachedEmail(uid, entry)
+		if err != nil {
+			missing = append(missing, uid)
+			continue
+		}
+		email.Ref = c.ref(email)
+		cached = append(cached, email)
+	}
+	return missing, cached
+}
+
+// refreshCached fetches the flags, and the Gmail labels and IDs, of the
+// emails found in the cache. Emails no longer in the folder are left out.
+func (c *Client) refreshCached(ctx context.Context, cached []Email) ([]Email, error) {
+	seq := &imap.SeqSet{}
+	for _, email := range cached {
+		seq.AddNum(imap.AsNumber(email.ID))
+	}
+	items := append([]string{"UID", "FLAGS"}, c.gmailItems()...)
+	cmd, err := c.do(ctx, func() (*imap.Command, error) {
+		return c.uidFetch(seq, items...)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to perform uid fetch: %w", err)
+	}
+
+	fields := make(map[uint32]imap.FieldMap, len(cmd.Data))
+	for _, msgData := range cmd.Data {
+		info := c.messageInfo(msgData)
+		fields[info.UID] = info.Attrs
+	}
+	var refreshed []Email
+	for _, email := range cached {
+		msgFields, ok := fields[imap.AsNumber(email.ID)]
+		if !ok {
+			continue
+		}
+		email.parseFlags(msgFields)
+		email.parseGmail(msgFields)
+		refreshed = append(refreshed, email)
+	}
+	return refreshed, nil
+}
+
+// toCache puts a freshly fetched email in the cache, failures are ignored.
+func (c *Client) toCache(email Email) {
+	c.Cache.Put(c.cacheKey(imap.AsNumber(email.ID)), CachedEmail{
+		InternalDate: email.InternalDate,
+		Raw:          email.raw,
+	})
+}
+
+// newCachedEmail parses a cached email as if it had just been fetched.
+func newCachedEmail(uid uint32, entry CachedEmail) (Email, error) {
+	header := entry.Raw
+	if i := bytes.Index(header, []byte("\r\n\r\n")); i >= 0 {
+		header = header[:i+2]
+	} else if i = bytes.Index(header, []byte("\n\n")); i >= 0 {
+		header = header[:i+1]
+	}
+	email, err := newEmail(imap.FieldMap{
+		"UID":           uid,
+		"RFC822.HEADER": header,
+		"BODY[]":        entry.Raw,
+	})
+	email.InternalDate = entry.InternalDate
+	return email, err
+}
@@ -0,0 +1,58 @@
+package eazye
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNewCachedEmail(t *testing.T) {
+	date := time.Date(2014, 8, 12, 11, 11, 28, 0, time.UTC)
+	email, err := newCachedEmail(42, CachedEmail{InternalDate: date, Raw: []byte(multipartHeader + multipartBody)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if email.Subject != "hi" || string(email.Text) != "Café at 10" {
+		t.Errorf("newCachedEmail() got Subject %q and Text %q", email.Subject, email.Text)
+	}
+	if !email.InternalDate.Equal(date) || email.ID != uint32(42) {
+		t.Errorf("newCachedEmail() got InternalDate %s and ID %v", email.InternalDate, email.ID)
+	}
+}
+
+// mapCache is a Cache in a map.
+type mapCache map[CacheKey]CachedEmail
+
+func (m mapCache) Get(key CacheKey) (CachedEmail, bool, error) {
+	email, ok := m[key]
+	return email, ok, nil
+}
+
+func (m mapCache) Put(key CacheKey, email CachedEmail) error {
+	m[key] = email
+	return nil
+}
+
+func TestCachedEmailFlags(t *testing.T) {
+	srv := testServer(t)
+	srv.AddMessage("INBOX", []byte("Subject: hi\r\n\r\nx\r\n"), `\Flagged`)
+
+	cache := mapCache{}
+	c := testClient(t, srv, SetCache(cache))
+	if _, err := c.GetAll(false, false); err != nil || len(cache) != 1 {
+		t.Fatalf("GetAll() cached %d emails, %v, want 1", len(cache), err)
+	}
+
+	srv.ResetCommands()
+	emails, err := c.GetAll(false, false)
+	if err != nil || len(emails) != 1 {
+		t.Fatalf("GetAll() got %d emails, %v, want 1", len(emails), err)
+	}
+	if fmt.Sprint(emails[0].Flags) != `[\Flagged]` {
+		t.Errorf("GetAll() from the cache got flags %q, want \\Flagged", emails[0].Flags)
+	}
+	if hasCommand(srv, "UID FETCH 1 (INTERNALDATE") {
+		t.Errorf("GetAll() fetched the cached email again: %q", srv.Commands())
+	}
+}
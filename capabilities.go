@@ -0,0 +1,77 @@
+package eazye
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// SetDisabledCapabilities is a functional option to set the
+// DisabledCapabilities attr.
+func SetDisabledCapabilities(capabilities ...string) Option {
+	return func(c *Client) {
+		c.DisabledCapabilities = capabilities
+	}
+}
+
+// Capabilities returns the capabilities the server announced, upper case and
+// sorted, leaving out the DisabledCapabilities. The Client checks them before
+// using an extension and falls back to plain IMAP4rev1 without it:
+//
+//   - MOVE: Move copies, flags as deleted and expunges.
+//   - UIDPLUS: expunging removes every email flagged as deleted.
+//   - IDLE: Watch polls every WatchPollInterval.
+//   - CONDSTORE: Changes returns ErrNoCondStore, fetches leave out the
+//     mod-sequence.
+//   - SPECIAL-USE: SpecialFolders uses XLIST or the usual names.
+//   - X-GM-EXT-1: the Gmail fields are left empty and tags are not labels.
+//   - ID: ID is not sent.
+//   - APPENDLIMIT: Append leaves the size up to the server.
+func (c *Client) Capabilities() []string {
+	server := c.server()
+	if server == nil {
+		return nil
+	}
+	var capabilities []string
+	for name, ok := range server.Capabilities() {
+		name = strings.ToUpper(name)
+		if ok && !c.disabled(name) {
+			capabilities = append(capabilities, name)
+		}
+	}
+	sort.Strings(capabilities)
+	return slices.Compact(capabilities)
+}
+
+// HasCapability tells whether the server announced the capability and it is
+// not one of the DisabledCapabilities, ignoring case.
+func (c *Client) HasCapability(capability string) bool {
+	return c.caps(strings.ToUpper(capability))
+}
+
+// caps tells whether the server announced the upper case capability and it is
+// not disabled.
+func (c *Client) caps(capability string) bool {
+	server := c.server()
+	if server == nil || c.disabled(capability) {
+		return false
+	}
+	if server.Capabilities()[capability] {
+		return true
+	}
+	for name, ok := range server.Capabilities() {
+		if ok && strings.EqualFold(name, capability) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) disabled(capability string) bool {
+	for _, name := range c.DisabledCapabilities {
+		if strings.EqualFold(name, capability) {
+			return true
+		}
+	}
+	return false
+}
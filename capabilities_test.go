@@ -0,0 +1,32 @@
+package eazye
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	conn := &fakeConn{caps: map[string]bool{"IMAP4rev1": true, "MOVE": true, "IDLE": true, "CONDSTORE": false}}
+	c := &Client{imapConn: conn, DisabledCapabilities: []string{"move"}}
+
+	want := []string{"IDLE", "IMAP4REV1"}
+	if got := c.Capabilities(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Capabilities() got %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		capability string
+		want       bool
+	}{
+		{"idle", true},
+		{"IMAP4REV1", true},
+		{"MOVE", false},
+		{"CONDSTORE", false},
+		{"UIDPLUS", false},
+	}
+	for _, test := range tests {
+		if got := c.HasCapability(test.capability); got != test.want {
+			t.Errorf("HasCapability(%q) got %v, want %v", test.capability, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// ErrNoCondStore is returned by Changes if the server does not support
+// CONDSTORE (RFC 7162).
+var ErrNoCondStore = errors.New("server does not support CONDSTORE")
+
+// Changes is what happened in the folder since some mod-sequence.
+type Changes struct {
+	// New holds the UIDs of the emails above the highest UID fetched so far,
+	// see SaveState.
+	New []uint32
+	// Flags holds the flags of the other emails that changed, by UID.
+	Flags map[uint32][]string
+	// Expunged holds the UIDs of the emails removed from the folder. It is
+	// only filled in by servers that support QRESYNC.
+	Expunged []uint32
+	// HighestModSeq is the mod-sequence to ask for changes since next time.
+	HighestModSeq uint64
+}
+
+// Changes will find the emails added, the flags changed and, if the server
+// supports QRESYNC, the emails expunged since the given mod-sequence, e.g.
+// State.HighestModSeq, without fetching any email. It needs CONDSTORE (RFC
+// 7162) on the server. The Client's highest mod-sequence is moved up to the
+// one returned.
+func (c *Client) Changes(sinceModSeq uint64) (Changes, error) {
+	return c.ChangesContext(context.Background(), sinceModSeq)
+}
+
+// ChangesContext is Changes with a context.
+func (c *Client) ChangesContext(ctx context.Context, sinceModSeq uint64) (Changes, error) {
+	changes := Changes{Flags: map[uint32][]string{}, HighestModSeq: sinceModSeq}
+	if !c.caps("CONDSTORE") {
+		return changes, ErrNoCondStore
+	}
+
+	qresync := c.caps("QRESYNC") && !c.SequenceNumbers
+	if qresync {
+		// VANISHED is only allowed once QRESYNC is enabled (RFC 5161)
+		if _, err := c.do(ctx, func() (*imap.Command, error) {
+			return c.server().Send("ENABLE", "QRESYNC")
+		}); err != nil {
+			return changes, fmt.Errorf("unable to enable QRESYNC: %w", err)
+		}
+	}
+
+	fetch := "UID FETCH"
+	items := []imap.Field{"UID", "FLAGS", "MODSEQ"}
+	if c.SequenceNumbers {
+		fetch = "FETCH"
+		items = items[1:]
+	}
+	modifiers := []imap.Field{"CHANGEDSINCE", strconv.FormatUint(sinceModSeq, 10)}
+	if qresync {
+		modifiers = append(modifiers, "VANISHED")
+	}
+
+	seq, _ := imap.NewSeqSet("1:*")
+	c.server().ClearUnilateral()
+	cmd, err := c.do(ctx, func() (*imap.Command, error) {
+		return c.server().Send(fetch, seq, items, modifiers)
+	})
+	if err != nil {
+		return changes, fmt.Errorf("unable to fetch changes: %w", err)
+	}
+
+	for _, rsp := range append(cmd.Data, c.server().Unilateral()...) {
+		switch rsp.Label {
+		case "FETCH":
+			info := c.messageInfo(rsp)
+			if info == nil {
+				continue
+			}
+			if m := modSeq(info.Attrs["MODSEQ"]); m > changes.HighestModSeq {
+				changes.HighestModSeq = m
+			}
+			if info.UID > c.highestUID {
+				changes.New = append(changes.New, info.UID)
+				continue
+			}
+			flags := []string{}
+			for flag := range info.Flags {
+				flags = append(flags, flag)
+			}
+			sort.Strings(flags)
+			changes.Flags[info.UID] = flags
+		case "VANISHED":
+			if len(rsp.Fields) == 0 {
+				continue
+			}
+			uids, err := expandSeqSet(imap.AsString(rsp.Fields[len(rsp.Fields)-1]))
+			if err != nil {
+				return changes, err
+			}
+			changes.Expunged = append(changes.Expunged, uids...)
+		}
+	}
+	c.server().ClearUnilateral()
+
+	sort.Slice(changes.New, func(i, j int) bool { return changes.New[i] < changes.New[j] })
+	sort.Slice(changes.Expunged, func(i, j int) bool { return changes.Expunged[i] < changes.Expunged[j] })
+	if changes.HighestModSeq > c.highestModSeq {
+		c.highestModSeq = changes.HighestModSeq
+	}
+	return changes, nil
+}
+
+// expandSeqSet lists the numbers of a sequence set such as "41,43:116" as
+// sent in VANISHED responses. Sets ending in * are not allowed there.
+func expandSeqSet(set string) ([]uint32, error) {
+	var nums []uint32
+	for _, part := range strings.Split(set, ",") {
+		from, to, isRange := strings.Cut(part, ":")
+		first, err := strconv.ParseUint(from, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("bad sequence set %q", set)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.ParseUint(to, 10, 32); err != nil {
+				return nil, fmt.Errorf("bad sequence set %q", set)
+			}
+		}
+		if first > last {
+			first, last = last, first
+		}
+		for n := first; n <= last; n++ {
+			nums = append(nums, uint32(n))
+		}
+	}
+	return nums, nil
+}
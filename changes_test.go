@@ -0,0 +1,33 @@
+package eazye
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExpandSeqSet(t *testing.T) {
+	tests := []struct {
+		set  string
+		want string
+	}{
+		{"7", "[7]"},
+		{"41,43:45", "[41 43 44 45]"},
+		{"5:3,9", "[3 4 5 9]"},
+	}
+	for _, test := range tests {
+		got, err := expandSeqSet(test.set)
+		if err != nil {
+			t.Errorf("expandSeqSet(%q) returned an error: %s", test.set, err)
+			continue
+		}
+		if fmt.Sprint(got) != test.want {
+			t.Errorf("expandSeqSet(%q) got %v, want %s", test.set, got, test.want)
+		}
+	}
+
+	for _, bad := range []string{"", "1:*", "a,b"} {
+		if _, err := expandSeqSet(bad); err == nil {
+			t.Errorf("expandSeqSet(%q) did not return an error", bad)
+		}
+	}
+}
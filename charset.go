@@ -0,0 +1,94 @@
+package eazye
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/paulrosania/go-charset/charset"
+	_ "github.com/paulrosania/go-charset/data"
+)
+
+// CharsetDecoder converts text in some charset to UTF-8.
+type CharsetDecoder func(r io.Reader) (io.Reader, error)
+
+var (
+	charsetsMu sync.RWMutex
+	charsets   = map[string]CharsetDecoder{}
+)
+
+// RegisterCharset sets the decoder used for the named charset, in bodies as
+// well as RFC 2047 encoded headers. Names are matched case insensitively.
+// Registered decoders take precedence over the ones built in, which cover
+// what go-charset knows, e.g. ISO-8859-1, windows-1251, GB2312 and Shift_JIS.
+// The exception are encoded words in UTF-8, US-ASCII and ISO-8859-1, which
+// mime.WordDecoder always decodes itself. A nil decoder removes the
+// registration.
+func RegisterCharset(name string, decoder CharsetDecoder) {
+	charsetsMu.Lock()
+	defer charsetsMu.Unlock()
+
+	name = strings.ToLower(name)
+	if decoder == nil {
+		delete(charsets, name)
+		return
+	}
+	charsets[name] = decoder
+}
+
+// decodeCharset converts text in the named charset to UTF-8. An RFC 2231
+// language suffix, as in "iso-8859-1*en", is ignored.
+func decodeCharset(name string, r io.Reader) (io.Reader, error) {
+	name, _, _ = strings.Cut(name, "*")
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	charsetsMu.RLock()
+	decoder, ok := charsets[name]
+	charsetsMu.RUnlock()
+	if ok {
+		return decoder(r)
+	}
+
+	switch name {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return r, nil
+	}
+	decoded, err := charset.NewReader(name, r)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q: %w", name, err)
+	}
+	return decoded, nil
+}
+
+// encodedWord matches an RFC 2047 encoded word, also with the spaces some
+// mailers leave in Q encoded ones.
+var encodedWord = regexp.MustCompile(`=\?[^?\s]+\?[bBqQ]\?[^?]*\?=`)
+
+// DecodeHeader decodes the RFC 2047 encoded words in a header value, as in
+// "=?ISO-8859-1?Q?Caf=E9?=", in any charset RegisterCharset or go-charset
+// knows. Words that cannot be decoded, e.g. for an unknown charset, are left
+// as they are, so one bad word does not keep the rest from being decoded.
+func DecodeHeader(s string) string {
+	if !strings.Contains(s, "=?") {
+		return s
+	}
+
+	var b strings.Builder
+	last, afterWord := 0, false
+	for _, loc := range encodedWord.FindAllStringIndex(s, -1) {
+		decoded, err := wordDecoder.Decode(s[loc[0]:loc[1]])
+		if err != nil {
+			continue
+		}
+		// the whitespace between two encoded words is not part of the text
+		if between := s[last:loc[0]]; !afterWord || strings.TrimSpace(between) != "" {
+			b.WriteString(between)
+		}
+		b.WriteString(decoded)
+		last, afterWord = loc[1], true
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
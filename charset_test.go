@@ -0,0 +1,83 @@
+package eazye
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+func TestRegisterCharset(t *testing.T) {
+	RegisterCharset("X-Upper", func(r io.Reader) (io.Reader, error) {
+		b, err := io.ReadAll(r)
+		return bytes.NewReader(bytes.ToUpper(b)), err
+	})
+	defer RegisterCharset("x-upper", nil)
+
+	header := "Subject: =?x-upper?q?hello?=\r\nContent-Type: text/plain; charset=X-UPPER\r\n\r\n"
+	email, err := newEmail(imap.FieldMap{
+		"RFC822.HEADER": []byte(header),
+		"BODY[]":        []byte(header + "see you at 10"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if email.Subject != "HELLO" {
+		t.Errorf("newEmail() got Subject %q, want %q", email.Subject, "HELLO")
+	}
+	if string(email.Text) != "SEE YOU AT 10" {
+		t.Errorf("newEmail() got Text %q, want %q", email.Text, "SEE YOU AT 10")
+	}
+}
+
+func TestDecodeCharsetPassthrough(t *testing.T) {
+	for _, name := range []string{"", "UTF-8", "us-ascii"} {
+		r, err := decodeCharset(name, bytes.NewReader([]byte("café")))
+		if err != nil {
+			t.Fatalf("decodeCharset(%q) returned an error: %s", name, err)
+		}
+		if b, _ := io.ReadAll(r); string(b) != "café" {
+			t.Errorf("decodeCharset(%q) got %q, want %q", name, b, "café")
+		}
+	}
+}
+
+func TestDecodeHeader(t *testing.T) {
+	tests := []struct {
+		given string
+		want  string
+	}{
+		{"Plain subject", "Plain subject"},
+		{"=?ISO-8859-1?Q?Caf=E9_cr=E8me?=", "Café crème"},
+		{"=?utf-8?b?SsO8cmdlbg==?= Müller", "Jürgen Müller"},
+		{"=?UTF-8?Q?a?= =?UTF-8?Q?b?=", "ab"},
+		{"=?UTF-8?Q?a?= and =?UTF-8?Q?b?=", "a and b"},
+		{"=?UTF-8?Q?with spaces?=", "with spaces"},
+		{"=?iso-8859-1*en?q?d=E9j=E0?=", "déjà"},
+		{"=?x-unknown?q?kept?= =?UTF-8?Q?decoded?=", "=?x-unknown?q?kept?= decoded"},
+		{"=?UTF-8?Q?broken", "=?UTF-8?Q?broken"},
+	}
+
+	for _, tt := range tests {
+		if got := DecodeHeader(tt.given); got != tt.want {
+			t.Errorf("DecodeHeader(%q) got %q, want %q", tt.given, got, tt.want)
+		}
+	}
+}
+
+func TestQuotedEncodedName(t *testing.T) {
+	header := "From: \"=?ISO-8859-1?Q?Andr=E9?=\" <andre@example.com>\r\nSubject: =?ISO-8859-1?Q?R=E9sum=E9?=\r\n\r\n"
+	email, err := newEmail(imap.FieldMap{"RFC822.HEADER": []byte(header)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if email.From == nil || email.From.Name != "André" {
+		t.Errorf("newEmail() got From %v, want André", email.From)
+	}
+	if email.Subject != "Résumé" {
+		t.Errorf("newEmail() got Subject %q, want %q", email.Subject, "Résumé")
+	}
+}
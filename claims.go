@@ -0,0 +1,214 @@
+package eazye
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// ClaimStore coordinates several workers handling the emails of a shared
+// folder so that each email is only handled once.
+type ClaimStore interface {
+	// Claim reports whether the email was claimed for this worker. It is
+	// false if another worker holds a claim on it or it was already handled.
+	Claim(uid uint32) (bool, error)
+	// Release gives up the claim so another worker can take the email.
+	Release(uid uint32) error
+	// Complete marks the email as handled for good.
+	Complete(uid uint32) error
+	// Completed reports whether any worker completed the email.
+	Completed(uid uint32) (bool, error)
+}
+
+const (
+	// claimPrefix starts the keyword of a claim, followed by the worker and
+	// the unix time it was made at: $Claimed-<worker>-<time>.
+	claimPrefix = "$Claimed-"
+	// HandledKeyword is set on an email once a worker completed it.
+	HandledKeyword = "$Handled"
+)
+
+// KeywordClaims is a ClaimStore that keeps the claims on the server as IMAP
+// keywords, so it needs nothing but a server that allows custom keywords.
+//
+// As IMAP has no compare-and-set, a worker sets its keyword first and then
+// checks for the keywords of others, backing off if there are any. Workers
+// racing for the same email may then all back off, in which case it is
+// claimed on a later poll, but it is never handled twice.
+type KeywordClaims struct {
+	Client *Client
+	Worker string
+	// TTL is how long a claim is honored, so the emails of a worker that died
+	// are eventually picked up by the others. 0 means forever.
+	TTL time.Duration
+}
+
+// NewKeywordClaims initializes a KeywordClaims for the worker, which has to
+// be a valid IMAP atom.
+func NewKeywordClaims(client *Client, worker string, ttl time.Duration) (*KeywordClaims, error) {
+	if worker == "" || !validKeyword(claimPrefix+worker) {
+		return nil, fmt.Errorf("invalid worker name: %q", worker)
+	}
+	return &KeywordClaims{Client: client, Worker: worker, TTL: ttl}, nil
+}
+
+// Claim claims the email for the worker, removing the stale claims of others
+// along the way.
+func (k *KeywordClaims) Claim(uid uint32) (bool, error) {
+	flags, err := k.flags(uid)
+	if err != nil {
+		return false, err
+	}
+	now := time.Now()
+	state := k.check(flags, now)
+	if state.handled || state.taken {
+		return false, nil
+	}
+
+	own := claimKeyword(k.Worker, now)
+	if len(state.stale) > 0 {
+		if err = k.store(uid, "-FLAGS.SILENT", state.stale...); err != nil {
+			return false, err
+		}
+	}
+	if err = k.store(uid, "+FLAGS.SILENT", own); err != nil {
+		return false, err
+	}
+
+	// someone else may have claimed it in the meantime
+	if flags, err = k.flags(uid); err != nil {
+		return false, err
+	}
+	state = k.check(flags, now)
+	if state.handled || state.taken {
+		return false, k.store(uid, "-FLAGS.SILENT", state.own...)
+	}
+	return true, nil
+}
+
+// Release removes the claims of the worker from the email.
+func (k *KeywordClaims) Release(uid uint32) error {
+	flags, err := k.flags(uid)
+	if err != nil {
+		return err
+	}
+	own := k.check(flags, time.Now()).own
+	if len(own) == 0 {
+		return nil
+	}
+	return k.store(uid, "-FLAGS.SILENT", own...)
+}
+
+// Complete sets HandledKeyword on the email and removes the claims of the
+// worker.
+func (k *KeywordClaims) Complete(uid uint32) error {
+	if err := k.store(uid, "+FLAGS.SILENT", HandledKeyword); err != nil {
+		return err
+	}
+	return k.Release(uid)
+}
+
+// Completed reports whether the email has HandledKeyword, or is gone.
+func (k *KeywordClaims) Completed(uid uint32) (bool, error) {
+	flags, err := k.flags(uid)
+	if err != nil {
+		return false, err
+	}
+	return flags == nil || k.check(flags, time.Now()).handled, nil
+}
+
+// flags returns the flags of the email, nil if there is no such email.
+func (k *KeywordClaims) flags(uid uint32) ([]string, error) {
+	seq := &imap.SeqSet{}
+	seq.AddNum(uid)
+	cmd, err := k.Client.wait(context.Background())(k.Client.uidFetch(seq, "UID", "FLAGS"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch flags: %w", err)
+	}
+
+	var flags []string
+	for _, rsp := range cmd.Data {
+		info := k.Client.messageInfo(rsp)
+		if info == nil || info.UID != uid {
+			continue
+		}
+		if flags == nil {
+			flags = []string{}
+		}
+		for flag := range info.Flags {
+			flags = append(flags, flag)
+		}
+	}
+	return flags, nil
+}
+
+func (k *KeywordClaims) store(uid uint32, item string, flags ...string) error {
+	if k.Client.SafeMode {
+		return ErrReadOnlyMode
+	}
+	seq := &imap.SeqSet{}
+	seq.AddNum(uid)
+	fields := make([]imap.Field, len(flags))
+	for i, flag := range flags {
+		fields[i] = flag
+	}
+	if _, err := k.Client.wait(context.Background())(k.Client.uidStore(seq, item, fields)); err != nil {
+		return fmt.Errorf("unable to store claim: %w", err)
+	}
+	return nil
+}
+
+// claimState is what the flags of an email say about its claims.
+type claimState struct {
+	handled bool
+	// taken is set if another worker holds a live claim.
+	taken bool
+	own   []string
+	stale []string
+}
+
+func (k *KeywordClaims) check(flags []string, now time.Time) claimState {
+	var state claimState
+	for _, flag := range flags {
+		if strings.EqualFold(flag, HandledKeyword) {
+			state.handled = true
+			continue
+		}
+		worker, at, ok := parseClaim(flag)
+		switch {
+		case !ok:
+		case worker == k.Worker:
+			state.own = append(state.own, flag)
+		case k.TTL > 0 && now.Sub(at) > k.TTL:
+			state.stale = append(state.stale, flag)
+		default:
+			state.taken = true
+		}
+	}
+	return state
+}
+
+func claimKeyword(worker string, at time.Time) string {
+	return claimPrefix + worker + "-" + strconv.FormatInt(at.Unix(), 10)
+}
+
+// parseClaim splits a claim keyword into its worker and time.
+func parseClaim(flag string) (string, time.Time, bool) {
+	if len(flag) < len(claimPrefix) || !strings.EqualFold(flag[:len(claimPrefix)], claimPrefix) {
+		return "", time.Time{}, false
+	}
+	rest := flag[len(claimPrefix):]
+	i := strings.LastIndexByte(rest, '-')
+	if i <= 0 {
+		return "", time.Time{}, false
+	}
+	secs, err := strconv.ParseInt(rest[i+1:], 10, 64)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return rest[:i], time.Unix(secs, 0), true
+}
@@ -0,0 +1,70 @@
+package eazye
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseClaim(t *testing.T) {
+	at := time.Unix(1457000000, 0)
+
+	worker, got, ok := parseClaim(claimKeyword("box-1", at))
+	if !ok || worker != "box-1" || !got.Equal(at) {
+		t.Errorf("parseClaim() got %q, %s, %t, want box-1, %s, true", worker, got, ok, at)
+	}
+
+	for _, flag := range []string{`\Seen`, "$Claimed-", "$Claimed-box", "$Claimed-box-abc", HandledKeyword} {
+		if _, _, ok := parseClaim(flag); ok {
+			t.Errorf("parseClaim(%q) did not fail", flag)
+		}
+	}
+}
+
+func TestKeywordClaimsCheck(t *testing.T) {
+	now := time.Unix(1457000000, 0)
+	k := &KeywordClaims{Worker: "a", TTL: time.Hour}
+
+	tests := []struct {
+		name  string
+		flags []string
+		want  claimState
+	}{
+		{"unclaimed", []string{`\Seen`}, claimState{}},
+		{"own", []string{claimKeyword("a", now)}, claimState{own: []string{claimKeyword("a", now)}}},
+		{"taken", []string{claimKeyword("b", now.Add(-time.Minute))}, claimState{taken: true}},
+		{"stale", []string{claimKeyword("b", now.Add(-2*time.Hour))}, claimState{stale: []string{claimKeyword("b", now.Add(-2*time.Hour))}}},
+		{"handled", []string{HandledKeyword}, claimState{handled: true}},
+	}
+	for _, test := range tests {
+		got := k.check(test.flags, now)
+		if got.handled != test.want.handled || got.taken != test.want.taken ||
+			len(got.own) != len(test.want.own) || len(got.stale) != len(test.want.stale) {
+			t.Errorf("check() for %s got %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNewKeywordClaims(t *testing.T) {
+	if _, err := NewKeywordClaims(nil, "worker 1", time.Minute); err == nil {
+		t.Error("NewKeywordClaims() with an invalid worker did not return an error")
+	}
+	if _, err := NewKeywordClaims(nil, "worker-1", time.Minute); err != nil {
+		t.Errorf("NewKeywordClaims() returned an error: %s", err)
+	}
+}
+
+func TestKeywordClaimsCompleted(t *testing.T) {
+	srv := testServer(t)
+	srv.AddMessage("INBOX", []byte("Subject: open\r\n\r\nx\r\n"))
+	srv.AddMessage("INBOX", []byte("Subject: done\r\n\r\nx\r\n"), HandledKeyword)
+
+	k, err := NewKeywordClaims(testClient(t, srv), "a", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for uid, want := range map[uint32]bool{1: false, 2: true, 3: true} {
+		if got, err := k.Completed(uid); err != nil || got != want {
+			t.Errorf("Completed(%d) got %t, %v, want %t", uid, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Command eazye runs maintenance tasks against a mailbox.
+//
+// The audit command records a snapshot of a folder the first time it is run,
+// and compares the folder against it on every run after that, reporting the
+// emails deleted or modified since. It exits with status 1 if there are any.
+//
+//	EAZYE_PASSWORD=... eazye audit -host imap.example.com:993 -user me@example.com -snapshot inbox.json
+//
+// The password is read from the EAZYE_PASSWORD environment variable so it
+// does not end up in the shell history.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/sluceno/eazye"
+)
+
+func main() {
+	if len(os.Args) < 2 || os.Args[1] != "audit" {
+		fmt.Fprintln(os.Stderr, "usage: eazye audit [flags]")
+		os.Exit(2)
+	}
+
+	clean, err := audit(os.Args[2:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "eazye:", err)
+		os.Exit(2)
+	}
+	if !clean {
+		os.Exit(1)
+	}
+}
+
+func audit(args []string) (bool, error) {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	var (
+		host     = fs.String("host", "", "IMAP server address, including the port")
+		user     = fs.String("user", "", "user to log in as")
+		folder   = fs.String("folder", "INBOX", "folder to audit")
+		useTLS   = fs.Bool("tls", true, "connect over TLS")
+		path     = fs.String("snapshot", "", "file the snapshot is kept in")
+		update   = fs.Bool("update", false, "replace the snapshot with the current state after comparing")
+		password = os.Getenv("EAZYE_PASSWORD")
+	)
+	fs.Parse(args)
+	if *host == "" || *user == "" || *path == "" {
+		return false, fmt.Errorf("-host, -user and -snapshot are required")
+	}
+
+	old, err := eazye.ReadSnapshot(*path)
+	first := os.IsNotExist(err)
+	if err != nil && !first {
+		return false, fmt.Errorf("unable to read snapshot: %s", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	client, err := eazye.NewContext(ctx, *host, *user, password,
+		eazye.SetTLS(*useTLS),
+		eazye.SetFolder(*folder),
+		eazye.SetSafeMode(true),
+	)
+	if err != nil {
+		return false, fmt.Errorf("unable to connect: %s", err)
+	}
+	defer client.Close()
+
+	snapshot, err := client.Snapshot(ctx)
+	if err != nil {
+		return false, fmt.Errorf("unable to take snapshot: %s", err)
+	}
+
+	if first {
+		fmt.Printf("recorded %d emails in %s\n", len(snapshot.Emails), *folder)
+		return true, snapshot.WriteFile(*path)
+	}
+
+	report := eazye.CompareSnapshots(old, snapshot)
+	printReport(old, report)
+	if *update {
+		if err = snapshot.WriteFile(*path); err != nil {
+			return false, fmt.Errorf("unable to write snapshot: %s", err)
+		}
+	}
+	return report.Clean(), nil
+}
+
+func printReport(old eazye.Snapshot, report eazye.AuditReport) {
+	fmt.Printf("compared against the snapshot of %s taken %s\n", old.Folder, old.TakenAt.Format("2006-01-02 15:04:05"))
+	if report.UIDValidityChanged {
+		fmt.Println("the folder was reset (UIDVALIDITY changed), UIDs can not be compared")
+	}
+	for _, email := range report.Deleted {
+		fmt.Printf("deleted  uid %d\n", email.UID)
+	}
+	for _, change := range report.Modified {
+		if change.ContentChanged() {
+			fmt.Printf("modified uid %d: content changed\n", change.Old.UID)
+			continue
+		}
+		fmt.Printf("modified uid %d: flags %s -> %s\n", change.Old.UID,
+			strings.Join(change.Old.Flags, " "), strings.Join(change.New.Flags, " "))
+	}
+	fmt.Printf("%d deleted, %d modified, %d added\n", len(report.Deleted), len(report.Modified), len(report.Added))
+}
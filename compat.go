@@ -0,0 +1,70 @@
+package eazye
+
+import (
+	"github.com/mxk/go-imap/imap"
+)
+
+// SetSequenceNumbers is a functional option to set the SequenceNumbers attr.
+func SetSequenceNumbers(seqNums bool) Option {
+	return func(c *Client) {
+		c.SequenceNumbers = seqNums
+	}
+}
+
+// uidSearch is UIDSearch, or SEARCH for servers without UID commands.
+func (c *Client) uidSearch(spec ...imap.Field) (*imap.Command, error) {
+	if c.SequenceNumbers {
+		return c.server().Search(spec...)
+	}
+	return c.server().UIDSearch(spec...)
+}
+
+// uidFetch is UIDFetch, or FETCH without the UID item for servers without
+// UID commands.
+func (c *Client) uidFetch(seq *imap.SeqSet, items ...string) (*imap.Command, error) {
+	if !c.SequenceNumbers {
+		return c.server().UIDFetch(seq, items...)
+	}
+	var seqItems []string
+	for _, item := range items {
+		if item != "UID" {
+			seqItems = append(seqItems, item)
+		}
+	}
+	return c.server().Fetch(seq, seqItems...)
+}
+
+// uidStore is UIDStore, or STORE for servers without UID commands.
+func (c *Client) uidStore(seq *imap.SeqSet, item string, value imap.Field) (*imap.Command, error) {
+	if c.SequenceNumbers {
+		return c.server().Store(seq, item, value)
+	}
+	return c.server().UIDStore(seq, item, value)
+}
+
+// uidCopy is UIDCopy, or COPY for servers without UID commands.
+func (c *Client) uidCopy(seq *imap.SeqSet, mbox string) (*imap.Command, error) {
+	if c.SequenceNumbers {
+		return c.server().Copy(seq, mbox)
+	}
+	return c.server().UIDCopy(seq, mbox)
+}
+
+// messageInfo returns the message info of a FETCH response. For servers
+// without UID commands the sequence number stands in for the UID, so the
+// rest of the code does not need to know the difference.
+func (c *Client) messageInfo(rsp *imap.Response) *imap.MessageInfo {
+	info := rsp.MessageInfo()
+	if !c.SequenceNumbers || info == nil {
+		return info
+	}
+
+	seqInfo := *info
+	seqInfo.UID = info.Seq
+	seqInfo.Attrs = make(imap.FieldMap, len(info.Attrs)+1)
+	for key, value := range info.Attrs {
+		seqInfo.Attrs[key] = value
+	}
+	seqInfo.Attrs["UID"] = info.Seq
+	return &seqInfo
+}
@@ -0,0 +1,74 @@
+package eazye
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+	"github.com/sluceno/eazye/eazyetest"
+)
+
+func TestSequenceNumbers(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(*Client) error
+		want string
+		// done tells whether the second email got what the command did
+		done func(*eazyetest.Server, uint32) bool
+	}{
+		{
+			"fetch",
+			func(c *Client) error {
+				emails, err := c.GetAll(false, false)
+				if len(emails) != 2 || imap.AsNumber(emails[1].ID) != 2 || emails[1].Subject != "two" {
+					t.Errorf("GetAll() got %+v, want the emails by sequence number", emails)
+				}
+				return err
+			},
+			"FETCH 1:2 (INTERNALDATE BODY[]",
+			func(srv *eazyetest.Server, uid uint32) bool { return !serverHasFlag(srv, "INBOX", uid, `\Seen`) },
+		},
+		{
+			"store",
+			func(c *Client) error { return c.SetAsRead(Email{ID: uint32(2)}) },
+			`STORE 2 +FLAGS`,
+			func(srv *eazyetest.Server, uid uint32) bool { return serverHasFlag(srv, "INBOX", uid, `\Seen`) },
+		},
+		{
+			"copy",
+			func(c *Client) error { return c.Copy(Email{ID: uint32(2)}, "Archive") },
+			"COPY 2 ",
+			func(srv *eazyetest.Server, uid uint32) bool {
+				archived := srv.Messages("Archive")
+				return len(archived) == 1 && strings.Contains(string(archived[0].Raw), "Subject: two")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		srv := testServer(t)
+		srv.AddFolder("Archive")
+		srv.AddMessage("INBOX", []byte("Subject: gone\r\n\r\nx\r\n"), `\Deleted`)
+		srv.AddMessage("INBOX", []byte("Subject: one\r\n\r\nx\r\n"))
+		uid := srv.AddMessage("INBOX", []byte("Subject: two\r\n\r\nx\r\n"))
+		c := testClient(t, srv, SetSequenceNumbers(true))
+		// renumber the emails so that sequence numbers and UIDs differ
+		if err := c.Expunge(); err != nil {
+			t.Fatalf("Expunge() returned an error: %s", err)
+		}
+		srv.ResetCommands()
+
+		if err := tt.run(c); err != nil {
+			t.Fatalf("%s: returned an error: %s", tt.name, err)
+		}
+		if !hasCommand(srv, tt.want) {
+			t.Errorf("%s: sent %q, want %q", tt.name, srv.Commands(), tt.want)
+		}
+		if hasCommand(srv, "UID ") {
+			t.Errorf("%s: sent %q, want no UID commands", tt.name, srv.Commands())
+		}
+		if !tt.done(srv, uid) {
+			t.Errorf("%s: got %+v on the server", tt.name, srv.Messages("INBOX"))
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// SetConcurrency is a functional option to set the Concurrency attr.
+func SetConcurrency(n int) Option {
+	return func(c *Client) {
+		c.Concurrency = n
+	}
+}
+
+// getEmailsConcurrently is getEmails split across up to c.Concurrency
+// connections, c's own included. Each gets a contiguous share of the UIDs, so
+// the emails are passed along in no particular order. With FailFast the first
+// error stops all of them.
+func (c *Client) getEmailsConcurrently(ctx context.Context, uids []uint32, markAsRead, delete, peek bool, responses chan Response) {
+	workers := min(c.Concurrency, (len(uids)+c.fetchBatchSize()-1)/c.fetchBatchSize())
+	sessions := []*Client{c}
+	for len(sessions) < workers {
+		session, err := c.WithFolder(c.Folder)
+		if err != nil {
+			// carry on with the connections we have
+			c.log(ctx, slog.LevelWarn, "unable to open another connection", "err", err)
+			break
+		}
+		defer session.Close()
+		sessions = append(sessions, session)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	size := (len(uids) + len(sessions) - 1) / len(sessions)
+	for i, session := range sessions {
+		share := uids[min(i*size, len(uids)):min((i+1)*size, len(uids))]
+		if len(share) == 0 {
+			continue
+		}
+
+		own := make(chan Response)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			defer close(own)
+			session.fetchEmails(ctx, share, markAsRead, delete, peek, own)
+		}()
+		go func() {
+			defer wg.Done()
+			for resp := range own {
+				if !send(ctx, responses, resp) {
+					continue
+				}
+				if resp.Err != nil && c.fatal(resp.Err) {
+					cancel()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, session := range sessions[1:] {
+		c.highestUID = max(c.highestUID, session.highestUID)
+		c.highestModSeq = max(c.highestModSeq, session.highestModSeq)
+	}
+}
+
+// fatal tells whether the error stops the generator under the Client's
+// ErrorStrategy, see errorHandler.
+func (c *Client) fatal(err error) bool {
+	if c.SkipBroken && errors.Is(err, ErrParse) {
+		return false
+	}
+	return c.ErrorStrategy == FailFast
+}
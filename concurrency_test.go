@@ -0,0 +1,68 @@
+package eazye
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFatal(t *testing.T) {
+	parseErr := (&Client{}).emailError(OpParse, 1, fmt.Errorf("broken"))
+	deleteErr := (&Client{}).emailError(OpDelete, 1, fmt.Errorf("refused"))
+
+	tests := []struct {
+		client Client
+		err    error
+		want   bool
+	}{
+		{Client{}, deleteErr, true},
+		{Client{}, parseErr, true},
+		{Client{SkipBroken: true}, parseErr, false},
+		{Client{SkipBroken: true}, deleteErr, true},
+		{Client{ErrorStrategy: SkipAndContinue}, deleteErr, false},
+		{Client{ErrorStrategy: CollectErrors}, deleteErr, false},
+	}
+	for i, test := range tests {
+		if got := test.client.fatal(test.err); got != test.want {
+			t.Errorf("%d: fatal(%s) got %v, want %v", i, test.err, got, test.want)
+		}
+	}
+}
+
+func TestGetAllConcurrently(t *testing.T) {
+	srv := testServer(t)
+	for n := 0; n < 10; n++ {
+		srv.AddMessage("INBOX", []byte(fmt.Sprintf("Subject: %d\r\n\r\nx\r\n", n)))
+	}
+
+	c := testClient(t, srv, SetConcurrency(3), SetFetchBatchSize(2))
+	emails, err := c.GetAll(true, false)
+	if err != nil {
+		t.Fatalf("GetAll() returned an error: %s", err)
+	}
+
+	subjects := map[string]bool{}
+	for _, email := range emails {
+		subjects[email.Subject] = true
+	}
+	if len(emails) != 10 || len(subjects) != 10 {
+		t.Errorf("GetAll() got %d emails, %d different, want 10", len(emails), len(subjects))
+	}
+	for _, msg := range srv.Messages("INBOX") {
+		if !serverHasFlag(srv, "INBOX", msg.UID, `\Seen`) {
+			t.Errorf("GetAll() left email %d unread", msg.UID)
+		}
+	}
+	logins := 0
+	for _, cmd := range srv.Commands() {
+		if strings.HasPrefix(cmd, "LOGIN") {
+			logins++
+		}
+	}
+	if logins != 3 {
+		t.Errorf("GetAll() logged in %d times, want 3", logins)
+	}
+	if state := c.SaveState(); state.HighestUID != 10 {
+		t.Errorf("GetAll() got highest UID %d, want 10", state.HighestUID)
+	}
+}
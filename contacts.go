@@ -0,0 +1,137 @@
+package eazye
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// Contact is an address seen in the From, To or Cc headers of a set of emails.
+type Contact struct {
+	Address string
+	// Names holds every distinct display name seen along with the address.
+	Names     []string
+	Messages  int
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// contactHeaders are the headers addresses are collected from.
+var contactHeaders = []string{"From", "To", "Cc"}
+
+// AddressBook collects the deduplicated contacts of a set of emails.
+type AddressBook struct {
+	contacts map[string]*Contact
+}
+
+// NewAddressBook initializes an empty AddressBook.
+func NewAddressBook() *AddressBook {
+	return &AddressBook{contacts: map[string]*Contact{}}
+}
+
+// Add records all of the addresses found in the header of a message received
+// at the given time. Addresses that can not be parsed are ignored.
+func (b *AddressBook) Add(header mail.Header, date time.Time) {
+	seen := map[string]bool{}
+	for _, key := range contactHeaders {
+		addrs, err := header.AddressList(key)
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			address := strings.ToLower(addr.Address)
+			contact, ok := b.contacts[address]
+			if !ok {
+				contact = &Contact{Address: address, FirstSeen: date, LastSeen: date}
+				b.contacts[address] = contact
+			}
+			if addr.Name != "" && !containsString(contact.Names, addr.Name) {
+				contact.Names = append(contact.Names, addr.Name)
+			}
+			// only count each message once per contact
+			if seen[address] {
+				continue
+			}
+			seen[address] = true
+			contact.Messages++
+			if date.Before(contact.FirstSeen) {
+				contact.FirstSeen = date
+			}
+			if date.After(contact.LastSeen) {
+				contact.LastSeen = date
+			}
+		}
+	}
+}
+
+// Contacts returns all of the contacts collected so far, sorted by address.
+func (b *AddressBook) Contacts() []Contact {
+	contacts := make([]Contact, 0, len(b.contacts))
+	for _, contact := range b.contacts {
+		contacts = append(contacts, *contact)
+	}
+	sort.Slice(contacts, func(i, j int) bool {
+		return contacts[i].Address < contacts[j].Address
+	})
+	return contacts
+}
+
+// Contacts will build the list of contacts found in the headers of all emails
+// matching the query. Only the headers are fetched and the emails are not
+// marked as read.
+func (c *Client) Contacts(q Query) ([]Contact, error) {
+	return c.ContactsContext(context.Background(), q)
+}
+
+// ContactsContext is Contacts with a context.
+func (c *Client) ContactsContext(ctx context.Context, q Query) ([]Contact, error) {
+	book := NewAddressBook()
+
+	cmd, err := c.findEmails(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	// fetch the headers in batches like fetchEmails, so huge folders
+	// neither time out nor have to fit in memory all at once
+	for _, uids := range batches(searchResults(cmd), c.fetchBatchSize()) {
+		seq := &imap.SeqSet{}
+		seq.AddNum(uids...)
+		fCmd, err := c.do(ctx, func() (*imap.Command, error) {
+			return c.uidFetch(seq, "UID", "INTERNALDATE", "RFC822.HEADER")
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to perform uid fetch: %w", err)
+		}
+
+		for _, msgData := range fCmd.Data {
+			info := c.messageInfo(msgData)
+			if _, ok := info.Attrs["RFC822.HEADER"]; !ok {
+				continue
+			}
+
+			msg, err := mail.ReadMessage(bytes.NewReader(imap.AsBytes(info.Attrs["RFC822.HEADER"])))
+			if err != nil {
+				return nil, fmt.Errorf("unable to read header: %w", err)
+			}
+			book.Add(msg.Header, info.InternalDate)
+		}
+	}
+
+	return book.Contacts(), nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAddressBook(t *testing.T) {
+	first := time.Date(2014, 8, 11, 0, 0, 0, 0, time.UTC)
+	last := first.Add(24 * time.Hour)
+
+	book := NewAddressBook()
+	book.Add(mail.Header{
+		"From": {`"Los Angeles Times" <news@e.latimes.com>`},
+		"To":   {"an.email.address@gmail.com"},
+	}, last)
+	book.Add(mail.Header{
+		"From": {"LA Times <NEWS@e.latimes.com>"},
+		"To":   {"an.email.address@gmail.com"},
+		"Cc":   {"an.email.address@gmail.com, other@example.com"},
+	}, first)
+
+	contacts := book.Contacts()
+	if len(contacts) != 3 {
+		t.Fatalf("Contacts() got %d contacts, want 3: %+v", len(contacts), contacts)
+	}
+
+	me := contacts[0]
+	if me.Address != "an.email.address@gmail.com" || me.Messages != 2 || len(me.Names) != 0 {
+		t.Errorf("Contacts() got unexpected contact: %+v", me)
+	}
+
+	news := contacts[1]
+	if news.Address != "news@e.latimes.com" || news.Messages != 2 || len(news.Names) != 2 {
+		t.Errorf("Contacts() got unexpected contact: %+v", news)
+	}
+	if !news.FirstSeen.Equal(first) || !news.LastSeen.Equal(last) {
+		t.Errorf("Contacts() got first seen %s and last seen %s, want %s and %s", news.FirstSeen, news.LastSeen, first, last)
+	}
+}
+
+func TestClientContacts(t *testing.T) {
+	srv := testServer(t)
+	first := time.Date(2014, 8, 11, 10, 0, 0, 0, time.UTC)
+	srv.AddMessageAt("INBOX", []byte("From: Alice <alice@example.com>\r\nTo: me@example.com\r\n\r\nx\r\n"), first)
+	srv.AddMessageAt("INBOX", []byte("From: bob@example.com\r\nTo: me@example.com\r\n\r\nx\r\n"), first.Add(time.Hour))
+	srv.AddMessageAt("INBOX", []byte("From: alice@example.com\r\nTo: me@example.com\r\n\r\nx\r\n"), first.Add(2*time.Hour))
+
+	c := testClient(t, srv, SetFetchBatchSize(2))
+	contacts, err := c.Contacts(All())
+	if err != nil {
+		t.Fatalf("Contacts() returned an error: %s", err)
+	}
+	var got []string
+	for _, contact := range contacts {
+		got = append(got, fmt.Sprintf("%s:%d", contact.Address, contact.Messages))
+	}
+	if want := []string{"alice@example.com:2", "bob@example.com:1", "me@example.com:3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Contacts() got %q, want %q", got, want)
+	}
+	if alice := contacts[0]; !alice.FirstSeen.Equal(first) || !alice.LastSeen.Equal(first.Add(2*time.Hour)) {
+		t.Errorf("Contacts() got alice seen from %s to %s", alice.FirstSeen, alice.LastSeen)
+	}
+	if !hasCommand(srv, "UID FETCH 1:2 ") || !hasCommand(srv, "UID FETCH 3 ") {
+		t.Errorf("Contacts() got commands %q, want the headers fetched in batches", srv.Commands())
+	}
+	if serverHasFlag(srv, "INBOX", 1, `\Seen`) {
+		t.Errorf("Contacts() marked the email as read")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = c.ContactsContext(ctx, All()); !errors.Is(err, context.Canceled) {
+		t.Errorf("ContactsContext() with a cancelled context got %v, want %v", err, context.Canceled)
+	}
+}
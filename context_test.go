@@ -0,0 +1,55 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestContextCancelled(t *testing.T) {
+	srv := testServer(t)
+	uid := srv.AddMessage("INBOX", []byte("Subject: hi\r\n\r\nx\r\n"))
+	email := Email{ID: uid}
+
+	tests := []struct {
+		name string
+		run  func(context.Context, *Client) error
+	}{
+		{"GetAllContext", func(ctx context.Context, c *Client) error {
+			_, err := c.GetAllContext(ctx, false, false)
+			return err
+		}},
+		{"GetUnreadContext", func(ctx context.Context, c *Client) error {
+			_, err := c.GetUnreadContext(ctx, false, false)
+			return err
+		}},
+		{"GetSinceContext", func(ctx context.Context, c *Client) error {
+			_, err := c.GetSinceContext(ctx, time.Now().Add(-time.Hour), false, false)
+			return err
+		}},
+		{"SearchContext", func(ctx context.Context, c *Client) error {
+			_, err := c.SearchContext(ctx, All(), false, false)
+			return err
+		}},
+		{"GetByUIDContext", func(ctx context.Context, c *Client) error {
+			_, err := c.GetByUIDContext(ctx, uid)
+			return err
+		}},
+		{"SetAsReadContext", func(ctx context.Context, c *Client) error { return c.SetAsReadContext(ctx, email) }},
+		{"DeleteEmailContext", func(ctx context.Context, c *Client) error { return c.DeleteEmailContext(ctx, email) }},
+		{"ExpungeContext", func(ctx context.Context, c *Client) error { return c.ExpungeContext(ctx) }},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, tt := range tests {
+		c := testClient(t, srv)
+		if err := tt.run(ctx, c); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s() got %v, want %s", tt.name, err, context.Canceled)
+		}
+		if serverHasFlag(srv, "INBOX", uid, `\Seen`) || serverHasFlag(srv, "INBOX", uid, `\Deleted`) {
+			t.Fatalf("%s() changed the email after the context was cancelled", tt.name)
+		}
+	}
+}
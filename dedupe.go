@@ -0,0 +1,159 @@
+package eazye
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// Duplicate is an email DeduplicateFolder removed, or would remove.
+type Duplicate struct {
+	UID uint32
+	// Original is the UID of the copy that is kept.
+	Original     uint32
+	MessageID    string
+	Subject      string
+	InternalDate time.Time
+}
+
+// dedupeBatchSize is the number of emails deleted per STORE command.
+const dedupeBatchSize = 500
+
+// DeduplicateFolder will find the emails in the folder that share a
+// Message-ID, or a fingerprint of their headers and size if they have none,
+// and delete all but the oldest copy. With dryRun set nothing is deleted.
+// Either way the duplicates are returned, sorted by UID.
+//
+// The duplicates are expunged right away if the server supports UIDPLUS,
+// otherwise they are only flagged as deleted, like DeleteEmail does. In safe
+// mode only dry runs are allowed.
+func (c *Client) DeduplicateFolder(folder string, dryRun bool) ([]Duplicate, error) {
+	if c.SafeMode && !dryRun {
+		return nil, ErrReadOnlyMode
+	}
+
+	session, err := c.WithFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+
+	cmd, err := session.wait(context.Background())(session.uidSearch("ALL"))
+	if err != nil {
+		return nil, fmt.Errorf("uid search failed: %w", err)
+	}
+	seq := &imap.SeqSet{}
+	seq.AddNum(searchResults(cmd)...)
+	if seq.Empty() {
+		return nil, nil
+	}
+
+	fCmd, err := session.wait(context.Background())(session.uidFetch(seq, "UID", "INTERNALDATE", "RFC822.SIZE", "RFC822.HEADER"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to perform uid fetch: %w", err)
+	}
+
+	var candidates []dedupeCandidate
+	for _, msgData := range fCmd.Data {
+		info := session.messageInfo(msgData)
+		if _, ok := info.Attrs["RFC822.HEADER"]; !ok {
+			continue
+		}
+		msg, err := mail.ReadMessage(bytes.NewReader(imap.AsBytes(info.Attrs["RFC822.HEADER"])))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read header: %w", err)
+		}
+		candidates = append(candidates, dedupeCandidate{
+			uid:          info.UID,
+			internalDate: info.InternalDate,
+			size:         info.Size,
+			header:       msg.Header,
+		})
+	}
+
+	dups := findDuplicates(candidates)
+	if dryRun || len(dups) == 0 {
+		return dups, nil
+	}
+
+	for start := 0; start < len(dups); start += dedupeBatchSize {
+		end := start + dedupeBatchSize
+		if end > len(dups) {
+			end = len(dups)
+		}
+
+		batch := &imap.SeqSet{}
+		for _, dup := range dups[start:end] {
+			batch.AddNum(dup.UID)
+		}
+		if _, err = session.wait(context.Background())(session.uidStore(batch, "+FLAGS.SILENT", `\Deleted`)); err != nil {
+			return dups[:start], fmt.Errorf("unable to delete duplicates: %w", err)
+		}
+		for _, dup := range dups[start:end] {
+			session.audit(AuditDeleted, dup.UID, "")
+		}
+		if session.caps("UIDPLUS") && !session.SequenceNumbers {
+			if _, err = session.wait(context.Background())(session.server().Expunge(batch)); err != nil {
+				return dups[:end], fmt.Errorf("unable to expunge duplicates: %w", err)
+			}
+		}
+	}
+
+	return dups, nil
+}
+
+type dedupeCandidate struct {
+	uid          uint32
+	internalDate time.Time
+	size         uint32
+	header       mail.Header
+}
+
+// key groups copies of the same email together, see idKey and sumKey.
+func (m dedupeCandidate) key() string {
+	if key := idKey(m.header.Get("Message-Id")); key != "" {
+		return key
+	}
+	h := m.header
+	return sumKey(h.Get("Date"), h.Get("From"), h.Get("To"), h.Get("Subject"), strconv.FormatUint(uint64(m.size), 10))
+}
+
+// findDuplicates returns every email but the oldest of each group, sorted by
+// UID. Ties on the internal date go to the lowest UID.
+func findDuplicates(candidates []dedupeCandidate) []Duplicate {
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].internalDate.Equal(candidates[j].internalDate) {
+			return candidates[i].internalDate.Before(candidates[j].internalDate)
+		}
+		return candidates[i].uid < candidates[j].uid
+	})
+
+	originals := map[string]uint32{}
+	var dups []Duplicate
+	for _, m := range candidates {
+		key := m.key()
+		original, ok := originals[key]
+		if !ok {
+			originals[key] = m.uid
+			continue
+		}
+		dups = append(dups, Duplicate{
+			UID:          m.uid,
+			Original:     original,
+			MessageID:    m.header.Get("Message-Id"),
+			Subject:      m.header.Get("Subject"),
+			InternalDate: m.internalDate,
+		})
+	}
+
+	sort.Slice(dups, func(i, j int) bool {
+		return dups[i].UID < dups[j].UID
+	})
+	return dups
+}
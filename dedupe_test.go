@@ -0,0 +1,41 @@
+package eazye
+
+import (
+	"net/mail"
+	"testing"
+	"time"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	day := time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)
+	withID := func(id string) mail.Header {
+		return mail.Header{"Message-Id": {id}, "Subject": {"hi"}}
+	}
+	noID := mail.Header{"From": {"a@example.com"}, "Subject": {"no id"}}
+
+	given := []dedupeCandidate{
+		{uid: 5, internalDate: day.Add(2 * time.Hour), header: withID("<1@x>")},
+		{uid: 2, internalDate: day, header: withID("<1@x>")},
+		{uid: 9, internalDate: day, header: withID("<1@x>")},
+		{uid: 3, internalDate: day, header: withID("<2@x>")},
+		{uid: 7, internalDate: day, size: 10, header: noID},
+		{uid: 8, internalDate: day, size: 10, header: noID},
+		// same headers but a different size is a different email
+		{uid: 10, internalDate: day, size: 11, header: noID},
+	}
+
+	got := findDuplicates(given)
+	want := []Duplicate{
+		{UID: 5, Original: 2, MessageID: "<1@x>"},
+		{UID: 8, Original: 7},
+		{UID: 9, Original: 2, MessageID: "<1@x>"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("findDuplicates() got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].UID != want[i].UID || got[i].Original != want[i].Original || got[i].MessageID != want[i].MessageID {
+			t.Errorf("findDuplicates()[%d] got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
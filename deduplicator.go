@@ -0,0 +1,230 @@
+package eazye
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// SeenStore remembers the keys of the emails a Deduplicator has seen.
+type SeenStore interface {
+	// Has reports whether the key was added before.
+	Has(key string) (bool, error)
+	// Add records the keys.
+	Add(keys ...string) error
+}
+
+// MemorySeenStore is a SeenStore that keeps the keys in memory, for
+// deduplicating across the folders or accounts of a single run.
+type MemorySeenStore struct {
+	mu   sync.Mutex
+	keys map[string]bool
+}
+
+// NewMemorySeenStore initializes an empty MemorySeenStore.
+func NewMemorySeenStore() *MemorySeenStore {
+	return &MemorySeenStore{keys: map[string]bool{}}
+}
+
+// Has reports whether the key was added before.
+func (s *MemorySeenStore) Has(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys[key], nil
+}
+
+// Add records the keys.
+func (s *MemorySeenStore) Add(keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		s.keys[key] = true
+	}
+	return nil
+}
+
+// FileSeenStore is a SeenStore that appends the keys to a file, one per line,
+// so they carry over to the next run.
+type FileSeenStore struct {
+	mu   sync.Mutex
+	path string
+	keys map[string]bool
+}
+
+// NewFileSeenStore loads the keys in the file at path, a missing file is not
+// an error.
+func NewFileSeenStore(path string) (*FileSeenStore, error) {
+	s := &FileSeenStore{path: path, keys: map[string]bool{}}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if key := strings.TrimSpace(scanner.Text()); key != "" {
+			s.keys[key] = true
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read seen keys: %w", err)
+	}
+	return s, nil
+}
+
+// Has reports whether the key was added before.
+func (s *FileSeenStore) Has(key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keys[key], nil
+}
+
+// Add appends the new keys to the file.
+func (s *FileSeenStore) Add(keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var buf bytes.Buffer
+	for _, key := range keys {
+		if !s.keys[key] {
+			buf.WriteString(key + "\n")
+		}
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		s.keys[key] = true
+	}
+	return nil
+}
+
+// Deduplicator tells apart the emails already seen in another folder or
+// account, or on an earlier run, from the new ones. Emails are matched by
+// Message-ID, and by a hash of their content if they have none or Content is
+// set.
+//
+// Checking and recording are separate steps so an email is only recorded
+// once it was handled. Workers sharing a store may then both handle an email
+// they check at the same time, use a ClaimStore to prevent that.
+type Deduplicator struct {
+	Store SeenStore
+	// Content also matches emails by a hash of their sender, subject and
+	// body, for copies whose Message-ID was changed along the way.
+	Content bool
+}
+
+// NewDeduplicator initializes a Deduplicator on top of the store.
+func NewDeduplicator(store SeenStore) *Deduplicator {
+	return &Deduplicator{Store: store}
+}
+
+// IsDuplicate reports whether an email with any of the keys of this one was
+// recorded before.
+func (d *Deduplicator) IsDuplicate(email Email) (bool, error) {
+	keys, err := d.Keys(email)
+	if err != nil {
+		return false, err
+	}
+	for _, key := range keys {
+		seen, err := d.Store.Has(key)
+		if err != nil {
+			return false, fmt.Errorf("unable to look up seen email: %w", err)
+		}
+		if seen {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Record records the email as seen.
+func (d *Deduplicator) Record(email Email) error {
+	keys, err := d.Keys(email)
+	if err != nil {
+		return err
+	}
+	if err = d.Store.Add(keys...); err != nil {
+		return fmt.Errorf("unable to record seen email: %w", err)
+	}
+	return nil
+}
+
+// Keys returns the keys the email is matched by: "id:" and its Message-ID,
+// and "sum:" and the hex SHA-256 of its content.
+func (d *Deduplicator) Keys(email Email) ([]string, error) {
+	var keys []string
+	if key := idKey(email.MessageID); key != "" {
+		keys = append(keys, key)
+	}
+	if len(keys) > 0 && !d.Content {
+		return keys, nil
+	}
+
+	sum, err := contentKey(email)
+	if err != nil {
+		return nil, err
+	}
+	return append(keys, sum), nil
+}
+
+// contentKey is the sumKey of the parts of an email that stay the same from
+// one copy to the next: the sender, the subject and the body, without the
+// headers servers add on the way.
+func contentKey(email Email) (string, error) {
+	raw, err := rawMessage(email)
+	if err != nil {
+		return "", fmt.Errorf("unable to hash email: %w", err)
+	}
+	_, body := splitMessage(raw)
+
+	var parts []string
+	if email.From != nil {
+		parts = append(parts, strings.ToLower(email.From.Address))
+	}
+	parts = append(parts, email.Subject, string(bytes.TrimRight(toCRLF(body), "\r\n")))
+	return sumKey(parts...), nil
+}
+
+// idKey is the key of copies of an email sharing a Message-ID, "" if the
+// header has none. Deduplicator and DeduplicateFolder match emails by it.
+func idKey(messageID string) string {
+	if ids := parseMessageIDs(messageID); len(ids) > 0 {
+		return "id:" + ids[0]
+	}
+	return ""
+}
+
+// sumKey is the key of copies of an email sharing the given parts: "sum:" and
+// the hex SHA-256 of them, separated by NUL bytes.
+func sumKey(parts ...string) string {
+	h := sha256.New()
+	for i, part := range parts {
+		if i > 0 {
+			h.Write([]byte{0})
+		}
+		h.Write([]byte(part))
+	}
+	return "sum:" + hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,76 @@
+package eazye
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDeduplicator(t *testing.T) {
+	original := "From: jane@example.com\r\nSubject: Hi\r\nMessage-Id: <1@x>\r\n\r\nHello\r\n"
+	tests := []struct {
+		name    string
+		content bool
+		raw     string
+		want    bool
+	}{
+		{"same Message-ID", false, "Received: by mx2\r\n" + original, true},
+		{"other Message-ID", false, "From: jane@example.com\r\nSubject: Hi\r\nMessage-Id: <2@x>\r\n\r\nHello\r\n", false},
+		{"other Message-ID by content", true, "From: jane@example.com\r\nSubject: Hi\r\nMessage-Id: <2@x>\r\n\r\nHello\n", true},
+		{"no Message-ID", false, "From: jane@example.com\r\nSubject: Hi\r\n\r\nHello\r\n", false},
+		{"other body", true, "From: jane@example.com\r\nSubject: Hi\r\nMessage-Id: <2@x>\r\n\r\nBye\r\n", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := NewDeduplicator(NewMemorySeenStore())
+			d.Content = test.content
+			if err := d.Record(rawEmail(t, original)); err != nil {
+				t.Fatalf("Record() returned an error: %s", err)
+			}
+			got, err := d.IsDuplicate(rawEmail(t, test.raw))
+			if err != nil {
+				t.Fatalf("IsDuplicate() returned an error: %s", err)
+			}
+			if got != test.want {
+				t.Errorf("IsDuplicate() got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDeduplicatorNoMessageID(t *testing.T) {
+	d := NewDeduplicator(NewMemorySeenStore())
+	raw := "From: jane@example.com\r\nSubject: Hi\r\n\r\nHello\r\n"
+	if err := d.Record(rawEmail(t, raw)); err != nil {
+		t.Fatalf("Record() returned an error: %s", err)
+	}
+	if got, _ := d.IsDuplicate(rawEmail(t, "Received: by mx2\r\n"+raw)); !got {
+		t.Error("IsDuplicate() of a copy without Message-ID got false, want true")
+	}
+}
+
+func TestFileSeenStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen")
+	store, err := NewFileSeenStore(path)
+	if err != nil {
+		t.Fatalf("NewFileSeenStore() with no file returned an error: %s", err)
+	}
+	if err = store.Add("id:<1@x>", "sum:ab"); err != nil {
+		t.Fatalf("Add() returned an error: %s", err)
+	}
+	if err = store.Add("id:<1@x>"); err != nil {
+		t.Fatalf("Add() returned an error: %s", err)
+	}
+
+	store, err = NewFileSeenStore(path)
+	if err != nil {
+		t.Fatalf("NewFileSeenStore() returned an error: %s", err)
+	}
+	for key, want := range map[string]bool{"id:<1@x>": true, "sum:ab": true, "id:<2@x>": false} {
+		if got, _ := store.Has(key); got != want {
+			t.Errorf("Has(%q) got %v, want %v", key, got, want)
+		}
+	}
+	if len(store.keys) != 2 {
+		t.Errorf("NewFileSeenStore() got %d keys, want 2", len(store.keys))
+	}
+}
@@ -0,0 +1,156 @@
+package eazye
+
+import (
+	"bytes"
+	"net/mail"
+	"sort"
+	"strings"
+)
+
+// EmailDiff is the difference between two revisions of an email.
+type EmailDiff struct {
+	Headers []HeaderChange
+	Text    []TextChange
+}
+
+// Equal reports whether the two emails had the same headers and text.
+func (d EmailDiff) Equal() bool {
+	return len(d.Headers) == 0 && len(d.Text) == 0
+}
+
+// HeaderChange is a header whose values differ, Old or New is empty if the
+// header was added or removed.
+type HeaderChange struct {
+	Key string
+	Old []string
+	New []string
+}
+
+// TextChange is a line removed from the first email's text or added in the
+// second one's.
+type TextChange struct {
+	Added bool
+	// Line is the number of the line, starting at 1 and not counting blank
+	// lines, in the text it belongs to.
+	Line int
+	Text string
+}
+
+// CompareEmails will diff the headers and the visible text of two emails,
+// for spotting what changed between an email and its edited re-send.
+// Headers such as Date and Message-Id are compared like any other, callers
+// only interested in some of them can filter the result. An email without a
+// Message, e.g. one built by hand, has no headers to compare.
+func CompareEmails(a, b Email) (EmailDiff, error) {
+	var diff EmailDiff
+
+	textA, err := compareText(a)
+	if err != nil {
+		return diff, err
+	}
+	textB, err := compareText(b)
+	if err != nil {
+		return diff, err
+	}
+
+	headerA, headerB := compareHeader(a), compareHeader(b)
+	keys := map[string]bool{}
+	for key := range headerA {
+		keys[key] = true
+	}
+	for key := range headerB {
+		keys[key] = true
+	}
+	for key := range keys {
+		old, new := headerA[key], headerB[key]
+		if !equalStrings(old, new) {
+			diff.Headers = append(diff.Headers, HeaderChange{Key: key, Old: old, New: new})
+		}
+	}
+	sort.Slice(diff.Headers, func(i, j int) bool {
+		return diff.Headers[i].Key < diff.Headers[j].Key
+	})
+
+	diff.Text = diffLines(splitLines(textA), splitLines(textB))
+	return diff, nil
+}
+
+// compareHeader returns the header of the email, nil if it has no Message.
+func compareHeader(e Email) mail.Header {
+	if e.Message == nil {
+		return nil
+	}
+	return e.Message.Header
+}
+
+// compareText returns the text of the email to compare. Without a Message
+// there is nothing to parse, so only its Text and HTML are used.
+func compareText(e Email) (string, error) {
+	if e.Message != nil {
+		return e.TextBody()
+	}
+	visible, err := e.VisibleText()
+	return string(bytes.Join(visible, []byte("\n"))), err
+}
+
+// diffLines returns the lines to remove from a and add from b to turn a into
+// b, based on their longest common subsequence.
+func diffLines(a, b []string) []TextChange {
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var changes []TextChange
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			changes = append(changes, TextChange{Line: i + 1, Text: a[i]})
+			i++
+		default:
+			changes = append(changes, TextChange{Added: true, Line: j + 1, Text: b[j]})
+			j++
+		}
+	}
+	return changes
+}
+
+// splitLines splits the text into trimmed lines, ignoring blank ones so a
+// change in spacing alone does not show up.
+func splitLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
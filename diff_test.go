@@ -0,0 +1,75 @@
+package eazye
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestCompareEmails(t *testing.T) {
+	read := func(raw string) Email {
+		msg, err := mail.ReadMessage(strings.NewReader(raw))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Email{Message: msg}
+	}
+
+	a := read("Subject: Standup\r\nContent-Type: text/plain\r\n\r\n" +
+		"When: Monday 10:00\r\nWhere: Room 4\r\n\r\nSee you there\r\n")
+	b := read("Subject: Updated: Standup\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n" +
+		"When: Monday 11:00\r\n\r\n  Where: Room 4\r\n\r\nSee you there\r\n")
+
+	diff, err := CompareEmails(a, b)
+	if err != nil {
+		t.Fatalf("CompareEmails() returned an error: %s", err)
+	}
+
+	if len(diff.Headers) != 2 || diff.Headers[0].Key != "Content-Type" || diff.Headers[1].Key != "Subject" {
+		t.Errorf("CompareEmails() got header changes %+v, want Content-Type and Subject", diff.Headers)
+	}
+
+	want := []TextChange{
+		{Line: 1, Text: "When: Monday 10:00"},
+		{Added: true, Line: 1, Text: "When: Monday 11:00"},
+	}
+	if len(diff.Text) != len(want) {
+		t.Fatalf("CompareEmails() got text changes %+v, want %+v", diff.Text, want)
+	}
+	for i := range want {
+		if diff.Text[i] != want[i] {
+			t.Errorf("CompareEmails() text change %d got %+v, want %+v", i, diff.Text[i], want[i])
+		}
+	}
+
+	// the bodies can be read again
+	if diff, err = CompareEmails(a, a); err != nil || !diff.Equal() {
+		t.Errorf("CompareEmails() of an email with itself got %+v, %v, want no changes", diff, err)
+	}
+}
+
+func TestCompareEmailsNoMessage(t *testing.T) {
+	built := Email{Subject: "Standup", Text: []byte("When: Monday 10:00\n")}
+	fetched := rawEmail(t, "Subject: Standup\r\nContent-Type: text/plain\r\n\r\nWhen: Monday 11:00\r\n")
+
+	diff, err := CompareEmails(built, fetched)
+	if err != nil {
+		t.Fatalf("CompareEmails() returned an error: %s", err)
+	}
+	if len(diff.Headers) != 2 || len(diff.Text) != 2 {
+		t.Errorf("CompareEmails() got %+v, want the headers added and the line changed", diff)
+	}
+	if diff, err = CompareEmails(Email{}, Email{}); err != nil || !diff.Equal() {
+		t.Errorf("CompareEmails() of empty emails got %+v, %v, want no changes", diff, err)
+	}
+}
+
+const multipartHeader = "Subject: hi\r\nMIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=xyz\r\n\r\n"
+
+const multipartBody = "--xyz\r\n" +
+	"Content-Type: multipart/alternative; boundary=abc\r\n\r\n" +
+	"--abc\r\nContent-Type: text/html\r\n\r\n<p>Caf&eacute; at 10</p>\r\n" +
+	"--abc\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\nCaf=C3=A9 at 10\r\n" +
+	"--abc--\r\n" +
+	"--xyz\r\nContent-Type: text/plain\r\nContent-Disposition: attachment; filename=notes.txt\r\n\r\nnot this\r\n" +
+	"--xyz--\r\n"
@@ -0,0 +1,218 @@
+package eazye
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// DiskCache is a Cache that keeps emails as zstd compressed files in a
+// directory. Once the files add up to more than its max size the least
+// recently used ones are removed. As the files' modification times track
+// their use, a DiskCache opened on the same directory after a restart picks
+// up where the last one left off.
+type DiskCache struct {
+	dir     string
+	maxSize int64
+
+	mu    sync.Mutex
+	size  int64
+	files map[string]*list.Element
+	// lru holds the *diskFile entries, most recently used first.
+	lru *list.List
+
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+type diskFile struct {
+	name string
+	size int64
+}
+
+const diskCacheExt = ".zst"
+
+// NewDiskCache opens the cache in the directory, creating it if need be,
+// holding at most maxSize bytes of compressed emails.
+func NewDiskCache(dir string, maxSize int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create cache directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read cache directory: %w", err)
+	}
+
+	type found struct {
+		diskFile
+		used time.Time
+	}
+	var existing []found
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), diskCacheExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		existing = append(existing, found{diskFile{entry.Name(), info.Size()}, info.ModTime()})
+	}
+	sort.Slice(existing, func(i, j int) bool {
+		return existing[i].used.After(existing[j].used)
+	})
+
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &DiskCache{
+		dir:     dir,
+		maxSize: maxSize,
+		files:   map[string]*list.Element{},
+		lru:     list.New(),
+		enc:     enc,
+		dec:     dec,
+	}
+	for i := range existing {
+		f := &existing[i].diskFile
+		d.files[f.name] = d.lru.PushBack(f)
+		d.size += f.size
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d, d.evict()
+}
+
+// Get reads the email from its file, if it is in the cache.
+func (d *DiskCache) Get(key CacheKey) (CachedEmail, bool, error) {
+	name := diskCacheName(key)
+	d.mu.Lock()
+	elem, ok := d.files[name]
+	if ok {
+		d.lru.MoveToFront(elem)
+	}
+	d.mu.Unlock()
+	if !ok {
+		return CachedEmail{}, false, nil
+	}
+
+	path := filepath.Join(d.dir, name)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		d.remove(name)
+		return CachedEmail{}, false, nil
+	}
+	if err != nil {
+		return CachedEmail{}, false, err
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+
+	email, err := d.decode(data)
+	if err != nil {
+		return CachedEmail{}, false, fmt.Errorf("unable to read cached email %s: %w", name, err)
+	}
+	return email, true, nil
+}
+
+// Put writes the email to its file, evicting the least recently used ones
+// if the cache grows too big.
+func (d *DiskCache) Put(key CacheKey, email CachedEmail) error {
+	data, err := d.encode(email)
+	if err != nil {
+		return err
+	}
+	name := diskCacheName(key)
+	if err = writeFileAtomic(filepath.Join(d.dir, name), data); err != nil {
+		return fmt.Errorf("unable to write cached email %s: %w", name, err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if elem, ok := d.files[name]; ok {
+		f := elem.Value.(*diskFile)
+		d.size += int64(len(data)) - f.size
+		f.size = int64(len(data))
+		d.lru.MoveToFront(elem)
+	} else {
+		d.files[name] = d.lru.PushFront(&diskFile{name, int64(len(data))})
+		d.size += int64(len(data))
+	}
+	return d.evict()
+}
+
+// Size returns the total size of the files in the cache.
+func (d *DiskCache) Size() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.size
+}
+
+// evict removes the least recently used files until the cache fits. The
+// lock must be held.
+func (d *DiskCache) evict() error {
+	for d.size > d.maxSize && d.lru.Len() > 0 {
+		f := d.lru.Remove(d.lru.Back()).(*diskFile)
+		delete(d.files, f.name)
+		d.size -= f.size
+		if err := os.Remove(filepath.Join(d.dir, f.name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("unable to evict cached email %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+func (d *DiskCache) remove(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if elem, ok := d.files[name]; ok {
+		d.size -= d.lru.Remove(elem).(*diskFile).size
+		delete(d.files, name)
+	}
+}
+
+// encode lays out a file as the length of the internal date, the internal
+// date and the compressed message.
+func (d *DiskCache) encode(email CachedEmail) ([]byte, error) {
+	date, err := email.InternalDate.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	data := append([]byte{byte(len(date))}, date...)
+	return d.enc.EncodeAll(email.Raw, data), nil
+}
+
+func (d *DiskCache) decode(data []byte) (CachedEmail, error) {
+	var email CachedEmail
+	if len(data) == 0 || len(data) < 1+int(data[0]) {
+		return email, errors.New("truncated file")
+	}
+	n := 1 + int(data[0])
+	if err := email.InternalDate.UnmarshalBinary(data[1:n]); err != nil {
+		return email, err
+	}
+	raw, err := d.dec.DecodeAll(data[n:], nil)
+	email.Raw = raw
+	return email, err
+}
+
+// diskCacheName is the file name of a cached email. The folder is hex
+// encoded to keep its separators out of the path.
+func diskCacheName(key CacheKey) string {
+	return fmt.Sprintf("%x-%d-%d%s", key.Folder, key.UIDValidity, key.UID, diskCacheExt)
+}
@@ -0,0 +1,61 @@
+package eazye
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiskCache(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewDiskCache(dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	date := time.Date(2014, 8, 12, 11, 11, 28, 0, time.FixedZone("", -6*60*60))
+	key := CacheKey{Folder: "INBOX/Work", UIDValidity: 7, UID: 42}
+	want := CachedEmail{InternalDate: date, Raw: []byte(multipartHeader + multipartBody)}
+	if err = cache.Put(key, want); err != nil {
+		t.Fatalf("Put() returned an error: %s", err)
+	}
+
+	// a new cache on the same directory still has it
+	cache, err = NewDiskCache(dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := cache.Get(key)
+	if err != nil || !ok {
+		t.Fatalf("Get() got %v, %v, want the email", ok, err)
+	}
+	if !got.InternalDate.Equal(date) || string(got.Raw) != string(want.Raw) {
+		t.Errorf("Get() got %+v, want %+v", got, want)
+	}
+
+	if _, ok, err = cache.Get(CacheKey{Folder: "INBOX/Work", UIDValidity: 8, UID: 42}); ok || err != nil {
+		t.Errorf("Get() of another UIDVALIDITY got %v, %v, want a miss", ok, err)
+	}
+}
+
+func TestDiskCacheEviction(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := CachedEmail{Raw: []byte("Subject: hi\r\n\r\nhello")}
+	if err = cache.Put(CacheKey{UID: 1}, email); err != nil {
+		t.Fatal(err)
+	}
+	// room for two emails only
+	cache.maxSize = 2 * cache.Size()
+
+	cache.Put(CacheKey{UID: 2}, email)
+	cache.Get(CacheKey{UID: 1})
+	cache.Put(CacheKey{UID: 3}, email)
+
+	for uid, want := range map[uint32]bool{1: true, 2: false, 3: true} {
+		if _, ok, _ := cache.Get(CacheKey{UID: uid}); ok != want {
+			t.Errorf("Get() of uid %d got %v, want %v", uid, ok, want)
+		}
+	}
+}
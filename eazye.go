@@ -2,28 +2,151 @@ package eazye
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"mime"
+	"net"
 	"net/mail"
+	"strings"
 	"time"
 
 	"github.com/mxk/go-imap/imap"
-	_ "github.com/paulrosania/go-charset/data"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/html"
 )
 
 // MailboxInfo holds onto the credentials and other information.
 // needed for connecting to an IMAP server.
 type Client struct {
-	TLS    bool
+	TLS bool
+	// TLSConfig is used to dial the server if TLS is set, the defaults are
+	// used if it is nil.
+	TLSConfig *tls.Config
+	// Folder is the selected folder, by its name in UTF-8. It is encoded to
+	// modified UTF-7 when sent to the server.
 	Folder string
 	// Read only mode, false (original logic) if not initialized
 	ReadOnly bool
+	// BufferSize of the responses channel, GenerateBufferSize if not set.
+	BufferSize int
+	// FetchBatchSize is the number of emails fetched per FETCH command,
+	// DefaultFetchBatchSize if not set.
+	FetchBatchSize int
+	// ErrorStrategy decides what happens when handling a single email fails.
+	ErrorStrategy ErrorStrategy
+	// SkipBroken passes emails that fail to parse along as errors and carries
+	// on with the rest, whatever the ErrorStrategy.
+	SkipBroken bool
+	// TagMode is the mechanism Tag, Untag and ListTags use.
+	TagMode TagMode
+	// SafeMode forbids anything that would modify the mailbox, see
+	// SetSafeMode.
+	SafeMode bool
+	// Peek fetches emails with BODY.PEEK[], which leaves \Seen alone, instead
+	// of fetching them with BODY[] and removing \Seen again afterwards when
+	// they are not to be marked as read. Other clients never see the emails
+	// flip to read and back.
+	Peek bool
+	// SequenceNumbers is for servers without the UID commands. Emails are
+	// searched, fetched and altered with plain SEARCH, FETCH and STORE, and
+	// their IDs are sequence numbers, which change whenever emails are
+	// expunged from the folder.
+	SequenceNumbers bool
+	// ID is sent to the server with the IMAP ID command (RFC 2971) before
+	// logging in, if set and the server supports it.
+	ID []string
+	// RetryPolicy controls reconnecting when the connection drops, which is
+	// disabled if not set.
+	RetryPolicy RetryPolicy
+	// Extractor, if set, is run over the attachments of every email fetched.
+	Extractor Extractor
+	// Throttle, if set, limits how fast data is read from the server.
+	Throttle *Throttle
+	// Cache, if set, is checked before fetching an email and filled with
+	// the emails fetched.
+	Cache Cache
+	// Redactor, if set, strips personal data out of the text of every email
+	// fetched before it is passed along.
+	Redactor *Redactor
+	// AuditSink, if set, receives a record of everything done to an email.
+	AuditSink AuditSink
+	// SyncStore, if set, keeps the state of the folders synced with SyncNew.
+	SyncStore SyncStore
+	// Auth logs in to the server, plain LOGIN with the user and password
+	// given to New if not set.
+	Auth Authenticator
+	// Logger, if set, is told about connecting, reconnecting and emails that
+	// could not be handled.
+	Logger *slog.Logger
+	// WireLog, if set, also logs every line sent to and received from the
+	// server to the Logger at debug level, with credentials redacted.
+	WireLog bool
+	// Metrics, if set, receives counts and timings of what the Client does.
+	Metrics Metrics
+	// CommandTimeout, if set, is how long to wait for the server to answer a
+	// command, including logging in, before giving up with an ErrTimeout.
+	CommandTimeout time.Duration
+	// Concurrency is the number of connections large fetches are split
+	// across, one if not set. The emails of a fetch split across several
+	// connections are passed along in no particular order.
+	Concurrency int
+	// MaxMessageSize, if set, is the size in bytes above which emails are
+	// not fetched whole but passed along as a skipped Response, so huge
+	// messages are never held in memory.
+	MaxMessageSize uint32
+	// DisabledCapabilities are capabilities of the server the Client acts
+	// as if it did not announce, e.g. MOVE or CONDSTORE on a server known to
+	// get them wrong. The fallbacks used without them are then used instead.
+	DisabledCapabilities []string
+	// DeleteToTrash makes DeleteEmail, and the generators when asked to
+	// delete, move emails to the Trash folder rather than flag them as
+	// deleted, as users expect from their mail clients. Emails in the trash
+	// are flagged as deleted as usual. It can not be used with
+	// SequenceNumbers.
+	DeleteToTrash bool
+	// TracerProvider, if set, gets spans for the searches, fetches, stores
+	// and parsing done by the Client.
+	TracerProvider trace.TracerProvider
 
+	// Imap is the connection to the server, nil if it was set with
+	// SetIMAPConn.
 	Imap *imap.Client
+
+	imapConn imapConn
+
+	host string
+	user string
+	pwd  string
+	conn net.Conn
+
+	// highest UID and mod-sequence fetched so far, see SaveState
+	highestUID    uint32
+	highestModSeq uint64
+
+	// special holds the special folders once looked up, see SpecialFolders
+	special *SpecialFolders
 }
 
+// ErrorStrategy controls how the generate functions deal with errors that
+// only affect a single email, such as a parse failure or a failed delete.
+type ErrorStrategy int
+
+const (
+	// FailFast passes the error along and stops. This is the default.
+	FailFast ErrorStrategy = iota
+	// SkipAndContinue passes the error along and carries on with the next
+	// email.
+	SkipAndContinue
+	// CollectErrors carries on with the next email and passes all of the
+	// errors along together once every email has been handled.
+	CollectErrors
+)
+
 // Option is a type which represents a functional option.
 type Option func(*Client)
 
@@ -48,123 +171,568 @@ func SetTLS(tls bool) Option {
 	}
 }
 
+// SetTLSConfig is a functional option to set the TLSConfig attr.
+func SetTLSConfig(config *tls.Config) Option {
+	return func(c *Client) {
+		c.TLSConfig = config
+	}
+}
+
+// SetBufferSize is a functional option to set the BufferSize attr.
+func SetBufferSize(size int) Option {
+	return func(c *Client) {
+		c.BufferSize = size
+	}
+}
+
+// SetFetchBatchSize is a functional option to set the FetchBatchSize attr.
+func SetFetchBatchSize(size int) Option {
+	return func(c *Client) {
+		c.FetchBatchSize = size
+	}
+}
+
+// SetErrorStrategy is a functional option to set the ErrorStrategy attr.
+func SetErrorStrategy(strategy ErrorStrategy) Option {
+	return func(c *Client) {
+		c.ErrorStrategy = strategy
+	}
+}
+
+// SetSkipBroken is a functional option to set the SkipBroken attr.
+func SetSkipBroken(skip bool) Option {
+	return func(c *Client) {
+		c.SkipBroken = skip
+	}
+}
+
+// SetPeek is a functional option to set the Peek attr.
+func SetPeek(peek bool) Option {
+	return func(c *Client) {
+		c.Peek = peek
+	}
+}
+
+// SetCommandTimeout is a functional option to set the CommandTimeout attr.
+func SetCommandTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.CommandTimeout = timeout
+	}
+}
+
+// SetID is a functional option to set the ID attr.
+func SetID(info ...string) Option {
+	return func(c *Client) {
+		c.ID = info
+	}
+}
+
 // New initializes  a new Client.
 func New(host, user, pwd string, options ...func(*Client)) (*Client, error) {
+	return NewContext(context.Background(), host, user, pwd, options...)
+}
+
+// NewContext initializes a new Client, giving up on connecting once the
+// context is done.
+func NewContext(ctx context.Context, host, user, pwd string, options ...func(*Client)) (*Client, error) {
 	client := &Client{
 		TLS:      false,
 		ReadOnly: false,
+		host:     host,
+		user:     user,
+		pwd:      pwd,
 	}
 
 	for _, option := range options {
 		option(client)
 	}
 
-	var imapClient *imap.Client
-	var err error
-	if client.TLS {
-		imapClient, err = imap.DialTLS(host, new(tls.Config))
-		if err != nil {
-			return client, err
+	err := client.connect(ctx)
+	return client, err
+}
+
+// WithFolder opens a second session on the same server, using the same
+// credentials and options as c, with the given folder selected.
+func (c *Client) WithFolder(folder string) (*Client, error) {
+	if c.imapConn != nil {
+		return nil, errors.New("unable to open a second session on a connection set with SetIMAPConn")
+	}
+
+	clone := *c
+	clone.Folder = folder
+	clone.Imap = nil
+	clone.conn = nil
+	clone.highestUID, clone.highestModSeq = 0, 0
+
+	if err := clone.connect(context.Background()); err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
+
+// logoutTimeout is how long Close waits for the server to say goodbye.
+const logoutTimeout = 30 * time.Second
+
+// Close logs out of the server and closes the connection, also one set with
+// SetIMAPConn.
+func (c *Client) Close() error {
+	if c.server() == nil {
+		return nil
+	}
+	_, err := c.server().Logout(logoutTimeout)
+	return err
+}
+
+// greetingTimeout is how long to wait for the server greeting after dialing.
+const greetingTimeout = 30 * time.Second
+
+// connect dials the server, logs in and selects the folder.
+func (c *Client) connect(ctx context.Context) error {
+	if c.imapConn != nil {
+		return c.open(c.imapConn)
+	}
+
+	parent := ctx
+	if c.CommandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.CommandTimeout)
+		defer cancel()
+	}
+	// tell the CommandTimeout apart from the caller giving up
+	ctxErr := func(err error) error {
+		if parent.Err() != nil {
+			return parent.Err()
 		}
+		return fmt.Errorf("%w after %s: %w", ErrTimeout, c.CommandTimeout, err)
+	}
+
+	var conn net.Conn
+	var err error
+	dialer := new(net.Dialer)
+	if c.TLS {
+		// the dialer fills in the ServerName from the host if it is not set
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: c.TLSConfig}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", c.host)
 	} else {
-		imapClient, err = imap.Dial(host)
+		conn, err = dialer.DialContext(ctx, "tcp", c.host)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctxErr(err)
+		}
+		return err
+	}
+
+	if c.Throttle != nil {
+		conn = &throttledConn{Conn: conn, throttle: c.Throttle}
+	}
+	if c.Metrics != nil {
+		conn = &meteredConn{Conn: conn, metrics: c.Metrics}
+	}
+	if c.WireLog && c.Logger != nil {
+		conn = newWireConn(conn, c.Logger)
+	}
+
+	// unblock whatever we are waiting on if the context is done
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	err = c.login(conn)
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return ctxErr(err)
+		}
+		c.log(ctx, slog.LevelError, "unable to log in", "err", err)
+		return err
+	}
+
+	c.log(ctx, slog.LevelDebug, "connected")
+	return nil
+}
+
+// login sets up the IMAP session on an open connection.
+func (c *Client) login(conn net.Conn) error {
+	host, _, _ := net.SplitHostPort(c.host)
+	client, err := imap.NewClient(conn, host, greetingTimeout)
+	if err != nil {
+		return err
+	}
+
+	if len(c.ID) > 0 && client.Caps["ID"] && !c.disabled("ID") {
+		_, err = imap.Wait(client.ID(c.ID...))
 		if err != nil {
-			return client, err
+			return err
 		}
 	}
 
-	_, err = imapClient.Login(user, pwd)
+	err = c.authenticator().Authenticate(client)
 	if err != nil {
-		return client, err
+		// only a NO or BAD from the server, not e.g. a dropped connection
+		var rspErr imap.ResponseError
+		if errors.As(err, &rspErr) && (rspErr.Status == imap.NO || rspErr.Status == imap.BAD) {
+			return fmt.Errorf("%w: %w", ErrAuthFailed, err)
+		}
+		return err
 	}
 
-	_, err = imap.Wait(imapClient.Select(client.Folder, client.ReadOnly))
+	if err = c.open(imapClient{client}); err != nil {
+		return err
+	}
+
+	c.Imap = client
+	c.conn = conn
+
+	return nil
+}
+
+// open selects the folder on a logged in connection.
+func (c *Client) open(server imapConn) error {
+	_, err := imap.Wait(server.Select(c.Folder, c.ReadOnly || c.SafeMode))
 	if err != nil {
-		return client, err
+		if server.State() == imap.Closed {
+			return fmt.Errorf("%w: %w", ErrConnectionLost, err)
+		}
+		return fmt.Errorf("%w: %s: %w", ErrFolderNotFound, c.Folder, err)
 	}
+	return nil
+}
 
-	client.Imap = imapClient
+// wait returns imap.Wait that gives up once the context is done, or the
+// CommandTimeout passes with an ErrTimeout. As there is no way to abort an
+// IMAP command this closes the connection. It is meant to wrap the command
+// itself, e.g. c.wait(ctx)(c.server().Noop()), so that sending the command is
+// covered as well.
+func (c *Client) wait(ctx context.Context) func(*imap.Command, error) (*imap.Command, error) {
+	if c.conn == nil || (ctx.Done() == nil && c.CommandTimeout <= 0) {
+		return imap.Wait
+	}
 
-	return client, nil
+	parent, cancel := ctx, context.CancelFunc(func() {})
+	if c.CommandTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.CommandTimeout)
+	}
+	conn := c.conn
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	return func(cmd *imap.Command, err error) (*imap.Command, error) {
+		// stop before cancelling, or the connection gets closed
+		defer cancel()
+		defer stop()
+		cmd, err = imap.Wait(cmd, err)
+		if err != nil && ctx.Err() != nil {
+			if parent.Err() != nil {
+				return cmd, parent.Err()
+			}
+			return cmd, fmt.Errorf("%w after %s: %w", ErrTimeout, c.CommandTimeout, err)
+		}
+		return cmd, err
+	}
 }
 
 // GetAll will pull all emails from the email folder and return them as a list.
 func (c *Client) GetAll(markAsRead, delete bool) ([]Email, error) {
+	return c.GetAllContext(context.Background(), markAsRead, delete)
+}
+
+// GetAllContext is GetAll with a context.
+func (c *Client) GetAllContext(ctx context.Context, markAsRead, delete bool) ([]Email, error) {
 	// call chan, put 'em in a list, return
-	var emails []Email
-	responses, err := c.GenerateAll(markAsRead, delete)
+	responses, err := c.GenerateAllContext(ctx, markAsRead, delete)
 	if err != nil {
-		return emails, err
+		return nil, err
 	}
 
-	for resp := range responses {
-		if resp.Err != nil {
-			return emails, resp.Err
-		}
-		emails = append(emails, resp.Email)
-	}
-
-	return emails, nil
+	return c.collect(ctx, responses)
 }
 
 // GenerateAll will find all emails in the email folder and pass them along to the responses channel.
 func (c *Client) GenerateAll(markAsRead, delete bool) (chan Response, error) {
-	return c.generateMail("ALL", nil, markAsRead, delete)
+	return c.GenerateAllContext(context.Background(), markAsRead, delete)
+}
+
+// GenerateAllContext is GenerateAll with a context. See generateMail for
+// how the context is handled.
+func (c *Client) GenerateAllContext(ctx context.Context, markAsRead, delete bool) (chan Response, error) {
+	return c.generateMail(ctx, All(), markAsRead, delete)
 }
 
 // GetUnread will find all unread emails in the folder and return them as a list.
 func (c *Client) GetUnread(markAsRead, delete bool) ([]Email, error) {
-	// call chan, put 'em in a list, return
-	var emails []Email
+	return c.GetUnreadContext(context.Background(), markAsRead, delete)
+}
 
-	responses, err := c.GenerateUnread(markAsRead, delete)
+// GetUnreadContext is GetUnread with a context.
+func (c *Client) GetUnreadContext(ctx context.Context, markAsRead, delete bool) ([]Email, error) {
+	// call chan, put 'em in a list, return
+	responses, err := c.GenerateUnreadContext(ctx, markAsRead, delete)
 	if err != nil {
-		return emails, err
-	}
-
-	for resp := range responses {
-		if resp.Err != nil {
-			return emails, resp.Err
-		}
-		emails = append(emails, resp.Email)
+		return nil, err
 	}
 
-	return emails, nil
+	return c.collect(ctx, responses)
 }
 
 // GenerateUnread will find all unread emails in the folder and pass them along to the responses channel.
 func (c *Client) GenerateUnread(markAsRead, delete bool) (chan Response, error) {
-	return c.generateMail("UNSEEN", nil, markAsRead, delete)
+	return c.GenerateUnreadContext(context.Background(), markAsRead, delete)
+}
+
+// GenerateUnreadContext is GenerateUnread with a context. See generateMail
+// for how the context is handled.
+func (c *Client) GenerateUnreadContext(ctx context.Context, markAsRead, delete bool) (chan Response, error) {
+	return c.generateMail(ctx, Unread(), markAsRead, delete)
 }
 
 // GetSince will pull all emails that have an internal date after the given time.
 func (c *Client) GetSince(since time.Time, markAsRead, delete bool) ([]Email, error) {
-	var emails []Email
-	responses, err := c.GenerateSince(since, markAsRead, delete)
+	return c.GetSinceContext(context.Background(), since, markAsRead, delete)
+}
+
+// GetSinceContext is GetSince with a context.
+func (c *Client) GetSinceContext(ctx context.Context, since time.Time, markAsRead, delete bool) ([]Email, error) {
+	responses, err := c.GenerateSinceContext(ctx, since, markAsRead, delete)
 	if err != nil {
-		return emails, err
+		return nil, err
+	}
+
+	return c.collect(ctx, responses)
+}
+
+// GenerateSince will find all emails that have an internal date after the given time and pass them along to the
+// responses channel.
+func (c *Client) GenerateSince(since time.Time, markAsRead, delete bool) (chan Response, error) {
+	return c.GenerateSinceContext(context.Background(), since, markAsRead, delete)
+}
+
+// GenerateSinceContext is GenerateSince with a context. See generateMail for
+// how the context is handled.
+func (c *Client) GenerateSinceContext(ctx context.Context, since time.Time, markAsRead, delete bool) (chan Response, error) {
+	return c.generateMail(ctx, Since(since), markAsRead, delete)
+}
+
+// GetNew will find all new emails in the folder, the ones that are both
+// recent and unread, and return them as a list. An email is recent if it
+// arrived since the last session to select the folder, which is what some
+// pollers rely on to never see an email twice without keeping any state.
+//
+// IMAP4rev2 (RFC 9051) dropped the \Recent flag, on servers that only speak
+// it this falls back to all unread emails.
+func (c *Client) GetNew(markAsRead, delete bool) ([]Email, error) {
+	return c.GetNewContext(context.Background(), markAsRead, delete)
+}
+
+// GetNewContext is GetNew with a context.
+func (c *Client) GetNewContext(ctx context.Context, markAsRead, delete bool) ([]Email, error) {
+	responses, err := c.GenerateNewContext(ctx, markAsRead, delete)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.collect(ctx, responses)
+}
+
+// GenerateNew will find all new emails in the folder and pass them along to
+// the responses channel. See GetNew for what new means.
+func (c *Client) GenerateNew(markAsRead, delete bool) (chan Response, error) {
+	return c.GenerateNewContext(context.Background(), markAsRead, delete)
+}
+
+// GenerateNewContext is GenerateNew with a context. See generateMail for how
+// the context is handled.
+func (c *Client) GenerateNewContext(ctx context.Context, markAsRead, delete bool) (chan Response, error) {
+	return c.generateMail(ctx, c.newQuery(), markAsRead, delete)
+}
+
+// newQuery matches the new emails, falling back to the unread ones if the
+// server does not support \Recent.
+func (c *Client) newQuery() Query {
+	if c.caps("IMAP4REV2") && !c.caps("IMAP4REV1") {
+		return Unread()
+	}
+	return Query{keys: []imap.Field{"NEW"}}
+}
+
+// Search will pull all emails matching the query.
+func (c *Client) Search(q Query, markAsRead, delete bool) ([]Email, error) {
+	return c.SearchContext(context.Background(), q, markAsRead, delete)
+}
+
+// SearchContext is Search with a context.
+func (c *Client) SearchContext(ctx context.Context, q Query, markAsRead, delete bool) ([]Email, error) {
+	responses, err := c.GenerateSearchContext(ctx, q, markAsRead, delete)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.collect(ctx, responses)
+}
+
+// GenerateSearch will find all emails matching the query and pass them along
+// to the responses channel.
+func (c *Client) GenerateSearch(q Query, markAsRead, delete bool) (chan Response, error) {
+	return c.GenerateSearchContext(context.Background(), q, markAsRead, delete)
+}
+
+// GenerateSearchContext is GenerateSearch with a context. See generateMail
+// for how the context is handled.
+func (c *Client) GenerateSearchContext(ctx context.Context, q Query, markAsRead, delete bool) (chan Response, error) {
+	return c.generateMail(ctx, q, markAsRead, delete)
+}
+
+// UnreadOlderThan will find the unread emails received more than d ago,
+// e.g. for alerting on emails that have been left unhandled for too long.
+// The emails are left unread.
+func (c *Client) UnreadOlderThan(d time.Duration) ([]Email, error) {
+	return c.UnreadOlderThanContext(context.Background(), d)
+}
+
+// UnreadOlderThanContext is UnreadOlderThan with a context.
+func (c *Client) UnreadOlderThanContext(ctx context.Context, d time.Duration) ([]Email, error) {
+	cutoff := time.Now().Add(-d)
+	// BEFORE only has day granularity, so search up to the day after and
+	// filter out the rest.
+	q := And(Unread(), Before(cutoff.AddDate(0, 0, 1)))
+	cmd, err := c.findEmails(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	uids, err := c.filterReceived(ctx, searchResults(cmd), func(date time.Time) bool {
+		return date.Before(cutoff)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.collect(ctx, c.generateUIDs(ctx, uids, false, false, false))
+}
+
+// ErrEmailNotFound is returned by GetByUID if there is no email with the UID
+// in the folder.
+var ErrEmailNotFound = errors.New("email not found")
+
+// GetByUID will pull the email with the given UID, e.g. one stored by a
+// previous run. Its flags are left alone. The UID is only meaningful as long
+// as the folder's UIDVALIDITY does not change.
+func (c *Client) GetByUID(uid uint32) (Email, error) {
+	return c.GetByUIDContext(context.Background(), uid)
+}
+
+// GetByUIDContext is GetByUID with a context.
+func (c *Client) GetByUIDContext(ctx context.Context, uid uint32) (Email, error) {
+	seq := &imap.SeqSet{}
+	seq.AddNum(uid)
+	start := time.Now()
+	fetchCtx, span := c.startSpan(ctx, "eazye.Fetch", attribute.Int("imap.emails", 1))
+	cmd, err := c.do(fetchCtx, func() (*imap.Command, error) {
+		items := append([]string{"INTERNALDATE", "BODY.PEEK[]", "UID", "RFC822.HEADER", "FLAGS"}, c.gmailItems()...)
+		return c.uidFetch(seq, items...)
+	})
+	endSpan(span, err)
+	c.metrics().FetchDuration(c.Folder, time.Since(start))
+	if err != nil {
+		return Email{}, fmt.Errorf("unable to perform uid fetch: %w", err)
+	}
+
+	for _, msgData := range cmd.Data {
+		info := c.messageInfo(msgData)
+		// skip unsolicited FETCH responses, see getEmails
+		if _, ok := info.Attrs["RFC822.HEADER"]; !ok || info.UID != uid {
+			continue
+		}
+		email, err := c.parse(ctx, info.Attrs)
+		if err != nil {
+			return Email{}, c.emailError(OpParse, uid, err)
+		}
+		c.process(&email)
+		c.audit(AuditFetched, uid, "")
+		c.metrics().EmailFetched(c.Folder)
+		return email, nil
 	}
+	return Email{}, ErrEmailNotFound
+}
 
+// collect puts the emails from the responses channel in a list. With the
+// FailFast strategy the first error is returned as is, otherwise all of the
+// errors are joined together. If the context is done the list may be cut
+// short, so its error is returned too.
+func (c *Client) collect(ctx context.Context, responses chan Response) ([]Email, error) {
+	var (
+		emails []Email
+		errs   []error
+	)
 	for resp := range responses {
 		if resp.Err != nil {
-			return emails, resp.Err
+			if c.ErrorStrategy == FailFast {
+				return emails, resp.Err
+			}
+			errs = append(errs, resp.Err)
+			continue
 		}
 		emails = append(emails, resp.Email)
 	}
+	if err := ctx.Err(); err != nil && c.ErrorStrategy == FailFast {
+		return emails, err
+	}
 
-	return emails, nil
-}
-
-// GenerateSince will find all emails that have an internal date after the given time and pass them along to the
-// responses channel.
-func (c *Client) GenerateSince(since time.Time, markAsRead, delete bool) (chan Response, error) {
-	return c.generateMail("", &since, markAsRead, delete)
+	return emails, errors.Join(append(errs, ctx.Err())...)
 }
 
 // Email is a raw Email message from the std lib
 type Email struct {
-	ID      imap.Field
-	Message *mail.Message
+	ID           imap.Field
+	InternalDate time.Time
+	Message      *mail.Message
+
+	// The most common headers, parsed and with any RFC 2047 encoded words
+	// decoded. Headers that fail to parse are left empty with a warning.
+	From    *mail.Address
+	To      []*mail.Address
+	Cc      []*mail.Address
+	Subject string
+	Date    time.Time
+	// MessageID is the Message-ID header as is, angle brackets included.
+	MessageID  string
+	Precedence string
+
+	// HTML and Text are the bodies of the email decoded to UTF-8, either may
+	// be empty. IsMultiPart is set for multipart messages.
+	HTML        []byte
+	Text        []byte
+	IsMultiPart bool
+
+	// GmailLabels, GmailThreadID and GmailMessageID are only set for emails
+	// fetched from Gmail, or other servers with the X-GM-EXT-1 capability.
+	// GmailMessageID, unlike the UID, never changes and is the same in
+	// every folder.
+	GmailLabels    []string
+	GmailThreadID  uint64
+	GmailMessageID uint64
+
+	// Flags holds the flags of the email, e.g. \Seen or \Flagged, as they
+	// are once the Client is done with it.
+	Flags []string
+
+	// Size is the size of the message in bytes.
+	Size uint32
+
+	// Ref identifies the email on the server for acting on it later, see
+	// MessageRef. It is only set for emails fetched by a Client.
+	Ref MessageRef
+
+	// ExtractedText holds the text the Client's Extractor found in the
+	// attachments, if it has one.
+	ExtractedText []ExtractedText
+
+	// Warnings holds any non fatal problems found while parsing the message,
+	// such as a bad date or a truncated MIME body.
+	Warnings []error
+
+	// raw is the full message as fetched, headers included.
+	raw []byte
 }
 
 var (
@@ -188,12 +756,18 @@ var (
 	}
 )
 
-func VisibleText(body io.Reader) ([][]byte, error) {
+// VisibleText returns the blocks of visible text of an HTML body, skipping
+// that of the style, script, head and similar elements, which can be changed
+// with SkipTags and KeepTags.
+func VisibleText(body io.Reader, options ...TextOption) ([][]byte, error) {
 	var (
 		text [][]byte
-		skip bool
 		err  error
+		// skipping and keeping are the numbers of skipped and kept
+		// elements the text is in
+		skipping, keeping int
 	)
+	o := newTextOptions(options)
 	z := html.NewTokenizer(body)
 	for {
 		tt := z.Next()
@@ -204,7 +778,7 @@ func VisibleText(body io.Reader) ([][]byte, error) {
 			}
 			return text, err
 		case html.TextToken:
-			if !skip {
+			if skipping == 0 || keeping > 0 {
 				tmp := bytes.TrimSpace(z.Text())
 				if len(tmp) == 0 {
 					continue
@@ -215,146 +789,466 @@ func VisibleText(body io.Reader) ([][]byte, error) {
 			}
 		case html.StartTagToken, html.EndTagToken:
 			tn, _ := z.TagName()
-			for _, nvTag := range nonVisibleTags {
-				if bytes.Equal(tn, nvTag) {
-					skip = (tt == html.StartTagToken)
-					break
-				}
+			tag := string(tn)
+			if voidTags[tag] {
+				continue
+			}
+			delta := 1
+			if tt == html.EndTagToken {
+				delta = -1
+			}
+			switch {
+			case o.keep[tag]:
+				keeping = max(keeping+delta, 0)
+			case o.skip[tag]:
+				skipping = max(skipping+delta, 0)
 			}
 		}
 	}
-	return text, nil
 }
 
 // Response is a helper struct to wrap the email responses and possible errors.
+// Errors with a single email are a *ResponseError.
 type Response struct {
 	Email Email
 	Err   error
+	// Folder is the folder the email is in, only set by GenerateAllFolders.
+	Folder string
+	// Skipped is set for emails over the Client's MaxMessageSize. Their
+	// Email only has the headers, flags and Size, and they are neither
+	// marked as read nor deleted.
+	Skipped bool
 }
 
 const dateFormat = "02-Jan-2006"
 
-// findEmails will run a find the UIDs of any emails that match the search.:
-func (c *Client) findEmails(search string, since *time.Time) (*imap.Command, error) {
-	var specs []imap.Field
-	if len(search) > 0 {
-		specs = append(specs, search)
-	}
-
-	if since != nil {
-		sinceStr := since.Format(dateFormat)
-		specs = append(specs, "SINCE", sinceStr)
-	}
-
+// findEmails will run a find the UIDs of any emails that match the query.
+func (c *Client) findEmails(ctx context.Context, q Query) (*imap.Command, error) {
+	ctx, span := c.startSpan(ctx, "eazye.Search")
 	// get headers and UID for UnSeen message in src inbox...
-	cmd, err := imap.Wait(c.Imap.UIDSearch(specs...))
+	cmd, err := c.do(ctx, func() (*imap.Command, error) {
+		return c.uidSearch(c.searchFields(q)...)
+	})
 	if err != nil {
-		return &imap.Command{}, fmt.Errorf("uid search failed: %s", err)
+		err = fmt.Errorf("uid search failed: %w", err)
+		endSpan(span, err)
+		return &imap.Command{}, err
 	}
+	span.SetAttributes(attribute.Int("imap.results", len(searchResults(cmd))))
+	endSpan(span, nil)
 	return cmd, nil
 }
 
+// searchResults collects the UIDs returned by a UID SEARCH command.
+func searchResults(cmd *imap.Command) []uint32 {
+	var uids []uint32
+	for _, rsp := range cmd.Data {
+		uids = append(uids, rsp.SearchResults()...)
+	}
+	return uids
+}
+
+// GenerateBufferSize is the default size of the responses channel buffer.
 var GenerateBufferSize = 100
 
-func (c *Client) generateMail(search string, since *time.Time, markAsRead, delete bool) (chan Response, error) {
+// DefaultFetchBatchSize is the default number of emails fetched per FETCH
+// command.
+const DefaultFetchBatchSize = 200
+
+// fetchBatchSize returns the number of emails to fetch per FETCH command.
+func (c *Client) fetchBatchSize() int {
+	if c.FetchBatchSize > 0 {
+		return c.FetchBatchSize
+	}
+	return DefaultFetchBatchSize
+}
+
+// batches splits the UIDs into batches of at most size UIDs.
+func batches(uids []uint32, size int) [][]uint32 {
+	var out [][]uint32
+	for len(uids) > size {
+		out = append(out, uids[:size:size])
+		uids = uids[size:]
+	}
+	if len(uids) > 0 {
+		out = append(out, uids)
+	}
+	return out
+}
+
+// bufferSize returns the size of the responses channel buffer to use.
+func (c *Client) bufferSize() int {
+	if c.BufferSize > 0 {
+		return c.BufferSize
+	}
+	return GenerateBufferSize
+}
+
+// generateMail finds the emails matching the query and passes them along to
+// the responses channel. If the context is done the generator stops without
+// sending anything else, so callers that stop reading from the channel early
+// should cancel it to avoid leaking the goroutine.
+func (c *Client) generateMail(ctx context.Context, q Query, markAsRead, delete bool) (chan Response, error) {
 	var err error
-	responses := make(chan Response, GenerateBufferSize)
+	responses := make(chan Response, c.bufferSize())
 
 	go func() {
 		defer func() {
-			// c.Imap.Close(true)
-			// c.Imap.Logout(30 * time.Second)
+			// c.server().Close(true)
+			// c.server().Logout(30 * time.Second)
 			close(responses)
 		}()
 
 		var cmd *imap.Command
 		// find all the UIDs
-		cmd, err = c.findEmails(search, since)
+		cmd, err = c.findEmails(ctx, q)
 		if err != nil {
-			responses <- Response{Err: err}
+			send(ctx, responses, Response{Err: err})
 			return
 		}
 		// gotta fetch 'em all
-		c.getEmails(cmd, markAsRead, delete, responses)
+		c.getEmails(ctx, searchResults(cmd), markAsRead, delete, false, responses)
 	}()
 
 	return responses, nil
 }
 
-func (c *Client) getEmails(cmd *imap.Command, markAsRead, delete bool, responses chan Response) {
-	seq := &imap.SeqSet{}
-	msgCount := 0
-	for _, rsp := range cmd.Data {
-		for _, uid := range rsp.SearchResults() {
-			msgCount++
-			seq.AddNum(uid)
-		}
+// generateUIDs will fetch the emails with the given UIDs and pass them along
+// to the responses channel. peek fetches them with BODY.PEEK[] whatever the
+// Peek setting, for callers that must leave \Seen alone.
+func (c *Client) generateUIDs(ctx context.Context, uids []uint32, markAsRead, delete, peek bool) chan Response {
+	responses := make(chan Response, c.bufferSize())
+
+	go func() {
+		defer close(responses)
+		c.getEmails(ctx, uids, markAsRead, delete, peek, responses)
+	}()
+
+	return responses
+}
+
+// send passes the response along unless the context is done first.
+func send(ctx context.Context, responses chan Response, resp Response) bool {
+	select {
+	case responses <- resp:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (c *Client) getEmails(ctx context.Context, uids []uint32, markAsRead, delete, peek bool, responses chan Response) {
+	// sequence numbers change under the other connections as emails are
+	// deleted, so those are fetched over a single one
+	if c.Concurrency > 1 && !c.SequenceNumbers && len(uids) > c.fetchBatchSize() {
+		c.getEmailsConcurrently(ctx, uids, markAsRead, delete, peek, responses)
+		return
 	}
+	c.fetchEmails(ctx, uids, markAsRead, delete, peek, responses)
+}
+
+// fetchEmails fetches the emails over the Client's own connection.
+func (c *Client) fetchEmails(ctx context.Context, uids []uint32, markAsRead, delete, peek bool, responses chan Response) {
+	seq := &imap.SeqSet{}
+	seq.AddNum(uids...)
 
 	// nothing to request?! why you even callin me, foolio?
 	if seq.Empty() {
 		return
 	}
 
-	fCmd, err := imap.Wait(c.Imap.UIDFetch(seq, "INTERNALDATE", "BODY[]", "UID", "RFC822.HEADER"))
-	if err != nil {
-		responses <- Response{Err: fmt.Errorf("unable to perform uid fetch: %s", err)}
+	if c.SafeMode && (markAsRead || delete) {
+		send(ctx, responses, Response{Err: ErrReadOnlyMode})
 		return
 	}
+	if delete && c.DeleteToTrash && c.SequenceNumbers {
+		send(ctx, responses, Response{Err: ErrTrashSequenceNumbers})
+		return
+	}
+	body := "BODY[]"
+	if peek || c.Peek || c.SafeMode {
+		// nothing to undo afterwards if \Seen is never set
+		body = "BODY.PEEK[]"
+	}
 
-	var email Email
-	for _, msgData := range fCmd.Data {
-		msgFields := msgData.MessageInfo().Attrs
+	items := []string{"INTERNALDATE", body, "UID", "RFC822.HEADER", "FLAGS"}
+	if c.caps("CONDSTORE") {
+		items = append(items, "MODSEQ")
+	}
+	items = append(items, c.gmailItems()...)
 
-		// make sure is a legit response before we attempt to parse it
-		// deal with unsolicited FETCH responses containing only flags
-		// I'm lookin' at YOU, Gmail!
-		// http://mailman13.u.washington.edu/pipermail/imap-protocol/2014-October/002355.html
-		// http://stackoverflow.com/questions/26262472/gmail-imap-is-sometimes-returning-bad-results-for-fetch
-		if _, ok := msgFields["RFC822.HEADER"]; !ok {
-			continue
+	var cached []Email
+	if c.Cache != nil {
+		uids, cached = c.fromCache(uids)
+	}
+	if len(cached) > 0 {
+		var err error
+		if cached, err = c.refreshCached(ctx, cached); err != nil {
+			send(ctx, responses, Response{Err: err})
+			return
 		}
+	}
 
-		email, err = newEmail(msgFields)
-		if err != nil {
-			responses <- Response{Err: fmt.Errorf("unable to parse email: %s", err)}
-			return
+	fail, done := c.errorHandler(ctx, responses)
+	defer done()
+
+	// handle passes the email along and sorts out its flags, telling whether
+	// to carry on. seen tells whether getting the email set \Seen: emails
+	// from the cache or peeked at have none to undo, but do need it set when
+	// marking them as read.
+	handle := func(email Email, seen bool) bool {
+		c.process(&email)
+		// pass the flags along as they will be once we are done
+		switch {
+		case markAsRead:
+			email.Flags = addFlag(email.Flags, `\Seen`)
+		case seen:
+			email.Flags = removeFlag(email.Flags, `\Seen`)
 		}
 
-		responses <- Response{Email: email}
+		if !send(ctx, responses, Response{Email: email}) {
+			return false
+		}
+		c.audit(AuditFetched, imap.AsNumber(email.ID), "")
+		c.metrics().EmailFetched(c.Folder)
 
-		if !markAsRead {
-			err = c.SetAsUnread(email)
-			if err != nil {
-				responses <- Response{Err: fmt.Errorf("unable to remove seen flag: %s", err)}
-				return
+		switch {
+		case seen && !markAsRead:
+			if err := c.SetAsUnreadContext(ctx, email); err != nil {
+				return fail(c.emailError(OpMarkUnread, imap.AsNumber(email.ID), err))
+			}
+		case !seen && markAsRead:
+			if err := c.SetAsReadContext(ctx, email); err != nil {
+				return fail(c.emailError(OpMarkRead, imap.AsNumber(email.ID), err))
 			}
 		}
 
 		if delete {
-			err = c.DeleteEmail(email)
+			if err := c.DeleteEmailContext(ctx, email); err != nil {
+				return fail(c.emailError(OpDelete, imap.AsNumber(email.ID), err))
+			}
+		}
+		return true
+	}
+
+	for _, email := range cached {
+		c.track(&imap.MessageInfo{UID: imap.AsNumber(email.ID)})
+		if !handle(email, false) {
+			return
+		}
+	}
+
+	// fetch fetches the emails and handles them, returning the UIDs the
+	// server did not send and whether to carry on.
+	fetch := func(uids []uint32) ([]uint32, bool) {
+		batch := &imap.SeqSet{}
+		batch.AddNum(uids...)
+		start := time.Now()
+		fetchCtx, span := c.startSpan(ctx, "eazye.Fetch", attribute.Int("imap.emails", len(uids)))
+		fCmd, err := c.do(fetchCtx, func() (*imap.Command, error) {
+			return c.uidFetch(batch, items...)
+		})
+		endSpan(span, err)
+		c.metrics().FetchDuration(c.Folder, time.Since(start))
+		if err != nil {
+			send(ctx, responses, Response{Err: fmt.Errorf("unable to perform uid fetch: %w", err)})
+			return nil, false
+		}
+
+		received := make(map[uint32]bool, len(uids))
+		for _, msgData := range fCmd.Data {
+			info := c.messageInfo(msgData)
+			msgFields := info.Attrs
+
+			// make sure is a legit response before we attempt to parse it
+			// deal with unsolicited FETCH responses containing only flags
+			// I'm lookin' at YOU, Gmail!
+			// http://mailman13.u.washington.edu/pipermail/imap-protocol/2014-October/002355.html
+			// http://stackoverflow.com/questions/26262472/gmail-imap-is-sometimes-returning-bad-results-for-fetch
+			if _, ok := msgFields["RFC822.HEADER"]; !ok {
+				continue
+			}
+			received[info.UID] = true
+			c.track(info)
+
+			email, err := c.parse(ctx, msgFields)
 			if err != nil {
-				responses <- Response{Err: fmt.Errorf("unable to delete email: %s", err)}
+				if fail(c.emailError(OpParse, imap.AsNumber(msgFields["UID"]), err)) {
+					continue
+				}
+				return nil, false
+			}
+			if c.Cache != nil {
+				c.toCache(email)
+			}
+
+			if !handle(email, body == "BODY[]") {
+				return nil, false
+			}
+		}
+		return missingUIDs(uids, received), true
+	}
+
+	// fetch in batches so huge folders neither time out nor have to fit in
+	// memory all at once
+	for _, batchUIDs := range batches(uids, c.fetchBatchSize()) {
+		if c.MaxMessageSize > 0 {
+			var ok bool
+			if batchUIDs, ok = c.skipLarge(ctx, batchUIDs, responses, fail); !ok {
 				return
 			}
 		}
+		missing, ok := fetch(batchUIDs)
+		if !ok {
+			return
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		// the server sent only flags for some of them, ask once more
+		if missing, ok = fetch(missing); !ok {
+			return
+		}
+		// the rest were expunged in the meantime
+		for _, uid := range missing {
+			c.log(ctx, slog.LevelDebug, "email gone before it was fetched", "uid", uid)
+		}
+	}
+}
+
+// missingUIDs returns the UIDs that were not received, in order.
+func missingUIDs(uids []uint32, received map[uint32]bool) []uint32 {
+	var missing []uint32
+	for _, uid := range uids {
+		if !received[uid] {
+			missing = append(missing, uid)
+		}
+	}
+	return missing
+}
+
+// errorHandler returns fail, which reports an error with a single email
+// according to the ErrorStrategy, or SkipBroken for parse failures, and tells
+// whether to carry on with the rest of them, and done, which passes along the
+// errors collected once every email has been handled.
+func (c *Client) errorHandler(ctx context.Context, responses chan Response) (fail func(error) bool, done func()) {
+	var errs []error
+	fail = func(err error) bool {
+		c.log(ctx, slog.LevelWarn, "unable to handle email", "err", err)
+		if c.SkipBroken && errors.Is(err, ErrParse) {
+			return send(ctx, responses, Response{Err: err})
+		}
+		switch c.ErrorStrategy {
+		case SkipAndContinue:
+			return send(ctx, responses, Response{Err: err})
+		case CollectErrors:
+			errs = append(errs, err)
+			return true
+		}
+		send(ctx, responses, Response{Err: err})
+		return false
+	}
+	done = func() {
+		if len(errs) > 0 {
+			send(ctx, responses, Response{Err: errors.Join(errs...)})
+		}
+	}
+	return fail, done
+}
+
+// process runs the Client's Extractor and Redactor over a freshly fetched
+// email.
+func (c *Client) process(email *Email) {
+	if c.Extractor != nil {
+		email.extract(c.Extractor)
+	}
+	if c.Redactor != nil {
+		c.Redactor.Email(email)
 	}
-	return
 }
 
 func (c *Client) DeleteEmail(email Email) error {
-	return c.alterEmail(email, "\\DELETED", true)
+	return c.DeleteEmailContext(context.Background(), email)
+}
+
+// DeleteEmailContext is DeleteEmail with a context.
+func (c *Client) DeleteEmailContext(ctx context.Context, email Email) error {
+	if c.DeleteToTrash {
+		return c.moveToTrash(ctx, email)
+	}
+	return c.alterEmail(ctx, email, "\\DELETED", true)
+}
+
+// Expunge will permanently remove all the emails flagged as deleted from the
+// folder.
+func (c *Client) Expunge() error {
+	return c.ExpungeContext(context.Background())
+}
+
+// ExpungeContext is Expunge with a context.
+func (c *Client) ExpungeContext(ctx context.Context) error {
+	if c.SafeMode {
+		return ErrReadOnlyMode
+	}
+	return c.expunge(ctx, nil)
+}
+
+// DeleteAndExpunge will delete the email and remove it from the folder right
+// away, rather than leaving it for the next expunge. Without UIDPLUS (RFC
+// 4315) on the server that removes any other email flagged as deleted as
+// well.
+func (c *Client) DeleteAndExpunge(email Email) error {
+	return c.DeleteAndExpungeContext(context.Background(), email)
+}
+
+// DeleteAndExpungeContext is DeleteAndExpunge with a context.
+func (c *Client) DeleteAndExpungeContext(ctx context.Context, email Email) error {
+	if err := c.DeleteEmailContext(ctx, email); err != nil {
+		return err
+	}
+	seq := &imap.SeqSet{}
+	seq.AddNum(imap.AsNumber(email.ID))
+	return c.expunge(ctx, seq)
+}
+
+// expunge removes the emails flagged as deleted, only those in the set if
+// the server supports UID EXPUNGE. A nil set removes them all.
+func (c *Client) expunge(ctx context.Context, uids *imap.SeqSet) error {
+	if !c.caps("UIDPLUS") || c.SequenceNumbers {
+		uids = nil
+	}
+	_, err := c.do(ctx, func() (*imap.Command, error) {
+		return c.server().Expunge(uids)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to expunge: %w", err)
+	}
+	return nil
 }
 
 func (c *Client) SetAsUnread(email Email) error {
-	return c.alterEmail(email, "\\SEEN", false)
+	return c.SetAsUnreadContext(context.Background(), email)
+}
+
+// SetAsUnreadContext is SetAsUnread with a context.
+func (c *Client) SetAsUnreadContext(ctx context.Context, email Email) error {
+	return c.alterEmail(ctx, email, "\\SEEN", false)
 }
 
 func (c *Client) SetAsRead(email Email) error {
-	return c.alterEmail(email, "\\SEEN", true)
+	return c.SetAsReadContext(context.Background(), email)
+}
+
+// SetAsReadContext is SetAsRead with a context.
+func (c *Client) SetAsReadContext(ctx context.Context, email Email) error {
+	return c.alterEmail(ctx, email, "\\SEEN", true)
 }
 
-func (c *Client) alterEmail(email Email, flag string, plus bool) error {
+func (c *Client) alterEmail(ctx context.Context, email Email, flag string, plus bool) error {
+	if c.SafeMode {
+		return ErrReadOnlyMode
+	}
 	UID := imap.AsNumber(email.ID)
 	flg := "-FLAGS"
 	if plus {
@@ -362,35 +1256,156 @@ func (c *Client) alterEmail(email Email, flag string, plus bool) error {
 	}
 	fSeq := &imap.SeqSet{}
 	fSeq.AddNum(UID)
-	_, err := imap.Wait(c.Imap.UIDStore(fSeq, flg, flag))
+	ctx, span := c.startSpan(ctx, "eazye.Store", attribute.String("imap.flags", flg+" "+flag))
+	_, err := c.do(ctx, func() (*imap.Command, error) {
+		return c.uidStore(fSeq, flg, flag)
+	})
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
 
+	c.auditStore(UID, flag, plus)
 	return nil
 }
 
+// auditStore records setting or removing \Deleted or \Seen on an email.
+func (c *Client) auditStore(uid uint32, flag string, plus bool) {
+	switch {
+	case strings.EqualFold(flag, "\\DELETED") && plus:
+		c.audit(AuditDeleted, uid, "")
+	case strings.EqualFold(flag, "\\SEEN") && plus:
+		c.audit(AuditMarkedRead, uid, "")
+	case strings.EqualFold(flag, "\\SEEN"):
+		c.audit(AuditMarkedUnread, uid, "")
+	}
+}
+
+// addFlag adds the flag to the list unless it is already there, ignoring
+// case.
+func addFlag(flags []string, flag string) []string {
+	for _, f := range flags {
+		if strings.EqualFold(f, flag) {
+			return flags
+		}
+	}
+	return append(flags, flag)
+}
+
+// removeFlag returns the list without the flag, ignoring case.
+func removeFlag(flags []string, flag string) []string {
+	var out []string
+	for _, f := range flags {
+		if !strings.EqualFold(f, flag) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// parse turns the fields of an email fetched from the selected folder into an
+// Email with its Ref set, see newEmail, in a span of its own. Every fetch of
+// emails goes through it.
+func (c *Client) parse(ctx context.Context, msgFields imap.FieldMap) (Email, error) {
+	_, span := c.startSpan(ctx, "eazye.Parse")
+	email, err := newEmail(msgFields)
+	email.Ref = c.ref(email)
+	endSpan(span, err)
+	return email, err
+}
+
 // newEmailMessage will parse an imap.FieldMap into an Email. This
 // will expect the message to container the internaldate and the body with
-// all headers included.
+// all headers included. Without a body only the headers are parsed.
 func newEmail(msgFields imap.FieldMap) (Email, error) {
-	// parse the header
-	var message bytes.Buffer
-
-	message.Write(imap.AsBytes(msgFields["RFC822.HEADER"]))
-	message.Write([]byte("\n\n"))
+	// parse the header, reading the body in place rather than copying it
 	rawBody := imap.AsBytes(msgFields["BODY[]"])
-	message.Write(rawBody)
+	message := io.MultiReader(
+		bytes.NewReader(imap.AsBytes(msgFields["RFC822.HEADER"])),
+		strings.NewReader("\n\n"),
+		bytes.NewReader(rawBody),
+	)
 
-	msg, err := mail.ReadMessage(&message)
+	msg, err := mail.ReadMessage(message)
 	if err != nil {
-		return Email{}, fmt.Errorf("unable to read header: %s", err)
+		return Email{}, fmt.Errorf("unable to read header: %w", err)
 	}
 
 	email := Email{
-		ID:      msgFields["UID"],
-		Message: msg,
+		ID:           msgFields["UID"],
+		InternalDate: imap.AsDateTime(msgFields["INTERNALDATE"]),
+		Message:      msg,
+	}
+	_, hasBody := msgFields["BODY[]"]
+	if hasBody {
+		email.Warnings = checkMessage(rawBody)
+		email.raw = rawBody
+		email.Size = uint32(len(rawBody))
+	} else {
+		email.Warnings = checkHeader(msg.Header)
+	}
+	if size, ok := msgFields["RFC822.SIZE"]; ok {
+		email.Size = imap.AsNumber(size)
+	}
+	email.parseHeader(msg.Header)
+	email.parseGmail(msgFields)
+	email.parseFlags(msgFields)
+	if !hasBody {
+		return email, nil
+	}
+	if email.HTML, email.Text, email.IsMultiPart, err = parseBody(msg.Header, rawBody); err != nil {
+		email.Warnings = append(email.Warnings, fmt.Errorf("unable to parse body: %w", err))
 	}
 
 	return email, nil
 }
+
+// parseFlags fills in the flags of the email, if they were fetched.
+func (e *Email) parseFlags(msgFields imap.FieldMap) {
+	if flags, ok := msgFields["FLAGS"]; ok {
+		e.Flags = []string{}
+		for _, flag := range imap.AsList(flags) {
+			e.Flags = append(e.Flags, imap.AsString(flag))
+		}
+	}
+}
+
+// wordDecoder decodes RFC 2047 encoded words in any charset decodeCharset
+// knows.
+var wordDecoder = &mime.WordDecoder{CharsetReader: decodeCharset}
+
+// parseHeader fills in the parsed header fields of the email.
+func (e *Email) parseHeader(header mail.Header) {
+	parser := &mail.AddressParser{WordDecoder: wordDecoder}
+	addresses := func(key string) []*mail.Address {
+		if header.Get(key) == "" {
+			return nil
+		}
+		list, err := parser.ParseList(header.Get(key))
+		if err != nil {
+			e.Warnings = append(e.Warnings, fmt.Errorf("bad %s header: %w", key, err))
+		}
+		for _, address := range list {
+			// encoded words in quotes are not decoded by the parser
+			address.Name = DecodeHeader(address.Name)
+		}
+		return list
+	}
+
+	if from := addresses("From"); len(from) > 0 {
+		e.From = from[0]
+	}
+	e.To = addresses("To")
+	e.Cc = addresses("Cc")
+	e.Subject = parseSubject(header.Get("Subject"))
+	// a bad date was already reported by checkHeader
+	e.Date, _ = header.Date()
+	e.MessageID = header.Get("Message-Id")
+	e.Precedence = header.Get("Precedence")
+}
+
+// parseSubject decodes any RFC 2047 encoded words in the subject, see
+// DecodeHeader.
+func parseSubject(subject string) string {
+	return DecodeHeader(subject)
+}
@@ -2,8 +2,16 @@ package eazye
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"net/mail"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+	"github.com/sluceno/eazye/eazyetest"
 )
 
 func TestParseQuotedBody(t *testing.T) {
@@ -102,6 +110,42 @@ func TestParseSubject(t *testing.T) {
 
 }
 
+func TestNewEmailHeaders(t *testing.T) {
+	header := "From: =?UTF-8?B?SsO8cmdlbg==?= <jurgen@example.com>\r\n" +
+		"To: a@example.com, \"B\" <b@example.com>\r\n" +
+		"Cc: not an address\r\n" +
+		"Subject: =?iso-8859-1?q?caf=E9?=\r\n" +
+		"Date: Mon, 11 Aug 2014 22:14:16 -0000\r\n" +
+		"Message-ID: <1@example.com>\r\n" +
+		"Precedence: bulk\r\n\r\n"
+	email, err := newEmail(imap.FieldMap{
+		"RFC822.HEADER": []byte(header),
+		"BODY[]":        []byte(header + "hi\r\n"),
+	})
+	if err != nil {
+		t.Fatalf("newEmail() returned an error: %s", err)
+	}
+
+	if email.From == nil || email.From.Name != "Jürgen" || email.From.Address != "jurgen@example.com" {
+		t.Errorf("newEmail() got From %v, want Jürgen <jurgen@example.com>", email.From)
+	}
+	if len(email.To) != 2 || email.To[1].Name != "B" {
+		t.Errorf("newEmail() got To %v, want 2 addresses", email.To)
+	}
+	if email.Cc != nil || len(email.Warnings) != 1 {
+		t.Errorf("newEmail() got Cc %v and warnings %v, want a warning for the bad Cc", email.Cc, email.Warnings)
+	}
+	if email.Subject != "café" {
+		t.Errorf("newEmail() got Subject %q, want %q", email.Subject, "café")
+	}
+	if !email.Date.Equal(time.Date(2014, 8, 11, 22, 14, 16, 0, time.UTC)) {
+		t.Errorf("newEmail() got Date %s", email.Date)
+	}
+	if email.MessageID != "<1@example.com>" || email.Precedence != "bulk" {
+		t.Errorf("newEmail() got MessageID %q and Precedence %q", email.MessageID, email.Precedence)
+	}
+}
+
 func TestVisibleText(t *testing.T) {
 	email := Email{
 		HTML: []byte(`<html>
@@ -413,6 +457,168 @@ Nations representative.`,
 	}
 }
 
+func TestBatches(t *testing.T) {
+	uids := []uint32{1, 2, 3, 4, 5}
+	tests := []struct {
+		size int
+		want string
+	}{
+		{2, "[[1 2] [3 4] [5]]"},
+		{5, "[[1 2 3 4 5]]"},
+		{10, "[[1 2 3 4 5]]"},
+	}
+	for _, test := range tests {
+		if got := fmt.Sprint(batches(uids, test.size)); got != test.want {
+			t.Errorf("batches(%v, %d) got %s, want %s", uids, test.size, got, test.want)
+		}
+	}
+	if got := batches(nil, 2); len(got) != 0 {
+		t.Errorf("batches(nil, 2) got %v, want none", got)
+	}
+}
+
+func TestNewEmailFlags(t *testing.T) {
+	email, err := newEmail(imap.FieldMap{
+		"RFC822.HEADER": []byte("Subject: hi\r\n\r\n"),
+		"FLAGS":         []imap.Field{`\Seen`, `\Flagged`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(email.Flags) != `[\Seen \Flagged]` {
+		t.Errorf("newEmail() got flags %q", email.Flags)
+	}
+
+	flags := addFlag(email.Flags, `\seen`)
+	if len(flags) != 2 {
+		t.Errorf("addFlag() added a flag that was there: %q", flags)
+	}
+	if flags = removeFlag(flags, `\SEEN`); fmt.Sprint(flags) != `[\Flagged]` {
+		t.Errorf("removeFlag() got %q, want [\\Flagged]", flags)
+	}
+}
+
+func TestMissingUIDs(t *testing.T) {
+	got := missingUIDs([]uint32{1, 2, 3, 4}, map[uint32]bool{1: true, 3: true})
+	if fmt.Sprint(got) != "[2 4]" {
+		t.Errorf("missingUIDs() got %v, want [2 4]", got)
+	}
+	if got := missingUIDs([]uint32{1}, map[uint32]bool{1: true}); len(got) != 0 {
+		t.Errorf("missingUIDs() got %v, want none", got)
+	}
+}
+
+func TestFetchEmailsExpunged(t *testing.T) {
+	srv := testServer(t)
+	uid := srv.AddMessage("INBOX", []byte("Subject: kept\r\n\r\nx\r\n"))
+
+	c := testClient(t, srv, SetErrorStrategy(FailFast))
+	emails, err := c.collect(context.Background(), c.generateUIDs(context.Background(), []uint32{uid, uid + 1}, false, false, false))
+	if err != nil {
+		t.Fatalf("generateUIDs() returned an error: %s", err)
+	}
+	if len(emails) != 1 || emails[0].Subject != "kept" {
+		t.Errorf("generateUIDs() got %d emails, want the one left", len(emails))
+	}
+}
+
+func TestGetAllFlags(t *testing.T) {
+	tests := []struct {
+		markAsRead, delete bool
+	}{
+		{false, false},
+		{true, false},
+		{false, true},
+		{true, true},
+	}
+
+	for _, tt := range tests {
+		srv := testServer(t)
+		uid := srv.AddMessage("INBOX", []byte("Subject: hi\r\n\r\nx\r\n"))
+		c := testClient(t, srv)
+
+		emails, err := c.GetAll(tt.markAsRead, tt.delete)
+		if err != nil || len(emails) != 1 {
+			t.Fatalf("GetAll(%v, %v) got %d emails, %v, want 1", tt.markAsRead, tt.delete, len(emails), err)
+		}
+		seen := len(removeFlag(emails[0].Flags, `\Seen`)) != len(emails[0].Flags)
+		if seen != tt.markAsRead || serverHasFlag(srv, "INBOX", uid, `\Seen`) != tt.markAsRead {
+			t.Errorf("GetAll(%v, %v) got flags %q and %+v on the server, want \\Seen %v",
+				tt.markAsRead, tt.delete, emails[0].Flags, srv.Messages("INBOX"), tt.markAsRead)
+		}
+		if serverHasFlag(srv, "INBOX", uid, `\Deleted`) != tt.delete {
+			t.Errorf("GetAll(%v, %v) got %+v on the server, want \\Deleted %v",
+				tt.markAsRead, tt.delete, srv.Messages("INBOX"), tt.delete)
+		}
+
+		if err = c.Expunge(); err != nil {
+			t.Fatalf("Expunge() returned an error: %s", err)
+		}
+		if left := len(srv.Messages("INBOX")); (left == 0) != tt.delete {
+			t.Errorf("Expunge() after GetAll(%v, %v) left %d emails", tt.markAsRead, tt.delete, left)
+		}
+	}
+}
+
+func TestPeek(t *testing.T) {
+	tests := []struct {
+		peek      bool
+		wantFetch string
+		wantReset bool
+	}{
+		{false, "UID FETCH 1 (INTERNALDATE BODY[]", true},
+		{true, "UID FETCH 1 (INTERNALDATE BODY.PEEK[]", false},
+	}
+
+	for _, tt := range tests {
+		srv := testServer(t)
+		uid := srv.AddMessage("INBOX", []byte("Subject: hi\r\n\r\nx\r\n"))
+		c := testClient(t, srv, SetPeek(tt.peek))
+
+		if _, err := c.GetAll(false, false); err != nil {
+			t.Fatalf("GetAll() with Peek %v returned an error: %s", tt.peek, err)
+		}
+		if !hasCommand(srv, tt.wantFetch) {
+			t.Errorf("GetAll() with Peek %v sent %q, want %q", tt.peek, srv.Commands(), tt.wantFetch)
+		}
+		if got := hasCommand(srv, "UID STORE 1 -FLAGS"); got != tt.wantReset {
+			t.Errorf("GetAll() with Peek %v reset \\Seen: %v, want %v", tt.peek, got, tt.wantReset)
+		}
+		if serverHasFlag(srv, "INBOX", uid, `\Seen`) {
+			t.Errorf("GetAll() with Peek %v left the email read", tt.peek)
+		}
+	}
+}
+
+func TestGetByUID(t *testing.T) {
+	srv := testServer(t)
+	srv.AddMessage("INBOX", []byte("Subject: first\r\n\r\nx\r\n"))
+	uid := srv.AddMessage("INBOX", []byte("Subject: second\r\n\r\nx\r\n"))
+	c := testClient(t, srv)
+
+	tests := []struct {
+		name    string
+		uid     uint32
+		want    string
+		wantErr error
+	}{
+		{"found", uid, "second", nil},
+		{"not found", uid + 1, "", ErrEmailNotFound},
+	}
+	for _, tt := range tests {
+		email, err := c.GetByUID(tt.uid)
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("%s: GetByUID() got error %v, want %v", tt.name, err, tt.wantErr)
+		}
+		if email.Subject != tt.want {
+			t.Errorf("%s: GetByUID() got subject %q, want %q", tt.name, email.Subject, tt.want)
+		}
+	}
+	if serverHasFlag(srv, "INBOX", uid, `\Seen`) {
+		t.Error("GetByUID() marked the email as read")
+	}
+}
+
 const quotedEmail = "Delivered-To: an.email.address@gmail.com\r\nReceived: by 10.220.224.7 with SMTP id im7csp165179vcb;\r\n        Mon, 11 Aug 2014 15:14:17 -0700 (PDT)\r\nX-Received: by 10.66.240.140 with SMTP id wa12mr524751pac.99.1407795256741;\r\n        Mon, 11 Aug 2014 15:14:16 -0700 (PDT)\r\nReturn-Path: <bo-b65ymr9bfbugyhauy2x7bbykuhtky7@b.e.latimes.com>\r\nReceived: from mta852.e.latimes.com (mta852.e.latimes.com. [63.232.236.160])\r\n        by mx.google.com with ESMTP id kd14si14437848pbb.64.2014.08.11.15.14.16\r\n        for <an.email.address@gmail.com>;\r\n        Mon, 11 Aug 2014 15:14:16 -0700 (PDT)\r\nReceived-SPF: pass (google.com: domain of bo-b65ymr9bfbugyhauy2x7bbykuhtky7@b.e.latimes.com designates 63.232.236.160 as permitted sender) client-ip=63.232.236.160;\r\nAuthentication-Results: mx.google.com;\r\n       spf=pass (google.com: domain of bo-b65ymr9bfbugyhauy2x7bbykuhtky7@b.e.latimes.com designates 63.232.236.160 as permitted sender) smtp.mail=bo-b65ymr9bfbugyhauy2x7bbykuhtky7@b.e.latimes.com;\r\n       dkim=pass header.i=@e.latimes.com\r\nDKIM-Signature: v=1; a=rsa-sha256; c=relaxed/relaxed; d=e.latimes.com;\r\n\ts=20120316; t=1407795256; x=1423692856;\r\n\tbh=CUzkYJbRqeJ0BB67gF474DY+T+fY0fLYMvAR3aPdlow=; h=From:Reply-To;\r\n\tb=gIPnif1mtRvQ/8DG4nqqCvaq6sNBJPAA8syDte/LQsMgPaXZBF4vEDc0t1ThWQtJF\r\n\t Yz8cDtWLfHv3fKx52DXhfTczzRGnOmpZYM1Z9DaYnIBzLcCxKwls/KYAjHmkSEZgeQ\r\n\t 13jIuKIu3eqVBRy5KypzJwPz9Ao5i0YSwOZYN/RM=\r\nDomainKey-Signature: a=rsa-sha1; q=dns; c=nofws;\r\n  s=200505; d=e.latimes.com;\r\n  b=WZ2Hpj3Ke741wPIrt7DXtfArp9aUrk63jUhl9Px7st2cUVj/dDxQM6F+jqdJmuyg6LgTBQ0gtSWU1VhXcYjgF1+t3y2CNap8lNi7+FYaWo9T2TZi2CnOLTfq5vc1i8uuTTqTginraNmYu1w+oj07GaKD5P2pTVwfJVdsAB6jNRk=;\r\n h=Date:Message-ID:List-Unsubscribe:From:To:Subject:MIME-Version:Reply-To:Content-type:Content-Transfer-Encoding;\r\nDate: Mon, 11 Aug 2014 22:14:16 -0000\r\nMessage-ID: <b65ymr9bfbugyhauy2x7bbykuhtky7.8225590.5365@mta852.e.latimes.com>\r\nList-Unsubscribe: <mailto:rm-0b65ymrykuhtky7@e.latimes.com>\r\nFrom: \"Los Angeles Times\" <news@e.latimes.com>\r\nTo: an.email.address@gmail.com\r\nSubject: Breaking News: Hostage in Stockton bank robbery was killed by officers, not suspects\r\nMIME-Version: 1.0\r\nReply-To: \"Los Angeles Times\" <support-b65ymr9bfbugyhauy2x7bbykuhtky7@e.latimes.com>\r\nContent-type: text/html; charset=\"iso-8859-1\"\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n\xC4pple<html><head></head><body onload=3D''><div class=3D\"module blurb clearfix\">\r\n\t<style><![CDATA[\r\n\t#email-wrapper {font-family: Georgia,Times,serif; font-size: 14px; width: =\r\n630px; padding: 10px;}\r\n\t#breaking-news-banner {width: 100%;}\r\n\t#emailadbox {width: 300px; height: 250px; margin-top: 3px;}\r\n#banner-graphic {margin-bottom:5px;}\r\n        #storyslug {width:280px; font-family: Georgia,Times,serif; font-siz=\r\ne: 14px;}\r\n\tp#emailad  {font-family: Arial,Helvetica,sans-serif; font-size: 10px; colo=\r\nr: #999; letter-spacing: 1px; text-align: center; margin-bottom:0px; margin=\r\n-top:5px;}\r\n\t.bottom-text {font-size: .85em; border-top: 1px solid #ccc; margin-top: 24=\r\npx; padding-top: 3px;}\r\n\t.bottom-text p {margin-top:4px; margin-bottom:1px;}\r\n\tp.email-head {margin-bottom:10px;}=09\r\n.email-date\t{color: #930000 ; font-style: italic; font-size: 11px; }\r\n.email-graph { margin-bottom: 12px;}\r\n]]></style><div id=3D\"email-wrapper\">\r\n\r\n<div id=3D\"banner-graphic\"><img src=3D\"http://www.latimes.com/media/graphic=\r\n/2010-02/52101671.png\" alt=3D\"Los Angeles Times\" /></div>\r\n\r\n<div id=3D\"breaking-news-banner\"><img src=3D\"http://www.latimes.com/media/a=\r\nlternatethumbnails/blurb/2012-07/47391835-16074348.gif\" alt=3D\"Breaking new=\r\ns\" border=3D\"0\" /></div>\r\n\r\n<table width=3D\"630\"><tr><td>\r\n<div id=3D\"storyslug\">\r\n<h1><a style=3D\"font-size: 20px; color: black\">Hostage in Stockton bank rob=\r\nbery was killed by officers, not suspects</a></h1>\r\n\r\n<!--<div style=3D\"margin-bottom: 12px;\" class=3D\"email-date\">Los Angeles Ti=\r\nmes | May 22, 2012 | 11:47 a.m.</div>-->\r\n<div style=3D\"margin-bottom: 12px;\" class=3D\"email-date\">Los Angeles Times =\r\n| August 11, 2014 |  3:09 PM</div>\r\n=20\r\n\r\n<p><p>Officials Monday said a Stockton woman taken hostage and used as a hu=\r\nman shield during a bank robbery turned police chase last month was killed =\r\nby gunfire from officers, not the suspects.</p>&#13;\r\n<p>A preliminary ballistics report indicates it was bullets from the police=\r\n that killed Misty Jean Holt-Singh during the chaotic July 16 gun battle, S=\r\ntockton Police Chief Eric Jones said. Initial reports suggest she was shot =\r\nabout 10 times, he added.</p>&#13;\r\n<p>The three suspects in the case -- two of whom were also killed -- fired =\r\nmore than 100 bullets during the one-hour incident, Jones said. Preliminary=\r\n reports show 33 police officers fired an estimated 600 bullets, he added.<=\r\n/p>&#13;\r\n<p>For the latest information go to <a href=3D\"http://e.latimes.com/a/hBT6T=\r\n8rB8hLWGB87vhDAAfYM2RC/exmp1\">www.latimes.com</a>.</p></p>\r\n</div>\r\n\r\n</td>\r\n<td width=3D\"330\" align=3D\"center\">\r\n\t\t<div style=3D\"margin: 30px 0;\"><center><span style=3D\"color: #c2c2c2;font=\r\n-family: arial; font-size:8px; line-height: 22px;\">ADVERTISEMENT</span></ce=\r\nnter>\r\n\t\t\t\t<table border=3D\"0\" cellpadding=3D\"0\" cellspacing=3D\"0\"><tr><td colspan=\r\n=3D\"2\"><a style=3D\"display: block; width: 300px; height: 250px;\" href=3D\"ht=\r\ntp://li.latimes.com/click?s=3D73326&t=3Dnewsletter&sz=3D300x250&li=3DLATime=\r\ns&e=3Dan.email.address@gmail.com&cm=3D001_hBT6T8rB8hLWGB87vhDAAfYM2RC\"=\r\n rel=3D\"nofollow\"><img src=3D\"http://li.latimes.com/imp?s=3D73326&t=3Dnewsl=\r\netter&sz=3D300x250&li=3DLATimes&e=3Dan.email.address@gmail.com&cm=3D00=\r\n1_hBT6T8rB8hLWGB87vhDAAfYM2RC\" border=3D\"0\" width=3D\"300\" height=3D\"250\" />=\r\n</a></td></tr><tr style=3D\"display:block; height:1px; line-height:1px;\"><td=\r\n><img src=3D\"http://li.latimes.com/imp?s=3D73327&t=3Dnewsletter&sz=3D1x1&li=\r\n=3DLATimes&e=3Dan.email.address@gmail.com&cm=3D001_hBT6T8rB8hLWGB87vhD=\r\nAAfYM2RC\" height=3D\"1\" width=3D\"10\" /></td><td><img src=3D\"http://li.latime=\r\ns.com/imp?s=3D73328&t=3Dnewsletter&sz=3D1x1&li=3DLATimes&e=3D@gmail.com&cm=3D001_hBT6T8rB8hLWGB87vhDAAfYM2RC\" height=3D\"1\" width=\r\n=3D\"10\" /></td></tr><tr><td align=3D\"left\"><a href=3D\"http://li.latimes.com=\r\n/click?s=3D49864&t=3Dnewsletter&sz=3D116x15&li=3DLATimes&e=3D@gmail.com&cm=3D001_hBT6T8rB8hLWGB87vhDAAfYM2RC\" rel=3D\"nofollow\"><i=\r\nmg src=3D\"http://li.latimes.com/imp?s=3D49864&t=3Dnewsletter&sz=3D116x15&li=\r\n=3DLATimes&e=3Dan.email.address@gmail.com&cm=3D001_hBT6T8rB8hLWGB87vhD=\r\nAAfYM2RC\" border=3D\"0\" /></a></td><td align=3D\"right\"><a href=3D\"http://li.=\r\nlatimes.com/click?s=3D49865&t=3Dnewsletter&sz=3D69x15&li=3DLATimes&e=3Db=\r\nkingr@gmail.com&cm=3D001_hBT6T8rB8hLWGB87vhDAAfYM2RC\" rel=3D\"no=\r\nfollow\"><img src=3D\"http://li.latimes.com/imp?s=3D49865&t=3Dnewsletter&sz=\r\n=3D69x15&li=3DLATimes&e=3Dan.email.address@gmail.com&cm=3D001_hBT6T8rB=\r\n8hLWGB87vhDAAfYM2RC\" border=3D\"0\" /></a></td></tr></table><br /><table cell=\r\npadding=3D\"0\" cellspacing=3D\"0\" border=3D\"0\" width=3D\"24\" height=3D\"6\"><tbo=\r\ndy><tr><td><img src=3D\"http://li.latimes.com/imp?s=3D77983&t=3Dnewsletter&s=\r\nz=3D2x1&li=3DLATimes&e=3Dan.email.address@gmail.com&cm=3D001_hBT6T8rB8=\r\nhLWGB87vhDAAfYM2RC\" width=3D\"2\" height=3D\"6\" border=3D\"0\" /></td><td><img s=\r\nrc=3D\"http://li.latimes.com/imp?s=3D77984&t=3Dnewsletter&sz=3D2x1&li=3DLATi=\r\nmes&e=3Dan.email.address@gmail.com&cm=3D001_hBT6T8rB8hLWGB87vhDAAfYM2R=\r\nC\" width=3D\"2\" height=3D\"6\" border=3D\"0\" /></td><td><img src=3D\"http://li.l=\r\natimes.com/imp?s=3D77985&t=3Dnewsletter&sz=3D2x1&li=3DLATimes&e=3Dr@gmail.com&cm=3D001_hBT6T8rB8hLWGB87vhDAAfYM2RC\" width=3D\"2\" he=\r\night=3D\"6\" border=3D\"0\" /></td><td><img src=3D\"http://li.latimes.com/imp?s=\r\n=3D77986&t=3Dnewsletter&sz=3D2x1&li=3DLATimes&e=3Dan.email.address@gma=\r\nil.com&cm=3D001_hBT6T8rB8hLWGB87vhDAAfYM2RC\" width=3D\"2\" height=3D\"6\" borde=\r\nr=3D\"0\" /></td><td><img src=3D\"http://li.latimes.com/imp?s=3D77987&t=3Dnews=\r\nletter&sz=3D2x1&li=3DLATimes&e=3Dan.email.address@gmail.com&cm=3D001_h=\r\nBT6T8rB8hLWGB87vhDAAfYM2RC\" width=3D\"2\" height=3D\"6\" border=3D\"0\" /></td><t=\r\nd><img src=3D\"http://li.latimes.com/imp?s=3D77988&t=3Dnewsletter&sz=3D2x1&l=\r\ni=3DLATimes&e=3Dan.email.address@gmail.com&cm=3D001_hBT6T8rB8hLWGB87vh=\r\nDAAfYM2RC\" width=3D\"2\" height=3D\"6\" border=3D\"0\" /></td><td><img src=3D\"htt=\r\np://li.latimes.com/imp?s=3D77989&t=3Dnewsletter&sz=3D2x1&li=3DLATimes&e=3Db=\r@gmail.com&cm=3D001_hBT6T8rB8hLWGB87vhDAAfYM2RC\" width=\r\n=3D\"2\" height=3D\"6\" border=3D\"0\" /></td><td><img src=3D\"http://li.latimes.c=\r\nom/imp?s=3D77990&t=3Dnewsletter&sz=3D2x1&li=3DLATimes&e=3D&cm=3D001_hBT6T8rB8hLWGB87vhDAAfYM2RC\" width=3D\"2\" height=3D\"=\r\n6\" border=3D\"0\" /></td><td><img src=3D\"http://li.latimes.com/imp?s=3D77991&=\r\nt=3Dnewsletter&sz=3D2x1&li=3DLATimes&e=3Dan.email.address@gmail.com&cm=\r\n=3D001_hBT6T8rB8hLWGB87vhDAAfYM2RC\" width=3D\"2\" height=3D\"6\" border=3D\"0\" /=\r\n></td><td><img src=3D\"http://li.latimes.com/imp?s=3D77992&t=3Dnewsletter&sz=\r\n=3D2x1&li=3DLATimes&e=3Dan.email.address@gmail.com&cm=3D001_hBT6T8rB8h=\r\nLWGB87vhDAAfYM2RC\" width=3D\"2\" height=3D\"6\" border=3D\"0\" /></td><td><img sr=\r\nc=3D\"http://li.latimes.com/imp?s=3D77993&t=3Dnewsletter&sz=3D2x1&li=3DLATim=\r\nes&e=3Dan.email.address@gmail.com&cm=3D001_hBT6T8rB8hLWGB87vhDAAfYM2RC=\r\n\" width=3D\"2\" height=3D\"6\" border=3D\"0\" /></td><td><img src=3D\"http://li.la=\r\ntimes.com/imp?s=3D77994&t=3Dnewsletter&sz=3D2x1&li=3DLATimes&e=3DD001_hBT6T8rB8hLWGB87vhDAAfYM2RC\" width=3D\"2\" hei=\r\nght=3D\"6\" border=3D\"0\" /></td></tr></tbody></table></div>\r\n</td>\r\n\r\n</tr></table><div class=3D\"bottom-text\">\r\n\t<p class=3D\"email-head\">Text \"=\r\news text alerts. You will receive 2 msgs/week. Msg&amp;data rates may apply=\r\n. Text HELP for help. Text STOP to cancel.</p>\r\n=09\r\n\t\t<p>California and the world: Visit <a href=3D\"http://e.latimes.com/a/hBT6=\r\nT8rB8hLWGB87vhDAAfYM2RC/exmp1\">http://www.latimes.com</a> for up-to-the-min=\r\nute news.</p>\r\n\t\t<p><strong>Follow</strong> @LATimes on Twitter: <a href=3D\"http://e.latim=\r\nes.com/a/hBT6T8rB8hLWGB87vhDAAfYM2RC/exmp2\">http://twitter.com/latimes</a><=\r\n/p>\r\n\t\t<p><strong>Connect</strong> with the L.A. Times on Facebook: <a href=3D\"h=\r\nttp://e.latimes.com/a/hBT6T8rB8hLWGB87vhDAAfYM2RC/exmp3\">http://facebook.co=\r\nm/latimes</a></p>\r\n\t\t<p><strong>Sign up</strong> for more email newsletters: <a href=3D\"http:/=\r\n/e.latimes.com/a/hBT6T8rB8hLWGB87vhDAAfYM2RC/exmp4\">http://latimes.com/news=\r\nletters</a></p>\r\n=09\r\n</div>\r\n\r\n\r\n\r\n<div class=3D\"bottom-text\">\r\n\t\t<p class=3D\"email-head\"><i>About this communication:</i></p>\r\n\t\t<p>You are receiving this email because you opted to receive Breaking New=\r\ns Alerts from the Los Angeles Times.</p>\r\n\t\t<p>You're currently subscribed to Los Angeles Times Breaking News with th=\r\ne address an.email.address@gmail.com. If you'd like to unsubscribe, pl=\r\nease click here: <a href=3D\"http://e.latimes.com/a/hBT6T8rB8hLWGB87vhDAAfYM=\r\n2RC/exmp5?email=3Dan.email.address@gmail.com\">http://ebm.cheetahmail.c=\r\nom/r/webunsub?t=3DBT6M2RC&amp;email=3Dcom&amp;n=3D1</a></p>\r\n\t\t<p>You can also unsubscribe by modifying your profile on latimes.com at <=\r\na href=3D\"http://e.latimes.com/a/hBT6hDAAfYM2RC/exmp6\">http://=\r\nwww.latimes.com/newsletters</a></p>\r\n\t\t<p>For information on how we protect your information, please read our pr=\r\nivacy policy at <a href=3D\"http://e.latimes.com/a/hBT6T8rB8hLWGB87vhDAAfYM2=\r\nRC/exmp7\">http://www.latimes.com/privacypolicy</a></p>\r\n=09\r\n</div>\r\n\r\n=09\r\n\r\n</div>\r\n</div>\r\n\r\n<!--x-Instance-Name: i5latisrapp08--><img src=3D\"http://e.latimes.com/a/hBT=\r\n6T8rB8hLWGB87vhDAAfYM2RC/spacer.gif\">\r\n</body></html>=\r\n\r\n"
 const htmlEmail = "Delivered-To: an.email.address@gmail.com\r\nReceived: by 10.220.224.7 with SMTP id im7csp226521vcb;\r\n        Tue, 12 Aug 2014 10:49:55 -0700 (PDT)\r\nX-Received: by 10.236.81.243 with SMTP id m79mr20366101yhe.28.1407865795556;\r\n        Tue, 12 Aug 2014 10:49:55 -0700 (PDT)\r\nReturn-Path: <foxnews_BADE9CA9E0AAA50AF208A0917BB3264E@response.foxnews.com>\r\nReceived: from vmta.response.foxnews.com ([216.87.167.12])\r\n        by mx.google.com with ESMTP id t94si33703331yhp.75.2014.08.12.10.49.55\r\n        for <an.email.address@gmail.com>;\r\n        Tue, 12 Aug 2014 10:49:55 -0700 (PDT)\r\nReceived-SPF: pass (google.com: domain of foxnews_BADE9CA9E0AAA50AF208A0917BB3264E@response.foxnews.com designates 216.87.167.12 as permitted sender) client-ip=216.87.167.12;\r\nAuthentication-Results: mx.google.com;\r\n       spf=pass (google.com: domain of foxnews_BADE9CA9E0AAA50AF208A0917BB3264E@response.foxnews.com designates 216.87.167.12 as permitted sender) smtp.mail=foxnews_BADE9CA9E0AAA50AF208A0917BB3264E@response.foxnews.com;\r\n       dkim=policy (weak key) header.i=@newsletters.foxnews.com\r\nDKIM-Signature: v=1; a=rsa-sha1; c=relaxed/relaxed; s=key1; d=newsletters.foxnews.com;\r\n h=Date:From:Reply-To:To:Message-ID:Subject:MIME-Version:Content-Type:Content-Transfer-Encoding:List-Unsubscribe; i=foxnews@newsletters.foxnews.com;\r\n bh=HkiAm/nPEUtzltXrGl+KGJnYQHE=;\r\n b=Gmo0MhqaZfy5xMJpIOvxM1kuJE+7j+viAp8Y7WkZzQgiaN1zrYfpbBabKMxjW/U5ri9r67/\r\n   rkL6YnMUYQ==\r\nDomainKey-Signature: a=rsa-sha1; c=nofws; q=dns; s=key1; d=newsletters.foxnews.com;\r\n b=qHcGch0YgOPIUsD8ggeQ8Sly0+QxGJ/xJ3JozcbeVLk5JcQOAbmmBP0Rj/9bS5q+EX1KkktNB65A\r\n   9I3Ina5nlQ==;\r\nReceived: from wc-robot.tpa.foxnews.com (192.168.193.69) by vmta.response.foxnews.com (PowerMTA(TM) v3.5r16) id ht99s60q7b88 for <an.email.address@gmail.com>; Tue, 12 Aug 2014 13:49:55 -0400 (envelope-from <foxnews_BADE9CA9E0AAA50AF208A0917BB3264E@response.foxnews.com>)\r\nDate: Tue, 12 Aug 2014 13:49:54 -0400\r\nFrom: \"FoxNews.com\" <foxnews@newsletters.foxnews.com>\r\nReply-To: foxnews_BADE9CA9E0AAA50AF208A0917BB3264E@newsletters.foxnews.com\r\nTo: an.email.address@gmail.com\r\nMessage-ID: <BADE9CA9E0AAA50AF208A0917BB3264E-d67ac706448a4e119df2ac91045dc209@response.foxnews.com>\r\nSubject: WATCH LIVE: News conference on death of Robin Williams\r\nMIME-Version: 1.0\r\nContent-Type: text/html; charset=UTF-8\r\nContent-Transfer-Encoding: 7bit\r\nX-Mailer: WhatCounts\r\nENVID: WC-1407865794717-BADE9CA9E0AAA50AF208A0917BB3264E-d67ac706448a4e119df2ac91045dc209\r\nList-Unsubscribe: <http://email.foxnews.com/u?id=BADE9A50AF208A0917BB3264E>\r\nX-Unsubscribe-Web: <http://email.foxnews.com/u?id=BADE9CA9E0AAA50AF208A0917BB3264E>\r\n\r\n<br />\r\n<br />\r\n <a href=\"http://email.foxnews.com/t?r=5&c=29452&l=35&ctl=57F1B:BADE9CA9E0AAA50AF208A0917BB3264E&\">http://video.foxnews.com/v/2553193403001/#sp=watch-live</a>\r\n<br />\r\n<br />\r\n <a href=\"\"></a>\r\n<br />\r\n<br />\r\nFor more news, please go to <a href=\"http://email.foxnews.com/t?r=5&c=29452&l=35&ctl=57F1C:BADE9CA9E0AAA50AF208A0917BB3264E&\">FoxNews.com</a> and watch Fox News Channel.\r\n<br />\r\n<br />\r\n<br />\r\n<tr>\r\n\r\n<td valign=\"top\" align=\"center\">\t\t\t                \t\r\n\r\n<p style=\"margin-top: 0; margin-bottom: 10px; color: #999999; font-family: arial; font-size:11px; font-weight:bold;\"><a style=\"color: #183A52; font-family: arial; font-size:11px; font-weight:bold; text-decoration:none\" href=\"http://email.foxnews.com/t?r=5&c=29452&l=35&ctl=57F1D:BADE9CA9E0AAA50AF208A0917BB3264E&\"><span style=\"color: #183a52;\">More Newsletters</span></a> | <a style=\"color: #183A52; font-family: arial; font-size:11px; font-weight:bold; text-decoration:none\" href=\"http://email.foxnews.com/u?id=BADE9CA9E0AAA50AF208A0917BB3264E\"><span style=\"color: #183a52;\">Unsubscribe</span></a> | <a style=\"color: #183A52; font-family: arial; font-size: 11px; text-decoration: none\" href=\"http://email.foxnews.com/t?r=5&c=29452&l=35&ctl=57F1E:BADE9CA9E0AAA50AF208A0917BB3264E&\"><span style=\"color: #183a52;\">Privacy Policy</span></a></p>\r\n\r\n<p style=\"margin-top: 0; margin-bottom: 10px; color: #666666; font-family: arial; font-size: 11px;\">&#169;2014 Fox News Network, LLC. All Rights Reserved.</p>\r\n\r\n</td>\r\n\r\n</tr>\r\n<p style=\"margin-top: 0; margin-bottom: 10px; color: #666666; font-family: arial; font-size: 11px;\">Fox News never sends unsolicited email. You received this email because you requested a subscription to Breaking Alerts from FoxNews.com."
 
@@ -482,3 +688,60 @@ Content-Transfer-Encoding: 7bit
 --eZDakj4l4DVQ=_?:--
 
 `
+
+// rawEmail parses a raw RFC 5322 message with UID 1.
+func rawEmail(t testing.TB, raw string) Email {
+	t.Helper()
+	email, err := parseRaw(uint32(1), []byte(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return email
+}
+
+// testServer starts an eazyetest.Server stopped at the end of the test.
+func testServer(t testing.TB) *eazyetest.Server {
+	t.Helper()
+	srv := eazyetest.NewServer()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// testClient connects a Client to the INBOX of the server with the options,
+// closing it at the end of the test.
+func testClient(t testing.TB, srv *eazyetest.Server, options ...func(*Client)) *Client {
+	t.Helper()
+	c, err := New(srv.Addr, "user", "secret", append([]func(*Client){SetFolder("INBOX")}, options...)...)
+	if err != nil {
+		t.Fatalf("New() returned an error: %s", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// hasCommand tells whether the server received a command starting with
+// prefix.
+func hasCommand(srv *eazyetest.Server, prefix string) bool {
+	for _, command := range srv.Commands() {
+		if strings.HasPrefix(command, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// serverHasFlag tells whether the message with the UID has the flag on the
+// server, ignoring case.
+func serverHasFlag(srv *eazyetest.Server, folder string, uid uint32, flag string) bool {
+	for _, msg := range srv.Messages(folder) {
+		if msg.UID != uid {
+			continue
+		}
+		for _, f := range msg.Flags {
+			if strings.EqualFold(f, flag) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package eazyetest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mimePart is a MIME part of a message, as BODYSTRUCTURE and BODY[<section>]
+// see it. The body is left transfer encoded.
+type mimePart struct {
+	header textproto.MIMEHeader
+	body   []byte
+	// parts are the children of a multipart
+	parts []*mimePart
+}
+
+// parseMIME splits the raw message into its MIME parts. A message that can
+// not be parsed is a single text/plain part.
+func parseMIME(raw []byte) *mimePart {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		_, text := splitMessage(raw)
+		return &mimePart{header: textproto.MIMEHeader{}, body: text}
+	}
+	body, _ := io.ReadAll(msg.Body)
+	return newMIMEPart(textproto.MIMEHeader(msg.Header), body)
+}
+
+func newMIMEPart(header textproto.MIMEHeader, body []byte) *mimePart {
+	p := &mimePart{header: header, body: body}
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return p
+	}
+	r := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := r.NextRawPart()
+		if err != nil {
+			break
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			break
+		}
+		p.parts = append(p.parts, newMIMEPart(part.Header, content))
+	}
+	return p
+}
+
+// section finds the part with the section number, e.g. 2.1. The body of a
+// message that is not a multipart is its section 1.
+func (p *mimePart) section(number string) (*mimePart, bool) {
+	for _, n := range strings.Split(number, ".") {
+		i, err := strconv.Atoi(n)
+		if err != nil || i < 1 {
+			return nil, false
+		}
+		if len(p.parts) == 0 {
+			if i != 1 {
+				return nil, false
+			}
+			continue
+		}
+		if i > len(p.parts) {
+			return nil, false
+		}
+		p = p.parts[i-1]
+	}
+	return p, true
+}
+
+// structure formats the BODYSTRUCTURE of the part. Parts of type
+// message/rfc822 are described like any other, without the message inside.
+func (p *mimePart) structure() string {
+	mediaType, params, err := mime.ParseMediaType(p.header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{"charset": "us-ascii"}
+	}
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+
+	if len(p.parts) > 0 {
+		var b strings.Builder
+		b.WriteString("(")
+		for _, part := range p.parts {
+			b.WriteString(part.structure())
+		}
+		b.WriteString(" " + imapString(subtype) + ")")
+		return b.String()
+	}
+
+	encoding := p.header.Get("Content-Transfer-Encoding")
+	if encoding == "" {
+		encoding = "7bit"
+	}
+	fields := []string{
+		imapString(typ),
+		imapString(subtype),
+		paramList(params),
+		nilString(p.header.Get("Content-Id")),
+		nilString(p.header.Get("Content-Description")),
+		imapString(encoding),
+		strconv.Itoa(len(p.body)),
+	}
+	if typ == "text" {
+		fields = append(fields, strconv.Itoa(bytes.Count(p.body, []byte("\n"))))
+	}
+	// the MD5, then the disposition
+	fields = append(fields, "NIL", disposition(p.header.Get("Content-Disposition")))
+	return "(" + strings.Join(fields, " ") + ")"
+}
+
+func disposition(value string) string {
+	if value == "" {
+		return "NIL"
+	}
+	dsp, params, err := mime.ParseMediaType(value)
+	if err != nil {
+		return "NIL"
+	}
+	return "(" + imapString(dsp) + " " + paramList(params) + ")"
+}
+
+// paramList formats the parameters as a list of names and values, sorted by
+// name so the structure does not change from one FETCH to the next.
+func paramList(params map[string]string) string {
+	if len(params) == 0 {
+		return "NIL"
+	}
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fields := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		fields = append(fields, imapString(name), imapString(params[name]))
+	}
+	return "(" + strings.Join(fields, " ") + ")"
+}
+
+func nilString(s string) string {
+	if s == "" {
+		return "NIL"
+	}
+	return imapString(s)
+}
+
+// imapString formats s as a quoted string, or a literal if it can not be
+// quoted.
+func imapString(s string) string {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e || s[i] == '"' || s[i] == '\\' {
+			return fmt.Sprintf("{%d}\r\n%s", len(s), s)
+		}
+	}
+	return `"` + s + `"`
+}
@@ -0,0 +1,508 @@
+package eazyetest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchDateFormat is the layout of dates in SEARCH keys.
+const searchDateFormat = "2-Jan-2006"
+
+// readCommand reads the next command, sending a continuation request for
+// every synchronizing literal. Atoms, quoted strings and literals all come
+// back as strings, parenthesized lists as []any. A command that can not be
+// parsed is skipped and returned as an error along with its tag.
+func (s *session) readCommand() (string, []any, error) {
+	tag, err := s.readAtom()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var args []any
+	for {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return "", nil, err
+		}
+		switch b {
+		case '\r':
+			continue
+		case '\n':
+			return tag, args, nil
+		case ' ':
+			value, err := s.readValue()
+			if err != nil {
+				if errors.Is(err, errSyntax) {
+					s.r.ReadString('\n')
+					return tag, nil, err
+				}
+				return "", nil, err
+			}
+			args = append(args, value)
+		default:
+			s.r.ReadString('\n')
+			return tag, nil, errSyntax
+		}
+	}
+}
+
+// errSyntax is returned for commands the server can not make sense of.
+var errSyntax = errors.New("syntax error")
+
+func (s *session) readValue() (any, error) {
+	b, err := s.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch b {
+	case '(':
+		var list []any
+		for {
+			if b, err = s.r.ReadByte(); err != nil {
+				return nil, err
+			}
+			switch b {
+			case ')':
+				return list, nil
+			case ' ':
+				continue
+			case '\r', '\n':
+				return nil, errSyntax
+			}
+			s.r.UnreadByte()
+			value, err := s.readValue()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+	case '"':
+		var buf bytes.Buffer
+		for {
+			if b, err = s.r.ReadByte(); err != nil {
+				return nil, err
+			}
+			switch b {
+			case '"':
+				return buf.String(), nil
+			case '\\':
+				if b, err = s.r.ReadByte(); err != nil {
+					return nil, err
+				}
+			case '\r', '\n':
+				return nil, errSyntax
+			}
+			buf.WriteByte(b)
+		}
+	case '{':
+		spec, err := s.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		spec = strings.TrimSuffix(strings.TrimSuffix(spec, "\n"), "\r")
+		if !strings.HasSuffix(spec, "}") {
+			return nil, errSyntax
+		}
+		spec = strings.TrimSuffix(spec, "}")
+		sync := !strings.HasSuffix(spec, "+")
+		n, err := strconv.Atoi(strings.TrimSuffix(spec, "+"))
+		if err != nil || n < 0 {
+			return nil, errSyntax
+		}
+		if sync {
+			s.w.WriteString("+ Ready for literal data\r\n")
+			if err = s.w.Flush(); err != nil {
+				return nil, err
+			}
+		}
+		data := make([]byte, n)
+		if _, err = io.ReadFull(s.r, data); err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+
+	s.r.UnreadByte()
+	return s.readAtom()
+}
+
+// readAtom reads an atom, keeping anything between brackets as is so that
+// fetch items like BODY.PEEK[HEADER.FIELDS (FROM)] are read whole.
+func (s *session) readAtom() (string, error) {
+	var buf bytes.Buffer
+	depth := 0
+	for {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case b == '[':
+			depth++
+		case b == ']' && depth > 0:
+			depth--
+		case depth == 0 && (b == ' ' || b == '(' || b == ')' || b == '\r' || b == '\n'):
+			s.r.UnreadByte()
+			if buf.Len() == 0 {
+				return "", errSyntax
+			}
+			return buf.String(), nil
+		case b == '\r' || b == '\n':
+			return "", errSyntax
+		}
+		buf.WriteByte(b)
+	}
+}
+
+// formatCommand formats a command the way Commands returns it, leaving out
+// the password of LOGIN.
+func formatCommand(name string, args []any) string {
+	if name == "LOGIN" && len(args) == 3 {
+		args = args[:2]
+	}
+	parts := []string{name}
+	for _, arg := range args[1:] {
+		parts = append(parts, formatValue(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = formatValue(item)
+		}
+		return "(" + strings.Join(parts, " ") + ")"
+	case string:
+		return quote(v)
+	}
+	return ""
+}
+
+// quote returns s as is if it can be sent as an atom, or quoted otherwise.
+func quote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \"(){%\r\n") {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+// seqSet is a sequence set, e.g. 1:4,7,9:*, of UIDs or sequence numbers.
+type seqSet struct {
+	uid    bool
+	ranges [][2]uint32
+}
+
+// setArg parses the sequence set argument against the messages of the
+// selected folder.
+func setArg(arg any, uid bool, msgs []*Message) (seqSet, error) {
+	spec, _ := arg.(string)
+	last := uint32(len(msgs))
+	if uid {
+		last = maxUID(msgs)
+	}
+	return parseSeqSet(spec, uid, last)
+}
+
+func parseSeqSet(spec string, uid bool, last uint32) (seqSet, error) {
+	set := seqSet{uid: uid}
+	if spec == "" {
+		return set, fmt.Errorf("empty sequence set")
+	}
+
+	number := func(s string) (uint32, error) {
+		if s == "*" {
+			return last, nil
+		}
+		n, err := strconv.ParseUint(s, 10, 32)
+		if err != nil || n == 0 {
+			return 0, fmt.Errorf("invalid sequence set %q", spec)
+		}
+		return uint32(n), nil
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		from, to, isRange := strings.Cut(part, ":")
+		lo, err := number(from)
+		if err != nil {
+			return set, err
+		}
+		hi := lo
+		if isRange {
+			if hi, err = number(to); err != nil {
+				return set, err
+			}
+		}
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		set.ranges = append(set.ranges, [2]uint32{lo, hi})
+	}
+	return set, nil
+}
+
+// contains tells whether the message, at sequence number seq, is in the set.
+func (set seqSet) contains(seq int, msg *Message) bool {
+	n := uint32(seq)
+	if set.uid {
+		n = msg.UID
+	}
+	for _, r := range set.ranges {
+		if n >= r[0] && n <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// matcher tells whether the message, at sequence number seq, matches a
+// search key.
+type matcher func(seq int, msg *Message) bool
+
+// parseSearch parses the search keys, all of which must match, for a folder
+// with count messages, the last one with lastUID.
+func parseSearch(keys []any, count int, lastUID uint32) (matcher, error) {
+	p := &searchParser{keys: keys, count: count, lastUID: lastUID}
+	var matchers []matcher
+	for p.pos < len(p.keys) {
+		m, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	return allOf(matchers), nil
+}
+
+type searchParser struct {
+	keys    []any
+	pos     int
+	count   int
+	lastUID uint32
+}
+
+func (p *searchParser) next() (string, error) {
+	if p.pos >= len(p.keys) {
+		return "", fmt.Errorf("missing search argument")
+	}
+	v, ok := p.keys[p.pos].(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected list in search")
+	}
+	p.pos++
+	return v, nil
+}
+
+func (p *searchParser) parseKey() (matcher, error) {
+	if p.pos >= len(p.keys) {
+		return nil, fmt.Errorf("missing search key")
+	}
+	if list, ok := p.keys[p.pos].([]any); ok {
+		p.pos++
+		m, err := parseSearch(list, p.count, p.lastUID)
+		if err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+
+	key, _ := p.next()
+	switch strings.ToUpper(key) {
+	case "ALL", "OLD":
+		return func(int, *Message) bool { return true }, nil
+	case "RECENT":
+		return func(int, *Message) bool { return false }, nil
+	case "ANSWERED", "DELETED", "DRAFT", "FLAGGED", "SEEN":
+		return hasFlag(`\` + capitalize(key)), nil
+	case "UNANSWERED", "UNDELETED", "UNDRAFT", "UNFLAGGED", "UNSEEN", "NEW":
+		flag := strings.TrimPrefix(strings.ToUpper(key), "UN")
+		if flag == "NEW" {
+			flag = "SEEN"
+		}
+		return not(hasFlag(`\` + capitalize(flag))), nil
+	case "KEYWORD", "UNKEYWORD":
+		flag, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if strings.EqualFold(key, "UNKEYWORD") {
+			return not(hasFlag(flag)), nil
+		}
+		return hasFlag(flag), nil
+	case "BEFORE", "ON", "SINCE", "SENTBEFORE", "SENTON", "SENTSINCE":
+		return p.dateKey(strings.ToUpper(key))
+	case "SUBJECT", "FROM", "TO", "CC", "BCC":
+		value, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		return headerContains(capitalize(key), value), nil
+	case "HEADER":
+		name, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		return headerContains(name, value), nil
+	case "BODY", "TEXT":
+		value, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		wholeMessage := strings.EqualFold(key, "TEXT")
+		return func(_ int, msg *Message) bool {
+			_, data := splitMessage(msg.Raw)
+			if wholeMessage {
+				data = msg.Raw
+			}
+			return containsFoldBytes(data, value)
+		}, nil
+	case "LARGER", "SMALLER":
+		value, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size %q", value)
+		}
+		if strings.EqualFold(key, "LARGER") {
+			return func(_ int, msg *Message) bool { return len(msg.Raw) > size }, nil
+		}
+		return func(_ int, msg *Message) bool { return len(msg.Raw) < size }, nil
+	case "UID":
+		value, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		set, err := parseSeqSet(value, true, p.lastUID)
+		if err != nil {
+			return nil, err
+		}
+		return set.contains, nil
+	case "NOT":
+		m, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		return not(m), nil
+	case "OR":
+		a, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		b, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		return func(seq int, msg *Message) bool { return a(seq, msg) || b(seq, msg) }, nil
+	}
+
+	if key != "" && (key[0] == '*' || key[0] >= '0' && key[0] <= '9') {
+		set, err := parseSeqSet(key, false, uint32(p.count))
+		if err != nil {
+			return nil, err
+		}
+		return set.contains, nil
+	}
+	return nil, fmt.Errorf("unsupported search key %s", key)
+}
+
+// dateKey parses the date of a date search key. Dates are compared without
+// their time, in the time zone of the message's date.
+func (p *searchParser) dateKey(key string) (matcher, error) {
+	value, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	date, err := time.Parse(searchDateFormat, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q", value)
+	}
+
+	return func(_ int, msg *Message) bool {
+		t := msg.InternalDate
+		if strings.HasPrefix(key, "SENT") {
+			m, err := mail.ReadMessage(bytes.NewReader(msg.Raw))
+			if err != nil {
+				return false
+			}
+			if t, err = m.Header.Date(); err != nil {
+				return false
+			}
+		}
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		switch strings.TrimPrefix(key, "SENT") {
+		case "BEFORE":
+			return day.Before(date)
+		case "ON":
+			return day.Equal(date)
+		default:
+			return !day.Before(date)
+		}
+	}, nil
+}
+
+func allOf(matchers []matcher) matcher {
+	return func(seq int, msg *Message) bool {
+		for _, m := range matchers {
+			if !m(seq, msg) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func not(m matcher) matcher {
+	return func(seq int, msg *Message) bool { return !m(seq, msg) }
+}
+
+func hasFlag(flag string) matcher {
+	return func(_ int, msg *Message) bool { return msg.hasFlag(flag) }
+}
+
+// headerContains matches messages with a header field containing value,
+// ignoring case. Encoded words are matched decoded.
+func headerContains(name, value string) matcher {
+	return func(_ int, msg *Message) bool {
+		m, err := mail.ReadMessage(bytes.NewReader(msg.Raw))
+		if err != nil {
+			return false
+		}
+		for _, field := range m.Header[textproto.CanonicalMIMEHeaderKey(name)] {
+			if decoded, err := new(mime.WordDecoder).DecodeHeader(field); err == nil {
+				field = decoded
+			}
+			if strings.Contains(strings.ToLower(field), strings.ToLower(value)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func containsFoldBytes(data []byte, value string) bool {
+	return bytes.Contains(bytes.ToLower(data), bytes.ToLower([]byte(value)))
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+}
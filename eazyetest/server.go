@@ -0,0 +1,240 @@
+// Package eazyetest provides an in-process IMAP server for testing code built
+// on eazye without a real mailbox.
+//
+//	srv := eazyetest.NewServer()
+//	defer srv.Close()
+//	srv.AddMessage("INBOX", raw)
+//
+//	client, err := eazye.New(srv.Addr, "user", "password")
+//
+// The server speaks enough IMAP4rev1 (RFC 3501) for everything eazye does:
+// LOGIN, SELECT, SEARCH, FETCH, STORE, COPY, EXPUNGE and the folder commands,
+// along with their UID variants. Every command it receives is recorded so
+// tests can assert on what was sent.
+package eazyetest
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is an email held by the Server.
+type Message struct {
+	UID          uint32
+	Flags        []string
+	InternalDate time.Time
+	Raw          []byte
+}
+
+// hasFlag tells whether the message has the flag, ignoring case.
+func (m *Message) hasFlag(flag string) bool {
+	for _, f := range m.Flags {
+		if strings.EqualFold(f, flag) {
+			return true
+		}
+	}
+	return false
+}
+
+type folder struct {
+	uidValidity uint32
+	uidNext     uint32
+	messages    []*Message
+}
+
+// Server is an in-process IMAP server holding its folders in memory. Its
+// methods are safe to call while clients are connected.
+type Server struct {
+	// Addr is the host:port to connect to, without TLS.
+	Addr string
+	// User and Password, if set, are the only credentials accepted.
+	// Otherwise any are.
+	User     string
+	Password string
+	// Caps are the capabilities announced besides IMAP4rev1, UIDPLUS and
+	// MOVE, e.g. "IDLE".
+	Caps []string
+
+	ln       net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	folders  map[string]*folder
+	commands []string
+	conns    map[net.Conn]bool
+	closed   bool
+}
+
+// NewServer starts a Server listening on a local port with an empty INBOX.
+// It panics if it can not listen, as there is no way a test can carry on.
+func NewServer() *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("eazyetest: unable to listen: %s", err))
+	}
+
+	s := &Server{
+		Addr:    ln.Addr().String(),
+		ln:      ln,
+		folders: map[string]*folder{},
+		conns:   map[net.Conn]bool{},
+	}
+	s.createFolder("INBOX")
+
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Close stops the server and closes any open connections.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.ln.Close()
+	s.wg.Wait()
+}
+
+// AddFolder creates the folder if it does not exist yet.
+func (s *Server) AddFolder(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.createFolder(name)
+}
+
+// AddMessage adds a message to the folder, creating the folder if needed,
+// and returns its UID. Its internal date is now.
+func (s *Server) AddMessage(folderName string, raw []byte, flags ...string) uint32 {
+	return s.AddMessageAt(folderName, raw, time.Now(), flags...)
+}
+
+// AddMessageAt is AddMessage with the given internal date.
+func (s *Server) AddMessageAt(folderName string, raw []byte, date time.Time, flags ...string) uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f := s.createFolder(folderName)
+	msg := &Message{
+		UID:          f.uidNext,
+		Flags:        append([]string{}, flags...),
+		InternalDate: date,
+		Raw:          append([]byte{}, raw...),
+	}
+	f.uidNext++
+	f.messages = append(f.messages, msg)
+	return msg.UID
+}
+
+// Messages returns a copy of the messages in the folder, in UID order.
+func (s *Server) Messages(folderName string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.folders[folderName]
+	if !ok {
+		return nil
+	}
+	msgs := make([]Message, 0, len(f.messages))
+	for _, msg := range f.messages {
+		m := *msg
+		m.Flags = append([]string{}, msg.Flags...)
+		msgs = append(msgs, m)
+	}
+	return msgs
+}
+
+// Folders returns the names of all the folders, sorted.
+func (s *Server) Folders() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var names []string
+	for name := range s.folders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Commands returns every command received so far without its tag, e.g.
+// "UID FETCH 1:3 (UID FLAGS)". The password of LOGIN is left out.
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.commands...)
+}
+
+// ResetCommands forgets the commands received so far.
+func (s *Server) ResetCommands() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = nil
+}
+
+// createFolder returns the folder, creating it if needed. s.mu must be held.
+func (s *Server) createFolder(name string) *folder {
+	if strings.EqualFold(name, "INBOX") {
+		name = "INBOX"
+	}
+	f, ok := s.folders[name]
+	if !ok {
+		f = &folder{uidValidity: uint32(time.Now().Unix()), uidNext: 1}
+		s.folders[name] = f
+	}
+	return f
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			sess := &session{
+				srv:  s,
+				conn: conn,
+				r:    bufio.NewReader(conn),
+				w:    bufio.NewWriter(conn),
+			}
+			sess.run()
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+// record saves the command for Commands.
+func (s *Server) record(command string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, command)
+}
+
+func (s *Server) capabilities() string {
+	return strings.Join(append([]string{"IMAP4rev1", "UIDPLUS", "MOVE"}, s.Caps...), " ")
+}
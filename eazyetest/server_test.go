@@ -0,0 +1,210 @@
+package eazyetest
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testMessage = "From: alice@example.com\r\nSubject: Hello\r\n\r\nHi there\r\n"
+
+// dial connects to the server and reads its greeting.
+func dial(t *testing.T, srv *Server) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", srv.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	r := bufio.NewReader(conn)
+	if line, _ := r.ReadString('\n'); !strings.HasPrefix(line, "* OK") {
+		t.Fatalf("greeting got %q", line)
+	}
+	return conn, r
+}
+
+// run sends the command and returns the responses up to and including the
+// tagged one, which must be OK.
+func run(t *testing.T, conn net.Conn, r *bufio.Reader, command string) string {
+	t.Helper()
+	if _, err := conn.Write([]byte("a " + command + "\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("%s: %s", command, err)
+		}
+		out.WriteString(line)
+		if strings.HasPrefix(line, "a ") {
+			if !strings.HasPrefix(line, "a OK") {
+				t.Fatalf("%s got %q", command, line)
+			}
+			return out.String()
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+
+	date := time.Date(2014, 8, 11, 22, 14, 16, 0, time.UTC)
+	srv.AddMessageAt("INBOX", []byte(testMessage), date)
+	srv.AddMessageAt("INBOX", []byte("Subject: Old\r\n\r\nbye\r\n"), date.AddDate(0, 0, -7), `\Seen`)
+	srv.AddFolder("Archive")
+
+	conn, r := dial(t, srv)
+	run(t, conn, r, "LOGIN user secret")
+	run(t, conn, r, "SELECT INBOX")
+
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"UID SEARCH UNSEEN", "* SEARCH 1\r\n"},
+		{"UID SEARCH SINCE 10-Aug-2014", "* SEARCH 1\r\n"},
+		{`UID SEARCH OR SUBJECT "old" FROM alice`, "* SEARCH 1 2\r\n"},
+		{"UID SEARCH NOT (SEEN)", "* SEARCH 1\r\n"},
+		{"UID FETCH 1 (RFC822.SIZE FLAGS)", "* 1 FETCH (UID 1 RFC822.SIZE 53 FLAGS ())\r\n"},
+		{"UID FETCH 1 (INTERNALDATE)", "* 1 FETCH (UID 1 INTERNALDATE \"11-Aug-2014 22:14:16 +0000\")\r\n"},
+		{"UID FETCH 2 (BODY.PEEK[]<9.3>)", "* 2 FETCH (UID 2 BODY[]<9> {3}\r\nOld)\r\n"},
+		{"UID STORE 2 +FLAGS.SILENT (\\Flagged)", ""},
+		{"UID SEARCH FLAGGED", "* SEARCH 2\r\n"},
+		{"UID COPY 1:* Archive", ""},
+	}
+	for _, test := range tests {
+		got := run(t, conn, r, test.command)
+		got = got[:strings.LastIndex(strings.TrimSuffix(got, "\n"), "\n")+1]
+		if got != test.want {
+			t.Errorf("%s got %q, want %q", test.command, got, test.want)
+		}
+	}
+
+	if got := len(srv.Messages("Archive")); got != 2 {
+		t.Errorf("Messages(Archive) got %d messages, want 2", got)
+	}
+}
+
+func TestServerFetchMarksRead(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddMessage("INBOX", []byte(testMessage))
+
+	conn, r := dial(t, srv)
+	run(t, conn, r, "LOGIN user secret")
+	run(t, conn, r, "SELECT INBOX")
+
+	got := run(t, conn, r, "UID FETCH 1 (BODY.PEEK[])")
+	if !strings.Contains(got, "BODY[] {53}\r\n"+testMessage) {
+		t.Errorf("BODY.PEEK[] got %q", got)
+	}
+	if flags := srv.Messages("INBOX")[0].Flags; len(flags) != 0 {
+		t.Errorf("BODY.PEEK[] set flags %q", flags)
+	}
+
+	got = run(t, conn, r, "UID FETCH 1 (BODY[])")
+	if !strings.Contains(got, `FLAGS (\Seen)`) {
+		t.Errorf("BODY[] got %q, want the new flags", got)
+	}
+	if flags := srv.Messages("INBOX")[0].Flags; !reflect.DeepEqual(flags, []string{`\Seen`}) {
+		t.Errorf("BODY[] left flags %q, want \\Seen", flags)
+	}
+}
+
+func TestServerFetchParts(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.AddMessage("INBOX", []byte("Subject: parts\r\n"+
+		"Content-Type: multipart/mixed; boundary=b\r\n\r\n"+
+		"--b\r\nContent-Type: text/plain\r\n\r\nhi\r\n"+
+		"--b\r\nContent-Type: application/pdf\r\nContent-Disposition: attachment; filename=a.pdf\r\n"+
+		"Content-Transfer-Encoding: base64\r\n\r\nJVBERi0x\r\n--b--\r\n"))
+
+	conn, r := dial(t, srv)
+	run(t, conn, r, "LOGIN user secret")
+	run(t, conn, r, "SELECT INBOX")
+
+	got := run(t, conn, r, "UID FETCH 1 (BODYSTRUCTURE)")
+	want := `BODYSTRUCTURE (("text" "plain" NIL NIL NIL "7bit" 2 0 NIL NIL)` +
+		`("application" "pdf" NIL NIL NIL "base64" 8 NIL ("attachment" ("filename" "a.pdf"))) "mixed")`
+	if !strings.Contains(got, want) {
+		t.Errorf("BODYSTRUCTURE got %q, want %q", got, want)
+	}
+
+	got = run(t, conn, r, "UID FETCH 1 (BODY.PEEK[2] BODY.PEEK[3])")
+	if !strings.Contains(got, "BODY[2] {8}\r\nJVBERi0x BODY[3] {0}\r\n") {
+		t.Errorf("BODY.PEEK[2] got %q", got)
+	}
+	if flags := srv.Messages("INBOX")[0].Flags; len(flags) != 0 {
+		t.Errorf("BODY.PEEK[2] set flags %q", flags)
+	}
+}
+
+func TestServerExpunge(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	for i := 0; i < 3; i++ {
+		srv.AddMessage("INBOX", []byte(testMessage))
+	}
+
+	conn, r := dial(t, srv)
+	run(t, conn, r, "LOGIN user secret")
+	run(t, conn, r, "SELECT INBOX")
+	run(t, conn, r, `STORE 1:2 +FLAGS (\Deleted)`)
+
+	got := run(t, conn, r, "UID EXPUNGE 2")
+	if !strings.HasPrefix(got, "* 2 EXPUNGE\r\n") {
+		t.Errorf("UID EXPUNGE got %q", got)
+	}
+	var uids []uint32
+	for _, msg := range srv.Messages("INBOX") {
+		uids = append(uids, msg.UID)
+	}
+	if !reflect.DeepEqual(uids, []uint32{1, 3}) {
+		t.Errorf("UID EXPUNGE left %v, want [1 3]", uids)
+	}
+}
+
+func TestServerLiteralAndCommands(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.User, srv.Password = "user", "secret"
+
+	conn, r := dial(t, srv)
+	conn.Write([]byte("a LOGIN user {6}\r\n"))
+	if line, _ := r.ReadString('\n'); !strings.HasPrefix(line, "+") {
+		t.Fatalf("literal got %q, want a continuation request", line)
+	}
+	conn.Write([]byte("secret\r\n"))
+	if line, _ := r.ReadString('\n'); !strings.HasPrefix(line, "a OK") {
+		t.Fatalf("LOGIN got %q", line)
+	}
+	run(t, conn, r, `CREATE "Work Stuff"`)
+
+	want := []string{"LOGIN user", `CREATE "Work Stuff"`}
+	if got := srv.Commands(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Commands() got %q, want %q", got, want)
+	}
+	if got := srv.Folders(); !reflect.DeepEqual(got, []string{"INBOX", "Work Stuff"}) {
+		t.Errorf("Folders() got %q", got)
+	}
+}
+
+func TestServerBadLogin(t *testing.T) {
+	srv := NewServer()
+	defer srv.Close()
+	srv.User, srv.Password = "user", "secret"
+
+	conn, r := dial(t, srv)
+	conn.Write([]byte("a LOGIN user wrong\r\n"))
+	if line, _ := r.ReadString('\n'); !strings.HasPrefix(line, "a NO") {
+		t.Errorf("LOGIN with a bad password got %q, want NO", line)
+	}
+}
@@ -0,0 +1,721 @@
+package eazyetest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// internalDateFormat is the layout of INTERNALDATE in FETCH responses.
+const internalDateFormat = "02-Jan-2006 15:04:05 -0700"
+
+// session is a single client connection.
+type session struct {
+	srv  *Server
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+
+	authenticated bool
+	selected      string
+	readOnly      bool
+}
+
+func (s *session) run() {
+	s.untagged("OK [CAPABILITY %s] eazyetest ready", s.srv.capabilities())
+	s.w.Flush()
+
+	for {
+		tag, args, err := s.readCommand()
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) && tag != "" {
+				s.tagged(tag, "BAD %s", err)
+				s.w.Flush()
+				continue
+			}
+			return
+		}
+		if len(args) == 0 {
+			s.tagged(tag, "BAD missing command")
+			s.w.Flush()
+			continue
+		}
+
+		name, _ := args[0].(string)
+		name = strings.ToUpper(name)
+		s.srv.record(formatCommand(name, args))
+
+		logout := name == "LOGOUT"
+		if err = s.handle(tag, name, args[1:]); err != nil {
+			s.tagged(tag, "%s", err)
+		}
+		if err = s.w.Flush(); err != nil || logout {
+			return
+		}
+	}
+}
+
+// handle runs the command. It writes the tagged OK itself and returns an
+// error with the rest of the tagged line, e.g. "NO no such folder", when the
+// command fails.
+func (s *session) handle(tag, name string, args []any) error {
+	switch name {
+	case "CAPABILITY":
+		s.untagged("CAPABILITY %s", s.srv.capabilities())
+	case "NOOP", "CHECK", "ENABLE":
+		if s.selected != "" {
+			s.srv.mu.Lock()
+			s.untagged("%d EXISTS", len(s.messages()))
+			s.srv.mu.Unlock()
+		}
+	case "ID":
+		s.untagged(`ID ("name" "eazyetest")`)
+	case "LOGOUT":
+		s.untagged("BYE logging out")
+	case "LOGIN":
+		return s.login(tag, args)
+	default:
+		if !s.authenticated {
+			return fmt.Errorf("BAD not authenticated")
+		}
+		return s.authenticatedCommand(tag, name, args)
+	}
+	s.tagged(tag, "OK %s completed", name)
+	return nil
+}
+
+func (s *session) authenticatedCommand(tag, name string, args []any) error {
+	switch name {
+	case "SELECT", "EXAMINE":
+		return s.selectFolder(tag, name, args)
+	case "LIST", "LSUB":
+		for _, folder := range s.srv.Folders() {
+			s.untagged(`%s () "/" %s`, name, quote(folder))
+		}
+	case "CREATE", "DELETE", "RENAME":
+		if err := s.manageFolder(name, args); err != nil {
+			return err
+		}
+	case "STATUS":
+		return s.status(tag, args)
+	case "APPEND":
+		return s.appendMessage(tag, args)
+	default:
+		if s.selected == "" {
+			return fmt.Errorf("BAD no folder selected")
+		}
+		uid := false
+		if name == "UID" {
+			if len(args) == 0 {
+				return fmt.Errorf("BAD missing command")
+			}
+			uid = true
+			name, _ = args[0].(string)
+			name = strings.ToUpper(name)
+			args = args[1:]
+		}
+		return s.selectedCommand(tag, name, args, uid)
+	}
+	s.tagged(tag, "OK %s completed", name)
+	return nil
+}
+
+func (s *session) selectedCommand(tag, name string, args []any, uid bool) error {
+	var err error
+	switch name {
+	case "SEARCH":
+		err = s.search(args, uid)
+	case "FETCH":
+		err = s.fetch(args, uid)
+	case "STORE":
+		err = s.store(args, uid)
+	case "COPY", "MOVE":
+		err = s.copy(args, uid, name == "MOVE")
+	case "EXPUNGE":
+		err = s.expunge(args, uid)
+	case "CLOSE", "UNSELECT":
+		if name == "CLOSE" && !s.readOnly {
+			s.srv.mu.Lock()
+			s.removeDeleted(nil, false)
+			s.srv.mu.Unlock()
+		}
+		s.selected = ""
+	default:
+		return fmt.Errorf("BAD unknown command %s", name)
+	}
+	if err != nil {
+		return err
+	}
+	if uid {
+		name = "UID " + name
+	}
+	s.tagged(tag, "OK %s completed", name)
+	return nil
+}
+
+func (s *session) login(tag string, args []any) error {
+	if len(args) != 2 {
+		return fmt.Errorf("BAD LOGIN takes a user and a password")
+	}
+	user, _ := args[0].(string)
+	password, _ := args[1].(string)
+	if s.srv.User != "" && (user != s.srv.User || password != s.srv.Password) {
+		return fmt.Errorf("NO [AUTHENTICATIONFAILED] invalid credentials")
+	}
+	s.authenticated = true
+	s.tagged(tag, "OK [CAPABILITY %s] logged in", s.srv.capabilities())
+	return nil
+}
+
+func (s *session) selectFolder(tag, name string, args []any) error {
+	if len(args) == 0 {
+		return fmt.Errorf("BAD missing folder")
+	}
+	folderName := folderArg(args[0])
+
+	s.srv.mu.Lock()
+	f, ok := s.srv.folders[folderName]
+	if !ok {
+		s.srv.mu.Unlock()
+		s.selected = ""
+		return fmt.Errorf("NO [NONEXISTENT] no such folder")
+	}
+	exists, uidValidity, uidNext := len(f.messages), f.uidValidity, f.uidNext
+	s.srv.mu.Unlock()
+
+	s.selected = folderName
+	s.readOnly = name == "EXAMINE"
+	s.untagged(`FLAGS (\Answered \Flagged \Deleted \Seen \Draft)`)
+	s.untagged(`OK [PERMANENTFLAGS (\Answered \Flagged \Deleted \Seen \Draft \*)] flags permitted`)
+	s.untagged("%d EXISTS", exists)
+	s.untagged("0 RECENT")
+	s.untagged("OK [UIDVALIDITY %d] UIDs valid", uidValidity)
+	s.untagged("OK [UIDNEXT %d] predicted next UID", uidNext)
+
+	mode := "READ-WRITE"
+	if s.readOnly {
+		mode = "READ-ONLY"
+	}
+	s.tagged(tag, "OK [%s] %s completed", mode, name)
+	return nil
+}
+
+func (s *session) manageFolder(name string, args []any) error {
+	s.srv.mu.Lock()
+	defer s.srv.mu.Unlock()
+
+	if len(args) == 0 {
+		return fmt.Errorf("BAD missing folder")
+	}
+	folderName := folderArg(args[0])
+	_, exists := s.srv.folders[folderName]
+
+	switch name {
+	case "CREATE":
+		if exists {
+			return fmt.Errorf("NO [ALREADYEXISTS] folder exists")
+		}
+		s.srv.createFolder(folderName)
+	case "DELETE":
+		if !exists || folderName == "INBOX" {
+			return fmt.Errorf("NO [NONEXISTENT] unable to delete folder")
+		}
+		delete(s.srv.folders, folderName)
+	case "RENAME":
+		if len(args) != 2 {
+			return fmt.Errorf("BAD RENAME takes two folders")
+		}
+		to := folderArg(args[1])
+		if !exists {
+			return fmt.Errorf("NO [NONEXISTENT] no such folder")
+		}
+		if _, ok := s.srv.folders[to]; ok {
+			return fmt.Errorf("NO [ALREADYEXISTS] folder exists")
+		}
+		s.srv.folders[to] = s.srv.folders[folderName]
+		delete(s.srv.folders, folderName)
+	}
+	return nil
+}
+
+func (s *session) status(tag string, args []any) error {
+	if len(args) != 2 {
+		return fmt.Errorf("BAD STATUS takes a folder and a list of items")
+	}
+	folderName := folderArg(args[0])
+	items, _ := args[1].([]any)
+
+	s.srv.mu.Lock()
+	defer s.srv.mu.Unlock()
+
+	f, ok := s.srv.folders[folderName]
+	if !ok {
+		return fmt.Errorf("NO [NONEXISTENT] no such folder")
+	}
+
+	var values []string
+	for _, item := range items {
+		name, _ := item.(string)
+		name = strings.ToUpper(name)
+		switch name {
+		case "MESSAGES":
+			values = append(values, fmt.Sprintf("MESSAGES %d", len(f.messages)))
+		case "RECENT":
+			values = append(values, "RECENT 0")
+		case "UIDNEXT":
+			values = append(values, fmt.Sprintf("UIDNEXT %d", f.uidNext))
+		case "UIDVALIDITY":
+			values = append(values, fmt.Sprintf("UIDVALIDITY %d", f.uidValidity))
+		case "UNSEEN":
+			unseen := 0
+			for _, msg := range f.messages {
+				if !msg.hasFlag(`\Seen`) {
+					unseen++
+				}
+			}
+			values = append(values, fmt.Sprintf("UNSEEN %d", unseen))
+		}
+	}
+	s.untagged("STATUS %s (%s)", quote(folderName), strings.Join(values, " "))
+	s.tagged(tag, "OK STATUS completed")
+	return nil
+}
+
+func (s *session) appendMessage(tag string, args []any) error {
+	if len(args) < 2 {
+		return fmt.Errorf("BAD APPEND takes a folder and a message")
+	}
+	folderName := folderArg(args[0])
+	raw, _ := args[len(args)-1].(string)
+
+	var flags []string
+	date := time.Now()
+	for _, arg := range args[1 : len(args)-1] {
+		switch v := arg.(type) {
+		case []any:
+			for _, flag := range v {
+				if f, ok := flag.(string); ok {
+					flags = append(flags, f)
+				}
+			}
+		case string:
+			if d, err := time.Parse(internalDateFormat, strings.TrimSpace(v)); err == nil {
+				date = d
+			}
+		}
+	}
+
+	s.srv.mu.Lock()
+	f, ok := s.srv.folders[folderName]
+	s.srv.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("NO [TRYCREATE] no such folder")
+	}
+
+	uid := s.srv.AddMessageAt(folderName, []byte(raw), date, flags...)
+	s.tagged(tag, "OK [APPENDUID %d %d] APPEND completed", f.uidValidity, uid)
+	return nil
+}
+
+func (s *session) search(args []any, uid bool) error {
+	if len(args) >= 2 {
+		if key, _ := args[0].(string); strings.EqualFold(key, "CHARSET") {
+			args = args[2:]
+		}
+	}
+
+	s.srv.mu.Lock()
+	defer s.srv.mu.Unlock()
+
+	msgs := s.messages()
+	match, err := parseSearch(args, len(msgs), maxUID(msgs))
+	if err != nil {
+		return fmt.Errorf("BAD %s", err)
+	}
+
+	var ids []string
+	for i, msg := range msgs {
+		if match(i+1, msg) {
+			id := uint32(i + 1)
+			if uid {
+				id = msg.UID
+			}
+			ids = append(ids, strconv.FormatUint(uint64(id), 10))
+		}
+	}
+	s.untagged("%s", strings.TrimSpace("SEARCH "+strings.Join(ids, " ")))
+	return nil
+}
+
+func (s *session) fetch(args []any, uid bool) error {
+	if len(args) != 2 {
+		return fmt.Errorf("BAD FETCH takes a set and a list of items")
+	}
+
+	var items []string
+	switch v := args[1].(type) {
+	case string:
+		items = []string{v}
+	case []any:
+		for _, item := range v {
+			if name, ok := item.(string); ok {
+				items = append(items, name)
+			}
+		}
+	}
+	items = expandMacros(items)
+	if uid {
+		items = append([]string{"UID"}, items...)
+	}
+
+	s.srv.mu.Lock()
+	defer s.srv.mu.Unlock()
+
+	msgs := s.messages()
+	set, err := setArg(args[0], uid, msgs)
+	if err != nil {
+		return fmt.Errorf("BAD %s", err)
+	}
+
+	for i, msg := range msgs {
+		if !set.contains(i+1, msg) {
+			continue
+		}
+		s.w.WriteString(fmt.Sprintf("* %d FETCH (", i+1))
+		s.writeItems(msg, items)
+		s.w.WriteString(")\r\n")
+	}
+	return nil
+}
+
+// writeItems writes the FETCH items of the message. Fetching a body without
+// PEEK marks the message as read, as any server would, and sends its FLAGS
+// along even if they were not asked for.
+func (s *session) writeItems(msg *Message, items []string) {
+	header, text := splitMessage(msg.Raw)
+
+	flagsChanged := false
+	for _, item := range items {
+		if isBodyItem(item) && !s.readOnly && !msg.hasFlag(`\Seen`) {
+			msg.Flags = append(msg.Flags, `\Seen`)
+			flagsChanged = true
+		}
+	}
+	if flagsChanged && !containsFold(items, "FLAGS") {
+		items = append(items, "FLAGS")
+	}
+
+	seen := map[string]bool{}
+	for _, item := range items {
+		name := strings.ToUpper(item)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var field string
+		var literal []byte
+		switch name {
+		case "UID":
+			field = fmt.Sprintf("UID %d", msg.UID)
+		case "FLAGS":
+			field = "FLAGS (" + strings.Join(msg.Flags, " ") + ")"
+		case "INTERNALDATE":
+			field = `INTERNALDATE "` + msg.InternalDate.Format(internalDateFormat) + `"`
+		case "RFC822.SIZE":
+			field = fmt.Sprintf("RFC822.SIZE %d", len(msg.Raw))
+		case "RFC822.HEADER", "BODY.PEEK[HEADER]", "BODY[HEADER]":
+			field, literal = strings.Replace(name, ".PEEK", "", 1), header
+		case "RFC822.TEXT", "BODY.PEEK[TEXT]", "BODY[TEXT]":
+			field, literal = strings.Replace(name, ".PEEK", "", 1), text
+		case "RFC822", "BODY[]", "BODY.PEEK[]":
+			field, literal = strings.Replace(name, ".PEEK", "", 1), msg.Raw
+		case "BODYSTRUCTURE":
+			field = "BODYSTRUCTURE " + parseMIME(msg.Raw).structure()
+		default:
+			if origin, size, ok := partial(name); ok {
+				start := min(origin, len(msg.Raw))
+				field = fmt.Sprintf("BODY[]<%d>", origin)
+				literal = msg.Raw[start:min(start+size, len(msg.Raw))]
+				break
+			}
+			number, ok := section(name)
+			if !ok {
+				continue
+			}
+			// a missing part comes back empty
+			field, literal = "BODY["+number+"]", []byte{}
+			if part, ok := parseMIME(msg.Raw).section(number); ok {
+				literal = part.body
+			}
+		}
+
+		if len(seen) > 1 {
+			s.w.WriteString(" ")
+		}
+		s.w.WriteString(field)
+		if literal != nil {
+			fmt.Fprintf(s.w, " {%d}\r\n", len(literal))
+			s.w.Write(literal)
+		}
+	}
+}
+
+// partial parses a partial fetch of the whole message, e.g. BODY[]<0.1024>.
+func partial(name string) (origin, size int, ok bool) {
+	name = strings.Replace(name, ".PEEK", "", 1)
+	if !strings.HasPrefix(name, "BODY[]<") || !strings.HasSuffix(name, ">") {
+		return 0, 0, false
+	}
+	_, err := fmt.Sscanf(name[len("BODY[]<"):len(name)-1], "%d.%d", &origin, &size)
+	return origin, size, err == nil
+}
+
+// section parses a fetch of a MIME part, e.g. BODY[2.1].
+func section(name string) (string, bool) {
+	name = strings.Replace(name, ".PEEK", "", 1)
+	if !strings.HasPrefix(name, "BODY[") || !strings.HasSuffix(name, "]") {
+		return "", false
+	}
+	number := name[len("BODY[") : len(name)-1]
+	if number == "" || strings.Trim(number, "0123456789.") != "" {
+		return "", false
+	}
+	return number, true
+}
+
+// isBodyItem tells whether fetching the item sets \Seen.
+func isBodyItem(item string) bool {
+	name := strings.ToUpper(item)
+	return name == "RFC822" || name == "RFC822.TEXT" || strings.HasPrefix(name, "BODY[")
+}
+
+func (s *session) store(args []any, uid bool) error {
+	if len(args) != 3 {
+		return fmt.Errorf("BAD STORE takes a set, an item and flags")
+	}
+	if s.readOnly {
+		return fmt.Errorf("NO folder is read-only")
+	}
+
+	item, _ := args[1].(string)
+	item = strings.ToUpper(item)
+	silent := strings.HasSuffix(item, ".SILENT")
+	item = strings.TrimSuffix(item, ".SILENT")
+
+	var flags []string
+	switch v := args[2].(type) {
+	case string:
+		flags = []string{v}
+	case []any:
+		for _, flag := range v {
+			if f, ok := flag.(string); ok {
+				flags = append(flags, f)
+			}
+		}
+	}
+
+	s.srv.mu.Lock()
+	defer s.srv.mu.Unlock()
+
+	msgs := s.messages()
+	set, err := setArg(args[0], uid, msgs)
+	if err != nil {
+		return fmt.Errorf("BAD %s", err)
+	}
+
+	for i, msg := range msgs {
+		if !set.contains(i+1, msg) {
+			continue
+		}
+		switch item {
+		case "FLAGS":
+			msg.Flags = append([]string{}, flags...)
+		case "+FLAGS":
+			for _, flag := range flags {
+				if !msg.hasFlag(flag) {
+					msg.Flags = append(msg.Flags, flag)
+				}
+			}
+		case "-FLAGS":
+			kept := msg.Flags[:0]
+			for _, f := range msg.Flags {
+				if !containsFold(flags, f) {
+					kept = append(kept, f)
+				}
+			}
+			msg.Flags = kept
+		default:
+			return fmt.Errorf("BAD unknown STORE item %s", item)
+		}
+		if !silent {
+			s.untagged("%d FETCH (UID %d FLAGS (%s))", i+1, msg.UID, strings.Join(msg.Flags, " "))
+		}
+	}
+	return nil
+}
+
+func (s *session) copy(args []any, uid, move bool) error {
+	if len(args) != 2 {
+		return fmt.Errorf("BAD COPY takes a set and a folder")
+	}
+	if move && s.readOnly {
+		return fmt.Errorf("NO folder is read-only")
+	}
+
+	s.srv.mu.Lock()
+	defer s.srv.mu.Unlock()
+
+	dest, ok := s.srv.folders[folderArg(args[1])]
+	if !ok {
+		return fmt.Errorf("NO [TRYCREATE] no such folder")
+	}
+	msgs := s.messages()
+	set, err := setArg(args[0], uid, msgs)
+	if err != nil {
+		return fmt.Errorf("BAD %s", err)
+	}
+
+	moved := map[*Message]bool{}
+	for i, msg := range msgs {
+		if !set.contains(i+1, msg) {
+			continue
+		}
+		cp := *msg
+		cp.UID = dest.uidNext
+		cp.Flags = append([]string{}, msg.Flags...)
+		dest.uidNext++
+		dest.messages = append(dest.messages, &cp)
+		moved[msg] = true
+	}
+	if move {
+		s.removeDeleted(moved, true)
+	}
+	return nil
+}
+
+func (s *session) expunge(args []any, uid bool) error {
+	if s.readOnly {
+		return fmt.Errorf("NO folder is read-only")
+	}
+
+	s.srv.mu.Lock()
+	defer s.srv.mu.Unlock()
+
+	var only map[*Message]bool
+	if uid && len(args) == 1 {
+		msgs := s.messages()
+		set, err := setArg(args[0], true, msgs)
+		if err != nil {
+			return fmt.Errorf("BAD %s", err)
+		}
+		only = map[*Message]bool{}
+		for i, msg := range msgs {
+			if set.contains(i+1, msg) {
+				only[msg] = true
+			}
+		}
+	}
+	s.removeDeleted(only, false)
+	return nil
+}
+
+// removeDeleted removes the messages flagged \Deleted from the selected
+// folder, or the ones in only if it is not nil, writing an EXPUNGE response
+// for each. With all set the \Deleted flag is not needed. s.srv.mu must be
+// held.
+func (s *session) removeDeleted(only map[*Message]bool, all bool) {
+	f := s.srv.folders[s.selected]
+	if f == nil {
+		return
+	}
+
+	kept := f.messages[:0]
+	seq := 1
+	for _, msg := range f.messages {
+		remove := (all || msg.hasFlag(`\Deleted`)) && (only == nil || only[msg])
+		if remove {
+			s.untagged("%d EXPUNGE", seq)
+			continue
+		}
+		kept = append(kept, msg)
+		seq++
+	}
+	for i := len(kept); i < len(f.messages); i++ {
+		f.messages[i] = nil
+	}
+	f.messages = kept
+}
+
+// messages returns the messages of the selected folder. s.srv.mu must be held.
+func (s *session) messages() []*Message {
+	if f := s.srv.folders[s.selected]; f != nil {
+		return f.messages
+	}
+	return nil
+}
+
+func (s *session) untagged(format string, args ...any) {
+	fmt.Fprintf(s.w, "* "+format+"\r\n", args...)
+}
+
+func (s *session) tagged(tag, format string, args ...any) {
+	fmt.Fprintf(s.w, tag+" "+format+"\r\n", args...)
+}
+
+// splitMessage splits the raw message into its header, blank line included,
+// and its text.
+func splitMessage(raw []byte) (header, text []byte) {
+	if i := bytes.Index(raw, []byte("\r\n\r\n")); i >= 0 {
+		return raw[:i+4], raw[i+4:]
+	}
+	if i := bytes.Index(raw, []byte("\n\n")); i >= 0 {
+		return raw[:i+2], raw[i+2:]
+	}
+	return raw, []byte{}
+}
+
+// expandMacros replaces the ALL, FAST and FULL FETCH macros with their items.
+func expandMacros(items []string) []string {
+	if len(items) != 1 {
+		return items
+	}
+	switch strings.ToUpper(items[0]) {
+	case "FAST":
+		return []string{"FLAGS", "INTERNALDATE", "RFC822.SIZE"}
+	case "ALL", "FULL":
+		return []string{"FLAGS", "INTERNALDATE", "RFC822.SIZE", "RFC822.HEADER"}
+	}
+	return items
+}
+
+// folderArg returns the folder name of a command argument.
+func folderArg(arg any) string {
+	name, _ := arg.(string)
+	if strings.EqualFold(name, "INBOX") {
+		return "INBOX"
+	}
+	return name
+}
+
+func maxUID(msgs []*Message) uint32 {
+	if len(msgs) == 0 {
+		return 0
+	}
+	return msgs[len(msgs)-1].UID
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package eazye
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors that can be told apart with errors.Is, whatever else they wrap.
+var (
+	// ErrAuthFailed is returned when the server refuses to log in.
+	ErrAuthFailed = errors.New("authentication failed")
+	// ErrFolderNotFound is returned when the folder can not be selected.
+	ErrFolderNotFound = errors.New("folder not found")
+	// ErrConnectionLost is returned when the connection dropped and could
+	// not be brought back as the RetryPolicy allows. It is worth trying
+	// again later.
+	ErrConnectionLost = errors.New("connection lost")
+	// ErrParse is matched by a *ResponseError for an email that could not
+	// be parsed.
+	ErrParse = errors.New("unable to parse email")
+	// ErrTimeout is returned when the server does not answer a command
+	// within the CommandTimeout.
+	ErrTimeout = errors.New("command timed out")
+	// ErrTooLarge is returned by Append for messages over the APPENDLIMIT of
+	// the server.
+	ErrTooLarge = errors.New("message too large")
+	// ErrBadSignature is returned by SMIME.Open for signed emails whose
+	// signature or signer does not verify.
+	ErrBadSignature = errors.New("bad signature")
+)
+
+// The operations a ResponseError can be about.
+const (
+	OpFetch      = "fetch"
+	OpParse      = "parse"
+	OpMarkRead   = "mark as read"
+	OpMarkUnread = "mark as unread"
+	OpDelete     = "delete"
+)
+
+// ResponseError is passed along the responses channel when handling a single
+// email fails, telling which email so it can be retried or set aside later.
+type ResponseError struct {
+	UID    uint32
+	Folder string
+	// Op is what failed, one of the Op constants.
+	Op  string
+	Err error
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("unable to %s email %d in %s: %s", e.Op, e.UID, e.Folder, e.Err)
+}
+
+func (e *ResponseError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is(err, ErrParse) hold for parse failures.
+func (e *ResponseError) Is(target error) bool {
+	return target == ErrParse && e.Op == OpParse
+}
+
+// emailError wraps an error with a single email of the selected folder.
+// Parse failures are counted in the Metrics.
+func (c *Client) emailError(op string, uid uint32, err error) error {
+	if op == OpParse {
+		c.metrics().ParseFailed(c.Folder)
+	}
+	return &ResponseError{UID: uid, Folder: c.Folder, Op: op, Err: err}
+}
@@ -0,0 +1,132 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResponseError(t *testing.T) {
+	c := &Client{Folder: "INBOX"}
+	cause := errors.New("connection reset")
+	err := c.emailError(OpDelete, 42, cause)
+
+	if got, want := err.Error(), "unable to delete email 42 in INBOX: connection reset"; got != want {
+		t.Errorf("Error() got %q, want %q", got, want)
+	}
+	if !errors.Is(err, cause) {
+		t.Error("errors.Is() did not find the cause")
+	}
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) || respErr.UID != 42 || respErr.Op != OpDelete {
+		t.Errorf("errors.As() got %+v", respErr)
+	}
+}
+
+func TestParseResponseError(t *testing.T) {
+	c := &Client{Folder: "INBOX"}
+	if err := c.emailError(OpParse, 7, errors.New("bad header")); !errors.Is(err, ErrParse) {
+		t.Error("errors.Is() did not match a parse failure to ErrParse")
+	}
+	if err := c.emailError(OpDelete, 7, errors.New("no")); errors.Is(err, ErrParse) {
+		t.Error("errors.Is() matched a delete failure to ErrParse")
+	}
+}
+
+func TestSkipBroken(t *testing.T) {
+	c := &Client{Folder: "INBOX", SkipBroken: true}
+	responses := make(chan Response, 2)
+	fail, done := c.errorHandler(context.Background(), responses)
+
+	if !fail(c.emailError(OpParse, 1, errors.New("bad header"))) {
+		t.Error("fail() with SkipBroken stopped on a parse failure")
+	}
+	if fail(c.emailError(OpDelete, 2, errors.New("no"))) {
+		t.Error("fail() with SkipBroken carried on after a delete failure")
+	}
+	done()
+	close(responses)
+
+	var uids []uint32
+	for resp := range responses {
+		var respErr *ResponseError
+		if errors.As(resp.Err, &respErr) {
+			uids = append(uids, respErr.UID)
+		}
+	}
+	if len(uids) != 2 || uids[0] != 1 || uids[1] != 2 {
+		t.Errorf("fail() passed along errors for %v, want [1 2]", uids)
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	conn, other := net.Pipe()
+	defer other.Close()
+	c := &Client{conn: conn, CommandTimeout: 10 * time.Millisecond}
+
+	wait := c.wait(context.Background())
+	// the read only returns once the connection is closed
+	if _, err := other.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("Read() got %v, want %s", err, io.EOF)
+	}
+	if _, err := conn.Write([]byte("a NOOP\r\n")); !errors.Is(err, io.ErrClosedPipe) {
+		t.Error("wait() did not close the connection after the CommandTimeout")
+	}
+	// the command fails as the connection was closed under it
+	if _, err := wait(nil, io.ErrClosedPipe); !errors.Is(err, ErrTimeout) {
+		t.Errorf("wait() got %v, want %s", err, ErrTimeout)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	conn, other = net.Pipe()
+	defer other.Close()
+	c.conn = conn
+	wait = c.wait(ctx)
+	cancel()
+	if _, err := wait(nil, io.ErrClosedPipe); err != context.Canceled {
+		t.Errorf("wait() after cancelling got %v, want %s", err, context.Canceled)
+	}
+}
+
+func TestErrorStrategy(t *testing.T) {
+	srv := testServer(t)
+	srv.AddMessage("INBOX", []byte("Subject: one\r\n\r\nx\r\n"))
+	srv.AddMessage("INBOX", []byte("not a header\r\n\r\nx\r\n"))
+	srv.AddMessage("INBOX", []byte("Subject: three\r\n\r\nx\r\n"))
+
+	tests := []struct {
+		strategy ErrorStrategy
+		want     []string
+	}{
+		{FailFast, []string{"one", "error"}},
+		{SkipAndContinue, []string{"one", "error", "three"}},
+		{CollectErrors, []string{"one", "three", "error"}},
+	}
+
+	for _, tt := range tests {
+		c := testClient(t, srv, SetErrorStrategy(tt.strategy))
+		responses, err := c.GenerateAll(false, false)
+		if err != nil {
+			t.Fatalf("GenerateAll() returned an error: %s", err)
+		}
+
+		var got []string
+		for resp := range responses {
+			switch {
+			case errors.Is(resp.Err, ErrParse):
+				got = append(got, "error")
+			case resp.Err != nil:
+				t.Errorf("GenerateAll() with strategy %d passed along %s", tt.strategy, resp.Err)
+			default:
+				got = append(got, resp.Email.Subject)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GenerateAll() with strategy %d got %v, want %v", tt.strategy, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package eazye
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/mail"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// FieldMask selects the optional fields written by ExportJSONL.
+type FieldMask uint
+
+const (
+	// ExportHeaders includes every header of the message.
+	ExportHeaders FieldMask = 1 << iota
+	// ExportText includes the text body, taken from the text/plain part or
+	// the visible text of the text/html part if there is none.
+	ExportText
+	// ExportAttachments includes the metadata of every attachment.
+	ExportAttachments
+)
+
+// ExportRecord is a single line written by ExportJSONL.
+type ExportRecord struct {
+	UID          uint32    `json:"uid"`
+	InternalDate time.Time `json:"internal_date"`
+	MessageID    string    `json:"message_id,omitempty"`
+	Date         string    `json:"date,omitempty"`
+	From         string    `json:"from,omitempty"`
+	To           string    `json:"to,omitempty"`
+	Cc           string    `json:"cc,omitempty"`
+	Subject      string    `json:"subject,omitempty"`
+
+	Headers     map[string][]string `json:"headers,omitempty"`
+	Text        string              `json:"text,omitempty"`
+	Attachments []ExportAttachment  `json:"attachments,omitempty"`
+}
+
+// ExportAttachment is the metadata of an attachment in an ExportRecord.
+type ExportAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        uint32 `json:"size"`
+}
+
+// ExportJSONL will write one JSON object (an ExportRecord) per line for every
+// email matching the query. Only the data needed for the selected fields is
+// fetched and the emails are not marked as read.
+func (c *Client) ExportJSONL(w io.Writer, q Query, fields FieldMask) error {
+	cmd, err := c.findEmails(context.Background(), q)
+	if err != nil {
+		return err
+	}
+
+	items := []string{"UID", "INTERNALDATE", "RFC822.HEADER"}
+	if fields&(ExportText|ExportAttachments) != 0 {
+		items = append(items, "BODYSTRUCTURE")
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	// fetch in batches like fetchEmails, so huge folders neither time out
+	// nor have to fit in memory all at once
+	for _, uids := range batches(searchResults(cmd), c.fetchBatchSize()) {
+		seq := &imap.SeqSet{}
+		seq.AddNum(uids...)
+		fCmd, err := c.do(context.Background(), func() (*imap.Command, error) {
+			return c.uidFetch(seq, items...)
+		})
+		if err != nil {
+			return fmt.Errorf("unable to perform uid fetch: %w", err)
+		}
+
+		for _, msgData := range fCmd.Data {
+			info := c.messageInfo(msgData)
+			if _, ok := info.Attrs["RFC822.HEADER"]; !ok {
+				continue
+			}
+
+			record, err := c.exportRecord(info, fields)
+			if err != nil {
+				return err
+			}
+			if err = enc.Encode(record); err != nil {
+				return err
+			}
+			c.audit(AuditExported, info.UID, "")
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) exportRecord(info *imap.MessageInfo, fields FieldMask) (ExportRecord, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(imap.AsBytes(info.Attrs["RFC822.HEADER"])))
+	if err != nil {
+		return ExportRecord{}, fmt.Errorf("unable to read header: %w", err)
+	}
+
+	decode := func(key string) string {
+		return DecodeHeader(msg.Header.Get(key))
+	}
+
+	record := ExportRecord{
+		UID:          info.UID,
+		InternalDate: info.InternalDate,
+		MessageID:    msg.Header.Get("Message-Id"),
+		Date:         msg.Header.Get("Date"),
+		From:         decode("From"),
+		To:           decode("To"),
+		Cc:           decode("Cc"),
+		Subject:      decode("Subject"),
+	}
+	if fields&ExportHeaders != 0 {
+		record.Headers = msg.Header
+	}
+
+	parts := parseBodyStructure(info.Attrs["BODYSTRUCTURE"], "")
+	if fields&ExportAttachments != 0 {
+		for _, part := range parts {
+			if part.IsAttachment() {
+				record.Attachments = append(record.Attachments, ExportAttachment{
+					Filename:    part.Filename(),
+					ContentType: part.MediaType(),
+					Size:        part.Size,
+				})
+			}
+		}
+	}
+	if fields&ExportText != 0 {
+		record.Text, err = c.fetchText(info.UID, parts)
+		if err != nil {
+			return record, err
+		}
+	}
+
+	return record, nil
+}
+
+// fetchText fetches the text body of a message, preferring the text/plain
+// part and falling back to the visible text of the text/html one.
+func (c *Client) fetchText(uid uint32, parts []Part) (string, error) {
+	var plain, html *Part
+	for i, part := range parts {
+		if part.Type != "text" || part.IsAttachment() {
+			continue
+		}
+		if part.Subtype == "plain" && plain == nil {
+			plain = &parts[i]
+		}
+		if part.Subtype == "html" && html == nil {
+			html = &parts[i]
+		}
+	}
+
+	var text string
+	sink := func(uid uint32, part Part, content io.Reader) (err error) {
+		text, err = partText(part, content)
+		return err
+	}
+
+	switch {
+	case plain != nil:
+		return text, c.fetchParts(context.Background(), uid, []Part{*plain}, sink)
+	case html != nil:
+		return text, c.fetchParts(context.Background(), uid, []Part{*html}, sink)
+	}
+	return "", nil
+}
+
+// partText returns the text of a text/plain part, or the visible text of a
+// text/html one, in UTF-8.
+func partText(part Part, content io.Reader) (string, error) {
+	content, err := decodeCharset(part.Params["charset"], content)
+	if err != nil {
+		return "", err
+	}
+	if part.Subtype == "plain" {
+		body, err := io.ReadAll(content)
+		return string(body), err
+	}
+	visible, err := VisibleText(content)
+	return string(bytes.Join(visible, []byte("\n"))), err
+}
@@ -0,0 +1,73 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sluceno/eazye"
+)
+
+// WriteEML writes the email to a .eml file in dir, returning the path of the
+// file. The message is written exactly as fetched from the server. The file
+// is named after the folder, UIDVALIDITY and UID of the email's Ref, e.g.
+// INBOX-1234-7.eml, or only its ID if it has no Ref. An existing file is
+// never overwritten, it is an error wrapping fs.ErrExist.
+func WriteEML(dir string, email eazye.Email) (string, error) {
+	raw, err := email.Raw()
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, emlName(email))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", err
+	}
+	if _, err = f.Write(raw); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+	if err = f.Close(); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// emlName returns the name of the .eml file of the email, see WriteEML.
+func emlName(email eazye.Email) string {
+	ref := email.Ref
+	if ref.Folder == "" {
+		return fmt.Sprintf("%v.eml", email.ID)
+	}
+	// folders are nested with / or . depending on the server
+	folder := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == '.' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, ref.Folder)
+	return fmt.Sprintf("%s-%d-%d.eml", folder, ref.UIDValidity, ref.UID)
+}
+
+// WriteEMLFiles writes every email passed along the responses channel to a
+// .eml file in dir, see WriteEML. The first error passed along the channel
+// stops the writing and is returned, cancel the context of the generator to
+// let it go.
+func WriteEMLFiles(dir string, responses <-chan eazye.Response) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	for resp := range responses {
+		if resp.Err != nil {
+			return resp.Err
+		}
+		if _, err := WriteEML(dir, resp.Email); err != nil {
+			return err
+		}
+	}
+	return nil
+}
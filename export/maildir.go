@@ -0,0 +1,112 @@
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sluceno/eazye"
+)
+
+// Maildir is the path of a maildir, with its tmp, new and cur directories.
+type Maildir string
+
+// maildirFlags maps IMAP flags to the maildir info flags.
+var maildirFlags = map[string]byte{
+	`\draft`:     'D',
+	`\flagged`:   'F',
+	`$forwarded`: 'P',
+	`\answered`:  'R',
+	`\seen`:      'S',
+	`\deleted`:   'T',
+}
+
+// deliveries makes the names of the files delivered by this process unique.
+var deliveries atomic.Uint64
+
+// Create creates the maildir's directories if they do not exist yet.
+func (m Maildir) Create() error {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(string(m), sub), 0o700); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deliver writes the email to the maildir, exactly as fetched from the
+// server, returning the path of the file. Emails without any flags end up in
+// new, the rest in cur with their flags in the info part of the file name,
+// e.g. ":2,FS" for a flagged email that has been read.
+func (m Maildir) Deliver(email eazye.Email) (string, error) {
+	raw, err := email.Raw()
+	if err != nil {
+		return "", err
+	}
+
+	name := uniqueName()
+	tmp := filepath.Join(string(m), "tmp", name)
+	if err = os.WriteFile(tmp, raw, 0o600); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(string(m), "new", name)
+	if info := maildirInfo(email.Flags); info != "" {
+		path = filepath.Join(string(m), "cur", name+":2,"+info)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return path, nil
+}
+
+// WriteMaildir writes every email passed along the responses channel to the
+// maildir at dir, creating it if needed, see Maildir.Deliver. The first error
+// passed along the channel stops the writing and is returned, cancel the
+// context of the generator to let it go.
+func WriteMaildir(dir string, responses <-chan eazye.Response) error {
+	m := Maildir(dir)
+	if err := m.Create(); err != nil {
+		return err
+	}
+	for resp := range responses {
+		if resp.Err != nil {
+			return resp.Err
+		}
+		if _, err := m.Deliver(resp.Email); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maildirInfo returns the info flags for the IMAP flags, in ASCII order as
+// the maildir spec asks. Flags without a maildir equivalent are dropped.
+func maildirInfo(flags []string) string {
+	var info []byte
+	for _, flag := range flags {
+		if f, ok := maildirFlags[strings.ToLower(flag)]; ok && !strings.ContainsRune(string(info), rune(f)) {
+			info = append(info, f)
+		}
+	}
+	sort.Slice(info, func(i, j int) bool { return info[i] < info[j] })
+	return string(info)
+}
+
+// uniqueName returns a file name no other delivery will use, in the
+// time.MusecPpidQn.host form.
+func uniqueName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	host = strings.NewReplacer("/", `\057`, ":", `\072`).Replace(host)
+
+	now := time.Now()
+	return fmt.Sprintf("%d.M%dP%dQ%d.%s", now.Unix(), now.Nanosecond()/1000, os.Getpid(), deliveries.Add(1), host)
+}
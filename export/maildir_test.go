@@ -0,0 +1,96 @@
+package export
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sluceno/eazye"
+)
+
+func TestMaildirInfo(t *testing.T) {
+	tests := []struct {
+		flags []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{`\Seen`, `\Flagged`, `\Recent`}, "FS"},
+		{[]string{`\Deleted`, `\Answered`, `\Draft`, `\Seen`, "$Forwarded"}, "DPRST"},
+	}
+	for _, test := range tests {
+		if got := maildirInfo(test.flags); got != test.want {
+			t.Errorf("maildirInfo(%q) got %q, want %q", test.flags, got, test.want)
+		}
+	}
+}
+
+func TestMaildirDeliver(t *testing.T) {
+	m := Maildir(t.TempDir())
+	if err := m.Create(); err != nil {
+		t.Fatalf("Create() returned an error: %s", err)
+	}
+
+	unread := newEmail(t, "Subject: unread\r\n\r\nhi\r\n")
+	path, err := m.Deliver(unread)
+	if err != nil {
+		t.Fatalf("Deliver() returned an error: %s", err)
+	}
+	if filepath.Base(filepath.Dir(path)) != "new" {
+		t.Errorf("Deliver() put an email without flags in %s, want new", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "Subject: unread\r\n\r\nhi\r\n" {
+		t.Errorf("Deliver() wrote %q", data)
+	}
+
+	read := newEmail(t, "Subject: read\r\n\r\nhi\r\n")
+	read.Flags = []string{`\Seen`}
+	if path, err = m.Deliver(read); err != nil {
+		t.Fatalf("Deliver() returned an error: %s", err)
+	}
+	if filepath.Base(filepath.Dir(path)) != "cur" || !strings.HasSuffix(path, ":2,S") {
+		t.Errorf("Deliver() put a read email in %s, want cur with :2,S", path)
+	}
+
+	if tmp, _ := os.ReadDir(filepath.Join(string(m), "tmp")); len(tmp) != 0 {
+		t.Errorf("Deliver() left %d files in tmp", len(tmp))
+	}
+}
+
+func TestWriteEML(t *testing.T) {
+	email := newEmail(t, "Subject: hi\r\n\r\nbody\r\n")
+	email.ID = uint32(7)
+
+	path, err := WriteEML(t.TempDir(), email)
+	if err != nil {
+		t.Fatalf("WriteEML() returned an error: %s", err)
+	}
+	if filepath.Base(path) != "7.eml" {
+		t.Errorf("WriteEML() wrote %s, want 7.eml", path)
+	}
+}
+
+func TestWriteEMLRef(t *testing.T) {
+	dir := t.TempDir()
+	email := newEmail(t, "Subject: hi\r\n\r\nbody\r\n")
+	email.ID = uint32(7)
+	email.Ref = eazye.MessageRef{Folder: "Work/2014", UIDValidity: 1234, UID: 7}
+
+	path, err := WriteEML(dir, email)
+	if err != nil {
+		t.Fatalf("WriteEML() returned an error: %s", err)
+	}
+	if filepath.Base(path) != "Work_2014-1234-7.eml" {
+		t.Errorf("WriteEML() wrote %s, want Work_2014-1234-7.eml", path)
+	}
+
+	if _, err = WriteEML(dir, email); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("WriteEML() of the same email again got %v, want %s", err, fs.ErrExist)
+	}
+}
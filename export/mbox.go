@@ -0,0 +1,75 @@
+// Package export writes emails fetched with eazye to the usual archive
+// formats.
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"github.com/sluceno/eazye"
+)
+
+// mboxDate is the asctime format of the date on From_ lines.
+const mboxDate = "Mon Jan _2 15:04:05 2006"
+
+// WriteMbox writes every email passed along the responses channel, e.g. by
+// GenerateAll, to w in mbox format (RFC 4155). Lines are escaped as in the
+// mboxrd variant: any line starting with "From ", after any number of '>',
+// gets another '>' so it can be told apart from the From_ line starting the
+// next email. Line endings are written as LF.
+//
+// The first error passed along the channel stops the writing and is returned,
+// cancel the context of the generator to let it go. The emails before it are
+// written in full.
+func WriteMbox(w io.Writer, responses <-chan eazye.Response) error {
+	bw := bufio.NewWriter(w)
+	for resp := range responses {
+		if resp.Err != nil {
+			// keep the emails written so far
+			bw.Flush()
+			return resp.Err
+		}
+		if err := writeMboxEmail(bw, resp.Email); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// writeMboxEmail writes the From_ line and the escaped message.
+func writeMboxEmail(w *bufio.Writer, email eazye.Email) error {
+	raw, err := email.Raw()
+	if err != nil {
+		return err
+	}
+
+	sender := "MAILER-DAEMON"
+	if email.From != nil && email.From.Address != "" {
+		sender = email.From.Address
+	}
+	w.WriteString("From " + sender + " " + email.InternalDate.UTC().Format(mboxDate) + "\n")
+
+	raw = bytes.ReplaceAll(raw, []byte("\r\n"), []byte("\n"))
+	for len(raw) > 0 {
+		line := raw
+		if i := bytes.IndexByte(raw, '\n'); i >= 0 {
+			line, raw = raw[:i], raw[i+1:]
+		} else {
+			raw = nil
+		}
+		if isFromLine(line) {
+			w.WriteByte('>')
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	// a blank line separates the emails
+	_, err = w.WriteString("\n")
+	return err
+}
+
+// isFromLine reports whether the line is "From " quoted any number of times.
+func isFromLine(line []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(line, ">"), []byte("From "))
+}
@@ -0,0 +1,61 @@
+package export
+
+import (
+	"bytes"
+	"errors"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sluceno/eazye"
+)
+
+func newEmail(t *testing.T, raw string) eazye.Email {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return eazye.Email{
+		Message:      msg,
+		From:         &mail.Address{Address: "news@example.com"},
+		InternalDate: time.Date(2014, 8, 11, 22, 14, 16, 0, time.UTC),
+	}
+}
+
+func TestWriteMbox(t *testing.T) {
+	responses := make(chan eazye.Response, 2)
+	responses <- eazye.Response{Email: newEmail(t, "Subject: one\r\n\r\nFrom here on\r\n>From there\r\nok\r\n")}
+	responses <- eazye.Response{Email: newEmail(t, "Subject: two\r\n\r\nbye\r\n")}
+	close(responses)
+
+	var buf bytes.Buffer
+	if err := WriteMbox(&buf, responses); err != nil {
+		t.Fatalf("WriteMbox() returned an error: %s", err)
+	}
+
+	want := "From news@example.com Mon Aug 11 22:14:16 2014\n" +
+		"Subject: one\n\n>From here on\n>>From there\nok\n\n" +
+		"From news@example.com Mon Aug 11 22:14:16 2014\n" +
+		"Subject: two\n\nbye\n\n"
+	if buf.String() != want {
+		t.Errorf("WriteMbox() got:\n%q\nwant:\n%q", buf.String(), want)
+	}
+}
+
+func TestWriteMboxError(t *testing.T) {
+	errFailed := errors.New("failed")
+	responses := make(chan eazye.Response, 2)
+	responses <- eazye.Response{Email: newEmail(t, "Subject: one\r\n\r\nhi\r\n")}
+	responses <- eazye.Response{Err: errFailed}
+	close(responses)
+
+	var buf bytes.Buffer
+	if err := WriteMbox(&buf, responses); err != errFailed {
+		t.Errorf("WriteMbox() got %v, want %s", err, errFailed)
+	}
+	if !strings.HasSuffix(buf.String(), "Subject: one\n\nhi\n\n") {
+		t.Errorf("WriteMbox() wrote %q, want the email before the error", buf.String())
+	}
+}
@@ -0,0 +1,115 @@
+package eazye
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+func TestExportRecord(t *testing.T) {
+	header := "From: =?utf-8?q?Jos=C3=A9?= <jose@example.com>\r\n" +
+		"To: ops@example.com\r\n" +
+		"Subject: Invoice\r\n" +
+		"Message-Id: <1@example.com>\r\n" +
+		"\r\n"
+	date := time.Date(2016, 3, 1, 10, 0, 0, 0, time.UTC)
+	info := &imap.MessageInfo{
+		UID:          42,
+		InternalDate: date,
+		Attrs: imap.FieldMap{
+			"RFC822.HEADER": []byte(header),
+			"BODYSTRUCTURE": []imap.Field{
+				[]imap.Field{"text", "plain", []imap.Field{"charset", "utf-8"}, nil, nil, "7bit", uint32(12), uint32(1), nil, nil, nil},
+				[]imap.Field{"application", "pdf", []imap.Field{"name", "invoice.pdf"}, nil, nil, "base64", uint32(2048), nil,
+					[]imap.Field{"attachment", []imap.Field{"filename", "invoice.pdf"}}, nil},
+				"mixed", []imap.Field{"boundary", "xyz"}, nil, nil,
+			},
+		},
+	}
+
+	c := &Client{}
+	record, err := c.exportRecord(info, ExportAttachments)
+	if err != nil {
+		t.Fatalf("exportRecord() returned an error: %s", err)
+	}
+
+	if record.UID != 42 || !record.InternalDate.Equal(date) || record.MessageID != "<1@example.com>" {
+		t.Errorf("exportRecord() got unexpected record: %+v", record)
+	}
+	if record.From != "José <jose@example.com>" || record.Subject != "Invoice" {
+		t.Errorf("exportRecord() did not decode the headers: %+v", record)
+	}
+	if record.Headers != nil || record.Text != "" {
+		t.Errorf("exportRecord() included fields that were not selected: %+v", record)
+	}
+	want := ExportAttachment{Filename: "invoice.pdf", ContentType: "application/pdf", Size: 2048}
+	if len(record.Attachments) != 1 || record.Attachments[0] != want {
+		t.Errorf("exportRecord() got attachments %+v, want [%+v]", record.Attachments, want)
+	}
+
+	record, err = c.exportRecord(info, ExportHeaders)
+	if err != nil {
+		t.Fatalf("exportRecord() returned an error: %s", err)
+	}
+	if record.Headers["To"][0] != "ops@example.com" || record.Attachments != nil {
+		t.Errorf("exportRecord() got unexpected record: %+v", record)
+	}
+
+	var line bytes.Buffer
+	if err = json.NewEncoder(&line).Encode(record); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Count(line.Bytes(), []byte("\n")) != 1 {
+		t.Errorf("record is not a single JSON line: %q", line.String())
+	}
+}
+
+func TestExportJSONLBatches(t *testing.T) {
+	srv := testServer(t)
+	for _, subject := range []string{"one", "two", "three"} {
+		srv.AddMessage("INBOX", []byte("Subject: "+subject+"\r\n\r\nx\r\n"))
+	}
+
+	c := testClient(t, srv, SetFetchBatchSize(2))
+	srv.ResetCommands()
+	var out bytes.Buffer
+	if err := c.ExportJSONL(&out, All(), 0); err != nil {
+		t.Fatalf("ExportJSONL() returned an error: %s", err)
+	}
+	if lines := bytes.Count(out.Bytes(), []byte("\n")); lines != 3 {
+		t.Errorf("ExportJSONL() wrote %d lines, want 3", lines)
+	}
+
+	fetches := 0
+	for _, cmd := range srv.Commands() {
+		if strings.HasPrefix(cmd, "UID FETCH") {
+			fetches++
+		}
+	}
+	if fetches != 2 {
+		t.Errorf("ExportJSONL() sent %d fetches, want 2: %q", fetches, srv.Commands())
+	}
+}
+
+func TestPartText(t *testing.T) {
+	tests := []struct {
+		part    Part
+		content string
+		want    string
+	}{
+		{Part{Subtype: "plain", Params: map[string]string{"charset": "iso-8859-1"}}, "Caf\xe9", "Café"},
+		{Part{Subtype: "plain"}, "Café", "Café"},
+		{Part{Subtype: "html", Params: map[string]string{"charset": "ISO-8859-1"}}, "<p>Caf\xe9</p>", "Café"},
+	}
+
+	for _, tt := range tests {
+		got, err := partText(tt.part, strings.NewReader(tt.content))
+		if err != nil || got != tt.want {
+			t.Errorf("partText(%q) got %q, %v, want %q", tt.content, got, err, tt.want)
+		}
+	}
+}
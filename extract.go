@@ -0,0 +1,59 @@
+package eazye
+
+import "fmt"
+
+// Extractor pulls the text out of attachments, e.g. from PDFs, office
+// documents or images through OCR, so it can be indexed along with the email.
+type Extractor interface {
+	// Extract returns the text of the attachment. Attachments it does not
+	// know how to handle should return "" and no error.
+	Extract(attachment Attachment) (string, error)
+}
+
+// ExtractorFunc is an adapter to use an ordinary function as an Extractor.
+type ExtractorFunc func(attachment Attachment) (string, error)
+
+// Extract calls f(attachment).
+func (f ExtractorFunc) Extract(attachment Attachment) (string, error) {
+	return f(attachment)
+}
+
+// ExtractedText is the text an Extractor pulled out of an attachment.
+type ExtractedText struct {
+	Filename    string
+	ContentType string
+	Text        string
+}
+
+// SetExtractor is a functional option to set the Extractor attr.
+func SetExtractor(extractor Extractor) Option {
+	return func(c *Client) {
+		c.Extractor = extractor
+	}
+}
+
+// extract runs the extractor over every attachment of the email. Failures do
+// not fail the email, they end up in its Warnings.
+func (e *Email) extract(extractor Extractor) {
+	attachments, err := e.Attachments()
+	if err != nil {
+		e.Warnings = append(e.Warnings, fmt.Errorf("unable to extract attachments: %w", err))
+		return
+	}
+
+	for _, attachment := range attachments {
+		text, err := extractor.Extract(attachment)
+		if err != nil {
+			e.Warnings = append(e.Warnings, fmt.Errorf("unable to extract text from %s: %w", attachment.Filename, err))
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		e.ExtractedText = append(e.ExtractedText, ExtractedText{
+			Filename:    attachment.Filename,
+			ContentType: attachment.ContentType,
+			Text:        text,
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package eazye
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+func TestEmailExtract(t *testing.T) {
+	email, err := newEmail(imap.FieldMap{
+		"RFC822.HEADER": []byte(multipartHeader),
+		"BODY[]":        []byte(multipartHeader + multipartBody),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	email.extract(ExtractorFunc(func(a Attachment) (string, error) {
+		content, err := io.ReadAll(a.Content)
+		return "extracted: " + string(content), err
+	}))
+	if len(email.ExtractedText) != 1 || email.ExtractedText[0].Filename != "notes.txt" || email.ExtractedText[0].Text != "extracted: not this" {
+		t.Errorf("extract() got %+v, want the text of notes.txt", email.ExtractedText)
+	}
+
+	warnings := len(email.Warnings)
+	email.extract(ExtractorFunc(func(a Attachment) (string, error) {
+		return "", errors.New("unsupported")
+	}))
+	if len(email.Warnings) != warnings+1 {
+		t.Errorf("extract() got warnings %v, want one for the failed extraction", email.Warnings)
+	}
+}
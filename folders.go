@@ -0,0 +1,187 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// Folder is a folder on the server, as listed by ListFolders.
+type Folder struct {
+	// Name is the full name of the folder, decoded from modified UTF-7 and
+	// ready to be passed to the other folder functions.
+	Name string
+	// Delimiter separates the levels of the hierarchy in Name, e.g. "/".
+	Delimiter string
+	// Attrs holds the LIST attributes of the folder, e.g. \Noselect or
+	// \HasChildren.
+	Attrs []string
+}
+
+// HasAttr tells whether the folder has the LIST attribute, ignoring case.
+func (f Folder) HasAttr(attr string) bool {
+	for _, a := range f.Attrs {
+		if strings.EqualFold(a, attr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Selectable tells whether the folder can hold emails. Container only
+// folders such as Gmail's "[Gmail]" are \Noselect, and with some servers
+// folders listed only because they have children are \NonExistent.
+func (f Folder) Selectable() bool {
+	return !f.HasAttr(`\Noselect`) && !f.HasAttr(`\NonExistent`)
+}
+
+// ListFolders will return all the folders on the server, sorted by name.
+func (c *Client) ListFolders() ([]Folder, error) {
+	return c.ListFoldersContext(context.Background())
+}
+
+// ListFoldersContext is ListFolders with a context.
+func (c *Client) ListFoldersContext(ctx context.Context) ([]Folder, error) {
+	cmd, err := c.do(ctx, func() (*imap.Command, error) {
+		return c.server().List("", "*")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list folders: %w", err)
+	}
+
+	var folders []Folder
+	for _, rsp := range cmd.Data {
+		info := rsp.MailboxInfo()
+		if info == nil {
+			continue
+		}
+		folder := Folder{Name: info.Name, Delimiter: info.Delim}
+		for attr := range info.Attrs {
+			folder.Attrs = append(folder.Attrs, attr)
+		}
+		sort.Strings(folder.Attrs)
+		folders = append(folders, folder)
+	}
+
+	sort.Slice(folders, func(i, j int) bool {
+		return folders[i].Name < folders[j].Name
+	})
+	return folders, nil
+}
+
+// SelectFolder will switch the Client to the folder on the same connection,
+// read-only if asked to or in safe mode. Everything after works on the new
+// folder, reconnecting included. If the folder can not be selected the Client
+// stays on the one it was on.
+func (c *Client) SelectFolder(name string, readOnly bool) error {
+	_, err := c.do(context.Background(), func() (*imap.Command, error) {
+		return c.server().Select(name, readOnly || c.SafeMode)
+	})
+	if err != nil {
+		if errors.Is(err, ErrConnectionLost) {
+			return err
+		}
+		// a failed SELECT leaves no folder selected, go back to the one
+		// before so the Client is as it was
+		if c.Folder != "" {
+			if _, reselectErr := c.do(context.Background(), func() (*imap.Command, error) {
+				return c.server().Select(c.Folder, c.ReadOnly || c.SafeMode)
+			}); reselectErr != nil {
+				c.Folder = ""
+			}
+		}
+		return fmt.Errorf("%w: %s: %w", ErrFolderNotFound, name, err)
+	}
+	if name != c.Folder {
+		c.highestUID, c.highestModSeq = 0, 0
+	}
+	c.Folder = name
+	c.ReadOnly = readOnly
+	return nil
+}
+
+// WalkFolders calls fn with every selectable folder on the server in turn,
+// see ListFolders for the order. The session passed to fn has the folder
+// selected and is only valid until fn returns. Folders that can not be
+// selected are skipped, the folders below them are still walked. An error
+// returned by fn stops the walk and is returned as is.
+func (c *Client) WalkFolders(fn func(session *Client, folder Folder) error) error {
+	folders, err := c.ListFolders()
+	if err != nil {
+		return err
+	}
+
+	var session *Client
+	for _, folder := range folders {
+		if !folder.Selectable() {
+			continue
+		}
+		if session == nil {
+			if session, err = c.WithFolder(folder.Name); err != nil {
+				return err
+			}
+			defer session.Close()
+		} else if err = session.SelectFolder(folder.Name, session.ReadOnly); err != nil {
+			return err
+		}
+
+		if err = fn(session, folder); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateFolder will create the folder. Parent folders are created as
+// needed by most servers.
+func (c *Client) CreateFolder(name string) error {
+	return c.folderCommand("create", func() (*imap.Command, error) {
+		return c.server().Create(name)
+	})
+}
+
+// DeleteFolder will delete the folder along with the emails in it.
+func (c *Client) DeleteFolder(name string) error {
+	return c.folderCommand("delete", func() (*imap.Command, error) {
+		return c.server().Delete(name)
+	})
+}
+
+// RenameFolder will rename the folder, along with the folders below it.
+func (c *Client) RenameFolder(from, to string) error {
+	return c.folderCommand("rename", func() (*imap.Command, error) {
+		return c.server().Rename(from, to)
+	})
+}
+
+func (c *Client) folderCommand(action string, command func() (*imap.Command, error)) error {
+	if c.SafeMode {
+		return ErrReadOnlyMode
+	}
+	if _, err := c.do(context.Background(), command); err != nil {
+		return fmt.Errorf("unable to %s folder: %w", action, err)
+	}
+	return nil
+}
+
+// encodeMailbox encodes the folder name to modified UTF-7, for the commands
+// sent by hand. The commands of go-imap, e.g. Select or Copy, take names in
+// UTF-8 and encode them on their own.
+func encodeMailbox(name string) string {
+	return imap.UTF7Encode(name)
+}
+
+// decodeMailbox decodes a modified UTF-7 folder name read by hand, leaving it
+// as is if it is not valid. go-imap decodes the names of MailboxInfo and
+// MailboxStatus on its own.
+func decodeMailbox(name string) string {
+	decoded, err := imap.UTF7Decode(name)
+	if err != nil {
+		return name
+	}
+	return decoded
+}
@@ -0,0 +1,121 @@
+package eazye
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestFolderHasAttr(t *testing.T) {
+	f := Folder{Name: "[Gmail]", Attrs: []string{`\HasChildren`, `\Noselect`}}
+	if !f.HasAttr(`\NoSelect`) {
+		t.Error(`HasAttr(\NoSelect) got false, want true`)
+	}
+	if f.HasAttr(`\Trash`) {
+		t.Error(`HasAttr(\Trash) got true, want false`)
+	}
+}
+
+func TestFolderSelectable(t *testing.T) {
+	tests := []struct {
+		attrs []string
+		want  bool
+	}{
+		{nil, true},
+		{[]string{`\HasNoChildren`, `\Sent`}, true},
+		{[]string{`\HasChildren`, `\Noselect`}, false},
+		{[]string{`\NonExistent`}, false},
+	}
+	for _, test := range tests {
+		if got := (Folder{Attrs: test.attrs}).Selectable(); got != test.want {
+			t.Errorf("Selectable() with %v got %v, want %v", test.attrs, got, test.want)
+		}
+	}
+}
+
+func TestSelectFolderMissing(t *testing.T) {
+	srv := testServer(t)
+	srv.AddMessage("INBOX", []byte("Subject: hi\r\n\r\nx\r\n"))
+
+	c := testClient(t, srv)
+	if err := c.SelectFolder("Missing", false); !errors.Is(err, ErrFolderNotFound) {
+		t.Fatalf("SelectFolder() got %v, want %s", err, ErrFolderNotFound)
+	}
+	if c.Folder != "INBOX" {
+		t.Errorf("SelectFolder() left Folder %q, want INBOX", c.Folder)
+	}
+	if emails, err := c.GetAll(false, false); err != nil || len(emails) != 1 {
+		t.Errorf("GetAll() after a failed SelectFolder got %d emails, %v, want 1", len(emails), err)
+	}
+}
+
+func TestSelectFolderNonASCII(t *testing.T) {
+	srv := testServer(t)
+	// the server knows the folder by its modified UTF-7 name
+	srv.AddMessage("Entw&APw-rfe", []byte("Subject: draft\r\n\r\nx\r\n"))
+
+	c := testClient(t, srv)
+	if err := c.SelectFolder("Entwürfe", false); err != nil {
+		t.Fatalf("SelectFolder() returned an error: %s", err)
+	}
+	if c.Folder != "Entwürfe" {
+		t.Errorf("SelectFolder() got Folder %q, want Entwürfe", c.Folder)
+	}
+	if !hasCommand(srv, "SELECT Entw&APw-rfe") {
+		t.Errorf("SelectFolder() got commands %q, want the name encoded once", srv.Commands())
+	}
+	if emails, err := c.GetAll(false, false); err != nil || len(emails) != 1 {
+		t.Errorf("GetAll() after SelectFolder got %d emails, %v, want 1", len(emails), err)
+	}
+}
+
+func TestMailboxEncoding(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"INBOX", "INBOX"},
+		{"Entwürfe", "Entw&APw-rfe"},
+		{"R&D", "R&-D"},
+	}
+	for _, test := range tests {
+		if got := encodeMailbox(test.name); got != test.encoded {
+			t.Errorf("encodeMailbox(%q) got %q, want %q", test.name, got, test.encoded)
+		}
+		if got := decodeMailbox(test.encoded); got != test.name {
+			t.Errorf("decodeMailbox(%q) got %q, want %q", test.encoded, got, test.name)
+		}
+	}
+}
+
+func TestFoldersNonASCII(t *testing.T) {
+	srv := testServer(t)
+	srv.AddMessage("INBOX", []byte("Subject: draft\r\n\r\nx\r\n"))
+
+	c := testClient(t, srv)
+	if err := c.CreateFolder("Entwürfe"); err != nil {
+		t.Fatalf("CreateFolder() returned an error: %s", err)
+	}
+	emails, err := c.GetAll(false, false)
+	if err != nil || len(emails) != 1 {
+		t.Fatalf("GetAll() got %d emails, %v, want 1", len(emails), err)
+	}
+	if err = c.Move(emails[0], "Entwürfe"); err != nil {
+		t.Fatalf("Move() returned an error: %s", err)
+	}
+	if got := len(srv.Messages("Entw&APw-rfe")); got != 1 {
+		t.Errorf("Move() left %d emails in Entw&APw-rfe, want 1", got)
+	}
+
+	var walked []string
+	err = c.WalkFolders(func(session *Client, folder Folder) error {
+		walked = append(walked, folder.Name+":"+session.Folder)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkFolders() returned an error: %s", err)
+	}
+	if want := []string{"Entwürfe:Entwürfe", "INBOX:INBOX"}; !reflect.DeepEqual(walked, want) {
+		t.Errorf("WalkFolders() walked %q, want %q", walked, want)
+	}
+}
@@ -0,0 +1,74 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// ErrNotGmail is returned when using the Gmail extensions on a server that
+// does not have the X-GM-EXT-1 capability.
+var ErrNotGmail = errors.New("server does not support the Gmail extensions")
+
+// GmailRaw matches the messages Gmail's own search syntax matches, e.g.
+// "has:attachment larger:5M". Only Gmail supports it.
+func GmailRaw(query string) Query {
+	return Query{keys: []imap.Field{"X-GM-RAW", searchString(query)}}
+}
+
+// GmailThread matches the messages of the Gmail thread, see
+// Email.GmailThreadID. Only Gmail supports it.
+func GmailThread(threadID uint64) Query {
+	return Query{keys: []imap.Field{"X-GM-THRID", strconv.FormatUint(threadID, 10)}}
+}
+
+// SearchGmailRaw will pull all emails matching a query in Gmail's own search
+// syntax, see GmailRaw.
+func (c *Client) SearchGmailRaw(query string, markAsRead, delete bool) ([]Email, error) {
+	return c.SearchGmailRawContext(context.Background(), query, markAsRead, delete)
+}
+
+// SearchGmailRawContext is SearchGmailRaw with a context.
+func (c *Client) SearchGmailRawContext(ctx context.Context, query string, markAsRead, delete bool) ([]Email, error) {
+	if !c.caps("X-GM-EXT-1") {
+		return nil, ErrNotGmail
+	}
+	return c.SearchContext(ctx, GmailRaw(query), markAsRead, delete)
+}
+
+// gmailItems returns the FETCH items for the Gmail labels and IDs if the
+// server has them.
+func (c *Client) gmailItems() []string {
+	if !c.caps("X-GM-EXT-1") {
+		return nil
+	}
+	return []string{"X-GM-LABELS", "X-GM-THRID", "X-GM-MSGID"}
+}
+
+// parseGmail fills in the Gmail labels and IDs of the email, if they were
+// fetched.
+func (e *Email) parseGmail(msgFields imap.FieldMap) {
+	if labels, ok := msgFields["X-GM-LABELS"]; ok {
+		e.GmailLabels = []string{}
+		for _, label := range imap.AsList(labels) {
+			e.GmailLabels = append(e.GmailLabels, decodeMailbox(imap.AsString(label)))
+		}
+	}
+	e.GmailThreadID = gmailID(msgFields["X-GM-THRID"])
+	e.GmailMessageID = gmailID(msgFields["X-GM-MSGID"])
+}
+
+// gmailID reads a 64-bit Gmail ID. go-imap only parses numbers that fit in 32
+// bits, larger ones are left as atoms (strings).
+func gmailID(f imap.Field) uint64 {
+	switch v := f.(type) {
+	case uint32:
+		return uint64(v)
+	case string:
+		id, _ := strconv.ParseUint(v, 10, 64)
+		return id
+	}
+	return 0
+}
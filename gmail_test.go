@@ -0,0 +1,39 @@
+package eazye
+
+import (
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+func TestParseGmail(t *testing.T) {
+	email, err := newEmail(imap.FieldMap{
+		"RFC822.HEADER": []byte("Subject: hi\r\n\r\n"),
+		"X-GM-LABELS":   []imap.Field{`\Inbox`, "Receipts"},
+		"X-GM-THRID":    "1278455344230334865",
+		"X-GM-MSGID":    uint32(42),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(email.GmailLabels) != 2 || email.GmailLabels[0] != `\Inbox` || email.GmailLabels[1] != "Receipts" {
+		t.Errorf("newEmail() got labels %q, want [\\Inbox Receipts]", email.GmailLabels)
+	}
+	if email.GmailThreadID != 1278455344230334865 {
+		t.Errorf("newEmail() got thread ID %d, want 1278455344230334865", email.GmailThreadID)
+	}
+	if email.GmailMessageID != 42 {
+		t.Errorf("newEmail() got message ID %d, want 42", email.GmailMessageID)
+	}
+}
+
+func TestParseGmailMissing(t *testing.T) {
+	email, err := newEmail(imap.FieldMap{"RFC822.HEADER": []byte("Subject: hi\r\n\r\n")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if email.GmailLabels != nil || email.GmailThreadID != 0 || email.GmailMessageID != 0 {
+		t.Errorf("newEmail() without Gmail extensions got %+v", email)
+	}
+}
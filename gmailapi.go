@@ -0,0 +1,300 @@
+package eazye
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GmailAPIURL is the Gmail API endpoint GmailAPI uses by default.
+const GmailAPIURL = "https://gmail.googleapis.com/gmail/v1"
+
+// GmailAPI is a Mailbox on Gmail using the Gmail REST API instead of IMAP,
+// e.g. to get around IMAP throttling or when only the API scopes are granted.
+// Labels take the place of folders. Emails are fetched in RAW format and
+// parsed the same as over IMAP, their IDs are Gmail API message IDs, which are
+// strings, and the Gmail fields are filled in as for the X-GM-EXT-1
+// extension, only with label IDs rather than names.
+type GmailAPI struct {
+	// Token returns an OAuth 2.0 access token with the gmail.modify scope.
+	// Getting and refreshing it is left to the caller.
+	Token func(ctx context.Context) (string, error)
+	// User is the email address of the mailbox, the signed in user if empty.
+	User string
+	// Label is the ID of the label to read, e.g. INBOX or Label_42, INBOX if
+	// empty.
+	Label string
+	// HTTPClient is used for the requests, http.DefaultClient if nil.
+	HTTPClient *http.Client
+	// BaseURL is the Gmail API endpoint, GmailAPIURL if empty.
+	BaseURL string
+}
+
+// NewGmailAPI returns a GmailAPI on the inbox of the given user.
+func NewGmailAPI(user string, token func(ctx context.Context) (string, error)) *GmailAPI {
+	return &GmailAPI{Token: token, User: user, Label: "INBOX"}
+}
+
+// gmailAPIMessage is a Gmail API message resource in RAW format.
+type gmailAPIMessage struct {
+	ID           string   `json:"id"`
+	ThreadID     string   `json:"threadId"`
+	LabelIDs     []string `json:"labelIds"`
+	InternalDate string   `json:"internalDate"`
+	Raw          string   `json:"raw"`
+}
+
+// GetAll will pull all emails with the label and return them as a list.
+func (g *GmailAPI) GetAll(markAsRead, delete bool) ([]Email, error) {
+	return g.GetAllContext(context.Background(), markAsRead, delete)
+}
+
+// GetAllContext is GetAll with a context.
+func (g *GmailAPI) GetAllContext(ctx context.Context, markAsRead, delete bool) ([]Email, error) {
+	return g.get(ctx, "", markAsRead, delete)
+}
+
+// GetUnread will find all unread emails with the label and return them as a
+// list.
+func (g *GmailAPI) GetUnread(markAsRead, delete bool) ([]Email, error) {
+	return g.GetUnreadContext(context.Background(), markAsRead, delete)
+}
+
+// GetUnreadContext is GetUnread with a context.
+func (g *GmailAPI) GetUnreadContext(ctx context.Context, markAsRead, delete bool) ([]Email, error) {
+	return g.get(ctx, "is:unread", markAsRead, delete)
+}
+
+// GetSince will pull all emails received since the given time and return them
+// as a list.
+func (g *GmailAPI) GetSince(since time.Time, markAsRead, delete bool) ([]Email, error) {
+	return g.GetSinceContext(context.Background(), since, markAsRead, delete)
+}
+
+// GetSinceContext is GetSince with a context.
+func (g *GmailAPI) GetSinceContext(ctx context.Context, since time.Time, markAsRead, delete bool) ([]Email, error) {
+	return g.get(ctx, "after:"+strconv.FormatInt(since.Unix(), 10), markAsRead, delete)
+}
+
+// SearchGmailRaw will find all emails with the label matching the Gmail search
+// query, e.g. "has:attachment larger:5M", and return them as a list.
+func (g *GmailAPI) SearchGmailRaw(query string, markAsRead, delete bool) ([]Email, error) {
+	return g.SearchGmailRawContext(context.Background(), query, markAsRead, delete)
+}
+
+// SearchGmailRawContext is SearchGmailRaw with a context.
+func (g *GmailAPI) SearchGmailRawContext(ctx context.Context, query string, markAsRead, delete bool) ([]Email, error) {
+	return g.get(ctx, query, markAsRead, delete)
+}
+
+// get lists the messages matching the search query and fetches each of them,
+// oldest first.
+func (g *GmailAPI) get(ctx context.Context, query string, markAsRead, delete bool) ([]Email, error) {
+	ids, err := g.list(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []Email
+	for _, id := range ids {
+		email, err := g.fetch(ctx, id)
+		if err != nil {
+			return emails, err
+		}
+		if markAsRead && !slices.Contains(email.Flags, `\Seen`) {
+			if err = g.SetAsReadContext(ctx, email); err != nil {
+				return emails, err
+			}
+			email.Flags = addFlag(email.Flags, `\Seen`)
+		}
+		if delete {
+			if err = g.DeleteEmailContext(ctx, email); err != nil {
+				return emails, err
+			}
+		}
+		emails = append(emails, email)
+	}
+	return emails, nil
+}
+
+// list returns the IDs of the messages with the label matching the query,
+// oldest first, following the pages of results.
+func (g *GmailAPI) list(ctx context.Context, query string) ([]string, error) {
+	params := url.Values{
+		"labelIds":   {g.label()},
+		"maxResults": {"500"},
+	}
+	if query != "" {
+		params.Set("q", query)
+	}
+
+	var ids []string
+	for {
+		var page struct {
+			Messages []struct {
+				ID string `json:"id"`
+			} `json:"messages"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		body, err := g.do(ctx, http.MethodGet, g.url("/messages")+"?"+params.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list messages: %w", err)
+		}
+		if err = json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("unable to list messages: %w", err)
+		}
+		for _, message := range page.Messages {
+			ids = append(ids, message.ID)
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		params.Set("pageToken", page.NextPageToken)
+	}
+
+	// the newest come first
+	slices.Reverse(ids)
+	return ids, nil
+}
+
+// fetch downloads a message in RAW format and parses it.
+func (g *GmailAPI) fetch(ctx context.Context, id string) (Email, error) {
+	body, err := g.do(ctx, http.MethodGet, g.url("/messages/"+url.PathEscape(id))+"?format=raw", nil)
+	if err != nil {
+		return Email{}, fmt.Errorf("unable to fetch message %s: %w", id, err)
+	}
+	var message gmailAPIMessage
+	if err = json.Unmarshal(body, &message); err != nil {
+		return Email{}, fmt.Errorf("unable to fetch message %s: %w", id, err)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(message.Raw, "="))
+	if err != nil {
+		return Email{}, fmt.Errorf("unable to decode message %s: %w", id, err)
+	}
+
+	email, err := parseRaw(message.ID, raw)
+	if err != nil {
+		return Email{}, fmt.Errorf("unable to %s message %s: %w", OpParse, id, err)
+	}
+	if ms, err := strconv.ParseInt(message.InternalDate, 10, 64); err == nil {
+		email.InternalDate = time.UnixMilli(ms)
+	}
+	// the IDs are those of X-GM-MSGID and X-GM-THRID in hex
+	email.GmailMessageID, _ = strconv.ParseUint(message.ID, 16, 64)
+	email.GmailThreadID, _ = strconv.ParseUint(message.ThreadID, 16, 64)
+	email.GmailLabels = message.LabelIDs
+	email.Flags = []string{}
+	if !slices.Contains(message.LabelIDs, "UNREAD") {
+		email.Flags = append(email.Flags, `\Seen`)
+	}
+	if slices.Contains(message.LabelIDs, "STARRED") {
+		email.Flags = append(email.Flags, `\Flagged`)
+	}
+	return email, nil
+}
+
+// SetAsRead marks the email as read.
+func (g *GmailAPI) SetAsRead(email Email) error {
+	return g.SetAsReadContext(context.Background(), email)
+}
+
+// SetAsReadContext is SetAsRead with a context.
+func (g *GmailAPI) SetAsReadContext(ctx context.Context, email Email) error {
+	return g.modify(ctx, email, OpMarkRead, nil, []string{"UNREAD"})
+}
+
+// SetAsUnread marks the email as unread.
+func (g *GmailAPI) SetAsUnread(email Email) error {
+	return g.SetAsUnreadContext(context.Background(), email)
+}
+
+// SetAsUnreadContext is SetAsUnread with a context.
+func (g *GmailAPI) SetAsUnreadContext(ctx context.Context, email Email) error {
+	return g.modify(ctx, email, OpMarkUnread, []string{"UNREAD"}, nil)
+}
+
+// Move moves the email to the label with the given ID, taking it out of the
+// GmailAPI's Label.
+func (g *GmailAPI) Move(email Email, label string) error {
+	return g.MoveContext(context.Background(), email, label)
+}
+
+// MoveContext is Move with a context.
+func (g *GmailAPI) MoveContext(ctx context.Context, email Email, label string) error {
+	return g.modify(ctx, email, "move", []string{label}, []string{g.label()})
+}
+
+// DeleteEmail moves the email to the trash, as the Gmail web interface does.
+func (g *GmailAPI) DeleteEmail(email Email) error {
+	return g.DeleteEmailContext(context.Background(), email)
+}
+
+// DeleteEmailContext is DeleteEmail with a context.
+func (g *GmailAPI) DeleteEmailContext(ctx context.Context, email Email) error {
+	id, err := gmailAPIID(email)
+	if err != nil {
+		return err
+	}
+	if _, err = g.do(ctx, http.MethodPost, g.url("/messages/"+url.PathEscape(id)+"/trash"), nil); err != nil {
+		return fmt.Errorf("unable to %s message %s: %w", OpDelete, id, err)
+	}
+	return nil
+}
+
+// Close does nothing, there is no connection to close.
+func (g *GmailAPI) Close() error {
+	return nil
+}
+
+// modify adds and removes labels of a single message to do op.
+func (g *GmailAPI) modify(ctx context.Context, email Email, op string, add, remove []string) error {
+	id, err := gmailAPIID(email)
+	if err != nil {
+		return err
+	}
+	payload := map[string][]string{"addLabelIds": add, "removeLabelIds": remove}
+	if _, err = g.do(ctx, http.MethodPost, g.url("/messages/"+url.PathEscape(id)+"/modify"), payload); err != nil {
+		return fmt.Errorf("unable to %s message %s: %w", op, id, err)
+	}
+	return nil
+}
+
+func gmailAPIID(email Email) (string, error) {
+	id, ok := email.ID.(string)
+	if !ok {
+		return "", fmt.Errorf("not a Gmail API message: %w", ErrEmailNotFound)
+	}
+	return id, nil
+}
+
+// do sends a request to the Gmail API.
+func (g *GmailAPI) do(ctx context.Context, method, target string, payload any) ([]byte, error) {
+	return restDo(ctx, g.HTTPClient, g.Token, method, target, payload)
+}
+
+// url returns the URL of a path under the user's mailbox.
+func (g *GmailAPI) url(path string) string {
+	base := g.BaseURL
+	if base == "" {
+		base = GmailAPIURL
+	}
+	user := g.User
+	if user == "" {
+		user = "me"
+	}
+	return base + "/users/" + url.PathEscape(user) + path
+}
+
+func (g *GmailAPI) label() string {
+	if g.Label == "" {
+		return "INBOX"
+	}
+	return g.Label
+}
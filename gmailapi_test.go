@@ -0,0 +1,62 @@
+package eazye
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGmailAPI(t *testing.T) {
+	raw := base64.URLEncoding.EncodeToString([]byte("Subject: hello\r\nContent-Type: text/plain\r\n\r\nhi there\r\n"))
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(body)))
+		switch r.URL.Path {
+		case "/users/me/messages":
+			if got := r.URL.Query().Get("q"); got != "after:1407715200" {
+				t.Errorf("list got q %q", got)
+			}
+			if r.URL.Query().Get("pageToken") == "" {
+				fmt.Fprint(w, `{"messages":[{"id":"147c3e6e3f8d1e2b"}],"nextPageToken":"p2"}`)
+			} else {
+				fmt.Fprint(w, `{"messages":[{"id":"147c3e6e3f8d1e2a"}]}`)
+			}
+		case "/users/me/messages/147c3e6e3f8d1e2a", "/users/me/messages/147c3e6e3f8d1e2b":
+			id := strings.TrimPrefix(r.URL.Path, "/users/me/messages/")
+			fmt.Fprintf(w, `{"id":%q,"threadId":"147c3e6e3f8d1e2a","labelIds":["INBOX","UNREAD"],"internalDate":"1407795256000","raw":%q}`, id, raw)
+		}
+	}))
+	defer srv.Close()
+
+	g := NewGmailAPI("", func(context.Context) (string, error) { return "tok", nil })
+	g.BaseURL = srv.URL
+
+	emails, err := g.GetSince(time.Date(2014, 8, 11, 0, 0, 0, 0, time.UTC), true, false)
+	if err != nil {
+		t.Fatalf("GetSince() returned error: %s", err)
+	}
+	if len(emails) != 2 {
+		t.Fatalf("GetSince() got %d emails, want 2", len(emails))
+	}
+	email := emails[0]
+	if email.ID != "147c3e6e3f8d1e2a" || email.Subject != "hello" || email.GmailThreadID != 0x147c3e6e3f8d1e2a {
+		t.Errorf("GetSince() got %+v, want the oldest hello email first", email)
+	}
+	if !email.InternalDate.Equal(time.UnixMilli(1407795256000)) {
+		t.Errorf("GetSince() got date %s", email.InternalDate)
+	}
+	if len(email.Flags) != 1 || email.Flags[0] != `\Seen` {
+		t.Errorf("GetSince() got flags %q, want [\\Seen]", email.Flags)
+	}
+	want := `POST /users/me/messages/147c3e6e3f8d1e2a/modify {"addLabelIds":null,"removeLabelIds":["UNREAD"]}`
+	if got := requests[3]; got != want {
+		t.Errorf("GetSince() marked as read with %q, want %q", got, want)
+	}
+}
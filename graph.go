@@ -0,0 +1,244 @@
+package eazye
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GraphURL is the Microsoft Graph endpoint Graph uses by default.
+const GraphURL = "https://graph.microsoft.com/v1.0"
+
+// Graph is a Mailbox on Office 365 using the Microsoft Graph API, for tenants
+// where IMAP is disabled. Emails are fetched in MIME format and parsed the
+// same as over IMAP, their IDs are Graph message IDs, which are strings.
+type Graph struct {
+	// Token returns an OAuth 2.0 access token with the Mail.ReadWrite
+	// permission. Getting and refreshing it is left to the caller.
+	Token func(ctx context.Context) (string, error)
+	// User is the user principal name or ID of the mailbox, the signed in
+	// user if empty.
+	User string
+	// Folder is the ID or well-known name of the mail folder, e.g. inbox or
+	// archive, inbox if empty.
+	Folder string
+	// HTTPClient is used for the requests, http.DefaultClient if nil.
+	HTTPClient *http.Client
+	// BaseURL is the Graph endpoint, GraphURL if empty.
+	BaseURL string
+}
+
+// NewGraph returns a Graph on the inbox of the given user.
+func NewGraph(user string, token func(ctx context.Context) (string, error)) *Graph {
+	return &Graph{Token: token, User: user, Folder: "inbox"}
+}
+
+// graphMessage is the part of a Graph message resource Graph asks for.
+type graphMessage struct {
+	ID               string    `json:"id"`
+	IsRead           bool      `json:"isRead"`
+	ReceivedDateTime time.Time `json:"receivedDateTime"`
+}
+
+// GetAll will pull all emails from the folder and return them as a list.
+func (g *Graph) GetAll(markAsRead, delete bool) ([]Email, error) {
+	return g.GetAllContext(context.Background(), markAsRead, delete)
+}
+
+// GetAllContext is GetAll with a context.
+func (g *Graph) GetAllContext(ctx context.Context, markAsRead, delete bool) ([]Email, error) {
+	return g.get(ctx, "", markAsRead, delete)
+}
+
+// GetUnread will find all unread emails in the folder and return them as a
+// list.
+func (g *Graph) GetUnread(markAsRead, delete bool) ([]Email, error) {
+	return g.GetUnreadContext(context.Background(), markAsRead, delete)
+}
+
+// GetUnreadContext is GetUnread with a context.
+func (g *Graph) GetUnreadContext(ctx context.Context, markAsRead, delete bool) ([]Email, error) {
+	return g.get(ctx, "isRead eq false", markAsRead, delete)
+}
+
+// GetSince will pull all emails received since the given time and return them
+// as a list.
+func (g *Graph) GetSince(since time.Time, markAsRead, delete bool) ([]Email, error) {
+	return g.GetSinceContext(context.Background(), since, markAsRead, delete)
+}
+
+// GetSinceContext is GetSince with a context.
+func (g *Graph) GetSinceContext(ctx context.Context, since time.Time, markAsRead, delete bool) ([]Email, error) {
+	return g.get(ctx, "receivedDateTime ge "+since.UTC().Format(time.RFC3339), markAsRead, delete)
+}
+
+// get lists the messages matching the OData filter and fetches each of them.
+func (g *Graph) get(ctx context.Context, filter string, markAsRead, delete bool) ([]Email, error) {
+	messages, err := g.list(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []Email
+	for _, message := range messages {
+		email, err := g.fetch(ctx, message)
+		if err != nil {
+			return emails, err
+		}
+		if markAsRead && !message.IsRead {
+			if err = g.SetAsReadContext(ctx, email); err != nil {
+				return emails, err
+			}
+			email.Flags = addFlag(email.Flags, `\Seen`)
+		}
+		if delete {
+			if err = g.DeleteEmailContext(ctx, email); err != nil {
+				return emails, err
+			}
+		}
+		emails = append(emails, email)
+	}
+	return emails, nil
+}
+
+// graphAnyDate is a filter on receivedDateTime every message matches.
+const graphAnyDate = "receivedDateTime ge 1900-01-01T00:00:00Z"
+
+// list returns the messages in the folder matching the filter, following the
+// pages of results.
+func (g *Graph) list(ctx context.Context, filter string) ([]graphMessage, error) {
+	// Graph refuses to sort by a property unless the filter starts with it
+	if !strings.HasPrefix(filter, "receivedDateTime ") {
+		filter = strings.TrimSuffix(graphAnyDate+" and "+filter, " and ")
+	}
+	query := url.Values{
+		"$select":  {"id,isRead,receivedDateTime"},
+		"$orderby": {"receivedDateTime"},
+		"$filter":  {filter},
+		"$top":     {"100"},
+	}
+	next := g.url("/mailFolders/"+url.PathEscape(g.folder())+"/messages") + "?" + query.Encode()
+
+	var messages []graphMessage
+	for next != "" {
+		var page struct {
+			Value    []graphMessage `json:"value"`
+			NextLink string         `json:"@odata.nextLink"`
+		}
+		body, err := g.do(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list messages: %w", err)
+		}
+		if err = json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("unable to list messages: %w", err)
+		}
+		messages = append(messages, page.Value...)
+		next = page.NextLink
+	}
+	return messages, nil
+}
+
+// fetch downloads the MIME content of a message and parses it.
+func (g *Graph) fetch(ctx context.Context, message graphMessage) (Email, error) {
+	raw, err := g.do(ctx, http.MethodGet, g.url("/messages/"+url.PathEscape(message.ID)+"/$value"), nil)
+	if err != nil {
+		return Email{}, fmt.Errorf("unable to fetch message %s: %w", message.ID, err)
+	}
+
+	email, err := parseRaw(message.ID, raw)
+	if err != nil {
+		return Email{}, fmt.Errorf("unable to %s message %s: %w", OpParse, message.ID, err)
+	}
+	email.InternalDate = message.ReceivedDateTime
+	email.Flags = []string{}
+	if message.IsRead {
+		email.Flags = append(email.Flags, `\Seen`)
+	}
+	return email, nil
+}
+
+// SetAsRead marks the email as read.
+func (g *Graph) SetAsRead(email Email) error {
+	return g.SetAsReadContext(context.Background(), email)
+}
+
+// SetAsReadContext is SetAsRead with a context.
+func (g *Graph) SetAsReadContext(ctx context.Context, email Email) error {
+	return g.update(ctx, email, OpMarkRead, http.MethodPatch, "", map[string]any{"isRead": true})
+}
+
+// SetAsUnread marks the email as unread.
+func (g *Graph) SetAsUnread(email Email) error {
+	return g.SetAsUnreadContext(context.Background(), email)
+}
+
+// SetAsUnreadContext is SetAsUnread with a context.
+func (g *Graph) SetAsUnreadContext(ctx context.Context, email Email) error {
+	return g.update(ctx, email, OpMarkUnread, http.MethodPatch, "", map[string]any{"isRead": false})
+}
+
+// Move moves the email to the folder with the given ID or well-known name.
+func (g *Graph) Move(email Email, folder string) error {
+	return g.MoveContext(context.Background(), email, folder)
+}
+
+// MoveContext is Move with a context.
+func (g *Graph) MoveContext(ctx context.Context, email Email, folder string) error {
+	return g.update(ctx, email, "move", http.MethodPost, "/move", map[string]any{"destinationId": folder})
+}
+
+// DeleteEmail moves the email to the Deleted Items folder, as Outlook does.
+func (g *Graph) DeleteEmail(email Email) error {
+	return g.DeleteEmailContext(context.Background(), email)
+}
+
+// DeleteEmailContext is DeleteEmail with a context.
+func (g *Graph) DeleteEmailContext(ctx context.Context, email Email) error {
+	return g.update(ctx, email, OpDelete, http.MethodDelete, "", nil)
+}
+
+// Close does nothing, there is no connection to close.
+func (g *Graph) Close() error {
+	return nil
+}
+
+// update sends a request to do op to a single message, with the payload as
+// JSON if it is not nil.
+func (g *Graph) update(ctx context.Context, email Email, op, method, action string, payload any) error {
+	id, ok := email.ID.(string)
+	if !ok {
+		return fmt.Errorf("not a Graph message: %w", ErrEmailNotFound)
+	}
+	if _, err := g.do(ctx, method, g.url("/messages/"+url.PathEscape(id)+action), payload); err != nil {
+		return fmt.Errorf("unable to %s message %s: %w", op, id, err)
+	}
+	return nil
+}
+
+// do sends a request to the Graph API.
+func (g *Graph) do(ctx context.Context, method, target string, payload any) ([]byte, error) {
+	return restDo(ctx, g.HTTPClient, g.Token, method, target, payload)
+}
+
+// url returns the URL of a path under the user's mailbox.
+func (g *Graph) url(path string) string {
+	base := g.BaseURL
+	if base == "" {
+		base = GraphURL
+	}
+	if g.User == "" {
+		return base + "/me" + path
+	}
+	return base + "/users/" + url.PathEscape(g.User) + path
+}
+
+func (g *Graph) folder() string {
+	if g.Folder == "" {
+		return "inbox"
+	}
+	return g.Folder
+}
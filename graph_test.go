@@ -0,0 +1,98 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGraph(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, strings.TrimSpace(r.Method+" "+r.URL.Path+" "+string(body)))
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/users/alice@example.com/mailFolders/inbox/messages":
+			if r.URL.Query().Get("$filter") != "receivedDateTime ge 1900-01-01T00:00:00Z and isRead eq false" {
+				t.Errorf("list got filter %q", r.URL.Query().Get("$filter"))
+			}
+			fmt.Fprint(w, `{"value":[{"id":"AAA=","isRead":false,"receivedDateTime":"2014-08-11T22:14:16Z"}]}`)
+		case r.URL.Path == "/users/alice@example.com/messages/AAA=/$value":
+			fmt.Fprint(w, "Subject: hello\r\nContent-Type: text/plain\r\n\r\nhi there\r\n")
+		case r.URL.Path == "/users/alice@example.com/messages/missing":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":{"code":"ErrorItemNotFound","message":"not found"}}`)
+		}
+	}))
+	defer srv.Close()
+
+	g := NewGraph("alice@example.com", func(context.Context) (string, error) { return "tok", nil })
+	g.BaseURL = srv.URL
+
+	emails, err := g.GetUnread(true, false)
+	if err != nil {
+		t.Fatalf("GetUnread() returned error: %s", err)
+	}
+	if len(emails) != 1 || emails[0].Subject != "hello" || emails[0].ID != "AAA=" || emails[0].InternalDate.Year() != 2014 {
+		t.Errorf("GetUnread() got %+v, want the hello email", emails)
+	}
+	if got := requests[len(requests)-1]; got != `PATCH /users/alice@example.com/messages/AAA= {"isRead":true}` {
+		t.Errorf("GetUnread() marked as read with %q", got)
+	}
+
+	err = g.DeleteEmail(Email{ID: "missing"})
+	if !errors.Is(err, ErrEmailNotFound) {
+		t.Errorf("DeleteEmail() got %v, want %s", err, ErrEmailNotFound)
+	}
+
+	g.Token = func(context.Context) (string, error) { return "expired", nil }
+	if _, err = g.GetAll(false, false); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("GetAll() got %v, want %s", err, ErrAuthFailed)
+	}
+}
+
+func TestGraphListQuery(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		fmt.Fprint(w, `{"value":[]}`)
+	}))
+	defer srv.Close()
+
+	g := NewGraph("alice@example.com", func(context.Context) (string, error) { return "tok", nil })
+	g.BaseURL = srv.URL
+	since := time.Date(2014, 8, 11, 22, 14, 16, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		get    func() ([]Email, error)
+		filter string
+	}{
+		{"GetAll", func() ([]Email, error) { return g.GetAll(false, false) }, "receivedDateTime ge 1900-01-01T00:00:00Z"},
+		{"GetUnread", func() ([]Email, error) { return g.GetUnread(false, false) }, "receivedDateTime ge 1900-01-01T00:00:00Z and isRead eq false"},
+		{"GetSince", func() ([]Email, error) { return g.GetSince(since, false, false) }, "receivedDateTime ge 2014-08-11T22:14:16Z"},
+	}
+
+	for _, tt := range tests {
+		if _, err := tt.get(); err != nil {
+			t.Fatalf("%s() returned an error: %s", tt.name, err)
+		}
+		if got := query.Get("$filter"); got != tt.filter {
+			t.Errorf("%s() got $filter %q, want %q", tt.name, got, tt.filter)
+		}
+		if got := query.Get("$orderby"); got != "receivedDateTime" {
+			t.Errorf("%s() got $orderby %q, want receivedDateTime", tt.name, got)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package eazye
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mxk/go-imap/imap"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// GetHeaders will pull the headers of all emails matching the query, without
+// their bodies, e.g. for listing the subjects of a large folder. The emails
+// are left unread and have no HTML, Text or Parts. Use GetByUID to fetch the
+// full email.
+func (c *Client) GetHeaders(q Query) ([]Email, error) {
+	return c.GetHeadersContext(context.Background(), q)
+}
+
+// GetHeadersContext is GetHeaders with a context.
+func (c *Client) GetHeadersContext(ctx context.Context, q Query) ([]Email, error) {
+	responses, err := c.GenerateHeadersContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.collect(ctx, responses)
+}
+
+// GenerateHeaders will find all emails matching the query and pass them
+// along to the responses channel with only their headers, see GetHeaders.
+func (c *Client) GenerateHeaders(q Query) (chan Response, error) {
+	return c.GenerateHeadersContext(context.Background(), q)
+}
+
+// GenerateHeadersContext is GenerateHeaders with a context. See
+// generateMail for how the context is handled.
+func (c *Client) GenerateHeadersContext(ctx context.Context, q Query) (chan Response, error) {
+	responses := make(chan Response, c.bufferSize())
+
+	go func() {
+		defer close(responses)
+
+		cmd, err := c.findEmails(ctx, q)
+		if err != nil {
+			send(ctx, responses, Response{Err: err})
+			return
+		}
+		c.getHeaders(ctx, searchResults(cmd), responses)
+	}()
+
+	return responses, nil
+}
+
+// getHeaders fetches the headers of the emails with the given UIDs. Unlike
+// BODY[], RFC822.HEADER never sets \Seen.
+func (c *Client) getHeaders(ctx context.Context, uids []uint32, responses chan Response) {
+	seq := &imap.SeqSet{}
+	seq.AddNum(uids...)
+	if seq.Empty() {
+		return
+	}
+
+	fetchCtx, span := c.startSpan(ctx, "eazye.Fetch", attribute.Int("imap.emails", len(uids)))
+	fCmd, err := c.do(fetchCtx, func() (*imap.Command, error) {
+		items := append([]string{"INTERNALDATE", "UID", "RFC822.HEADER", "FLAGS"}, c.gmailItems()...)
+		return c.uidFetch(seq, items...)
+	})
+	endSpan(span, err)
+	if err != nil {
+		send(ctx, responses, Response{Err: fmt.Errorf("unable to perform uid fetch: %w", err)})
+		return
+	}
+
+	fail, done := c.errorHandler(ctx, responses)
+	defer done()
+
+	for _, msgData := range fCmd.Data {
+		info := c.messageInfo(msgData)
+		msgFields := info.Attrs
+		// skip unsolicited FETCH responses, see getEmails
+		if _, ok := msgFields["RFC822.HEADER"]; !ok {
+			continue
+		}
+		c.track(info)
+
+		email, err := c.parse(ctx, msgFields)
+		if err != nil {
+			if fail(c.emailError(OpParse, imap.AsNumber(msgFields["UID"]), err)) {
+				continue
+			}
+			return
+		}
+		if c.Redactor != nil {
+			c.Redactor.Email(&email)
+		}
+		if !send(ctx, responses, Response{Email: email}) {
+			return
+		}
+	}
+}
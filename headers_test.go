@@ -0,0 +1,24 @@
+package eazye
+
+import (
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+func TestNewEmailHeadersOnly(t *testing.T) {
+	email, err := newEmail(imap.FieldMap{
+		"UID":           uint32(7),
+		"RFC822.HEADER": []byte("Date: Mon, 11 Aug 2014 22:14:16 +0000\r\n" + multipartHeader),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if email.Subject != "hi" {
+		t.Errorf("newEmail() got Subject %q, want %q", email.Subject, "hi")
+	}
+	if len(email.Warnings) > 0 || email.HTML != nil || email.Text != nil || email.IsMultiPart {
+		t.Errorf("newEmail() of headers only got warnings %v and a body", email.Warnings)
+	}
+}
@@ -0,0 +1,101 @@
+package eazye
+
+import (
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// imapConn is the part of an IMAP client eazye uses. *imap.Client, wrapped
+// in imapClient, is the default, others can be plugged in with SetIMAPConn,
+// e.g. to record or fake the commands sent in tests.
+type imapConn interface {
+	// State returns the state of the connection.
+	State() imap.ConnState
+	// Capabilities returns the capabilities the server announced.
+	Capabilities() map[string]bool
+	// SelectedMailbox returns the status of the selected folder, or nil if
+	// none is selected.
+	SelectedMailbox() *imap.MailboxStatus
+	// Unilateral returns the responses received outside of any command.
+	Unilateral() []*imap.Response
+	// ClearUnilateral forgets the responses received outside of any command.
+	ClearUnilateral()
+	// Quote returns the value as a string, or a literal if it has to be.
+	Quote(v interface{}) imap.Field
+
+	Send(name string, fields ...imap.Field) (*imap.Command, error)
+	Recv(timeout time.Duration) error
+	Noop() (*imap.Command, error)
+	Logout(timeout time.Duration) (*imap.Command, error)
+	Select(mbox string, readonly bool) (*imap.Command, error)
+	Create(mbox string) (*imap.Command, error)
+	Delete(mbox string) (*imap.Command, error)
+	Rename(old, new string) (*imap.Command, error)
+	List(ref, mbox string) (*imap.Command, error)
+	Status(mbox string, items ...string) (*imap.Command, error)
+	Expunge(uids *imap.SeqSet) (*imap.Command, error)
+	Search(spec ...imap.Field) (*imap.Command, error)
+	Fetch(seq *imap.SeqSet, items ...string) (*imap.Command, error)
+	Store(seq *imap.SeqSet, item string, value imap.Field) (*imap.Command, error)
+	Copy(seq *imap.SeqSet, mbox string) (*imap.Command, error)
+	Append(mbox string, flags imap.FlagSet, idate *time.Time, msg imap.Literal) (*imap.Command, error)
+	UIDSearch(spec ...imap.Field) (*imap.Command, error)
+	UIDFetch(seq *imap.SeqSet, items ...string) (*imap.Command, error)
+	UIDStore(seq *imap.SeqSet, item string, value imap.Field) (*imap.Command, error)
+	UIDCopy(seq *imap.SeqSet, mbox string) (*imap.Command, error)
+	Idle() (*imap.Command, error)
+	IdleTerm() (*imap.Command, error)
+}
+
+// imapClient adapts *imap.Client to imapConn.
+type imapClient struct {
+	*imap.Client
+}
+
+func (c imapClient) Capabilities() map[string]bool {
+	return c.Client.Caps
+}
+
+func (c imapClient) SelectedMailbox() *imap.MailboxStatus {
+	return c.Client.Mailbox
+}
+
+func (c imapClient) Unilateral() []*imap.Response {
+	return c.Client.Data
+}
+
+func (c imapClient) ClearUnilateral() {
+	c.Client.Data = nil
+}
+
+// SetIMAPConn is a functional option to use conn for every command instead of
+// dialing the server. The connection must be logged in already, the folder is
+// selected on it as usual and Close logs out of it. As there is no way to
+// dial it again, a dropped connection is not reconnected and WithFolder
+// fails.
+func SetIMAPConn(conn imapConn) Option {
+	return func(c *Client) {
+		c.imapConn = conn
+	}
+}
+
+// server returns the connection commands go to: the one set with SetIMAPConn,
+// or else Imap. It is nil if there is neither.
+func (c *Client) server() imapConn {
+	if c.imapConn != nil {
+		return c.imapConn
+	}
+	if c.Imap != nil {
+		return imapClient{c.Imap}
+	}
+	return nil
+}
+
+// mailbox returns the status of the selected folder, or nil if there is none.
+func (c *Client) mailbox() *imap.MailboxStatus {
+	if server := c.server(); server != nil {
+		return server.SelectedMailbox()
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package eazye
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// fakeConn is an imapConn that refuses to select any folder.
+type fakeConn struct {
+	imapConn
+	caps      map[string]bool
+	selected  []string
+	loggedOut bool
+}
+
+func (f *fakeConn) State() imap.ConnState         { return imap.Auth }
+func (f *fakeConn) Capabilities() map[string]bool { return f.caps }
+
+func (f *fakeConn) Logout(timeout time.Duration) (*imap.Command, error) {
+	f.loggedOut = true
+	return nil, nil
+}
+
+func (f *fakeConn) Select(mbox string, readonly bool) (*imap.Command, error) {
+	f.selected = append(f.selected, mbox)
+	return nil, errors.New("no such folder")
+}
+
+func TestSetIMAPConn(t *testing.T) {
+	conn := &fakeConn{caps: map[string]bool{"IDLE": true}}
+	c, err := New("", "user", "pwd", SetIMAPConn(conn), SetFolder("Missing"))
+	if !errors.Is(err, ErrFolderNotFound) {
+		t.Errorf("New() got %v, want %s", err, ErrFolderNotFound)
+	}
+	if len(conn.selected) != 1 || conn.selected[0] != "Missing" {
+		t.Errorf("New() selected %q, want [Missing]", conn.selected)
+	}
+	if !c.caps("IDLE") || c.caps("MOVE") {
+		t.Errorf("caps() does not match the injected connection")
+	}
+	if _, err = c.WithFolder("INBOX"); err == nil {
+		t.Errorf("WithFolder() on an injected connection returned no error")
+	}
+	if err = c.Close(); err != nil || !conn.loggedOut {
+		t.Errorf("Close() got %v, logged out %v, want the injected connection logged out", err, conn.loggedOut)
+	}
+}
+
+func TestServerDefault(t *testing.T) {
+	c := &Client{}
+	if c.server() != nil {
+		t.Errorf("server() without a connection got %v, want nil", c.server())
+	}
+
+	c.Imap = &imap.Client{Caps: map[string]bool{"UIDPLUS": true}}
+	if !c.caps("UIDPLUS") {
+		t.Errorf("caps() does not fall back to Imap")
+	}
+}
@@ -0,0 +1,95 @@
+package eazye
+
+import (
+	"encoding/base64"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// cidURL matches the cid: URLs (RFC 2392) HTML bodies refer to inline parts
+// with, in attributes and CSS alike.
+var cidURL = regexp.MustCompile(`(?i)cid:([^"'\s()<>]+)`)
+
+// InlineHTML returns the HTML body of the email with the cid: URLs of the
+// inline images and other parts it shows replaced by data: URLs holding
+// them, so that it renders on its own, outside of a mail client. URLs of
+// parts the email does not have are left as they are. It is "" if the email
+// has no HTML body.
+func (e Email) InlineHTML() (string, error) {
+	return e.ResolveCIDs(func(part BodyPart) (string, error) {
+		return "data:" + part.MediaType() + ";base64," + base64.StdEncoding.EncodeToString(part.Content), nil
+	})
+}
+
+// ResolveCIDs returns the HTML body of the email with each cid: URL replaced
+// by the URL resolve returns for the part it refers to, e.g. that of the
+// file it was saved to. resolve is called once per part, however many times
+// it is referred to. URLs of parts the email does not have are left as they
+// are.
+func (e Email) ResolveCIDs(resolve func(part BodyPart) (string, error)) (string, error) {
+	html, _, err := e.bodies()
+	if err != nil || len(html) == 0 {
+		return "", err
+	}
+	if !cidURL.Match(html) {
+		return string(html), nil
+	}
+
+	parts, err := e.Parts()
+	if err != nil {
+		return "", err
+	}
+	byID := map[string]BodyPart{}
+	for _, part := range parts {
+		if id := contentID(part.ID); id != "" {
+			byID[id] = part
+		}
+	}
+
+	resolved := map[string]string{}
+	var resolveErr error
+	body := cidURL.ReplaceAllStringFunc(string(html), func(ref string) string {
+		id := ref[len("cid:"):]
+		if unescaped, err := url.PathUnescape(id); err == nil {
+			id = unescaped
+		}
+		part, ok := findPart(byID, id)
+		if !ok || resolveErr != nil {
+			return ref
+		}
+		if u, ok := resolved[part.ID]; ok {
+			return u
+		}
+		u, err := resolve(part)
+		if err != nil {
+			resolveErr = err
+			return ref
+		}
+		resolved[part.ID] = u
+		return u
+	})
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+	return body, nil
+}
+
+// contentID returns the Content-ID without its angle brackets.
+func contentID(id string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(id), "<"), ">")
+}
+
+// findPart returns the part with the Content-ID, matched case insensitively
+// if no part has it exactly, as some mail clients change the case.
+func findPart(byID map[string]BodyPart, id string) (BodyPart, bool) {
+	if part, ok := byID[id]; ok {
+		return part, true
+	}
+	for partID, part := range byID {
+		if strings.EqualFold(partID, id) {
+			return part, true
+		}
+	}
+	return BodyPart{}, false
+}
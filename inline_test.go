@@ -0,0 +1,63 @@
+package eazye
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+const relatedBody = "--rel\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n\r\n" +
+	`<p><img src="cid:logo@example.com"> <img src='CID:Logo@Example.com'> <td style="background: url(cid:bg%40example.com)"> <img src="cid:missing"></p>` + "\r\n" +
+	"--rel\r\nContent-Type: image/png\r\nContent-ID: <logo@example.com>\r\nContent-Transfer-Encoding: base64\r\n\r\niVBORw0KGgo=\r\n" +
+	"--rel\r\nContent-Type: image/gif\r\nContent-ID: <bg@example.com>\r\n\r\nGIF89a\r\n" +
+	"--rel--\r\n"
+
+func relatedEmail(t *testing.T) Email {
+	header := "Subject: hi\r\nMIME-Version: 1.0\r\nContent-Type: multipart/related; boundary=rel\r\n\r\n"
+	email, err := newEmail(imap.FieldMap{
+		"RFC822.HEADER": []byte(header),
+		"BODY[]":        []byte(header + relatedBody),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return email
+}
+
+func TestInlineHTML(t *testing.T) {
+	got, err := relatedEmail(t).InlineHTML()
+	if err != nil {
+		t.Fatalf("InlineHTML() returned an error: %s", err)
+	}
+
+	want := `<p><img src="data:image/png;base64,iVBORw0KGgo="> <img src='data:image/png;base64,iVBORw0KGgo='> <td style="background: url(data:image/gif;base64,R0lGODlh)"> <img src="cid:missing"></p>`
+	if got != want {
+		t.Errorf("InlineHTML() got %q, want %q", got, want)
+	}
+}
+
+func TestResolveCIDs(t *testing.T) {
+	calls := 0
+	got, err := relatedEmail(t).ResolveCIDs(func(part BodyPart) (string, error) {
+		calls++
+		return "files/" + part.Subtype, nil
+	})
+	if err != nil {
+		t.Fatalf("ResolveCIDs() returned an error: %s", err)
+	}
+
+	want := `<p><img src="files/png"> <img src='files/png'> <td style="background: url(files/gif)"> <img src="cid:missing"></p>`
+	if got != want {
+		t.Errorf("ResolveCIDs() got %q, want %q", got, want)
+	}
+	if calls != 2 {
+		t.Errorf("ResolveCIDs() called resolve %d times, want 2", calls)
+	}
+
+	failed := errors.New("disk full")
+	if _, err = relatedEmail(t).ResolveCIDs(func(BodyPart) (string, error) { return "", failed }); !errors.Is(err, failed) {
+		t.Errorf("ResolveCIDs() got error %v, want %v", err, failed)
+	}
+}
@@ -0,0 +1,74 @@
+package eazye
+
+import (
+	"context"
+	"iter"
+	"time"
+)
+
+// All returns an iterator over all emails in the folder. The emails are left
+// as they are. Breaking out of the loop stops fetching, there is no need to
+// drain anything.
+//
+//	for email, err := range client.All(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (c *Client) All(ctx context.Context) iter.Seq2[Email, error] {
+	return c.seq(ctx, All())
+}
+
+// Unread returns an iterator over all unread emails in the folder, see All.
+func (c *Client) Unread(ctx context.Context) iter.Seq2[Email, error] {
+	return c.seq(ctx, Unread())
+}
+
+// Since returns an iterator over all emails that have an internal date after
+// the given time, see All.
+func (c *Client) Since(ctx context.Context, since time.Time) iter.Seq2[Email, error] {
+	return c.seq(ctx, Since(since))
+}
+
+// Matching returns an iterator over all emails matching the query, see All.
+func (c *Client) Matching(ctx context.Context, q Query) iter.Seq2[Email, error] {
+	return c.seq(ctx, q)
+}
+
+// seq runs the query when the iterator is ranged over, cancelling the
+// generator once the loop is done so it does not leak.
+func (c *Client) seq(ctx context.Context, q Query) iter.Seq2[Email, error] {
+	return func(yield func(Email, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		responses, err := c.generateMail(ctx, q, false, false)
+		if err != nil {
+			yield(Email{}, err)
+			return
+		}
+		for email, err := range responseSeq(responses) {
+			if !yield(email, err) {
+				// wait for the generator to stop, so it does not run
+				// commands on the connection once the loop is done
+				cancel()
+				for range responses {
+				}
+				return
+			}
+		}
+	}
+}
+
+// responseSeq turns a responses channel into an iterator. Errors are yielded
+// with an empty Email.
+func responseSeq(responses chan Response) iter.Seq2[Email, error] {
+	return func(yield func(Email, error) bool) {
+		for resp := range responses {
+			if !yield(resp.Email, resp.Err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestResponseSeq(t *testing.T) {
+	errFailed := errors.New("failed")
+	responses := make(chan Response, 3)
+	responses <- Response{Email: Email{Subject: "one"}}
+	responses <- Response{Err: errFailed}
+	responses <- Response{Email: Email{Subject: "two"}}
+	close(responses)
+
+	var (
+		subjects []string
+		errs     []error
+	)
+	for email, err := range responseSeq(responses) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		subjects = append(subjects, email.Subject)
+	}
+	if len(subjects) != 2 || subjects[0] != "one" || subjects[1] != "two" {
+		t.Errorf("responseSeq() got subjects %q, want [one two]", subjects)
+	}
+	if len(errs) != 1 || errs[0] != errFailed {
+		t.Errorf("responseSeq() got errors %v, want [%s]", errs, errFailed)
+	}
+}
+
+func TestResponseSeqBreak(t *testing.T) {
+	responses := make(chan Response, 2)
+	responses <- Response{Email: Email{Subject: "one"}}
+	responses <- Response{Email: Email{Subject: "two"}}
+	close(responses)
+
+	count := 0
+	for range responseSeq(responses) {
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("responseSeq() yielded %d emails after break, want 1", count)
+	}
+}
+
+func TestSeqBreak(t *testing.T) {
+	srv := testServer(t)
+	for _, subject := range []string{"one", "two", "three"} {
+		srv.AddMessage("INBOX", []byte("Subject: "+subject+"\r\n\r\nx\r\n"))
+	}
+
+	// cancelling a command in flight drops the connection
+	c := testClient(t, srv, SetFetchBatchSize(1), SetBufferSize(1), SetRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	for email, err := range c.All(context.Background()) {
+		if err != nil || email.Subject != "one" {
+			t.Fatalf("All() got %q, %v, want one", email.Subject, err)
+		}
+		break
+	}
+
+	// the generator is done with the connection
+	srv.ResetCommands()
+	emails, err := c.GetAll(false, false)
+	if err != nil || len(emails) != 3 {
+		t.Errorf("GetAll() after a break got %d emails, %v, want 3", len(emails), err)
+	}
+	for _, cmd := range srv.Commands() {
+		if cmd == "UID SEARCH ALL" {
+			break
+		}
+		if strings.HasPrefix(cmd, "UID FETCH") {
+			t.Errorf("GetAll() after a break saw %q from the iterator", cmd)
+		}
+	}
+}
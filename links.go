@@ -0,0 +1,182 @@
+package eazye
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// TrackerDomains are the domains of known email trackers, see
+// DetectTrackingPixels. Images from their subdomains are from them too.
+var TrackerDomains = []string{
+	"list-manage.com",
+	"sendgrid.net",
+	"mandrillapp.com",
+	"mailgun.org",
+	"sparkpostmail.com",
+	"exacttarget.com",
+	"awstrack.me",
+	"pstmrk.it",
+	"hubspotemail.net",
+	"hubspotlinks.com",
+	"mailtrack.io",
+	"mixmax.com",
+	"yesware.com",
+	"mailstat.us",
+	"bananatag.com",
+	"getnotify.com",
+	"emltrk.com",
+	"superhuman.com",
+	"customer.io",
+	"klclick.com",
+	"mlsend.com",
+	"google-analytics.com",
+}
+
+// TrackingPixel is an image in an HTML body that looks like it is there to
+// tell the sender the email was opened.
+type TrackingPixel struct {
+	Src string `json:"src"`
+	// Domain is the one of TrackerDomains the image is from, or "" if none.
+	Domain string `json:"domain,omitempty"`
+	// Tiny is set for images of at most 1x1 pixel and hidden ones.
+	Tiny bool `json:"tiny"`
+}
+
+// ExtractLinks returns the links of an HTML body, in order, with their
+// visible text. Anchors without an href are left out.
+func ExtractLinks(body io.Reader) ([]Link, error) {
+	doc, err := html.Parse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return links(doc), nil
+}
+
+// links returns the links under n, see ExtractLinks.
+func links(n *html.Node) []Link {
+	var links []Link
+	walkElements(n, func(n *html.Node) {
+		if n.Data != "a" {
+			return
+		}
+		if href := strings.TrimSpace(attr(n, "href")); href != "" {
+			links = append(links, Link{Text: strings.Join(strings.Fields(nodeText(n)), " "), Href: href})
+		}
+	})
+	return links
+}
+
+// DetectTrackingPixels returns the images of an HTML body that are tiny or
+// hidden, or come from one of TrackerDomains, in order.
+func DetectTrackingPixels(body io.Reader) ([]TrackingPixel, error) {
+	doc, err := html.Parse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return trackingPixels(doc), nil
+}
+
+// trackingPixels returns the tracking pixels under n, see
+// DetectTrackingPixels.
+func trackingPixels(n *html.Node) []TrackingPixel {
+	var pixels []TrackingPixel
+	walkElements(n, func(n *html.Node) {
+		if n.Data != "img" {
+			return
+		}
+		pixel := TrackingPixel{
+			Src:    strings.TrimSpace(attr(n, "src")),
+			Domain: trackerDomain(attr(n, "src")),
+			Tiny:   tinyImage(n),
+		}
+		if pixel.Src != "" && (pixel.Domain != "" || pixel.Tiny) {
+			pixels = append(pixels, pixel)
+		}
+	})
+	return pixels
+}
+
+// Links returns the links of the HTML body of the email, see ExtractLinks.
+func (e Email) Links() ([]Link, error) {
+	html, _, err := e.bodies()
+	if err != nil || len(html) == 0 {
+		return nil, err
+	}
+	return ExtractLinks(bytes.NewReader(html))
+}
+
+// TrackingPixels returns the tracking pixels of the HTML body of the email,
+// see DetectTrackingPixels.
+func (e Email) TrackingPixels() ([]TrackingPixel, error) {
+	html, _, err := e.bodies()
+	if err != nil || len(html) == 0 {
+		return nil, err
+	}
+	return DetectTrackingPixels(bytes.NewReader(html))
+}
+
+// walkElements calls fn for every element under n, skipping the tags
+// VisibleText skips.
+func walkElements(n *html.Node, fn func(n *html.Node)) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode {
+			if isNonVisible(child.Data) {
+				continue
+			}
+			fn(child)
+		}
+		walkElements(child, fn)
+	}
+}
+
+// trackerDomain returns the one of TrackerDomains the URL is on, or "".
+func trackerDomain(src string) string {
+	u, err := url.Parse(strings.TrimSpace(src))
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range TrackerDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return domain
+		}
+	}
+	return ""
+}
+
+// tinyImage tells whether the image is at most 1x1 pixel, by its attributes
+// or style, or hidden.
+func tinyImage(img *html.Node) bool {
+	width, height := attr(img, "width"), attr(img, "height")
+	style := map[string]string{}
+	for _, decl := range strings.Split(attr(img, "style"), ";") {
+		if key, val, ok := strings.Cut(decl, ":"); ok {
+			style[strings.ToLower(strings.TrimSpace(key))] = strings.ToLower(strings.TrimSpace(val))
+		}
+	}
+	if style["display"] == "none" || style["visibility"] == "hidden" {
+		return true
+	}
+	if w, ok := style["width"]; ok {
+		width = w
+	}
+	if h, ok := style["height"]; ok {
+		height = h
+	}
+	return atMostOnePixel(width) && atMostOnePixel(height)
+}
+
+// atMostOnePixel tells whether the size, like "1" or "1px", is 1 pixel or
+// less.
+func atMostOnePixel(size string) bool {
+	size = strings.TrimSuffix(strings.TrimSpace(size), "px")
+	n, err := strconv.ParseFloat(size, 64)
+	return err == nil && n <= 1
+}
@@ -0,0 +1,104 @@
+package eazye
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestExtractLinks(t *testing.T) {
+	body := `<p>Read <a href="https://example.com/post">the
+	post</a> or <a name="top">nothing</a> <a href=" mailto:bob@example.com ">mail <b>Bob</b></a></p>`
+
+	links, err := ExtractLinks(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("ExtractLinks() returned an error: %s", err)
+	}
+
+	want := []Link{
+		{Text: "the post", Href: "https://example.com/post"},
+		{Text: "mail Bob", Href: "mailto:bob@example.com"},
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("ExtractLinks() got %+v, want %+v", links, want)
+	}
+}
+
+func TestDetectTrackingPixels(t *testing.T) {
+	body := `<img src="https://example.com/logo.png" width="120" height="40">
+<img src="https://example.com/o.gif" width="1" height="1">
+<img src="https://example.com/h.gif" style="display: none">
+<img src="https://example.com/s.gif" style="width:0px;height:0px">
+<img src="https://abc.list-manage.com/track/open.php?u=1" width="10" height="10">
+<img width="1" height="1">`
+
+	pixels, err := DetectTrackingPixels(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("DetectTrackingPixels() returned an error: %s", err)
+	}
+
+	want := []TrackingPixel{
+		{Src: "https://example.com/o.gif", Tiny: true},
+		{Src: "https://example.com/h.gif", Tiny: true},
+		{Src: "https://example.com/s.gif", Tiny: true},
+		{Src: "https://abc.list-manage.com/track/open.php?u=1", Domain: "list-manage.com"},
+	}
+	if !reflect.DeepEqual(pixels, want) {
+		t.Errorf("DetectTrackingPixels() got %+v, want %+v", pixels, want)
+	}
+}
+
+func TestTinyImage(t *testing.T) {
+	tests := []struct {
+		width, height, style string
+		want                 bool
+	}{
+		{"1", "1", "", true},
+		{"1px", "0", "", true},
+		{"", "", "", false},
+		{"1", "", "", false},
+		{"600", "1", "", false},
+		{"600", "300", "WIDTH: 1px; height: 1px", true},
+		{"", "", "visibility:hidden", true},
+	}
+
+	for _, tt := range tests {
+		img := imgNode(tt.width, tt.height, tt.style)
+		if got := tinyImage(img); got != tt.want {
+			t.Errorf("tinyImage(%q, %q, %q) got %v, want %v", tt.width, tt.height, tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestTrackerDomain(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"https://ct.sendgrid.net/wf/open?upn=1", "sendgrid.net"},
+		{"https://SENDGRID.NET/o.gif", "sendgrid.net"},
+		{"https://notsendgrid.net/o.gif", ""},
+		{"https://example.com/sendgrid.net.gif", ""},
+		{"cid:logo", ""},
+	}
+
+	for _, tt := range tests {
+		if got := trackerDomain(tt.src); got != tt.want {
+			t.Errorf("trackerDomain(%q) got %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+// imgNode returns an img element with the given attributes, those that are
+// not "".
+func imgNode(width, height, style string) *html.Node {
+	img := &html.Node{Type: html.ElementNode, Data: "img"}
+	for _, a := range []html.Attribute{{Key: "width", Val: width}, {Key: "height", Val: height}, {Key: "style", Val: style}} {
+		if a.Val != "" {
+			img.Attr = append(img.Attr, a)
+		}
+	}
+	return img
+}
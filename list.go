@@ -0,0 +1,261 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrNoUnsubscribe is returned by Unsubscribe for lists that can only be
+// unsubscribed from by visiting a web page.
+var ErrNoUnsubscribe = errors.New("no way to unsubscribe without a browser")
+
+// MailingList is the mailing list metadata of an email, from its List-*
+// headers (RFC 2369, RFC 2919 and RFC 8058) and Precedence.
+type MailingList struct {
+	// ID is the List-Id without the angle brackets, e.g.
+	// "news.example.com".
+	ID string `json:"id,omitempty"`
+	// Name is the description of the list in the List-Id, if any.
+	Name string `json:"name,omitempty"`
+	// UnsubscribeURLs holds the List-Unsubscribe URLs, mailto: and https: ones,
+	// in the order of preference of the sender.
+	UnsubscribeURLs []string `json:"unsubscribe_urls,omitempty"`
+	// OneClick is set if List-Unsubscribe-Post asks for one-click
+	// unsubscription (RFC 8058): a POST to the https: URL unsubscribes,
+	// without anything to confirm. As RFC 8058 requires, it is only set if
+	// a DKIM signature covering List-Unsubscribe and List-Unsubscribe-Post
+	// passed, according to a trusted authserv-id, see MailingList.
+	OneClick bool `json:"one_click"`
+	// Post is the List-Post URL, "" if the list takes no posts.
+	Post string `json:"post,omitempty"`
+	// Help and Archive are the List-Help and List-Archive URLs.
+	Help    string `json:"help,omitempty"`
+	Archive string `json:"archive,omitempty"`
+	// Precedence is the Precedence header, e.g. "list" or "bulk".
+	Precedence string `json:"precedence,omitempty"`
+}
+
+// MailingList returns the mailing list metadata of the email, and whether
+// it has any: a List-Id or List-Unsubscribe header, or a Precedence of
+// "list" or "bulk". The DKIM results of the Authentication-Results of the
+// trusted authserv-ids tell whether one-click unsubscription can be used,
+// without any it never is.
+func (e Email) MailingList(trusted ...string) (MailingList, bool) {
+	if e.Message == nil {
+		return MailingList{}, false
+	}
+	header := e.Message.Header
+
+	list := MailingList{
+		UnsubscribeURLs: listURLs(header.Get("List-Unsubscribe")),
+		Precedence:      strings.ToLower(strings.TrimSpace(header.Get("Precedence"))),
+	}
+	list.ID, list.Name = parseListID(header.Get("List-Id"))
+	list.OneClick = strings.EqualFold(strings.TrimSpace(header.Get("List-Unsubscribe-Post")), "List-Unsubscribe=One-Click") &&
+		e.signedUnsubscribe(trusted)
+	if post := listURLs(header.Get("List-Post")); len(post) > 0 {
+		list.Post = post[0]
+	}
+	if help := listURLs(header.Get("List-Help")); len(help) > 0 {
+		list.Help = help[0]
+	}
+	if archive := listURLs(header.Get("List-Archive")); len(archive) > 0 {
+		list.Archive = archive[0]
+	}
+
+	ok := list.ID != "" || len(list.UnsubscribeURLs) > 0 || list.Precedence == "list" || list.Precedence == "bulk"
+	return list, ok
+}
+
+// Unsubscribe unsubscribes from the list the way that needs no one to
+// confirm it: with a one-click POST to its https: URL if the list allows, or
+// else by sending an email to its mailto: URL from from with sender. The
+// POST never follows redirects. A nil client refuses to connect to loopback
+// and private addresses, a given one is trusted to. A nil sender or from
+// rules out emails. Lists that only have a web page to unsubscribe on return
+// ErrNoUnsubscribe, their URLs are in UnsubscribeURLs.
+func (l MailingList) Unsubscribe(ctx context.Context, client *http.Client, sender Sender, from *mail.Address) error {
+	if l.OneClick {
+		for _, u := range l.UnsubscribeURLs {
+			if strings.HasPrefix(strings.ToLower(u), "https:") {
+				return oneClickUnsubscribe(ctx, client, u)
+			}
+		}
+	}
+	if sender != nil && from != nil {
+		for _, u := range l.UnsubscribeURLs {
+			if strings.HasPrefix(strings.ToLower(u), "mailto:") {
+				return mailtoUnsubscribe(sender, from, u)
+			}
+		}
+	}
+	return ErrNoUnsubscribe
+}
+
+// oneClickUnsubscribe sends the POST of RFC 8058 to the URL.
+func oneClickUnsubscribe(ctx context.Context, client *http.Client, target string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader("List-Unsubscribe=One-Click"))
+	if err != nil {
+		return fmt.Errorf("unable to unsubscribe: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if client == nil {
+		client = unsubscribeClient
+	}
+	// the URL comes from the email, it is not to send us anywhere else
+	noRedirects := *client
+	noRedirects.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	resp, err := noRedirects.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to unsubscribe: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unable to unsubscribe: server said %s", resp.Status)
+	}
+	return nil
+}
+
+// unsubscribeClient is the http.Client of one-click unsubscription, which
+// refuses to connect to the addresses of the local network. It goes without
+// a proxy, as only the address of the proxy would be checked then.
+var unsubscribeClient = &http.Client{
+	Timeout: time.Minute,
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 30 * time.Second,
+			Control: refusePrivate,
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+// refusePrivate is a net.Dialer Control refusing loopback, private, link
+// local and unspecified addresses, checked once the host is resolved.
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("refusing to connect to %s", host)
+	}
+	return nil
+}
+
+// signedUnsubscribe tells whether a DKIM signature that passed, according to
+// the Authentication-Results of the trusted authserv-ids, covers
+// List-Unsubscribe and List-Unsubscribe-Post.
+func (e Email) signedUnsubscribe(trusted []string) bool {
+	if len(trusted) == 0 {
+		return false
+	}
+	var passed []AuthResult
+	for _, results := range e.AuthenticationResults() {
+		if !containsFold(trusted, results.ServID) {
+			continue
+		}
+		for _, result := range results.Results {
+			if result.Method == "dkim" && result.Result == "pass" {
+				passed = append(passed, result)
+			}
+		}
+	}
+
+	for _, value := range e.Message.Header["Dkim-Signature"] {
+		tags := parseTagList(value)
+		signed := strings.Split(strings.ToLower(tags["h"]), ":")
+		if !containsFold(signed, "list-unsubscribe") || !containsFold(signed, "list-unsubscribe-post") {
+			continue
+		}
+		for _, result := range passed {
+			// header.b, if there, tells apart signatures of the same domain
+			if strings.EqualFold(dkimDomain(result), tags["d"]) && strings.HasPrefix(tags["b"], result.Props["header.b"]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mailtoUnsubscribe sends the email a mailto: URL (RFC 6068) describes to its
+// first recipient, with "unsubscribe" as the subject if it has none. The
+// body is always "unsubscribe", whatever the URL asks for.
+func mailtoUnsubscribe(sender Sender, from *mail.Address, target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("unable to parse %s: %w", target, err)
+	}
+	addr, err := url.PathUnescape(u.Opaque)
+	if err != nil {
+		return fmt.Errorf("unable to parse %s: %w", target, err)
+	}
+	to, err := mail.ParseAddressList(addr)
+	if err != nil {
+		return fmt.Errorf("unable to parse %s: %w", target, err)
+	}
+
+	query := u.Query()
+	subject := query.Get("subject")
+	if subject == "" {
+		subject = "unsubscribe"
+	}
+	id, err := newMessageID(from)
+	if err != nil {
+		return err
+	}
+
+	msg := Reply{From: from, To: to[:1], Subject: subject, MessageID: id, Body: "unsubscribe"}
+	if err = msg.Send(sender); err != nil {
+		return fmt.Errorf("unable to unsubscribe: %w", err)
+	}
+	return nil
+}
+
+// listURLs returns the URLs of a List-* header, those in angle brackets
+// before any comments. A value of "NO" has none.
+func listURLs(value string) []string {
+	var urls []string
+	for {
+		start := strings.IndexByte(value, '<')
+		if start < 0 {
+			return urls
+		}
+		end := strings.IndexByte(value[start:], '>')
+		if end < 0 {
+			return urls
+		}
+		// URLs can be folded over several lines
+		if u := strings.Join(strings.Fields(value[start+1:start+end]), ""); u != "" {
+			urls = append(urls, u)
+		}
+		value = value[start+end+1:]
+	}
+}
+
+// parseListID splits a List-Id like `"News" <news.example.com>` into the ID
+// and description.
+func parseListID(value string) (id, name string) {
+	start, end := strings.LastIndexByte(value, '<'), strings.LastIndexByte(value, '>')
+	if start < 0 || end < start {
+		return strings.TrimSpace(value), ""
+	}
+	name = strings.Trim(strings.TrimSpace(value[:start]), `"`)
+	return strings.TrimSpace(value[start+1 : end]), DecodeHeader(name)
+}
@@ -0,0 +1,138 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func listEmail(t *testing.T, header string) Email {
+	return rawEmail(t, header+"Subject: news\r\n\r\n")
+}
+
+func TestMailingList(t *testing.T) {
+	email := listEmail(t, "List-Id: \"Weekly =?utf-8?q?n=C3=A9ws?=\" <weekly.news.example.com>\r\n"+
+		"List-Unsubscribe: <mailto:leave@news.example.com?subject=stop>,\r\n <https://news.example.com/u\r\n ?id=1> (web)\r\n"+
+		"List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n"+
+		"List-Post: NO (posting not allowed)\r\n"+
+		"List-Archive: <https://news.example.com/archive>\r\n"+
+		"Precedence: Bulk\r\n"+
+		"Authentication-Results: mx.example.com; dkim=pass header.d=news.example.com header.b=abcd\r\n"+
+		"DKIM-Signature: v=1; d=news.example.com; h=From:List-Unsubscribe:\r\n List-Unsubscribe-Post; b=abcdef\r\n")
+
+	list, ok := email.MailingList("mx.example.com")
+	if !ok {
+		t.Fatal("MailingList() got false, want true")
+	}
+	want := MailingList{
+		ID:              "weekly.news.example.com",
+		Name:            "Weekly néws",
+		UnsubscribeURLs: []string{"mailto:leave@news.example.com?subject=stop", "https://news.example.com/u?id=1"},
+		OneClick:        true,
+		Archive:         "https://news.example.com/archive",
+		Precedence:      "bulk",
+	}
+	if !reflect.DeepEqual(list, want) {
+		t.Errorf("MailingList() got %+v, want %+v", list, want)
+	}
+
+	if _, ok = listEmail(t, "From: bob@example.com\r\n").MailingList(); ok {
+		t.Error("MailingList() got true for a personal email, want false")
+	}
+}
+
+func TestMailingListOneClickSigned(t *testing.T) {
+	const unsubscribe = "List-Unsubscribe: <https://news.example.com/u>\r\nList-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n"
+	const signed = "DKIM-Signature: d=news.example.com; h=from:list-unsubscribe:list-unsubscribe-post; b=abcd\r\n"
+	tests := []struct {
+		name    string
+		header  string
+		trusted []string
+		want    bool
+	}{
+		{"signed", signed + "Authentication-Results: mx.example.com; dkim=pass header.d=news.example.com\r\n", []string{"mx.example.com"}, true},
+		{"no trusted authserv-id", signed + "Authentication-Results: mx.example.com; dkim=pass header.d=news.example.com\r\n", nil, false},
+		{"untrusted authserv-id", signed + "Authentication-Results: evil.example.com; dkim=pass header.d=news.example.com\r\n", []string{"mx.example.com"}, false},
+		{"failed", signed + "Authentication-Results: mx.example.com; dkim=fail header.d=news.example.com\r\n", []string{"mx.example.com"}, false},
+		{"other domain", signed + "Authentication-Results: mx.example.com; dkim=pass header.d=evil.example.com\r\n", []string{"mx.example.com"}, false},
+		{"other signature", signed + "Authentication-Results: mx.example.com; dkim=pass header.d=news.example.com header.b=wxyz\r\n", []string{"mx.example.com"}, false},
+		{"not covered", "DKIM-Signature: d=news.example.com; h=from:list-unsubscribe; b=abcd\r\nAuthentication-Results: mx.example.com; dkim=pass header.d=news.example.com\r\n", []string{"mx.example.com"}, false},
+	}
+
+	for _, tt := range tests {
+		list, _ := listEmail(t, unsubscribe+tt.header).MailingList(tt.trusted...)
+		if list.OneClick != tt.want {
+			t.Errorf("%s: MailingList() got OneClick %t, want %t", tt.name, list.OneClick, tt.want)
+		}
+	}
+}
+
+func TestUnsubscribeOneClick(t *testing.T) {
+	var got string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = r.Method + " " + r.URL.RequestURI() + " " + string(body)
+	}))
+	defer server.Close()
+
+	list := MailingList{UnsubscribeURLs: []string{"mailto:leave@example.com", server.URL + "/u?id=1"}, OneClick: true}
+	if err := list.Unsubscribe(context.Background(), server.Client(), nil, nil); err != nil {
+		t.Fatalf("Unsubscribe() returned an error: %s", err)
+	}
+	if want := "POST /u?id=1 List-Unsubscribe=One-Click"; got != want {
+		t.Errorf("Unsubscribe() sent %q, want %q", got, want)
+	}
+}
+
+func TestUnsubscribeOneClickRefused(t *testing.T) {
+	redirect := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://169.254.169.254/latest", http.StatusTemporaryRedirect)
+	}))
+	defer redirect.Close()
+
+	list := MailingList{UnsubscribeURLs: []string{redirect.URL}, OneClick: true}
+	if err := list.Unsubscribe(context.Background(), redirect.Client(), nil, nil); err == nil || !strings.Contains(err.Error(), "307") {
+		t.Errorf("Unsubscribe() with a redirect got %v, want the redirect refused", err)
+	}
+	if err := list.Unsubscribe(context.Background(), nil, nil, nil); err == nil || !strings.Contains(err.Error(), "refusing to connect") {
+		t.Errorf("Unsubscribe() to a loopback address got %v, want it refused", err)
+	}
+}
+
+func TestUnsubscribeOneClickNoProxy(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "http://127.0.0.1:3128")
+	if unsubscribeClient.Transport.(*http.Transport).Proxy != nil {
+		t.Errorf("unsubscribeClient uses a proxy, want the addresses checked directly")
+	}
+
+	list := MailingList{UnsubscribeURLs: []string{"https://10.0.0.1/u"}, OneClick: true}
+	err := list.Unsubscribe(context.Background(), nil, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "refusing to connect") || strings.Contains(err.Error(), "proxy") {
+		t.Errorf("Unsubscribe() to a private address with HTTPS_PROXY set got %v, want it refused", err)
+	}
+}
+
+func TestUnsubscribeMailto(t *testing.T) {
+	sender := &recordingSender{}
+	from := &mail.Address{Address: "me@example.com"}
+	list := MailingList{UnsubscribeURLs: []string{"https://example.com/u", "mailto:leave%2B1@example.com,boss@example.com?subject=stop%20it&body=I%20quit&cc=hr@example.com"}}
+
+	if err := list.Unsubscribe(context.Background(), nil, sender, from); err != nil {
+		t.Fatalf("Unsubscribe() returned an error: %s", err)
+	}
+	if sender.from != "me@example.com" || !reflect.DeepEqual(sender.to, []string{"leave+1@example.com"}) || !strings.Contains(string(sender.msg), "Subject: stop it\r\n") ||
+		!strings.HasSuffix(string(sender.msg), "\r\n\r\nunsubscribe") {
+		t.Errorf("Unsubscribe() sent %s to %v: %q", sender.from, sender.to, sender.msg)
+	}
+
+	list = MailingList{UnsubscribeURLs: []string{"https://example.com/u"}}
+	if err := list.Unsubscribe(context.Background(), nil, sender, from); !errors.Is(err, ErrNoUnsubscribe) {
+		t.Errorf("Unsubscribe() got error %v, want %v", err, ErrNoUnsubscribe)
+	}
+}
@@ -0,0 +1,168 @@
+package eazye
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// SetLogger is a functional option to set the Logger attr.
+func SetLogger(logger *slog.Logger) Option {
+	return func(c *Client) {
+		c.Logger = logger
+	}
+}
+
+// SetWireLog is a functional option to set the WireLog attr.
+func SetWireLog(wireLog bool) Option {
+	return func(c *Client) {
+		c.WireLog = wireLog
+	}
+}
+
+// log logs the message with the Client's Logger, if it has one.
+func (c *Client) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if c.Logger == nil {
+		return
+	}
+	c.Logger.Log(ctx, level, msg, append([]any{"host", c.host, "folder", c.Folder}, args...)...)
+}
+
+// redacted replaces credentials in the wire log.
+const redacted = "[redacted]"
+
+// wireConn is a connection that logs every line sent and received at debug
+// level. The password of LOGIN and everything sent during AUTHENTICATE is
+// redacted, and literals are left out, so message bodies do not flood the log.
+type wireConn struct {
+	net.Conn
+	client wireLog
+	server wireLog
+	// authenticating is set between AUTHENTICATE and its tagged response
+	authenticating atomic.Bool
+	// loginLiteral is set while the rest of LOGIN follows a literal
+	loginLiteral bool
+}
+
+func newWireConn(conn net.Conn, logger *slog.Logger) *wireConn {
+	c := &wireConn{Conn: conn}
+	c.client = wireLog{logger: logger, prefix: "C: ", redact: c.redactCommand}
+	c.server = wireLog{logger: logger, prefix: "S: ", redact: c.watchResponse}
+	return c
+}
+
+func (c *wireConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.server.write(p[:n])
+	return n, err
+}
+
+func (c *wireConn) Write(p []byte) (int, error) {
+	c.client.write(p)
+	return c.Conn.Write(p)
+}
+
+// redactCommand hides the credentials in a line sent to the server.
+func (c *wireConn) redactCommand(line string) string {
+	if c.authenticating.Load() && line != "" {
+		return redacted
+	}
+	if c.loginLiteral {
+		c.loginLiteral = literalSize(line) > 0
+		if line == "" {
+			return ""
+		}
+		return redacted
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return line
+	}
+	switch strings.ToUpper(fields[1]) {
+	case "LOGIN":
+		c.loginLiteral = literalSize(line) > 0
+		if len(fields) > 3 {
+			return strings.Join(fields[:3], " ") + " " + redacted
+		}
+	case "AUTHENTICATE":
+		c.authenticating.Store(true)
+		if len(fields) > 3 {
+			return strings.Join(fields[:3], " ") + " " + redacted
+		}
+	}
+	return line
+}
+
+// watchResponse notices the end of AUTHENTICATE in a line sent by the server.
+func (c *wireConn) watchResponse(line string) string {
+	if !strings.HasPrefix(line, "*") && !strings.HasPrefix(line, "+") {
+		c.authenticating.Store(false)
+	}
+	return line
+}
+
+// wireLog splits what is sent in one direction into lines and logs them.
+type wireLog struct {
+	logger *slog.Logger
+	prefix string
+	redact func(string) string
+
+	mu      sync.Mutex
+	line    []byte
+	literal int
+}
+
+func (w *wireLog) write(p []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for len(p) > 0 {
+		if w.literal > 0 {
+			n := min(w.literal, len(p))
+			w.literal -= n
+			p = p[n:]
+			continue
+		}
+
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.line = append(w.line, p...)
+			return
+		}
+		w.line = append(w.line, p[:i]...)
+		p = p[i+1:]
+
+		line := strings.TrimSuffix(string(w.line), "\r")
+		w.line = w.line[:0]
+		w.literal = literalSize(line)
+		line = w.redact(line)
+		if line == "" {
+			// what is left of a line after a literal
+			continue
+		}
+		w.logger.Debug(w.prefix + line)
+	}
+}
+
+// literalSize returns the size of the literal announced at the end of the
+// line, e.g. {42} or {42+}, or 0 if there is none.
+func literalSize(line string) int {
+	if !strings.HasSuffix(line, "}") {
+		return 0
+	}
+	i := strings.LastIndexByte(line, '{')
+	if i < 0 {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(line[i+1:len(line)-1], "+"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
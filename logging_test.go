@@ -0,0 +1,70 @@
+package eazye
+
+import (
+	"bytes"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+)
+
+// scriptedConn reads the server's replies from a buffer and throws away
+// whatever is written to it.
+type scriptedConn struct {
+	net.Conn
+	replies *strings.Reader
+}
+
+func (c *scriptedConn) Read(p []byte) (int, error)  { return c.replies.Read(p) }
+func (c *scriptedConn) Write(p []byte) (int, error) { return len(p), nil }
+
+func TestWireConn(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	scripted := &scriptedConn{}
+	conn := newWireConn(scripted, logger)
+
+	exchange := []struct{ command, reply string }{
+		{"a1 LOGIN alice s3cret\r\n", "a1 OK\r\n"},
+		{"a2 LOGIN {5}\r\nalice {6}\r\ns3cret\r\n", "a2 OK\r\n"},
+		{"a3 AUTHENTICATE XOAUTH2\r\n", "+ \r\n"},
+		{"dXNlcj1hbGljZQ==\r\n", "a3 OK\r\n"},
+		{"a4 UID FETCH 1 (BODY[])\r\n", "* 1 FETCH (UID 1 BODY[] {14}\r\nSubject: hi\r\n)\r\n"},
+	}
+	readBuf := make([]byte, 1024)
+	for _, e := range exchange {
+		conn.Write([]byte(e.command))
+		scripted.replies = strings.NewReader(e.reply)
+		conn.Read(readBuf)
+	}
+
+	log := buf.String()
+	if strings.Contains(log, "s3cret") || strings.Contains(log, "dXNlcj1hbGljZQ") {
+		t.Errorf("wire log leaks credentials:\n%s", log)
+	}
+	if strings.Contains(log, "Subject: hi") {
+		t.Errorf("wire log has the literal in it:\n%s", log)
+	}
+	for _, want := range []string{`"C: a1 LOGIN alice [redacted]"`, `"C: a4 UID FETCH 1 (BODY[])"`, `"S: * 1 FETCH (UID 1 BODY[] {14}"`} {
+		if !strings.Contains(log, want) {
+			t.Errorf("wire log is missing %s:\n%s", want, log)
+		}
+	}
+}
+
+func TestLiteralSize(t *testing.T) {
+	tests := []struct {
+		line string
+		want int
+	}{
+		{"a LOGIN {5}", 5},
+		{"a APPEND INBOX {310+}", 310},
+		{"* 1 FETCH (UID 1)", 0},
+		{"a SEARCH SUBJECT {x}", 0},
+	}
+	for _, test := range tests {
+		if got := literalSize(test.line); got != test.want {
+			t.Errorf("literalSize(%q) got %d, want %d", test.line, got, test.want)
+		}
+	}
+}
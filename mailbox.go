@@ -0,0 +1,43 @@
+package eazye
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// Mailbox is what an application needs to read a mailbox, whatever the
+// protocol behind it. Client, POP3, Graph and GmailAPI are all Mailboxes.
+type Mailbox interface {
+	GetAll(markAsRead, delete bool) ([]Email, error)
+	GetUnread(markAsRead, delete bool) ([]Email, error)
+	GetSince(since time.Time, markAsRead, delete bool) ([]Email, error)
+	DeleteEmail(email Email) error
+	SetAsRead(email Email) error
+	Close() error
+}
+
+var (
+	_ Mailbox = (*Client)(nil)
+	_ Mailbox = (*POP3)(nil)
+	_ Mailbox = (*Graph)(nil)
+	_ Mailbox = (*GmailAPI)(nil)
+)
+
+// parseRaw parses a full message fetched some other way than over IMAP into
+// an Email with the given ID.
+func parseRaw(id imap.Field, raw []byte) (Email, error) {
+	header := raw
+	if i := bytes.Index(raw, []byte("\n\n")); i >= 0 {
+		header = raw[:i+1]
+	}
+	if i := bytes.Index(raw, []byte("\r\n\r\n")); i >= 0 && i < len(header) {
+		header = raw[:i+2]
+	}
+	return newEmail(imap.FieldMap{
+		"UID":           id,
+		"RFC822.HEADER": header,
+		"BODY[]":        raw,
+	})
+}
@@ -0,0 +1,392 @@
+package eazye
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// blockTags are the elements rendered as blocks of their own.
+var blockTags = map[string]bool{
+	"address": true, "article": true, "aside": true, "center": true, "dd": true, "div": true,
+	"dl": true, "dt": true, "fieldset": true, "figure": true, "footer": true, "form": true,
+	"header": true, "main": true, "nav": true, "p": true, "section": true,
+	// the tables emails are laid out with
+	"table": true, "td": true, "th": true, "tr": true,
+}
+
+// markdownEscaper escapes the characters that would otherwise be taken for
+// Markdown syntax in text.
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`)
+
+// HTMLToMarkdown converts an HTML body to Markdown, keeping the links,
+// emphasis, lists, headings, quotes and code of the visible text. Tables with
+// header cells become Markdown tables, other tables are taken to be there
+// for the layout and their cells become paragraphs. Images are only kept if
+// they have alt text, which leaves tracking pixels out.
+func HTMLToMarkdown(body io.Reader) (string, error) {
+	doc, err := html.Parse(body)
+	if err != nil {
+		return "", err
+	}
+	w := &markdownWriter{}
+	w.children(doc)
+	return strings.TrimSpace(w.out.String()) + "\n", nil
+}
+
+// Markdown returns the HTML body of the email converted to Markdown, see
+// HTMLToMarkdown, or the plain text body as is if it has no HTML body.
+func (e Email) Markdown() (string, error) {
+	html, text, err := e.bodies()
+	if err != nil {
+		return "", err
+	}
+	if len(html) == 0 {
+		return string(text), nil
+	}
+	return HTMLToMarkdown(bytes.NewReader(html))
+}
+
+// markdownWriter writes Markdown, putting the prefixes of the quotes and
+// lists it is in at the start of every line.
+type markdownWriter struct {
+	out strings.Builder
+	// prefix is put at the start of every line, e.g. "> " in a quote
+	prefix []string
+	// breaks is the number of line breaks to write before the next text,
+	// breakPrefix is the prefix when they were asked for
+	breaks      int
+	breakPrefix string
+	// marker is the marker of the list item about to start, written on the
+	// line of its first text after markerPrefix
+	marker       string
+	markerPrefix string
+	// lineStart is set at the start of a line, where spaces are dropped
+	lineStart bool
+	// spaced is set if the last text ended with a space
+	spaced bool
+	// lists holds the number of the next item of the lists the writer is
+	// in, 0 for unordered ones
+	lists []int
+}
+
+// lineBreak makes sure there are at least n line breaks before the next text,
+// 2 for a new block. The breaks between a list marker and the first text of
+// the item are dropped.
+func (w *markdownWriter) lineBreak(n int) {
+	if w.marker != "" {
+		return
+	}
+	if w.breaks == 0 {
+		w.breakPrefix = strings.Join(w.prefix, "")
+	}
+	w.breaks = max(w.breaks, n)
+}
+
+// flush writes the pending line breaks and list marker. The blank lines get
+// the part of the prefix that is the same before and after them, so that
+// they end quotes and lists.
+func (w *markdownWriter) flush() {
+	prefix := strings.Join(w.prefix, "")
+	if w.marker != "" {
+		prefix = w.markerPrefix + w.marker
+	}
+	if w.out.Len() == 0 {
+		w.breaks = 1
+	}
+	blank := strings.TrimRight(commonPrefix(w.breakPrefix, prefix), " ")
+	for i := 0; i < w.breaks; i++ {
+		if w.out.Len() > 0 {
+			w.out.WriteString("\n")
+		}
+		if i < w.breaks-1 {
+			w.out.WriteString(blank)
+		} else {
+			w.out.WriteString(prefix)
+		}
+		w.lineStart = true
+	}
+	w.breaks, w.marker = 0, ""
+}
+
+// text writes inline text, dropping the spaces at the start of a line and
+// those following another.
+func (w *markdownWriter) text(s string) {
+	if w.out.Len() == 0 || w.lineStart || w.breaks > 0 || w.spaced {
+		s = strings.TrimLeft(s, " ")
+	}
+	if s == "" {
+		return
+	}
+	w.flush()
+	if w.lineStart {
+		if s = strings.TrimLeft(s, " "); s == "" {
+			return
+		}
+	}
+	w.out.WriteString(s)
+	w.lineStart = false
+	w.spaced = strings.HasSuffix(s, " ")
+}
+
+// raw writes text as is, with the prefix after every line break in it.
+func (w *markdownWriter) raw(s string) {
+	w.flush()
+	w.out.WriteString(strings.ReplaceAll(s, "\n", "\n"+strings.Join(w.prefix, "")))
+	w.lineStart, w.spaced = false, false
+}
+
+func (w *markdownWriter) children(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		w.node(child)
+	}
+}
+
+func (w *markdownWriter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text(markdownEscaper.Replace(collapseSpace(n.Data)))
+		return
+	case html.ElementNode:
+	default:
+		w.children(n)
+		return
+	}
+	if isNonVisible(n.Data) {
+		return
+	}
+
+	switch tag := n.Data; tag {
+	case "br":
+		w.lineBreak(1)
+	case "hr":
+		w.lineBreak(2)
+		w.raw("---")
+		w.lineBreak(2)
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		if text := inlineMarkdown(n); text != "" {
+			w.lineBreak(2)
+			w.raw(strings.Repeat("#", int(tag[1]-'0')) + " " + text)
+			w.lineBreak(2)
+		}
+	case "b", "strong":
+		w.emphasis(n, "**")
+	case "i", "em":
+		w.emphasis(n, "*")
+	case "s", "strike", "del":
+		w.emphasis(n, "~~")
+	case "code", "tt":
+		if code := collapseSpace(nodeText(n)); strings.TrimSpace(code) != "" {
+			w.text("`" + strings.TrimSpace(code) + "`")
+		}
+	case "pre":
+		w.lineBreak(2)
+		w.raw("```\n" + strings.Trim(nodeText(n), "\n") + "\n```")
+		w.lineBreak(2)
+	case "a":
+		w.link(n)
+	case "img":
+		alt, src := attr(n, "alt"), attr(n, "src")
+		if strings.TrimSpace(alt) != "" && (strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://")) {
+			w.text(fmt.Sprintf("![%s](%s)", markdownEscaper.Replace(strings.TrimSpace(alt)), src))
+		}
+	case "blockquote":
+		w.lineBreak(2)
+		w.prefix = append(w.prefix, "> ")
+		w.children(n)
+		w.prefix = w.prefix[:len(w.prefix)-1]
+		w.lineBreak(2)
+	case "ul", "ol":
+		w.list(n, tag == "ol")
+	case "li":
+		w.item(n)
+	case "table":
+		if rows := tableRows(n); hasHeader(n) && len(rows) > 0 {
+			w.lineBreak(2)
+			w.raw(markdownTable(rows))
+			w.lineBreak(2)
+			return
+		}
+		w.block(n)
+	default:
+		if blockTags[tag] {
+			w.block(n)
+			return
+		}
+		w.children(n)
+	}
+}
+
+// block writes the children of n as a block of their own.
+func (w *markdownWriter) block(n *html.Node) {
+	w.lineBreak(2)
+	w.children(n)
+	w.lineBreak(2)
+}
+
+// emphasis writes the text of n between the markers, leaving the spaces
+// around it outside of them.
+func (w *markdownWriter) emphasis(n *html.Node, marker string) {
+	text := inlineMarkdown(n)
+	if text == "" {
+		return
+	}
+	inner := collapseSpace(nodeText(n))
+	if strings.HasPrefix(inner, " ") {
+		w.text(" ")
+	}
+	w.text(marker + text + marker)
+	if strings.HasSuffix(inner, " ") {
+		w.text(" ")
+	}
+}
+
+// link writes a link with the text of n, or just the text if it goes nowhere.
+func (w *markdownWriter) link(n *html.Node) {
+	text, href := inlineMarkdown(n), strings.TrimSpace(attr(n, "href"))
+	switch {
+	case href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:"):
+		w.text(text)
+	case text == "" || text == markdownEscaper.Replace(href):
+		w.text("<" + href + ">")
+	default:
+		w.text("[" + text + "](" + strings.ReplaceAll(href, " ", "%20") + ")")
+	}
+}
+
+func (w *markdownWriter) list(n *html.Node, ordered bool) {
+	if len(w.lists) > 0 {
+		w.lineBreak(1)
+	} else {
+		w.lineBreak(2)
+	}
+	next := 0
+	if ordered {
+		next = 1
+	}
+	w.lists = append(w.lists, next)
+	w.children(n)
+	w.lists = w.lists[:len(w.lists)-1]
+	w.lineBreak(2)
+}
+
+func (w *markdownWriter) item(n *html.Node) {
+	marker := "- "
+	if len(w.lists) > 0 && w.lists[len(w.lists)-1] > 0 {
+		marker = fmt.Sprintf("%d. ", w.lists[len(w.lists)-1])
+		w.lists[len(w.lists)-1]++
+	}
+	w.lineBreak(1)
+	w.marker, w.markerPrefix = marker, strings.Join(w.prefix, "")
+	w.prefix = append(w.prefix, strings.Repeat(" ", len(marker)))
+	w.children(n)
+	w.prefix = w.prefix[:len(w.prefix)-1]
+	w.marker = ""
+	w.lineBreak(1)
+}
+
+// inlineMarkdown returns the Markdown of the children of n on one line.
+func inlineMarkdown(n *html.Node) string {
+	w := &markdownWriter{}
+	w.children(n)
+	return strings.Join(strings.Fields(w.out.String()), " ")
+}
+
+// markdownTable renders the rows as a Markdown table, the first as the
+// header.
+func markdownTable(rows [][]string) string {
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	line := func(cells []string) string {
+		escaped := make([]string, width)
+		for i := range escaped {
+			if i < len(cells) {
+				escaped[i] = strings.ReplaceAll(markdownEscaper.Replace(cells[i]), "|", `\|`)
+			}
+		}
+		return "| " + strings.Join(escaped, " | ") + " |"
+	}
+
+	lines := []string{line(rows[0]), "|" + strings.Repeat(" --- |", width)}
+	for _, row := range rows[1:] {
+		lines = append(lines, line(row))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// hasHeader tells whether the table has header cells of its own.
+func hasHeader(table *html.Node) bool {
+	var found bool
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil && !found; child = child.NextSibling {
+			switch {
+			case isElement(child, "table"):
+				continue
+			case isElement(child, "th"), isElement(child, "thead"):
+				found = true
+			default:
+				walk(child)
+			}
+		}
+	}
+	walk(table)
+	return found
+}
+
+// commonPrefix returns the longest prefix a and b share.
+func commonPrefix(a, b string) string {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return a[:i]
+}
+
+// collapseSpace replaces every run of whitespace with a single space.
+func collapseSpace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// nodeText returns all of the text in n as is.
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(nodeText(child))
+	}
+	return b.String()
+}
+
+// attr returns the value of the attribute of n, or "" if it has none.
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
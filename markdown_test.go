@@ -0,0 +1,63 @@
+package eazye
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "inline",
+			body: `<p>Hello <b>Bob</b>, see <a href="https://example.com/a">the  docs</a> and <em>1*2</em>.</p>`,
+			want: "Hello **Bob**, see [the docs](https://example.com/a) and *1\\*2*.\n",
+		},
+		{
+			name: "headings and paragraphs",
+			body: `<h1>Title</h1><p>One</p><div>Two<br>Three</div><hr><h3>End</h3>`,
+			want: "# Title\n\nOne\n\nTwo\nThree\n\n---\n\n### End\n",
+		},
+		{
+			name: "lists",
+			body: `<ul><li>a</li><li>b<ol><li>c</li><li>d</li></ol></li></ul>`,
+			want: "- a\n- b\n  1. c\n  2. d\n",
+		},
+		{
+			name: "quote and code",
+			body: `<blockquote><p>said</p><p>this</p></blockquote><pre>x := 1
+y := 2</pre>`,
+			want: "> said\n>\n> this\n\n```\nx := 1\ny := 2\n```\n",
+		},
+		{
+			name: "links and images",
+			body: `<a href="https://example.com">https://example.com</a> <a href="#top">top</a> <img src="https://t.example.com/p.gif"><img alt="Logo" src="https://example.com/l.png">`,
+			want: "<https://example.com> top ![Logo](https://example.com/l.png)\n",
+		},
+		{
+			name: "tables",
+			body: `<table><tr><td><p>Layout</p></td></tr></table><table><tr><th>Item</th><th>Price</th></tr><tr><td>Mug</td><td>$8</td></tr></table>`,
+			want: "Layout\n\n| Item | Price |\n| --- | --- |\n| Mug | $8 |\n",
+		},
+		{
+			name: "non visible",
+			body: `<html><head><title>x</title><style>p {}</style></head><body><script>var a;</script>Text</body></html>`,
+			want: "Text\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HTMLToMarkdown(strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("HTMLToMarkdown() returned an error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("HTMLToMarkdown() got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package eazye
+
+import (
+	"net"
+	"time"
+)
+
+// Metrics receives measurements of what a Client does, e.g. to feed
+// Prometheus counters and histograms. The methods are called synchronously,
+// so they should be quick, and may be called from several goroutines at once.
+type Metrics interface {
+	// EmailFetched is called for every email passed along.
+	EmailFetched(folder string)
+	// FetchDuration is called with how long each FETCH of emails took.
+	FetchDuration(folder string, d time.Duration)
+	// BytesReceived and BytesSent are called with the bytes read from and
+	// written to the connection.
+	BytesReceived(n int)
+	BytesSent(n int)
+	// Reconnected is called every time a dropped connection is replaced.
+	Reconnected()
+	// ParseFailed is called for every email that could not be parsed.
+	ParseFailed(folder string)
+}
+
+// SetMetrics is a functional option to set the Metrics attr.
+func SetMetrics(metrics Metrics) Option {
+	return func(c *Client) {
+		c.Metrics = metrics
+	}
+}
+
+// metrics returns the Client's Metrics, or one that throws everything away
+// if it is not set.
+func (c *Client) metrics() Metrics {
+	if c.Metrics == nil {
+		return nopMetrics{}
+	}
+	return c.Metrics
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) EmailFetched(string)                 {}
+func (nopMetrics) FetchDuration(string, time.Duration) {}
+func (nopMetrics) BytesReceived(int)                   {}
+func (nopMetrics) BytesSent(int)                       {}
+func (nopMetrics) Reconnected()                        {}
+func (nopMetrics) ParseFailed(string)                  {}
+
+// meteredConn is a connection that reports the bytes going through it.
+type meteredConn struct {
+	net.Conn
+	metrics Metrics
+}
+
+func (c *meteredConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.metrics.BytesReceived(n)
+	}
+	return n, err
+}
+
+func (c *meteredConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	if n > 0 {
+		c.metrics.BytesSent(n)
+	}
+	return n, err
+}
@@ -0,0 +1,44 @@
+package eazye
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type countingMetrics struct {
+	nopMetrics
+	mu             sync.Mutex
+	received, sent int
+	parseFailures  int
+}
+
+func (m *countingMetrics) BytesReceived(n int) { m.mu.Lock(); m.received += n; m.mu.Unlock() }
+func (m *countingMetrics) BytesSent(n int)     { m.mu.Lock(); m.sent += n; m.mu.Unlock() }
+func (m *countingMetrics) ParseFailed(string)  { m.mu.Lock(); m.parseFailures++; m.mu.Unlock() }
+
+func TestMeteredConn(t *testing.T) {
+	metrics := &countingMetrics{}
+	conn := &meteredConn{Conn: &scriptedConn{replies: strings.NewReader("* OK ready\r\n")}, metrics: metrics}
+
+	conn.Write([]byte("a1 NOOP\r\n"))
+	conn.Read(make([]byte, 64))
+	if metrics.sent != 9 || metrics.received != 12 {
+		t.Errorf("meteredConn counted %d bytes sent and %d received, want 9 and 12", metrics.sent, metrics.received)
+	}
+}
+
+func TestParseFailedMetric(t *testing.T) {
+	metrics := &countingMetrics{}
+	c := &Client{Metrics: metrics}
+
+	c.emailError(OpParse, 1, ErrEmailNotFound)
+	c.emailError(OpDelete, 2, ErrEmailNotFound)
+	if metrics.parseFailures != 1 {
+		t.Errorf("emailError() counted %d parse failures, want 1", metrics.parseFailures)
+	}
+
+	// without Metrics nothing is counted, and nothing breaks
+	(&Client{}).metrics().FetchDuration("INBOX", time.Second)
+}
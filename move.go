@@ -0,0 +1,82 @@
+package eazye
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// Copy will copy the email to the folder. If the connection drops once the
+// command is sent it is not run again, as the email may have been copied, and
+// ErrConnectionLost is returned.
+func (c *Client) Copy(email Email, folder string) error {
+	return c.CopyContext(context.Background(), email, folder)
+}
+
+// CopyContext is Copy with a context.
+func (c *Client) CopyContext(ctx context.Context, email Email, folder string) error {
+	if c.SafeMode {
+		return ErrReadOnlyMode
+	}
+	seq := &imap.SeqSet{}
+	seq.AddNum(imap.AsNumber(email.ID))
+
+	_, err := c.doOnce(ctx, func() (*imap.Command, error) {
+		return c.uidCopy(seq, folder)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to copy email: %w", err)
+	}
+	c.audit(AuditCopied, imap.AsNumber(email.ID), folder)
+	return nil
+}
+
+// Move will move the email to the folder. Servers with the MOVE capability
+// (RFC 6851) do so in one go, otherwise the email is copied, flagged as
+// deleted and expunged. Without UIDPLUS (RFC 4315) that expunge removes any
+// other email flagged as deleted in the folder as well. Like Copy, it is not
+// run again if the connection drops once the command is sent.
+func (c *Client) Move(email Email, folder string) error {
+	return c.MoveContext(context.Background(), email, folder)
+}
+
+// MoveContext is Move with a context.
+func (c *Client) MoveContext(ctx context.Context, email Email, folder string) error {
+	if c.SafeMode {
+		return ErrReadOnlyMode
+	}
+	seq := &imap.SeqSet{}
+	seq.AddNum(imap.AsNumber(email.ID))
+
+	if c.caps("MOVE") {
+		move := "UID MOVE"
+		if c.SequenceNumbers {
+			move = "MOVE"
+		}
+		_, err := c.doOnce(ctx, func() (*imap.Command, error) {
+			return c.server().Send(move, seq, c.server().Quote(encodeMailbox(folder)))
+		})
+		if err != nil {
+			return fmt.Errorf("unable to move email: %w", err)
+		}
+		c.audit(AuditMoved, imap.AsNumber(email.ID), folder)
+		return nil
+	}
+
+	if err := c.CopyContext(ctx, email, folder); err != nil {
+		return err
+	}
+	_, err := c.do(ctx, func() (*imap.Command, error) {
+		return c.uidStore(seq, "+FLAGS.SILENT", `\Deleted`)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to delete moved email: %w", err)
+	}
+
+	if err = c.expunge(ctx, seq); err != nil {
+		return err
+	}
+	c.audit(AuditMoved, imap.AsNumber(email.ID), folder)
+	return nil
+}
@@ -0,0 +1,33 @@
+package eazye
+
+import "testing"
+
+func TestMove(t *testing.T) {
+	tests := []struct {
+		disabled []string
+		want     string
+	}{
+		{nil, "UID MOVE"},
+		{[]string{"MOVE"}, "UID COPY"},
+		{[]string{"MOVE", "UIDPLUS"}, "UID COPY"},
+	}
+
+	for _, tt := range tests {
+		srv := testServer(t)
+		srv.AddFolder("Archive")
+		uid := srv.AddMessage("INBOX", []byte("Subject: moved\r\n\r\nx\r\n"))
+		srv.AddMessage("INBOX", []byte("Subject: kept\r\n\r\nx\r\n"))
+
+		c := testClient(t, srv, SetDisabledCapabilities(tt.disabled...))
+		if err := c.Move(Email{ID: uid}, "Archive"); err != nil {
+			t.Fatalf("Move() without %q returned an error: %s", tt.disabled, err)
+		}
+		if !hasCommand(srv, tt.want) {
+			t.Errorf("Move() without %q sent %q, want %s", tt.disabled, srv.Commands(), tt.want)
+		}
+		inbox, archive := srv.Messages("INBOX"), srv.Messages("Archive")
+		if len(inbox) != 1 || len(archive) != 1 || inbox[0].UID == uid {
+			t.Errorf("Move() without %q left %d emails in INBOX and %d in Archive, want 1 and 1", tt.disabled, len(inbox), len(archive))
+		}
+	}
+}
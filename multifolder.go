@@ -0,0 +1,89 @@
+package eazye
+
+import "context"
+
+// GenerateAllFolders will find the emails matching the query in each of the
+// folders in turn, every selectable folder on the server if none are given,
+// and pass them along on the responses channel with the Folder of each
+// Response set. The folders are searched on a session of their own, so c keeps
+// its folder selected. A folder that can not be selected is passed along as
+// an error and skipped, unless the ErrorStrategy is FailFast.
+func (c *Client) GenerateAllFolders(q Query, markAsRead, delete bool, folders ...string) (chan Response, error) {
+	return c.GenerateAllFoldersContext(context.Background(), q, markAsRead, delete, folders...)
+}
+
+// GenerateAllFoldersContext is GenerateAllFolders with a context.
+func (c *Client) GenerateAllFoldersContext(ctx context.Context, q Query, markAsRead, delete bool, folders ...string) (chan Response, error) {
+	if len(folders) == 0 {
+		all, err := c.ListFoldersContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, folder := range all {
+			if folder.Selectable() {
+				folders = append(folders, folder.Name)
+			}
+		}
+	}
+	if len(folders) == 0 {
+		responses := make(chan Response)
+		close(responses)
+		return responses, nil
+	}
+
+	responses := make(chan Response, c.bufferSize())
+	go func() {
+		defer close(responses)
+
+		var session *Client
+		for _, folder := range folders {
+			var err error
+			if session == nil {
+				if session, err = c.WithFolder(folder); err == nil {
+					defer session.Close()
+				}
+			} else {
+				err = session.SelectFolder(folder, session.ReadOnly)
+			}
+			if err != nil {
+				if !send(ctx, responses, Response{Folder: folder, Err: err}) || c.fatal(err) {
+					return
+				}
+				continue
+			}
+			if !session.generateFolder(ctx, folder, q, markAsRead, delete, responses) {
+				return
+			}
+		}
+	}()
+	return responses, nil
+}
+
+// generateFolder passes along the emails matching the query in the selected
+// folder, tagged with its name, and tells whether to carry on with the next
+// folder.
+func (c *Client) generateFolder(ctx context.Context, folder string, q Query, markAsRead, delete bool, responses chan Response) bool {
+	cmd, err := c.findEmails(ctx, q)
+	if err != nil {
+		return send(ctx, responses, Response{Folder: folder, Err: err}) && !c.fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	own := make(chan Response)
+	go func() {
+		defer close(own)
+		c.getEmails(ctx, searchResults(cmd), markAsRead, delete, false, own)
+	}()
+
+	carryOn := true
+	for resp := range own {
+		resp.Folder = folder
+		if !send(ctx, responses, resp) || (resp.Err != nil && c.fatal(resp.Err)) {
+			// stop fetching, what is left of own is drained
+			carryOn = false
+			cancel()
+		}
+	}
+	return carryOn
+}
@@ -0,0 +1,89 @@
+package eazye
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateAllFolders(t *testing.T) {
+	srv := testServer(t)
+	srv.AddFolder("Archive")
+	srv.AddFolder("Empty")
+	srv.AddMessage("INBOX", []byte("Subject: new\r\n\r\nx\r\n"))
+	srv.AddMessage("INBOX", []byte("Subject: other\r\n\r\nx\r\n"))
+	srv.AddMessage("Archive", []byte("Subject: old\r\n\r\nx\r\n"))
+	c := testClient(t, srv)
+	if err := c.SelectFolder("Empty", false); err != nil {
+		t.Fatalf("SelectFolder() returned an error: %s", err)
+	}
+
+	tests := []struct {
+		name    string
+		q       Query
+		folders []string
+		want    map[string][]string
+	}{
+		{"every folder", All(), nil, map[string][]string{"INBOX": {"new", "other"}, "Archive": {"old"}}},
+		{"given folders", All(), []string{"Archive"}, map[string][]string{"Archive": {"old"}}},
+		{"query", Subject("o"), []string{"INBOX", "Archive"}, map[string][]string{"INBOX": {"other"}, "Archive": {"old"}}},
+	}
+	for _, tt := range tests {
+		responses, err := c.GenerateAllFolders(tt.q, false, false, tt.folders...)
+		if err != nil {
+			t.Fatalf("%s: GenerateAllFolders() returned an error: %s", tt.name, err)
+		}
+
+		got := map[string][]string{}
+		for resp := range responses {
+			if resp.Err != nil {
+				t.Fatalf("%s: GenerateAllFolders() passed along %s for %s", tt.name, resp.Err, resp.Folder)
+			}
+			got[resp.Folder] = append(got[resp.Folder], resp.Email.Subject)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: GenerateAllFolders() got %v, want %v", tt.name, got, tt.want)
+		}
+		if c.Folder != "Empty" {
+			t.Errorf("%s: GenerateAllFolders() changed the folder to %q", tt.name, c.Folder)
+		}
+	}
+}
+
+func TestGenerateAllFoldersMissing(t *testing.T) {
+	srv := testServer(t)
+	srv.AddFolder("Archive")
+	srv.AddMessage("INBOX", []byte("Subject: new\r\n\r\nx\r\n"))
+	srv.AddMessage("Archive", []byte("Subject: old\r\n\r\nx\r\n"))
+
+	tests := []struct {
+		strategy ErrorStrategy
+		want     map[string]string
+	}{
+		{FailFast, map[string]string{"Missing": "error"}},
+		{SkipAndContinue, map[string]string{"Missing": "error", "INBOX": "new", "Archive": "old"}},
+	}
+
+	for _, tt := range tests {
+		c := testClient(t, srv, SetErrorStrategy(tt.strategy))
+		responses, err := c.GenerateAllFolders(All(), false, false, "Missing", "INBOX", "Archive")
+		if err != nil {
+			t.Fatalf("GenerateAllFolders() returned an error: %s", err)
+		}
+
+		got := map[string]string{}
+		for resp := range responses {
+			switch {
+			case errors.Is(resp.Err, ErrFolderNotFound):
+				got[resp.Folder] = "error"
+			case resp.Err != nil:
+				t.Errorf("GenerateAllFolders() passed along %s for %s", resp.Err, resp.Folder)
+			default:
+				got[resp.Folder] = resp.Email.Subject
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GenerateAllFolders() with strategy %d got %v, want %v", tt.strategy, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package eazye
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Sender delivers outgoing messages.
+type Sender interface {
+	Send(from string, to []string, msg []byte) error
+}
+
+// SMTPSender is a Sender that delivers messages with net/smtp.
+type SMTPSender struct {
+	// Addr is the address of the SMTP server, including the port.
+	Addr string
+	Auth smtp.Auth
+}
+
+// Send delivers the message with smtp.SendMail.
+func (s SMTPSender) Send(from string, to []string, msg []byte) error {
+	return smtp.SendMail(s.Addr, s.Auth, from, to, msg)
+}
+
+// OutboxEntry is a message waiting in an Outbox.
+type OutboxEntry struct {
+	ID          string    `json:"id"`
+	From        string    `json:"from"`
+	To          []string  `json:"to"`
+	Message     []byte    `json:"message"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// Outbox persists outgoing messages to disk and keeps retrying their delivery
+// with an exponential backoff until they go through, so replies are not lost
+// when the SMTP server is temporarily down.
+type Outbox struct {
+	// Dir holds one JSON file per pending message. Messages that ran out of
+	// attempts are moved to its "failed" subdirectory.
+	Dir    string
+	Sender Sender
+
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxAttempts is the number of delivery attempts before a message is
+	// given up on, 0 means retry forever.
+	MaxAttempts int
+
+	mu sync.Mutex
+}
+
+// NewOutbox initializes an Outbox keeping its messages in dir, which is
+// created if needed.
+func NewOutbox(dir string, sender Sender) (*Outbox, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "failed"), 0700); err != nil {
+		return nil, err
+	}
+	return &Outbox{
+		Dir:        dir,
+		Sender:     sender,
+		MinBackoff: 30 * time.Second,
+		MaxBackoff: time.Hour,
+	}, nil
+}
+
+// Enqueue persists a message for delivery and returns its ID. The message is
+// sent on the next Flush.
+func (o *Outbox) Enqueue(from string, to []string, msg []byte) (string, error) {
+	id, err := newOutboxID()
+	if err != nil {
+		return "", err
+	}
+
+	entry := OutboxEntry{
+		ID:          id,
+		From:        from,
+		To:          to,
+		Message:     msg,
+		NextAttempt: time.Now(),
+	}
+	if err = o.save(entry); err != nil {
+		return "", fmt.Errorf("unable to save message: %w", err)
+	}
+	return id, nil
+}
+
+// Pending returns all of the messages waiting for delivery, oldest first.
+func (o *Outbox) Pending() ([]OutboxEntry, error) {
+	paths, err := filepath.Glob(filepath.Join(o.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var entries []OutboxEntry
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return entries, err
+		}
+		var entry OutboxEntry
+		if err = json.Unmarshal(data, &entry); err != nil {
+			return entries, fmt.Errorf("unable to read %s: %w", path, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Flush tries to deliver every message that is due. Messages that fail are
+// rescheduled, the first delivery error is returned after all messages have
+// been tried.
+func (o *Outbox) Flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	entries, err := o.Pending()
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.NextAttempt.After(now) {
+			continue
+		}
+
+		err = o.Sender.Send(entry.From, entry.To, entry.Message)
+		if err == nil {
+			if err = os.Remove(o.path(entry.ID)); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("unable to send %s: %w", entry.ID, err)
+		}
+
+		entry.Attempts++
+		entry.LastError = err.Error()
+		entry.NextAttempt = now.Add(o.backoff(entry.Attempts))
+		if o.MaxAttempts > 0 && entry.Attempts >= o.MaxAttempts {
+			err = o.fail(entry)
+		} else {
+			err = o.save(entry)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return firstErr
+}
+
+// Run calls Flush every interval until the context is done. Delivery errors
+// are passed to onError, if set, rather than stopping the loop.
+func (o *Outbox) Run(ctx context.Context, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := o.Flush(); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// backoff returns how long to wait before the next delivery attempt.
+func (o *Outbox) backoff(attempts int) time.Duration {
+	wait := o.MinBackoff
+	for i := 1; i < attempts && wait < o.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if o.MaxBackoff > 0 && wait > o.MaxBackoff {
+		wait = o.MaxBackoff
+	}
+	return wait
+}
+
+func (o *Outbox) path(id string) string {
+	return filepath.Join(o.Dir, id+".json")
+}
+
+func (o *Outbox) save(entry OutboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(o.path(entry.ID), data)
+}
+
+// fail moves a message that ran out of attempts to the failed directory.
+func (o *Outbox) fail(entry OutboxEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err = writeFileAtomic(filepath.Join(o.Dir, "failed", entry.ID+".json"), data); err != nil {
+		return err
+	}
+	return os.Remove(o.path(entry.ID))
+}
+
+// newOutboxID returns an ID that sorts in the order messages were enqueued.
+func newOutboxID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%020d-%s", time.Now().UnixNano(), hex.EncodeToString(b)), nil
+}
@@ -0,0 +1,73 @@
+package eazye
+
+import (
+	"errors"
+	"testing"
+)
+
+type fakeSender struct {
+	fails int
+	sent  [][]byte
+}
+
+func (s *fakeSender) Send(from string, to []string, msg []byte) error {
+	if s.fails > 0 {
+		s.fails--
+		return errors.New("connection refused")
+	}
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func TestOutbox(t *testing.T) {
+	sender := &fakeSender{fails: 1}
+	outbox, err := NewOutbox(t.TempDir(), sender)
+	if err != nil {
+		t.Fatalf("NewOutbox() returned an error: %s", err)
+	}
+	// retry right away
+	outbox.MinBackoff = 0
+
+	if _, err = outbox.Enqueue("me@example.com", []string{"you@example.com"}, []byte("Subject: hi\r\n\r\nhello")); err != nil {
+		t.Fatalf("Enqueue() returned an error: %s", err)
+	}
+
+	if err = outbox.Flush(); err == nil {
+		t.Error("Flush() did not return the delivery error")
+	}
+	pending, err := outbox.Pending()
+	if err != nil {
+		t.Fatalf("Pending() returned an error: %s", err)
+	}
+	if len(pending) != 1 || pending[0].Attempts != 1 || pending[0].LastError == "" {
+		t.Fatalf("Pending() after a failed delivery got %+v", pending)
+	}
+
+	if err = outbox.Flush(); err != nil {
+		t.Errorf("Flush() returned an error: %s", err)
+	}
+	if len(sender.sent) != 1 {
+		t.Errorf("Flush() sent %d messages, want 1", len(sender.sent))
+	}
+	if pending, _ = outbox.Pending(); len(pending) != 0 {
+		t.Errorf("Pending() after a successful delivery got %+v", pending)
+	}
+}
+
+func TestOutboxMaxAttempts(t *testing.T) {
+	sender := &fakeSender{fails: 10}
+	outbox, err := NewOutbox(t.TempDir(), sender)
+	if err != nil {
+		t.Fatalf("NewOutbox() returned an error: %s", err)
+	}
+	outbox.MinBackoff = 0
+	outbox.MaxAttempts = 2
+
+	outbox.Enqueue("me@example.com", []string{"you@example.com"}, []byte("hello"))
+	outbox.Flush()
+	outbox.Flush()
+
+	if pending, _ := outbox.Pending(); len(pending) != 0 {
+		t.Errorf("Pending() after running out of attempts got %+v", pending)
+	}
+}
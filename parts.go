@@ -0,0 +1,126 @@
+package eazye
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// BodyPart is a single (non multipart) MIME part of an email along with its
+// content, with the transfer encoding undone. Its Size is that of the
+// decoded content.
+type BodyPart struct {
+	Part
+	Content []byte
+}
+
+// Attachment is a file attached to an email.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	// Size of the decoded content in bytes.
+	Size    int
+	Content io.Reader
+}
+
+// Parts will walk the body of the email and return all of its leaf MIME
+// parts, numbered like the server does in the BODYSTRUCTURE. Attached
+// messages are not descended into.
+func (e Email) Parts() ([]BodyPart, error) {
+	raw, err := rawMessage(e)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read message: %w", err)
+	}
+
+	return walkParts(textproto.MIMEHeader(msg.Header), msg.Body, "")
+}
+
+// Attachments returns the parts of the email that are attachments rather
+// than a part of its body.
+func (e Email) Attachments() ([]Attachment, error) {
+	parts, err := e.Parts()
+	if err != nil {
+		return nil, err
+	}
+
+	var attachments []Attachment
+	for _, part := range parts {
+		if !part.IsAttachment() {
+			continue
+		}
+		attachments = append(attachments, Attachment{
+			Filename:    part.Filename(),
+			ContentType: part.MediaType(),
+			Size:        len(part.Content),
+			Content:     bytes.NewReader(part.Content),
+		})
+	}
+	return attachments, nil
+}
+
+// walkParts returns the leaf parts of a MIME part, which is the section given.
+func walkParts(header textproto.MIMEHeader, body io.Reader, section string) ([]BodyPart, error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// RFC 2045 says to assume plain text
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		var parts []BodyPart
+		mr := multipart.NewReader(body, params["boundary"])
+		for i := 1; ; i++ {
+			child, err := mr.NextPart()
+			if err == io.EOF {
+				return parts, nil
+			}
+			if err != nil {
+				return parts, fmt.Errorf("unable to read MIME part: %w", err)
+			}
+			// quoted-printable parts are decoded by the multipart reader itself
+			childParts, err := walkParts(child.Header, child, joinSection(section, i))
+			parts = append(parts, childParts...)
+			if err != nil {
+				return parts, err
+			}
+		}
+	}
+
+	if section == "" {
+		section = "1"
+	}
+	encoding := strings.ToLower(header.Get("Content-Transfer-Encoding"))
+	content, err := io.ReadAll(decodeTransfer(encoding, body))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode part %s: %w", section, err)
+	}
+
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	part := BodyPart{
+		Part: Part{
+			Section:     section,
+			Type:        typ,
+			Subtype:     subtype,
+			Params:      params,
+			ID:          header.Get("Content-Id"),
+			Description: header.Get("Content-Description"),
+			Encoding:    encoding,
+			Size:        uint32(len(content)),
+		},
+		Content: content,
+	}
+	if disposition, dispositionParams, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		part.Disposition = disposition
+		part.DispositionParams = dispositionParams
+	}
+	return []BodyPart{part}, nil
+}
@@ -0,0 +1,69 @@
+package eazye
+
+import (
+	"io"
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+func TestEmailParts(t *testing.T) {
+	email, err := newEmail(imap.FieldMap{
+		"RFC822.HEADER": []byte(multipartHeader),
+		"BODY[]":        []byte(multipartHeader + multipartBody),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts, err := email.Parts()
+	if err != nil {
+		t.Fatalf("Parts() returned an error: %s", err)
+	}
+	want := []struct {
+		section, mediaType, content string
+	}{
+		{"1.1", "text/html", "<p>Caf&eacute; at 10</p>"},
+		{"1.2", "text/plain", "Café at 10"},
+		{"2", "text/plain", "not this"},
+	}
+	if len(parts) != len(want) {
+		t.Fatalf("Parts() got %d parts, want %d", len(parts), len(want))
+	}
+	for i, w := range want {
+		if parts[i].Section != w.section || parts[i].MediaType() != w.mediaType || string(parts[i].Content) != w.content {
+			t.Errorf("Parts()[%d] got %s %s %q, want %s %s %q", i, parts[i].Section, parts[i].MediaType(), parts[i].Content, w.section, w.mediaType, w.content)
+		}
+	}
+
+	attachments, err := email.Attachments()
+	if err != nil {
+		t.Fatalf("Attachments() returned an error: %s", err)
+	}
+	if len(attachments) != 1 || attachments[0].Filename != "notes.txt" || attachments[0].Size != 8 {
+		t.Fatalf("Attachments() got %+v, want notes.txt", attachments)
+	}
+	content, _ := io.ReadAll(attachments[0].Content)
+	if string(content) != "not this" {
+		t.Errorf("Attachments() got content %q, want %q", content, "not this")
+	}
+}
+
+func TestEmailPartsBase64(t *testing.T) {
+	header := "Content-Type: application/pdf; name=invoice.pdf\r\nContent-Transfer-Encoding: base64\r\n\r\n"
+	email, err := newEmail(imap.FieldMap{
+		"RFC822.HEADER": []byte(header),
+		"BODY[]":        []byte(header + "JVBERi0x\r\nLjQK\r\n"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts, err := email.Parts()
+	if err != nil {
+		t.Fatalf("Parts() returned an error: %s", err)
+	}
+	if len(parts) != 1 || parts[0].Section != "1" || string(parts[0].Content) != "%PDF-1.4\n" || !parts[0].IsAttachment() {
+		t.Errorf("Parts() got %+v, want the decoded PDF", parts)
+	}
+}
@@ -0,0 +1,410 @@
+package eazye
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// Mark is the high-water mark of a Poller: the last email it handled.
+type Mark struct {
+	InternalDate time.Time `json:"internal_date"`
+	UID          uint32    `json:"uid"`
+	// UIDValidity of the folder the UID belongs to. UIDs can not be compared
+	// across different values of it.
+	UIDValidity uint32 `json:"uid_validity"`
+}
+
+// MarkStore persists the high-water mark of a Poller between runs.
+type MarkStore interface {
+	// Load returns the saved mark, or the zero Mark if there is none.
+	Load() (Mark, error)
+	Save(Mark) error
+}
+
+// FileMarkStore is a MarkStore that keeps the mark as JSON in the file at the
+// given path.
+type FileMarkStore string
+
+// Load reads the mark from the file, a missing file is not an error.
+func (f FileMarkStore) Load() (Mark, error) {
+	var mark Mark
+	data, err := os.ReadFile(string(f))
+	if os.IsNotExist(err) {
+		return mark, nil
+	}
+	if err != nil {
+		return mark, err
+	}
+	err = json.Unmarshal(data, &mark)
+	return mark, err
+}
+
+// Save writes the mark to the file.
+func (f FileMarkStore) Save(mark Mark) error {
+	data, err := json.Marshal(mark)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(string(f), data)
+}
+
+// writeFileAtomic writes the data to a temporary file and renames it over the
+// given path so a crash never leaves a partially written file behind.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Poller repeatedly fetches the emails received since its high-water mark,
+// handing each one off exactly once.
+type Poller struct {
+	Client   *Client
+	Store    MarkStore
+	Interval time.Duration
+
+	MarkAsRead bool
+	Delete     bool
+
+	// Claims, if set, is used to share the folder with other workers. Emails
+	// claimed by another worker are skipped, and the mark stays below them
+	// until they are completed.
+	Claims ClaimStore
+
+	// Dedupe, if set, skips the emails it has seen before, e.g. in another
+	// folder polled with the same Deduplicator. Skipped emails still move
+	// the mark.
+	Dedupe *Deduplicator
+}
+
+// NewPoller initializes a new Poller checking for emails every interval.
+func NewPoller(client *Client, store MarkStore, interval time.Duration) *Poller {
+	return &Poller{
+		Client:   client,
+		Store:    store,
+		Interval: interval,
+	}
+}
+
+// Poll will fetch the emails received since the mark and pass them along to
+// handle in UID order. The mark is saved after every email handled, an error
+// from handle stops the poll and the email will be handed off again next time.
+// Emails are only marked as read or deleted once handled and the mark saved.
+func (p *Poller) Poll(handle func(Email) error) error {
+	return p.PollContext(context.Background(), handle)
+}
+
+// PollContext is Poll with a context.
+func (p *Poller) PollContext(ctx context.Context, handle func(Email) error) error {
+	if p.Client.SafeMode && (p.MarkAsRead || p.Delete) {
+		return ErrReadOnlyMode
+	}
+	mark, err := p.Store.Load()
+	if err != nil {
+		return fmt.Errorf("unable to load mark: %w", err)
+	}
+
+	candidates, err := p.newUIDs(ctx, mark)
+	if err != nil {
+		return err
+	}
+	uids, err := p.claim(candidates)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+
+	// the emails other workers have are in the way of the mark until they
+	// are completed, so the ones they release are offered again
+	claimed := make(map[uint32]bool, len(uids))
+	for _, uid := range uids {
+		claimed[uid] = true
+	}
+	others := missingUIDs(candidates, claimed)
+	sort.Slice(others, func(i, j int) bool { return others[i] < others[j] })
+
+	validity := p.Client.uidValidity()
+	blocked := false
+	for _, batch := range batches(uids, p.Client.fetchBatchSize()) {
+		var emails []Email
+		if emails, err = p.fetch(ctx, batch); err != nil {
+			p.releaseFrom(uids, batch[0])
+			return err
+		}
+
+		for _, email := range emails {
+			uid := imap.AsNumber(email.ID)
+			if err = p.handle(email, handle); err != nil {
+				p.releaseFrom(uids, uid)
+				return err
+			}
+			if p.Claims != nil {
+				if err = p.Claims.Complete(uid); err != nil {
+					p.releaseFrom(uids, uid)
+					return fmt.Errorf("unable to complete claim: %w", err)
+				}
+			}
+			if !blocked {
+				if blocked, err = p.passOthers(&mark, &others, validity, uid); err != nil {
+					p.releaseFrom(uids, uid+1)
+					return err
+				}
+			}
+			if !blocked {
+				mark = Mark{
+					InternalDate: email.InternalDate,
+					UID:          uid,
+					UIDValidity:  validity,
+				}
+				if err = p.Store.Save(mark); err != nil {
+					p.releaseFrom(uids, uid+1)
+					return fmt.Errorf("unable to save mark: %w", err)
+				}
+			}
+			if err = p.settle(ctx, email); err != nil {
+				p.releaseFrom(uids, uid+1)
+				return err
+			}
+		}
+	}
+
+	if !blocked && len(others) > 0 {
+		_, err = p.passOthers(&mark, &others, validity, 0)
+	}
+	return err
+}
+
+// fetch fetches a batch of the emails, in UID order. They are peeked at so an
+// email that is not handled in the end is left as it was.
+func (p *Poller) fetch(ctx context.Context, uids []uint32) ([]Email, error) {
+	var emails []Email
+	for resp := range p.Client.generateUIDs(ctx, uids, false, false, true) {
+		if resp.Err != nil {
+			return nil, resp.Err
+		}
+		emails = append(emails, resp.Email)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(emails, func(i, j int) bool {
+		return imap.AsNumber(emails[i].ID) < imap.AsNumber(emails[j].ID)
+	})
+	return emails, nil
+}
+
+// passOthers moves the mark past the emails of other workers below the UID,
+// or all of them if it is 0, as long as they were completed, and saves it.
+// It tells whether an email that is not completed blocks the way.
+func (p *Poller) passOthers(mark *Mark, others *[]uint32, validity, below uint32) (bool, error) {
+	passed := false
+	blocked := false
+	for len(*others) > 0 && (below == 0 || (*others)[0] < below) {
+		done, err := p.Claims.Completed((*others)[0])
+		if err != nil {
+			return true, fmt.Errorf("unable to check claim: %w", err)
+		}
+		if !done {
+			blocked = true
+			break
+		}
+		// the internal date of the email is unknown, the one of the mark is
+		// older and only widens the search after a UIDVALIDITY change
+		mark.UID, mark.UIDValidity = (*others)[0], validity
+		*others = (*others)[1:]
+		passed = true
+	}
+	if passed {
+		if err := p.Store.Save(*mark); err != nil {
+			return true, fmt.Errorf("unable to save mark: %w", err)
+		}
+	}
+	return blocked, nil
+}
+
+// settle marks the handled email as read or deletes it, as set.
+func (p *Poller) settle(ctx context.Context, email Email) error {
+	if p.MarkAsRead {
+		if err := p.Client.SetAsReadContext(ctx, email); err != nil {
+			return p.Client.emailError(OpMarkRead, imap.AsNumber(email.ID), err)
+		}
+	}
+	if p.Delete {
+		if err := p.Client.DeleteEmailContext(ctx, email); err != nil {
+			return p.Client.emailError(OpDelete, imap.AsNumber(email.ID), err)
+		}
+	}
+	return nil
+}
+
+// handle passes the email along to handle unless Dedupe has seen it, and
+// records it once handled.
+func (p *Poller) handle(email Email, handle func(Email) error) error {
+	if p.Dedupe == nil {
+		return handle(email)
+	}
+	dup, err := p.Dedupe.IsDuplicate(email)
+	if err != nil || dup {
+		return err
+	}
+	if err = handle(email); err != nil {
+		return err
+	}
+	return p.Dedupe.Record(email)
+}
+
+// Run calls PollContext every Interval until the context is done or a poll
+// fails.
+func (p *Poller) Run(ctx context.Context, handle func(Email) error) error {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.PollContext(ctx, handle); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// claim filters the UIDs down to the ones claimed for this worker.
+func (p *Poller) claim(uids []uint32) ([]uint32, error) {
+	if p.Claims == nil {
+		return uids, nil
+	}
+	var claimed []uint32
+	for _, uid := range uids {
+		ok, err := p.Claims.Claim(uid)
+		if err != nil {
+			p.releaseUIDs(claimed)
+			return nil, fmt.Errorf("unable to claim email: %w", err)
+		}
+		if ok {
+			claimed = append(claimed, uid)
+		}
+	}
+	return claimed, nil
+}
+
+// releaseFrom gives up the claims on the UIDs from the given one on, which
+// were not handled. The UIDs are sorted.
+func (p *Poller) releaseFrom(uids []uint32, from uint32) {
+	i := sort.Search(len(uids), func(i int) bool { return uids[i] >= from })
+	p.releaseUIDs(uids[i:])
+}
+
+func (p *Poller) releaseUIDs(uids []uint32) {
+	if p.Claims == nil {
+		return
+	}
+	for _, uid := range uids {
+		// a claim that can not be released expires on its own
+		p.Claims.Release(uid)
+	}
+}
+
+// newUIDs finds the UIDs of the emails above the mark. While the UIDVALIDITY
+// stays the same they are searched by UID, as emails moved or appended to the
+// folder can have older internal dates than the mark.
+func (p *Poller) newUIDs(ctx context.Context, mark Mark) ([]uint32, error) {
+	sameUIDs := mark.UIDValidity == p.Client.uidValidity()
+	q := All()
+	switch {
+	case sameUIDs && mark.UID > 0 && !p.Client.SequenceNumbers:
+		seq := &imap.SeqSet{}
+		seq.AddRange(mark.UID+1, 0)
+		q = Query{keys: []imap.Field{"UID", seq}}
+	case !mark.InternalDate.IsZero():
+		// SINCE only has day granularity and servers are free to pick the
+		// timezone, so search from the day before and filter out the rest.
+		q = Since(mark.InternalDate.AddDate(0, 0, -1))
+	}
+
+	cmd, err := p.Client.findEmails(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	candidates := searchResults(cmd)
+
+	if sameUIDs {
+		// n:* matches the highest UID even if it is below n
+		var uids []uint32
+		for _, uid := range candidates {
+			if uid > mark.UID {
+				uids = append(uids, uid)
+			}
+		}
+		return uids, nil
+	}
+
+	// the UIDs were reset, fall back to comparing internal dates
+	return p.Client.receivedAfter(ctx, candidates, mark.InternalDate)
+}
+
+// uidValidity returns the UIDVALIDITY of the selected folder.
+func (c *Client) uidValidity() uint32 {
+	mailbox := c.mailbox()
+	if mailbox == nil {
+		return 0
+	}
+	return mailbox.UIDValidity
+}
+
+// receivedAfter filters the UIDs down to the emails with an internal date
+// after the given time.
+func (c *Client) receivedAfter(ctx context.Context, uids []uint32, after time.Time) ([]uint32, error) {
+	return c.filterReceived(ctx, uids, func(date time.Time) bool {
+		return date.After(after)
+	})
+}
+
+// filterReceived filters the UIDs down to the emails whose internal date is
+// kept.
+func (c *Client) filterReceived(ctx context.Context, uids []uint32, keep func(time.Time) bool) ([]uint32, error) {
+	seq := &imap.SeqSet{}
+	seq.AddNum(uids...)
+	if seq.Empty() {
+		return nil, nil
+	}
+
+	fCmd, err := c.wait(ctx)(c.uidFetch(seq, "UID", "INTERNALDATE"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to perform uid fetch: %w", err)
+	}
+
+	var found []uint32
+	for _, msgData := range fCmd.Data {
+		info := c.messageInfo(msgData)
+		if _, ok := info.Attrs["INTERNALDATE"]; !ok {
+			continue
+		}
+		if keep(info.InternalDate) {
+			found = append(found, info.UID)
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,264 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFileMarkStore(t *testing.T) {
+	store := FileMarkStore(filepath.Join(t.TempDir(), "mark.json"))
+
+	mark, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() with no file returned an error: %s", err)
+	}
+	if mark != (Mark{}) {
+		t.Errorf("Load() with no file got %+v, want the zero Mark", mark)
+	}
+
+	want := Mark{
+		InternalDate: time.Date(2014, 8, 12, 10, 20, 6, 0, time.UTC),
+		UID:          42,
+		UIDValidity:  7,
+	}
+	if err = store.Save(want); err != nil {
+		t.Fatalf("Save() returned an error: %s", err)
+	}
+
+	got, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() returned an error: %s", err)
+	}
+	if !got.InternalDate.Equal(want.InternalDate) || got.UID != want.UID || got.UIDValidity != want.UIDValidity {
+		t.Errorf("Load() got %+v, want %+v", got, want)
+	}
+}
+
+func TestPollerMarksHandledEmails(t *testing.T) {
+	srv := testServer(t)
+	srv.AddMessage("INBOX", []byte("Subject: one\r\n\r\n1\r\n"))
+	srv.AddMessage("INBOX", []byte("Subject: two\r\n\r\n2\r\n"))
+
+	c := testClient(t, srv)
+	p := NewPoller(c, FileMarkStore(filepath.Join(t.TempDir(), "mark.json")), time.Minute)
+	p.MarkAsRead = true
+
+	failed := errors.New("handler failed")
+	err := p.Poll(func(email Email) error {
+		if email.Subject == "two" {
+			return failed
+		}
+		return nil
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Poll() got %v, want %v", err, failed)
+	}
+	if hasCommand(srv, "UID FETCH 1:2 (INTERNALDATE BODY[]") {
+		t.Errorf("Poll() fetched without peeking: %q", srv.Commands())
+	}
+	if !serverHasFlag(srv, "INBOX", 1, `\Seen`) || serverHasFlag(srv, "INBOX", 2, `\Seen`) {
+		t.Errorf("Poll() with a failing handler got %+v, want only the handled email read", srv.Messages("INBOX"))
+	}
+
+	var handled []string
+	err = p.PollContext(context.Background(), func(email Email) error {
+		handled = append(handled, email.Subject)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("PollContext() returned an error: %s", err)
+	}
+	if !reflect.DeepEqual(handled, []string{"two"}) {
+		t.Errorf("PollContext() handled %q, want [two]", handled)
+	}
+	if !serverHasFlag(srv, "INBOX", 2, `\Seen`) {
+		t.Errorf("PollContext() left the handled email unread")
+	}
+}
+
+// testClaims is a ClaimStore in memory, holding the claims of other workers
+// as "other".
+type testClaims struct {
+	owners    map[uint32]string
+	completed map[uint32]bool
+}
+
+func (c *testClaims) Claim(uid uint32) (bool, error) {
+	if c.completed[uid] || c.owners[uid] == "other" {
+		return false, nil
+	}
+	c.owners[uid] = "me"
+	return true, nil
+}
+
+func (c *testClaims) Release(uid uint32) error {
+	delete(c.owners, uid)
+	return nil
+}
+
+func (c *testClaims) Complete(uid uint32) error {
+	c.completed[uid] = true
+	return c.Release(uid)
+}
+
+func (c *testClaims) Completed(uid uint32) (bool, error) {
+	return c.completed[uid], nil
+}
+
+func TestPollerMarkWaitsForOtherWorkers(t *testing.T) {
+	srv := testServer(t)
+	for _, subject := range []string{"one", "two", "three", "four"} {
+		srv.AddMessage("INBOX", []byte("Subject: "+subject+"\r\n\r\nx\r\n"))
+	}
+
+	c := testClient(t, srv)
+	store := FileMarkStore(filepath.Join(t.TempDir(), "mark.json"))
+	claims := &testClaims{owners: map[uint32]string{2: "other", 4: "other"}, completed: map[uint32]bool{4: true}}
+	p := NewPoller(c, store, time.Minute)
+	p.Claims = claims
+
+	tests := []struct {
+		name    string
+		before  func()
+		handled []string
+		mark    uint32
+	}{
+		{"other worker holds 2", func() {}, []string{"one", "three"}, 1},
+		{"other worker released 2", func() { claims.Release(2) }, []string{"two"}, 4},
+		{"nothing new", func() {}, nil, 4},
+	}
+
+	for _, tt := range tests {
+		tt.before()
+		var handled []string
+		err := p.Poll(func(email Email) error {
+			handled = append(handled, email.Subject)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%s: Poll() returned an error: %s", tt.name, err)
+		}
+		if !reflect.DeepEqual(handled, tt.handled) {
+			t.Errorf("%s: Poll() handled %q, want %q", tt.name, handled, tt.handled)
+		}
+		if mark, _ := store.Load(); mark.UID != tt.mark {
+			t.Errorf("%s: Poll() got mark %d, want %d", tt.name, mark.UID, tt.mark)
+		}
+	}
+}
+
+func TestPollerDelete(t *testing.T) {
+	srv := testServer(t)
+	srv.AddMessage("INBOX", []byte("Subject: one\r\n\r\n1\r\n"))
+	srv.AddMessage("INBOX", []byte("Subject: two\r\n\r\n2\r\n"))
+
+	c := testClient(t, srv)
+	p := NewPoller(c, FileMarkStore(filepath.Join(t.TempDir(), "mark.json")), time.Minute)
+	p.Delete = true
+
+	var handled []string
+	err := p.Poll(func(email Email) error {
+		handled = append(handled, email.Subject)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Poll() returned an error: %s", err)
+	}
+	if !reflect.DeepEqual(handled, []string{"one", "two"}) {
+		t.Errorf("Poll() handled %q, want [one two]", handled)
+	}
+	if !serverHasFlag(srv, "INBOX", 1, `\Deleted`) || !serverHasFlag(srv, "INBOX", 2, `\Deleted`) {
+		t.Errorf("Poll() got %+v, want both emails deleted", srv.Messages("INBOX"))
+	}
+
+	handled = nil
+	if err = p.Poll(func(email Email) error {
+		handled = append(handled, email.Subject)
+		return nil
+	}); err != nil || len(handled) != 0 {
+		t.Errorf("Poll() again handled %q, %v, want none", handled, err)
+	}
+}
+
+func TestPollerFindsOlderEmailsAboveTheMark(t *testing.T) {
+	srv := testServer(t)
+	srv.AddMessage("INBOX", []byte("Subject: one\r\n\r\n1\r\n"))
+
+	c := testClient(t, srv)
+	p := NewPoller(c, FileMarkStore(filepath.Join(t.TempDir(), "mark.json")), time.Minute)
+	if err := p.Poll(func(Email) error { return nil }); err != nil {
+		t.Fatalf("Poll() returned an error: %s", err)
+	}
+
+	// moved in from another folder, with the internal date it had there
+	srv.AddMessageAt("INBOX", []byte("Subject: two\r\n\r\n2\r\n"), time.Now().AddDate(0, -1, 0))
+	var handled []string
+	err := p.Poll(func(email Email) error {
+		handled = append(handled, email.Subject)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Poll() returned an error: %s", err)
+	}
+	if !reflect.DeepEqual(handled, []string{"two"}) {
+		t.Errorf("Poll() handled %q, want [two]", handled)
+	}
+}
+
+func TestPollerFetchesInBatches(t *testing.T) {
+	srv := testServer(t)
+	for _, subject := range []string{"one", "two", "three"} {
+		srv.AddMessage("INBOX", []byte("Subject: "+subject+"\r\n\r\nx\r\n"))
+	}
+
+	c := testClient(t, srv, SetFetchBatchSize(2))
+	p := NewPoller(c, FileMarkStore(filepath.Join(t.TempDir(), "mark.json")), time.Minute)
+	var handled []string
+	err := p.Poll(func(email Email) error {
+		if len(handled) == 0 && hasCommand(srv, "UID FETCH 3 ") {
+			t.Errorf("Poll() fetched the second batch before handling the first")
+		}
+		handled = append(handled, email.Subject)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Poll() returned an error: %s", err)
+	}
+	if !reflect.DeepEqual(handled, []string{"one", "two", "three"}) {
+		t.Errorf("Poll() handled %q, want [one two three]", handled)
+	}
+	if !hasCommand(srv, "UID FETCH 1:2 ") || !hasCommand(srv, "UID FETCH 3 ") {
+		t.Errorf("Poll() got commands %q, want two batches", srv.Commands())
+	}
+}
+
+func TestPollerReleasesClaimsWhenSettleFails(t *testing.T) {
+	srv := testServer(t)
+	for _, subject := range []string{"one", "two", "three"} {
+		srv.AddMessage("INBOX", []byte("Subject: "+subject+"\r\n\r\nx\r\n"))
+	}
+
+	c := testClient(t, srv)
+	claims := &testClaims{owners: map[uint32]string{}, completed: map[uint32]bool{}}
+	p := NewPoller(c, FileMarkStore(filepath.Join(t.TempDir(), "mark.json")), time.Minute)
+	p.Claims = claims
+	p.MarkAsRead = true
+
+	// marking the first email as read fails once the context is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := p.PollContext(ctx, func(Email) error {
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("PollContext() got %v, want %v", err, context.Canceled)
+	}
+	if !claims.completed[1] || len(claims.owners) != 0 {
+		t.Errorf("PollContext() left claims %v, want 1 completed and the rest released", claims.owners)
+	}
+}
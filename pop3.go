@@ -0,0 +1,268 @@
+package eazye
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// POP3 is a Mailbox on a POP3 server (RFC 1939), for providers that offer
+// nothing else. POP3 has no folders or flags: the emails are those in the
+// inbox, their IDs are message numbers and deleted emails are only removed
+// once the POP3 is closed.
+//
+// The server does not know which emails were read, so the POP3 keeps track
+// of them by their unique ID (UIDL) in Seen, which can be saved and set
+// again to carry it over to the next session.
+type POP3 struct {
+	// Seen holds the unique IDs of the emails marked as read.
+	Seen map[string]bool
+
+	host string
+	conn net.Conn
+	text *textproto.Conn
+	// uidls maps message numbers to unique IDs, see list
+	uidls map[uint32]string
+}
+
+// NewPOP3 connects and logs in to the POP3 server at host, a host:port pair.
+// Of the options only TLS, TLSConfig and CommandTimeout are used.
+func NewPOP3(host, user, pwd string, options ...func(*Client)) (*POP3, error) {
+	return NewPOP3Context(context.Background(), host, user, pwd, options...)
+}
+
+// NewPOP3Context is NewPOP3 with a context.
+func NewPOP3Context(ctx context.Context, host, user, pwd string, options ...func(*Client)) (*POP3, error) {
+	config := &Client{}
+	for _, option := range options {
+		option(config)
+	}
+	if config.CommandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.CommandTimeout)
+		defer cancel()
+	}
+
+	var conn net.Conn
+	var err error
+	dialer := new(net.Dialer)
+	if config.TLS {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: config.TLSConfig}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", host)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", host)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p := &POP3{
+		Seen: map[string]bool{},
+		host: host,
+		conn: conn,
+		text: textproto.NewConn(conn),
+	}
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+
+	if err = p.login(user, pwd); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// login reads the greeting and logs in with USER and PASS.
+func (p *POP3) login(user, pwd string) error {
+	line, err := p.text.ReadLine()
+	if err != nil {
+		return fmt.Errorf("unable to read greeting: %w", err)
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("unexpected greeting: %s", line)
+	}
+
+	if _, err = p.cmd("USER %s", user); err != nil {
+		return fmt.Errorf("%w: %w", ErrAuthFailed, err)
+	}
+	if _, err = p.cmd("PASS %s", pwd); err != nil {
+		return fmt.Errorf("%w: %w", ErrAuthFailed, err)
+	}
+	return nil
+}
+
+// cmd sends a command and returns the rest of the line of a +OK response.
+func (p *POP3) cmd(format string, args ...any) (string, error) {
+	if err := p.text.PrintfLine(format, args...); err != nil {
+		return "", err
+	}
+	line, err := p.text.ReadLine()
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return "", fmt.Errorf("server said: %s", line)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, "+OK")), nil
+}
+
+// multiline sends a command with a multi-line response and returns the lines
+// after the first, dot-unstuffed.
+func (p *POP3) multiline(format string, args ...any) ([]byte, error) {
+	if _, err := p.cmd(format, args...); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(p.text.DotReader())
+}
+
+// list returns the message numbers of the emails in the mailbox, in order,
+// and remembers their unique IDs.
+func (p *POP3) list() ([]uint32, error) {
+	data, err := p.multiline("UIDL")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list emails: %w", err)
+	}
+
+	var nums []uint32
+	p.uidls = map[uint32]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		num, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil {
+			continue
+		}
+		nums = append(nums, uint32(num))
+		p.uidls[uint32(num)] = fields[1]
+	}
+	return nums, nil
+}
+
+// retrieve fetches and parses an email.
+func (p *POP3) retrieve(num uint32) (Email, error) {
+	raw, err := p.multiline("RETR %d", num)
+	if err != nil {
+		return Email{}, fmt.Errorf("unable to retrieve email %d: %w", num, err)
+	}
+
+	email, err := parseRaw(num, raw)
+	if err != nil {
+		return Email{}, &ResponseError{UID: num, Folder: "INBOX", Op: OpParse, Err: err}
+	}
+	if p.Seen[p.uidls[num]] {
+		email.Flags = []string{`\Seen`}
+	} else {
+		email.Flags = []string{}
+	}
+	return email, nil
+}
+
+// GetAll will pull all emails from the mailbox and return them as a list.
+func (p *POP3) GetAll(markAsRead, delete bool) ([]Email, error) {
+	return p.get(func(uint32) (bool, error) { return true, nil }, markAsRead, delete)
+}
+
+// GetUnread will find all emails not yet marked as read and return them as a
+// list.
+func (p *POP3) GetUnread(markAsRead, delete bool) ([]Email, error) {
+	return p.get(func(num uint32) (bool, error) {
+		return !p.Seen[p.uidls[num]], nil
+	}, markAsRead, delete)
+}
+
+// GetSince will pull all emails dated since the given time and return them as
+// a list. Emails without a valid Date header are left out.
+func (p *POP3) GetSince(since time.Time, markAsRead, delete bool) ([]Email, error) {
+	return p.get(func(num uint32) (bool, error) {
+		header, err := p.multiline("TOP %d 0", num)
+		if err != nil {
+			return false, fmt.Errorf("unable to retrieve headers of %d: %w", num, err)
+		}
+		msg, err := mail.ReadMessage(bytes.NewReader(header))
+		if err != nil {
+			return false, nil
+		}
+		date, err := msg.Header.Date()
+		return err == nil && !date.Before(since), nil
+	}, markAsRead, delete)
+}
+
+// get retrieves the emails accepted by want, marking them as read and
+// deleting them if asked to.
+func (p *POP3) get(want func(num uint32) (bool, error), markAsRead, delete bool) ([]Email, error) {
+	nums, err := p.list()
+	if err != nil {
+		return nil, err
+	}
+
+	var emails []Email
+	for _, num := range nums {
+		ok, err := want(num)
+		if err != nil {
+			return emails, err
+		}
+		if !ok {
+			continue
+		}
+
+		email, err := p.retrieve(num)
+		if err != nil {
+			return emails, err
+		}
+		if markAsRead {
+			p.Seen[p.uidls[num]] = true
+			email.Flags = addFlag(email.Flags, `\Seen`)
+		}
+		if delete {
+			if err = p.DeleteEmail(email); err != nil {
+				return emails, err
+			}
+		}
+		emails = append(emails, email)
+	}
+	return emails, nil
+}
+
+// DeleteEmail marks the email for deletion. It is removed when the POP3 is
+// closed.
+func (p *POP3) DeleteEmail(email Email) error {
+	num := imap.AsNumber(email.ID)
+	if _, err := p.cmd("DELE %d", num); err != nil {
+		return fmt.Errorf("unable to delete email %d: %w", num, err)
+	}
+	return nil
+}
+
+// SetAsRead adds the email to Seen.
+func (p *POP3) SetAsRead(email Email) error {
+	uidl, ok := p.uidls[imap.AsNumber(email.ID)]
+	if !ok {
+		return ErrEmailNotFound
+	}
+	p.Seen[uidl] = true
+	return nil
+}
+
+// Close logs out, removing the emails marked for deletion, and closes the
+// connection.
+func (p *POP3) Close() error {
+	_, err := p.cmd("QUIT")
+	if cerr := p.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
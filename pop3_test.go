@@ -0,0 +1,102 @@
+package eazye
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// servePOP3 answers a single POP3 session with the given messages, keyed by
+// unique ID, and returns the address and the commands it was sent.
+func servePOP3(t *testing.T, uidls []string, messages map[string]string) (string, chan []string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	commands := make(chan []string, 1)
+	go func() {
+		defer l.Close()
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var got []string
+		defer func() { commands <- got }()
+		r := bufio.NewReader(conn)
+		fmt.Fprint(conn, "+OK ready\r\n")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimSpace(line)
+			got = append(got, line)
+			var num int
+			switch fields := strings.Fields(line); fields[0] {
+			case "UIDL":
+				fmt.Fprint(conn, "+OK\r\n")
+				for i, uidl := range uidls {
+					fmt.Fprintf(conn, "%d %s\r\n", i+1, uidl)
+				}
+				fmt.Fprint(conn, ".\r\n")
+			case "RETR", "TOP":
+				fmt.Sscan(fields[1], &num)
+				msg := messages[uidls[num-1]]
+				if fields[0] == "TOP" {
+					msg = msg[:strings.Index(msg, "\r\n\r\n")+2]
+				}
+				fmt.Fprintf(conn, "+OK\r\n%s.\r\n", msg)
+			case "QUIT":
+				fmt.Fprint(conn, "+OK bye\r\n")
+				return
+			default:
+				fmt.Fprint(conn, "+OK\r\n")
+			}
+		}
+	}()
+	return l.Addr().String(), commands
+}
+
+func TestPOP3(t *testing.T) {
+	messages := map[string]string{
+		"a": "Subject: old\r\nDate: Mon, 4 Aug 2014 10:00:00 +0000\r\n\r\nold one\r\n",
+		"b": "Subject: new\r\nDate: Mon, 11 Aug 2014 10:00:00 +0000\r\n\r\nnew one\r\n",
+	}
+	addr, commands := servePOP3(t, []string{"a", "b"}, messages)
+
+	var p Mailbox
+	p, err := NewPOP3(addr, "user", "pwd")
+	if err != nil {
+		t.Fatalf("NewPOP3() returned error: %s", err)
+	}
+	pop := p.(*POP3)
+	pop.Seen["a"] = true
+
+	emails, err := p.GetUnread(false, false)
+	if err != nil || len(emails) != 1 || emails[0].Subject != "new" || string(emails[0].Text) != "new one\n" {
+		t.Errorf("GetUnread() got %+v, %v, want the new email", emails, err)
+	}
+
+	since := time.Date(2014, 8, 10, 0, 0, 0, 0, time.UTC)
+	emails, err = p.GetSince(since, true, true)
+	if err != nil || len(emails) != 1 || emails[0].Subject != "new" {
+		t.Errorf("GetSince() got %+v, %v, want the new email", emails, err)
+	}
+	if !pop.Seen["b"] {
+		t.Errorf("GetSince() did not mark the email as read")
+	}
+
+	if err = p.Close(); err != nil {
+		t.Errorf("Close() returned error: %s", err)
+	}
+	got := strings.Join(<-commands, "; ")
+	want := "USER user; PASS pwd; UIDL; RETR 2; UIDL; TOP 1 0; TOP 2 0; RETR 2; DELE 2; QUIT"
+	if got != want {
+		t.Errorf("POP3 sent %q, want %q", got, want)
+	}
+}
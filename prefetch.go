@@ -0,0 +1,127 @@
+package eazye
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// PrefetchOrder decides which emails Prefetch spends its budget on first.
+type PrefetchOrder int
+
+const (
+	// NewestFirst fetches the emails with the latest internal date first.
+	NewestFirst PrefetchOrder = iota
+	// SmallestFirst fetches the smallest emails first, getting as many of
+	// them as possible within the budget.
+	SmallestFirst
+)
+
+// Prefetched is the result of Prefetch.
+type Prefetched struct {
+	// Full holds the emails that fit in the budget, fetched in full.
+	Full []Email
+	// HeadersOnly holds the rest of the emails, with only their headers as
+	// with GetHeaders, in the order they would have been fetched.
+	HeadersOnly []Email
+	// Used is the number of bytes of the budget spent on Full.
+	Used int64
+}
+
+// Prefetch will fetch the headers of all emails matching the query, and the
+// full emails, attachments and all, only as long as their sizes fit in a
+// budget of bytes, e.g. for devices on a metered or slow connection. Emails
+// too large for what is left of the budget are skipped in favour of later
+// ones that fit. Sizes are the RFC822.SIZE the server reports. The emails are
+// left unread.
+func (c *Client) Prefetch(q Query, budget int64, order PrefetchOrder) (Prefetched, error) {
+	return c.PrefetchContext(context.Background(), q, budget, order)
+}
+
+// PrefetchContext is Prefetch with a context.
+func (c *Client) PrefetchContext(ctx context.Context, q Query, budget int64, order PrefetchOrder) (Prefetched, error) {
+	var result Prefetched
+
+	cmd, err := c.findEmails(ctx, q)
+	if err != nil {
+		return result, err
+	}
+
+	var candidates []prefetchCandidate
+	for _, batchUIDs := range batches(searchResults(cmd), c.fetchBatchSize()) {
+		batch := &imap.SeqSet{}
+		batch.AddNum(batchUIDs...)
+		fCmd, err := c.do(ctx, func() (*imap.Command, error) {
+			return c.uidFetch(batch, "INTERNALDATE", "UID", "RFC822.SIZE", "RFC822.HEADER")
+		})
+		if err != nil {
+			return result, fmt.Errorf("unable to perform uid fetch: %w", err)
+		}
+
+		for _, msgData := range fCmd.Data {
+			info := c.messageInfo(msgData)
+			// skip unsolicited FETCH responses, see getEmails
+			if _, ok := info.Attrs["RFC822.HEADER"]; !ok {
+				continue
+			}
+			email, err := c.parse(ctx, info.Attrs)
+			if err != nil {
+				return result, c.emailError(OpParse, info.UID, err)
+			}
+			candidates = append(candidates, prefetchCandidate{
+				email: email,
+				uid:   info.UID,
+				size:  int64(info.Size),
+			})
+		}
+	}
+
+	full, used := planPrefetch(candidates, budget, order)
+	result.Used = used
+
+	var uids []uint32
+	for _, candidate := range candidates {
+		if full[candidate.uid] {
+			uids = append(uids, candidate.uid)
+			continue
+		}
+		if c.Redactor != nil {
+			c.Redactor.Email(&candidate.email)
+		}
+		result.HeadersOnly = append(result.HeadersOnly, candidate.email)
+	}
+
+	result.Full, err = c.collect(ctx, c.generateUIDs(ctx, uids, false, false, true))
+	return result, err
+}
+
+type prefetchCandidate struct {
+	email Email
+	uid   uint32
+	size  int64
+}
+
+// planPrefetch sorts the candidates in the order they are to be fetched and
+// picks the ones that fit in the budget, returning their UIDs and the bytes
+// they use up.
+func planPrefetch(candidates []prefetchCandidate, budget int64, order PrefetchOrder) (map[uint32]bool, int64) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if order == SmallestFirst {
+			return candidates[i].size < candidates[j].size
+		}
+		return candidates[i].email.InternalDate.After(candidates[j].email.InternalDate)
+	})
+
+	full := map[uint32]bool{}
+	var used int64
+	for _, candidate := range candidates {
+		if used+candidate.size > budget {
+			continue
+		}
+		used += candidate.size
+		full[candidate.uid] = true
+	}
+	return full, used
+}
@@ -0,0 +1,73 @@
+package eazye
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanPrefetch(t *testing.T) {
+	day := time.Date(2014, 8, 11, 0, 0, 0, 0, time.UTC)
+	candidates := func() []prefetchCandidate {
+		return []prefetchCandidate{
+			{uid: 1, size: 250, email: Email{InternalDate: day}},
+			{uid: 2, size: 500, email: Email{InternalDate: day.AddDate(0, 0, 1)}},
+			{uid: 3, size: 300, email: Email{InternalDate: day.AddDate(0, 0, 2)}},
+			{uid: 4, size: 50, email: Email{InternalDate: day.AddDate(0, 0, 3)}},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		order PrefetchOrder
+		want  map[uint32]bool
+		used  int64
+	}{
+		// 4 and 3 fit, which leaves no room for 2 or 1
+		{"newest first", NewestFirst, map[uint32]bool{4: true, 3: true}, 350},
+		{"smallest first", SmallestFirst, map[uint32]bool{4: true, 1: true}, 300},
+	}
+	for _, test := range tests {
+		full, used := planPrefetch(candidates(), 500, test.order)
+		if used != test.used {
+			t.Errorf("%s: planPrefetch() used %d bytes, want %d", test.name, used, test.used)
+		}
+		if len(full) != len(test.want) {
+			t.Errorf("%s: planPrefetch() got %v, want %v", test.name, full, test.want)
+			continue
+		}
+		for uid := range test.want {
+			if !full[uid] {
+				t.Errorf("%s: planPrefetch() got %v, want %v", test.name, full, test.want)
+			}
+		}
+	}
+
+	if full, used := planPrefetch(candidates(), 0, NewestFirst); len(full) != 0 || used != 0 {
+		t.Errorf("planPrefetch() with no budget got %v and %d bytes, want nothing", full, used)
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	srv := testServer(t)
+	srv.AddMessage("INBOX", []byte("Subject: small\r\n\r\n1\r\n"), `\Seen`)
+	srv.AddMessage("INBOX", []byte("Subject: large\r\n\r\n"+strings.Repeat("x", 100)+"\r\n"))
+
+	c := testClient(t, srv)
+	got, err := c.Prefetch(All(), 50, SmallestFirst)
+	if err != nil {
+		t.Fatalf("Prefetch() returned an error: %s", err)
+	}
+	if len(got.Full) != 1 || got.Full[0].Subject != "small" || len(got.HeadersOnly) != 1 || got.HeadersOnly[0].Subject != "large" {
+		t.Fatalf("Prefetch() got %+v, want small in full and large headers only", got)
+	}
+	if ref := got.HeadersOnly[0].Ref; ref.UID != 2 || ref.Folder != "INBOX" {
+		t.Errorf("Prefetch() got headers only Ref %+v, want UID 2 in INBOX", ref)
+	}
+	if !serverHasFlag(srv, "INBOX", 1, `\Seen`) || serverHasFlag(srv, "INBOX", 2, `\Seen`) {
+		t.Errorf("Prefetch() changed the flags: %+v", srv.Messages("INBOX"))
+	}
+	if hasCommand(srv, "UID STORE") {
+		t.Errorf("Prefetch() stored flags: %q", srv.Commands())
+	}
+}
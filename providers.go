@@ -0,0 +1,82 @@
+package eazye
+
+import "fmt"
+
+// Provider identifies a mail provider with a built-in connection Profile.
+type Provider string
+
+const (
+	Gmail     Provider = "gmail"
+	Office365 Provider = "office365"
+	Yahoo     Provider = "yahoo"
+	ICloud    Provider = "icloud"
+	Fastmail  Provider = "fastmail"
+)
+
+// Profile holds the connection settings and known quirks of a mail provider.
+type Profile struct {
+	// Host is the IMAP server address, including the port.
+	Host string
+	TLS  bool
+	// ID is sent with the IMAP ID command before logging in. Some servers
+	// refuse to cooperate with clients that do not identify themselves.
+	ID []string
+	// OAuthScopes are the scopes an access token needs for IMAP access.
+	OAuthScopes []string
+	// UnsolicitedFetch marks servers known to send FETCH responses containing
+	// only flags in the middle of a UID FETCH. eazye always skips those
+	// responses, this is here for documentation purposes.
+	UnsolicitedFetch bool
+}
+
+// Profiles holds the built-in provider profiles used by NewForProvider.
+var Profiles = map[Provider]Profile{
+	Gmail: {
+		Host:             "imap.gmail.com:993",
+		TLS:              true,
+		OAuthScopes:      []string{"https://mail.google.com/"},
+		UnsolicitedFetch: true,
+	},
+	Office365: {
+		Host:        "outlook.office365.com:993",
+		TLS:         true,
+		OAuthScopes: []string{"https://outlook.office.com/IMAP.AccessAsUser.All", "offline_access"},
+	},
+	Yahoo: {
+		Host: "imap.mail.yahoo.com:993",
+		TLS:  true,
+		// Yahoo rejects commands from clients that have not sent an ID.
+		ID:          []string{"GUID", "1"},
+		OAuthScopes: []string{"mail-w"},
+	},
+	ICloud: {
+		// iCloud only supports app-specific passwords, no OAuth.
+		Host: "imap.mail.me.com:993",
+		TLS:  true,
+	},
+	Fastmail: {
+		Host:        "imap.fastmail.com:993",
+		TLS:         true,
+		OAuthScopes: []string{"https://www.fastmail.com/dev/protocol-imap"},
+	},
+}
+
+// NewForProvider initializes a new Client using the built-in Profile of the
+// given provider. The folder defaults to INBOX, any options given are applied
+// after the profile so they can override it. For providers that no longer
+// accept passwords pass SetXOAuth2 with a token for the profile's OAuthScopes.
+func NewForProvider(provider Provider, user, creds string, options ...func(*Client)) (*Client, error) {
+	profile, ok := Profiles[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider: %s", provider)
+	}
+
+	opts := []func(*Client){
+		SetTLS(profile.TLS),
+		SetFolder("INBOX"),
+		SetID(profile.ID...),
+	}
+	opts = append(opts, options...)
+
+	return New(profile.Host, user, creds, opts...)
+}
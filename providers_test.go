@@ -0,0 +1,21 @@
+package eazye
+
+import "testing"
+
+func TestNewForProviderUnknown(t *testing.T) {
+	_, err := NewForProvider(Provider("aol"), "user", "pwd")
+	if err == nil {
+		t.Error("NewForProvider() with an unknown provider did not return an error")
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	for provider, profile := range Profiles {
+		if profile.Host == "" {
+			t.Errorf("profile for %s has no host", provider)
+		}
+		if !profile.TLS {
+			t.Errorf("profile for %s does not use TLS", provider)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package eazye
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// Query is a set of IMAP search keys used to select messages in the folder.
+type Query struct {
+	keys []imap.Field
+}
+
+// All matches every message in the folder.
+func All() Query {
+	return Query{keys: []imap.Field{"ALL"}}
+}
+
+// Unread matches all messages without the \Seen flag.
+func Unread() Query {
+	return Query{keys: []imap.Field{"UNSEEN"}}
+}
+
+// Since matches all messages with an internal date on or after the given day.
+func Since(since time.Time) Query {
+	return Query{keys: []imap.Field{"SINCE", since.Format(dateFormat)}}
+}
+
+// Before matches all messages with an internal date before the given day.
+func Before(before time.Time) Query {
+	return Query{keys: []imap.Field{"BEFORE", before.Format(dateFormat)}}
+}
+
+// Subject matches all messages with the given string in their Subject header.
+// Non-ASCII strings are searched for using the UTF-8 charset.
+func Subject(s string) Query {
+	return Query{keys: []imap.Field{"SUBJECT", searchString(s)}}
+}
+
+// From matches all messages with the given string in their From header.
+func From(s string) Query {
+	return Query{keys: []imap.Field{"FROM", searchString(s)}}
+}
+
+// To matches all messages with the given string in their To header.
+func To(s string) Query {
+	return Query{keys: []imap.Field{"TO", searchString(s)}}
+}
+
+// Larger matches all messages larger than the given number of bytes.
+func Larger(size uint32) Query {
+	return Query{keys: []imap.Field{"LARGER", size}}
+}
+
+// Smaller matches all messages smaller than the given number of bytes.
+func Smaller(size uint32) Query {
+	return Query{keys: []imap.Field{"SMALLER", size}}
+}
+
+// Flagged matches all messages with the \Flagged flag.
+func Flagged() Query {
+	return Query{keys: []imap.Field{"FLAGGED"}}
+}
+
+// And matches the messages matched by all of the queries.
+func And(queries ...Query) Query {
+	var keys []imap.Field
+	for _, q := range queries {
+		keys = append(keys, q.keys...)
+	}
+	return Query{keys: keys}
+}
+
+// Not matches the messages not matched by the query.
+func Not(q Query) Query {
+	// lists are sent parenthesized, which makes the query a single key
+	return Query{keys: []imap.Field{"NOT", q.fields()}}
+}
+
+// Or matches the messages matched by either query.
+func Or(a, b Query) Query {
+	return Query{keys: []imap.Field{"OR", a.fields(), b.fields()}}
+}
+
+// searchString is a user supplied search key argument that has to be quoted
+// before it is sent to the server.
+type searchString string
+
+// fields returns the search keys, matching everything if the query is empty.
+func (q Query) fields() []imap.Field {
+	if len(q.keys) == 0 {
+		return []imap.Field{"ALL"}
+	}
+	return q.keys
+}
+
+// searchFields prepares the query for UIDSearch by quoting its strings. Any
+// non-ASCII strings are sent as literals (which go-imap sends as LITERAL+ if
+// the server allows it) and the search is done with CHARSET UTF-8, since many
+// servers refuse 8-bit quoted strings or assume US-ASCII otherwise.
+func (c *Client) searchFields(q Query) []imap.Field {
+	var charset bool
+	fields := c.quoteFields(q.fields(), &charset)
+	if charset {
+		fields = append([]imap.Field{"CHARSET", "UTF-8"}, fields...)
+	}
+	return fields
+}
+
+// quoteFields quotes the strings of the search keys, including those nested in
+// the lists of NOT and OR, and sets charset if any of them are not ASCII.
+func (c *Client) quoteFields(keys []imap.Field, charset *bool) []imap.Field {
+	fields := make([]imap.Field, 0, len(keys))
+	for _, key := range keys {
+		switch key := key.(type) {
+		case searchString:
+			if !isASCII(string(key)) {
+				*charset = true
+			}
+			fields = append(fields, c.server().Quote(string(key)))
+		case []imap.Field:
+			fields = append(fields, c.quoteFields(key, charset))
+		default:
+			fields = append(fields, key)
+		}
+	}
+	return fields
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package eazye
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+func TestSearchFieldsCharset(t *testing.T) {
+	c := &Client{Imap: &imap.Client{}}
+
+	tests := []struct {
+		given   Query
+		charset bool
+	}{
+		{All(), false},
+		{Subject("Breaking News"), false},
+		{Subject("Новости"), true},
+		{Subject("速報"), true},
+	}
+
+	for _, test := range tests {
+		fields := c.searchFields(test.given)
+		got := len(fields) > 1 && fields[0] == "CHARSET" && fields[1] == "UTF-8"
+		if got != test.charset {
+			t.Errorf("searchFields(%v) got charset %t, want %t", test.given.keys, got, test.charset)
+		}
+	}
+}
+
+func TestQueryBuilder(t *testing.T) {
+	day := time.Date(2016, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	q := And(From("bob@example.com"), Or(Flagged(), Larger(1024)), Not(And(Unread(), Before(day))))
+	want := "[FROM bob@example.com OR [FLAGGED] [LARGER 1024] NOT [UNSEEN BEFORE 01-Mar-2016]]"
+	if got := fmt.Sprint(q.fields()); got != want {
+		t.Errorf("fields() got %s, want %s", got, want)
+	}
+
+	if got := fmt.Sprint(Not(Query{}).fields()); got != "[NOT [ALL]]" {
+		t.Errorf("Not() of an empty query got %s, want [NOT [ALL]]", got)
+	}
+}
+
+func TestSearchFieldsNested(t *testing.T) {
+	c := &Client{Imap: &imap.Client{}}
+
+	fields := c.searchFields(Not(Subject("Новости")))
+	if len(fields) < 2 || fields[0] != "CHARSET" {
+		t.Errorf("searchFields() got %v, want CHARSET UTF-8 for a nested non-ASCII string", fields)
+	}
+	if _, ok := fields[len(fields)-1].([]imap.Field)[1].(searchString); ok {
+		t.Error("searchFields() did not quote a nested string")
+	}
+}
+
+func TestNewQuery(t *testing.T) {
+	c := &Client{Imap: &imap.Client{Caps: map[string]bool{"IMAP4REV1": true, "IMAP4REV2": true}}}
+	if got := fmt.Sprint(c.newQuery().fields()); got != "[NEW]" {
+		t.Errorf("newQuery() got %s, want [NEW]", got)
+	}
+
+	c.Imap.Caps = map[string]bool{"IMAP4REV2": true}
+	if got := fmt.Sprint(c.newQuery().fields()); got != "[UNSEEN]" {
+		t.Errorf("newQuery() on an IMAP4rev2 server got %s, want [UNSEEN]", got)
+	}
+}
@@ -0,0 +1,61 @@
+package eazye
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// modifyingCommands are the commands Raw refuses in safe mode.
+var modifyingCommands = []string{
+	"APPEND", "COPY", "CREATE", "DELETE", "DELETEACL", "EXPUNGE", "MOVE", "RENAME", "SELECT",
+	"SETACL", "SETMETADATA", "SETQUOTA", "STORE", "SUBSCRIBE", "UNSUBSCRIBE",
+}
+
+// Raw sends a command the Client has no function for, e.g. a server specific
+// extension such as GETQUOTAROOT, on the Client's connection and waits for it
+// to complete, reconnecting as the RetryPolicy allows. Strings in fields are
+// sent as atoms, so values that need quoting have to be quoted with
+// Quote. The untagged responses are in the Data of the command returned.
+// Commands that change the state of the connection, such as SELECT or
+// LOGOUT, leave the Client confused and should not be sent. In safe mode
+// commands that modify the mailbox return ErrReadOnlyMode.
+func (c *Client) Raw(cmd string, fields ...imap.Field) (*imap.Command, error) {
+	return c.RawContext(context.Background(), cmd, fields...)
+}
+
+// RawContext is Raw with a context.
+func (c *Client) RawContext(ctx context.Context, cmd string, fields ...imap.Field) (*imap.Command, error) {
+	name := strings.ToUpper(cmd)
+	if c.SafeMode && modifying(name, fields) {
+		return nil, ErrReadOnlyMode
+	}
+	rsp, err := c.do(ctx, func() (*imap.Command, error) {
+		return c.server().Send(name, fields...)
+	})
+	if err != nil {
+		return rsp, fmt.Errorf("unable to run %s: %w", name, err)
+	}
+	return rsp, nil
+}
+
+// Quote returns the value as a quoted string, or a literal if it has to be,
+// for use with Raw.
+func (c *Client) Quote(v any) imap.Field {
+	return c.server().Quote(v)
+}
+
+// modifying tells whether the command may modify the mailbox. Of the UID
+// commands only UID FETCH and UID SEARCH are taken not to.
+func modifying(name string, fields []imap.Field) bool {
+	if name == "UID" && len(fields) > 0 {
+		name += " " + strings.ToUpper(imap.AsAtom(fields[0]))
+	}
+	if sub, ok := strings.CutPrefix(name, "UID "); ok {
+		return sub != "FETCH" && sub != "SEARCH"
+	}
+	return slices.Contains(modifyingCommands, name)
+}
@@ -0,0 +1,29 @@
+package eazye
+
+import (
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+func TestModifying(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []imap.Field
+		want   bool
+	}{
+		{"GETQUOTAROOT", []imap.Field{"INBOX"}, false},
+		{"NAMESPACE", nil, false},
+		{"STORE", []imap.Field{"1", "+FLAGS", `\Seen`}, true},
+		{"UID MOVE", []imap.Field{"1", "Archive"}, true},
+		{"UID SEARCH", []imap.Field{"ALL"}, false},
+		{"UID", []imap.Field{"EXPUNGE", "1"}, true},
+		{"UID", []imap.Field{"FETCH", "1", "FLAGS"}, false},
+		{"SETMETADATA", []imap.Field{"INBOX"}, true},
+	}
+	for _, test := range tests {
+		if got := modifying(test.name, test.fields); got != test.want {
+			t.Errorf("modifying(%q, %v) got %v, want %v", test.name, test.fields, got, test.want)
+		}
+	}
+}
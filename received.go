@@ -0,0 +1,195 @@
+package eazye
+
+import (
+	"net"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Hop is a server an email passed through on its way, as recorded by the
+// server in a Received header.
+type Hop struct {
+	// From is the name the previous server introduced itself with and FromIP
+	// its address, if the server recorded it.
+	From   string
+	FromIP net.IP
+	// By is the name of the server that added the header.
+	By string
+	// With is the protocol the email was received with, e.g. ESMTPS.
+	With string
+	ID   string
+	For  string
+	Date time.Time
+	// Delay is how long the email took to get here from the previous hop. It
+	// is 0 for the first hop, or if either one has no date.
+	Delay time.Duration
+	// Raw is the header as is, for anything that did not parse.
+	Raw string
+}
+
+// DeliveryPath returns the servers the email passed through according to its
+// Received headers, in the order it went through them. The headers can be
+// forged by anyone before the first server that can be trusted.
+func (e Email) DeliveryPath() []Hop {
+	if e.Message == nil {
+		return nil
+	}
+
+	// every server prepends its header, so the newest one comes first
+	received := e.Message.Header["Received"]
+	hops := make([]Hop, 0, len(received))
+	for i := len(received) - 1; i >= 0; i-- {
+		hop := parseReceived(received[i])
+		if n := len(hops); n > 0 && !hop.Date.IsZero() && !hops[n-1].Date.IsZero() {
+			hop.Delay = hop.Date.Sub(hops[n-1].Date)
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+// OriginIP returns the address the email was first sent from: that of the
+// earliest hop from a public address, leaving out the private networks the
+// sender's servers may be in. It is nil if no hop has one. Like the rest of
+// the DeliveryPath, it can be forged by the sender.
+func (e Email) OriginIP() net.IP {
+	for _, hop := range e.DeliveryPath() {
+		if ip := hop.FromIP; ip != nil && ip.IsGlobalUnicast() && !ip.IsPrivate() {
+			return ip
+		}
+	}
+	return nil
+}
+
+// TransitDelay returns how long the email took from being sent, according
+// to its Date header, or else the date of its first hop, to reaching the last
+// hop. It is 0 if there are not enough dates to tell. As the clocks of the
+// servers are not in sync it is only an estimate, which can even be negative.
+func (e Email) TransitDelay() time.Duration {
+	hops := e.DeliveryPath()
+	if len(hops) == 0 || hops[len(hops)-1].Date.IsZero() {
+		return 0
+	}
+
+	sent := e.Date
+	if sent.IsZero() {
+		sent = hops[0].Date
+	}
+	if sent.IsZero() {
+		return 0
+	}
+	return hops[len(hops)-1].Date.Sub(sent)
+}
+
+// parseReceived parses a Received header as described by RFC 5321 section
+// 4.4, being lenient as servers are very creative with it.
+func parseReceived(value string) Hop {
+	hop := Hop{Raw: value}
+
+	clauses := value
+	if i := strings.LastIndexByte(value, ';'); i >= 0 {
+		clauses = value[:i]
+		if date, err := mail.ParseDate(strings.TrimSpace(value[i+1:])); err == nil {
+			hop.Date = date
+		}
+	}
+
+	tokens := receivedTokens(clauses)
+	for i := 0; i < len(tokens); i++ {
+		keyword := strings.ToLower(tokens[i])
+		if i+1 >= len(tokens) || isComment(tokens[i+1]) {
+			continue
+		}
+		value := tokens[i+1]
+
+		switch keyword {
+		case "from":
+			hop.From = strings.Trim(value, "[]")
+			hop.FromIP = parseIP(value)
+			// the address is usually in the comment that follows
+			for j := i + 2; j < len(tokens) && isComment(tokens[j]) && hop.FromIP == nil; j++ {
+				hop.FromIP = findIP(tokens[j])
+			}
+		case "by":
+			hop.By = value
+		case "with":
+			hop.With = value
+		case "id":
+			hop.ID = value
+		case "for":
+			hop.For = strings.Trim(value, "<>")
+		default:
+			continue
+		}
+		i++
+	}
+	return hop
+}
+
+// receivedTokens splits the clauses of a Received header on whitespace,
+// keeping parenthesized comments together as a single token.
+func receivedTokens(s string) []string {
+	var (
+		tokens []string
+		depth  int
+		start  = -1
+	)
+	for i, r := range s {
+		switch {
+		case r == '(':
+			if depth == 0 {
+				if start >= 0 {
+					tokens = append(tokens, s[start:i])
+				}
+				start = i
+			}
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+			if depth == 0 {
+				tokens = append(tokens, s[start:i+1])
+				start = -1
+			}
+		case depth > 0:
+		case r == ' ' || r == '\t' || r == '\r' || r == '\n':
+			if start >= 0 {
+				tokens = append(tokens, s[start:i])
+				start = -1
+			}
+		case start < 0:
+			start = i
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, s[start:])
+	}
+	return tokens
+}
+
+func isComment(token string) bool {
+	return strings.HasPrefix(token, "(")
+}
+
+// parseIP parses an address literal such as [192.0.2.1] or [IPv6:2001:db8::1].
+func parseIP(s string) net.IP {
+	s = strings.Trim(s, "[]")
+	if len(s) > 5 && strings.EqualFold(s[:5], "ipv6:") {
+		s = s[5:]
+	}
+	return net.ParseIP(s)
+}
+
+// findIP returns the first address found in a comment such as
+// (mail.example.com. [192.0.2.1]) or (192.0.2.1).
+func findIP(comment string) net.IP {
+	fields := strings.FieldsFunc(comment, func(r rune) bool {
+		return strings.ContainsRune(" \t()=", r)
+	})
+	for _, field := range fields {
+		if ip := parseIP(field); ip != nil {
+			return ip
+		}
+	}
+	return nil
+}
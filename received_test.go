@@ -0,0 +1,74 @@
+package eazye
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeliveryPath(t *testing.T) {
+	msg, err := mail.ReadMessage(strings.NewReader(quotedEmail))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hops := Email{Message: msg}.DeliveryPath()
+	if len(hops) != 2 {
+		t.Fatalf("DeliveryPath() got %d hops, want 2", len(hops))
+	}
+
+	first := hops[0]
+	if first.From != "mta852.e.latimes.com" || first.FromIP.String() != "63.232.236.160" {
+		t.Errorf("DeliveryPath() got first hop from %s [%s]", first.From, first.FromIP)
+	}
+	if first.By != "mx.google.com" || first.With != "ESMTP" || first.ID != "kd14si14437848pbb.64.2014.08.11.15.14.16" || first.For != "an.email.address@gmail.com" {
+		t.Errorf("DeliveryPath() got unexpected first hop: %+v", first)
+	}
+	if !first.Date.Equal(time.Date(2014, 8, 11, 22, 14, 16, 0, time.UTC)) {
+		t.Errorf("DeliveryPath() got first hop date %s", first.Date)
+	}
+
+	second := hops[1]
+	if second.From != "" || second.By != "10.220.224.7" || second.With != "SMTP" || second.ID != "im7csp165179vcb" {
+		t.Errorf("DeliveryPath() got unexpected second hop: %+v", second)
+	}
+	if second.Delay != time.Second {
+		t.Errorf("DeliveryPath() got delay %s, want 1s", second.Delay)
+	}
+}
+
+func TestOriginIP(t *testing.T) {
+	header := "Received: from mx.example.org (mx.example.org [198.51.100.7]) by mx.example.com; Tue, 1 Mar 2016 10:00:09 +0000\r\n" +
+		"Received: from laptop (unknown [10.0.0.12]) by mx.example.org; Tue, 1 Mar 2016 10:00:04 +0000\r\n" +
+		"Received: from localhost (localhost [127.0.0.1]) by laptop; Tue, 1 Mar 2016 10:00:02 +0000\r\n" +
+		"Date: Tue, 1 Mar 2016 10:00:00 +0000\r\n\r\n"
+	msg, err := mail.ReadMessage(strings.NewReader(header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	date, _ := msg.Header.Date()
+	email := Email{Message: msg, Date: date}
+
+	if ip := email.OriginIP(); ip.String() != "198.51.100.7" {
+		t.Errorf("OriginIP() got %s, want 198.51.100.7", ip)
+	}
+	if delay := email.TransitDelay(); delay != 9*time.Second {
+		t.Errorf("TransitDelay() got %s, want 9s", delay)
+	}
+
+	email.Date = time.Time{}
+	if delay := email.TransitDelay(); delay != 7*time.Second {
+		t.Errorf("TransitDelay() without Date got %s, want 7s", delay)
+	}
+	if ip, delay := (Email{}).OriginIP(), (Email{}).TransitDelay(); ip != nil || delay != 0 {
+		t.Errorf("OriginIP(), TransitDelay() got %s, %s without headers", ip, delay)
+	}
+}
+
+func TestParseReceivedIPv6(t *testing.T) {
+	hop := parseReceived("from [IPv6:2001:db8::1] (unknown) by mx.example.com (Postfix) with ESMTPSA; Tue, 1 Mar 2016 10:00:00 +0000")
+	if hop.FromIP.String() != "2001:db8::1" || hop.By != "mx.example.com" || hop.With != "ESMTPSA" {
+		t.Errorf("parseReceived() got %+v", hop)
+	}
+}
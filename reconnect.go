@@ -0,0 +1,134 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// RetryPolicy controls how a Client reconnects when the connection to the
+// server drops in the middle of a command.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times to reconnect and run the command
+	// again before giving up, 0 disables reconnecting.
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DefaultRetryPolicy is a sensible RetryPolicy for long-lived clients.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	MinBackoff:  time.Second,
+	MaxBackoff:  time.Minute,
+}
+
+// ErrUIDValidityChanged is returned when the folder was reset while the
+// Client was reconnecting, so the UIDs it was working with are meaningless.
+var ErrUIDValidityChanged = errors.New("uid validity changed while reconnecting")
+
+// SetRetryPolicy is a functional option to set the RetryPolicy attr.
+func SetRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.RetryPolicy = policy
+	}
+}
+
+// backoff returns how long to wait before the given reconnect attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	wait := p.MinBackoff
+	for i := 1; i < attempt && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	return wait
+}
+
+// do runs the command like wait does. If the connection drops it reconnects,
+// logging in and selecting the folder again, and runs the command again as
+// the RetryPolicy allows. The command has to call c.server() when run, as it
+// is a different client after reconnecting.
+func (c *Client) do(ctx context.Context, command func() (*imap.Command, error)) (*imap.Command, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	cmd, err := c.wait(ctx)(command())
+	for attempt := 1; attempt <= c.RetryPolicy.MaxAttempts && c.dropped(ctx, err); attempt++ {
+		select {
+		case <-ctx.Done():
+			return cmd, ctx.Err()
+		case <-time.After(c.RetryPolicy.backoff(attempt)):
+		}
+
+		c.log(ctx, slog.LevelWarn, "connection lost, reconnecting", "attempt", attempt, "err", err)
+		if err = c.reconnect(ctx); err != nil {
+			if errors.Is(err, ErrUIDValidityChanged) {
+				return nil, err
+			}
+			continue
+		}
+		cmd, err = c.wait(ctx)(command())
+	}
+	if c.dropped(ctx, err) {
+		return cmd, fmt.Errorf("%w: %w", ErrConnectionLost, err)
+	}
+	return cmd, err
+}
+
+// doOnce is do for commands that must not run twice, such as COPY or
+// APPEND. It only reconnects and runs the command again if it could not be
+// sent, as the server may have carried out one that dropped or timed out
+// while waiting for the answer.
+func (c *Client) doOnce(ctx context.Context, command func() (*imap.Command, error)) (*imap.Command, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	wait := c.wait(ctx)
+	cmd, err := command()
+	if err != nil && c.dropped(ctx, err) {
+		wait(cmd, err)
+		return c.do(ctx, command)
+	}
+	if cmd, err = wait(cmd, err); c.dropped(ctx, err) {
+		return cmd, fmt.Errorf("%w: %w", ErrConnectionLost, err)
+	}
+	return cmd, err
+}
+
+// dropped reports whether the error is down to a lost connection rather than
+// the server refusing the command.
+func (c *Client) dropped(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if server := c.server(); server == nil || server.State() == imap.Closed {
+		return true
+	}
+	var netErr net.Error
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr)
+}
+
+// reconnect replaces the connection with a new one.
+func (c *Client) reconnect(ctx context.Context) error {
+	validity := c.uidValidity()
+	if c.conn != nil {
+		c.conn.Close()
+	}
+
+	if err := c.connect(ctx); err != nil {
+		return err
+	}
+	c.metrics().Reconnected()
+	if validity != 0 && c.uidValidity() != validity {
+		return ErrUIDValidityChanged
+	}
+	return nil
+}
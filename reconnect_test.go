@@ -0,0 +1,136 @@
+package eazye
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, MinBackoff: time.Second, MaxBackoff: 10 * time.Second}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{9, 10 * time.Second},
+	}
+	for _, test := range tests {
+		if got := policy.backoff(test.attempt); got != test.want {
+			t.Errorf("backoff(%d) got %s, want %s", test.attempt, got, test.want)
+		}
+	}
+}
+
+// dropProxy forwards connections to the server, cutting the first one off
+// once it has passed a command containing cut along. It counts the
+// connections that passed one.
+type dropProxy struct {
+	net.Listener
+	addr string
+	cut  string
+
+	mu   sync.Mutex
+	sent int
+}
+
+func newDropProxy(t *testing.T, addr, cut string) *dropProxy {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	p := &dropProxy{Listener: l, addr: addr, cut: cut}
+	go p.serve()
+	return p
+}
+
+func (p *dropProxy) serve() {
+	for {
+		client, err := p.Accept()
+		if err != nil {
+			return
+		}
+		server, err := net.Dial("tcp", p.addr)
+		if err != nil {
+			client.Close()
+			continue
+		}
+		go func() {
+			io.Copy(client, server)
+			client.Close()
+		}()
+		go p.forward(client, server)
+	}
+}
+
+func (p *dropProxy) forward(client, server net.Conn) {
+	defer server.Close()
+	defer client.Close()
+	lines := bufio.NewReader(client)
+	for {
+		line, err := lines.ReadString('\n')
+		if err != nil {
+			return
+		}
+		if _, err = server.Write([]byte(line)); err != nil {
+			return
+		}
+		if strings.Contains(line, p.cut) {
+			p.mu.Lock()
+			p.sent++
+			first := p.sent == 1
+			p.mu.Unlock()
+			if first {
+				return
+			}
+		}
+	}
+}
+
+func (p *dropProxy) commands() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sent
+}
+
+func TestDoOnceDropped(t *testing.T) {
+	tests := []struct {
+		cut string
+		run func(*Client, Email) error
+	}{
+		{" UID COPY ", func(c *Client, email Email) error { return c.Copy(email, "Archive") }},
+		{" UID MOVE ", func(c *Client, email Email) error { return c.Move(email, "Archive") }},
+	}
+
+	for _, tt := range tests {
+		srv := testServer(t)
+		srv.AddFolder("Archive")
+		uid := srv.AddMessage("INBOX", []byte("Subject: hi\r\n\r\nx\r\n"))
+		proxy := newDropProxy(t, srv.Addr, tt.cut)
+
+		c, err := New(proxy.Addr().String(), "user", "secret", SetFolder("INBOX"), SetRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+		if err != nil {
+			t.Fatalf("New() returned an error: %s", err)
+		}
+		err = tt.run(c, Email{ID: uid})
+		if !errors.Is(err, ErrConnectionLost) {
+			t.Errorf("%q: got %v, want %s", tt.cut, err, ErrConnectionLost)
+		}
+		if got := proxy.commands(); got != 1 {
+			t.Errorf("%q: sent %d times, want once", tt.cut, got)
+		}
+		c.Close()
+	}
+}
@@ -0,0 +1,100 @@
+package eazye
+
+import "regexp"
+
+// DefaultRedaction replaces whatever a Redactor redacts if it has no
+// Replacement set.
+const DefaultRedaction = "[REDACTED]"
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	cardPattern  = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	phonePattern = regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{2,4}\)[\s.-]?|\b\d{2,4}[\s.-])\d{3,4}[\s.-]?\d{3,4}\b`)
+)
+
+// Redactor strips personal data out of the text of emails, for passing their
+// content along to logs or other systems that must not see it. Out of the box
+// it redacts email addresses, phone numbers and credit card numbers, the
+// latter only when they pass the Luhn check.
+type Redactor struct {
+	// Replacement is put in place of anything redacted, DefaultRedaction if
+	// not set.
+	Replacement string
+
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor returns a Redactor that also redacts anything matching the
+// given patterns.
+func NewRedactor(patterns ...*regexp.Regexp) *Redactor {
+	return &Redactor{patterns: patterns}
+}
+
+// SetRedactor is a functional option to set the Redactor attr.
+func SetRedactor(redactor *Redactor) Option {
+	return func(c *Client) {
+		c.Redactor = redactor
+	}
+}
+
+// Redact returns the text with all personal data replaced.
+func (r *Redactor) Redact(text string) string {
+	return string(r.redact([]byte(text)))
+}
+
+// Email redacts the Subject, HTML, Text and ExtractedText of the email in
+// place. The Message, Parts and Attachments still hold the original
+// content, as do the From, To and Cc addresses.
+func (r *Redactor) Email(e *Email) {
+	e.Subject = r.Redact(e.Subject)
+	if e.HTML != nil {
+		e.HTML = r.redact(e.HTML)
+	}
+	if e.Text != nil {
+		e.Text = r.redact(e.Text)
+	}
+	for i := range e.ExtractedText {
+		e.ExtractedText[i].Text = r.Redact(e.ExtractedText[i].Text)
+	}
+}
+
+func (r *Redactor) redact(text []byte) []byte {
+	replacement := []byte(r.Replacement)
+	if len(replacement) == 0 {
+		replacement = []byte(DefaultRedaction)
+	}
+
+	text = emailPattern.ReplaceAllLiteral(text, replacement)
+	// cards go before phones so their digits are not taken for a number
+	text = cardPattern.ReplaceAllFunc(text, func(match []byte) []byte {
+		if !luhn(match) {
+			return match
+		}
+		return replacement
+	})
+	text = phonePattern.ReplaceAllLiteral(text, replacement)
+	for _, pattern := range r.patterns {
+		text = pattern.ReplaceAllLiteral(text, replacement)
+	}
+	return text
+}
+
+// luhn tells whether the digits in s pass the Luhn checksum used by card
+// numbers. Anything other than a digit is ignored.
+func luhn(s []byte) bool {
+	var sum, n int
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		d := int(s[i] - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
@@ -0,0 +1,45 @@
+package eazye
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	r := NewRedactor(regexp.MustCompile(`ACME-\d+`))
+
+	tests := []struct {
+		given string
+		want  string
+	}{
+		{"write to jane.doe@example.com today", "write to [REDACTED] today"},
+		{"call +1 (555) 123-4567 or 555-123-4567", "call [REDACTED] or [REDACTED]"},
+		{"card 4111 1111 1111 1111 on file", "card [REDACTED] on file"},
+		// fails the Luhn check
+		{"order 4111111111111112", "order 4111111111111112"},
+		{"meeting on 2014-08-12 at 10:30", "meeting on 2014-08-12 at 10:30"},
+		{"ticket ACME-1234", "ticket [REDACTED]"},
+	}
+	for _, test := range tests {
+		if got := r.Redact(test.given); got != test.want {
+			t.Errorf("Redact(%q) got %q, want %q", test.given, got, test.want)
+		}
+	}
+}
+
+func TestRedactEmail(t *testing.T) {
+	r := &Redactor{Replacement: "***"}
+	email := Email{
+		Subject:       "from bob@example.com",
+		Text:          []byte("reach me at bob@example.com"),
+		ExtractedText: []ExtractedText{{Filename: "cv.pdf", Text: "bob@example.com"}},
+	}
+	r.Email(&email)
+
+	if email.Subject != "from ***" || string(email.Text) != "reach me at ***" || email.ExtractedText[0].Text != "***" {
+		t.Errorf("Email() got %q, %q, %q", email.Subject, email.Text, email.ExtractedText[0].Text)
+	}
+	if email.HTML != nil {
+		t.Errorf("Email() got HTML %q, want nil", email.HTML)
+	}
+}
@@ -0,0 +1,136 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// MessageRef identifies an email on a server in a way that can be saved and
+// acted on later, from another Client or another run. Every email a Client
+// fetches carries one in its Ref field.
+type MessageRef struct {
+	// Account is the user and host of the Client the email was fetched with,
+	// user@host.
+	Account string `json:"account"`
+	Folder  string `json:"folder"`
+	// UIDValidity of the folder the UID belongs to. If it changed since, or
+	// the Client uses SequenceNumbers, the email is looked up by its
+	// MessageID.
+	UIDValidity uint32 `json:"uid_validity"`
+	UID         uint32 `json:"uid"`
+	// MessageID is the Message-ID header as is, angle brackets included.
+	MessageID string `json:"message_id,omitempty"`
+}
+
+var (
+	// ErrWrongAccount is returned when acting on a MessageRef of another
+	// account than the Client's.
+	ErrWrongAccount = errors.New("message of another account")
+	// ErrStaleRef is returned when the email of a MessageRef can no longer be
+	// told apart for sure: the UIDVALIDITY of its folder changed and its
+	// Message-ID matches no email, or several.
+	ErrStaleRef = errors.New("stale message reference")
+)
+
+// account returns the Account of the MessageRefs of the Client.
+func (c *Client) account() string {
+	return c.user + "@" + c.host
+}
+
+// ref returns the MessageRef of an email fetched from the selected folder.
+func (c *Client) ref(email Email) MessageRef {
+	return MessageRef{
+		Account:     c.account(),
+		Folder:      c.Folder,
+		UIDValidity: c.uidValidity(),
+		UID:         imap.AsNumber(email.ID),
+		MessageID:   email.MessageID,
+	}
+}
+
+// DeleteRef deletes the email of the reference, see DeleteEmail.
+func (c *Client) DeleteRef(ref MessageRef) error {
+	return c.DeleteRefContext(context.Background(), ref)
+}
+
+// DeleteRefContext is DeleteRef with a context.
+func (c *Client) DeleteRefContext(ctx context.Context, ref MessageRef) error {
+	return c.withRef(ctx, ref, func(session *Client, email Email) error {
+		return session.DeleteEmailContext(ctx, email)
+	})
+}
+
+// MoveRef moves the email of the reference to the folder, see Move.
+func (c *Client) MoveRef(ref MessageRef, folder string) error {
+	return c.MoveRefContext(context.Background(), ref, folder)
+}
+
+// MoveRefContext is MoveRef with a context.
+func (c *Client) MoveRefContext(ctx context.Context, ref MessageRef, folder string) error {
+	return c.withRef(ctx, ref, func(session *Client, email Email) error {
+		return session.MoveContext(ctx, email, folder)
+	})
+}
+
+// FlagRef sets the flag on the email of the reference, or removes it if add
+// is false.
+func (c *Client) FlagRef(ref MessageRef, flag string, add bool) error {
+	return c.FlagRefContext(context.Background(), ref, flag, add)
+}
+
+// FlagRefContext is FlagRef with a context.
+func (c *Client) FlagRefContext(ctx context.Context, ref MessageRef, flag string, add bool) error {
+	return c.withRef(ctx, ref, func(session *Client, email Email) error {
+		return session.alterEmail(ctx, email, flag, add)
+	})
+}
+
+// withRef finds the email of the reference and calls fn with it and a Client
+// on its folder, a session of its own if it is not the selected one.
+func (c *Client) withRef(ctx context.Context, ref MessageRef, fn func(session *Client, email Email) error) error {
+	if c.SafeMode {
+		return ErrReadOnlyMode
+	}
+	if ref.Account != "" && ref.Account != c.account() {
+		return fmt.Errorf("%w: %s", ErrWrongAccount, ref.Account)
+	}
+
+	session := c
+	if ref.Folder != "" && ref.Folder != c.Folder {
+		var err error
+		if session, err = c.WithFolder(ref.Folder); err != nil {
+			return err
+		}
+		defer session.Close()
+	}
+
+	uid, err := session.resolveRef(ctx, ref)
+	if err != nil {
+		return err
+	}
+	return fn(session, Email{ID: uid, MessageID: ref.MessageID})
+}
+
+// resolveRef returns the UID of the email of the reference in the selected
+// folder, looking it up by Message-ID if the UIDs were reset.
+func (c *Client) resolveRef(ctx context.Context, ref MessageRef) (uint32, error) {
+	if ref.UIDValidity == c.uidValidity() && !c.SequenceNumbers {
+		return ref.UID, nil
+	}
+	if ref.MessageID == "" {
+		return 0, fmt.Errorf("%w: UIDVALIDITY changed and there is no Message-ID", ErrStaleRef)
+	}
+
+	cmd, err := c.wait(ctx)(c.uidSearch("HEADER", "Message-ID", c.server().Quote(ref.MessageID)))
+	if err != nil {
+		return 0, fmt.Errorf("uid search failed: %w", err)
+	}
+	uids := searchResults(cmd)
+	if len(uids) != 1 {
+		return 0, fmt.Errorf("%w: %d emails with Message-ID %s", ErrStaleRef, len(uids), ref.MessageID)
+	}
+	return uids[0], nil
+}
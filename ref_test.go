@@ -0,0 +1,89 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// mailboxConn is a fakeConn with a folder selected.
+type mailboxConn struct {
+	fakeConn
+	mailbox *imap.MailboxStatus
+}
+
+func (m *mailboxConn) SelectedMailbox() *imap.MailboxStatus { return m.mailbox }
+
+func TestClientRef(t *testing.T) {
+	c := &Client{
+		host:     "imap.example.com:993",
+		user:     "jane",
+		Folder:   "INBOX",
+		imapConn: &mailboxConn{mailbox: &imap.MailboxStatus{UIDValidity: 7}},
+	}
+	email := Email{ID: uint32(42), MessageID: "<1@x>"}
+	want := MessageRef{Account: "jane@imap.example.com:993", Folder: "INBOX", UIDValidity: 7, UID: 42, MessageID: "<1@x>"}
+	if got := c.ref(email); got != want {
+		t.Errorf("ref() got %+v, want %+v", got, want)
+	}
+}
+
+func TestWithRef(t *testing.T) {
+	conn := &mailboxConn{mailbox: &imap.MailboxStatus{UIDValidity: 7}}
+	c := &Client{host: "imap.example.com:993", user: "jane", Folder: "INBOX", imapConn: conn}
+
+	tests := []struct {
+		name    string
+		ref     MessageRef
+		wantUID uint32
+		wantErr error
+	}{
+		{"same validity", MessageRef{Account: "jane@imap.example.com:993", Folder: "INBOX", UIDValidity: 7, UID: 42}, 42, nil},
+		{"no account", MessageRef{UIDValidity: 7, UID: 42}, 42, nil},
+		{"other account", MessageRef{Account: "joe@imap.example.com:993", UIDValidity: 7, UID: 42}, 0, ErrWrongAccount},
+		{"stale without Message-ID", MessageRef{Folder: "INBOX", UIDValidity: 6, UID: 42}, 0, ErrStaleRef},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got uint32
+			err := c.withRef(context.Background(), test.ref, func(session *Client, email Email) error {
+				got = imap.AsNumber(email.ID)
+				return nil
+			})
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("withRef() got error %v, want %v", err, test.wantErr)
+			}
+			if got != test.wantUID {
+				t.Errorf("withRef() got UID %d, want %d", got, test.wantUID)
+			}
+		})
+	}
+
+	c.SafeMode = true
+	if err := c.DeleteRef(MessageRef{UIDValidity: 7, UID: 42}); !errors.Is(err, ErrReadOnlyMode) {
+		t.Errorf("DeleteRef() in safe mode got %v, want %s", err, ErrReadOnlyMode)
+	}
+}
+
+func TestFetchedRef(t *testing.T) {
+	srv := testServer(t)
+	uid := srv.AddMessage("INBOX", []byte("Message-ID: <1@example.com>\r\nSubject: hi\r\n\r\nx\r\n"))
+	c := testClient(t, srv)
+
+	fetch := map[string]func() ([]Email, error){
+		"GetAll":     func() ([]Email, error) { return c.GetAll(false, false) },
+		"GetHeaders": func() ([]Email, error) { return c.GetHeaders(All()) },
+	}
+	for name, get := range fetch {
+		emails, err := get()
+		if err != nil || len(emails) != 1 {
+			t.Fatalf("%s() got %d emails, %v, want 1", name, len(emails), err)
+		}
+		ref := emails[0].Ref
+		if ref.Folder != "INBOX" || ref.UID != uid || ref.UIDValidity != c.uidValidity() || ref.MessageID != "<1@example.com>" {
+			t.Errorf("%s() got Ref %+v", name, ref)
+		}
+	}
+}
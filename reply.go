@@ -0,0 +1,121 @@
+package eazye
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// Reply builds a reply to the email from the given address, with the text of
+// the email quoted below the body. Send it with Reply.Send.
+func (e Email) Reply(from *mail.Address, body string) (Reply, error) {
+	reply, err := NewReply(e, from, body)
+	if err != nil {
+		return Reply{}, err
+	}
+	text, err := e.TextBody()
+	if err != nil {
+		return Reply{}, fmt.Errorf("unable to quote email: %w", err)
+	}
+
+	var quoted strings.Builder
+	quoted.WriteString(body)
+	quoted.WriteString("\n\n")
+	quoted.WriteString(e.attribution())
+	quoted.WriteString("\n")
+	for _, line := range strings.Split(strings.TrimRight(text, "\r\n"), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(line, ">") {
+			quoted.WriteString(">" + line + "\n")
+		} else {
+			quoted.WriteString("> " + line + "\n")
+		}
+	}
+	reply.Body = quoted.String()
+	return reply, nil
+}
+
+// Forward builds a forward of the email from and to the given addresses, with
+// the headers and text of the email below the body. It refers to the email
+// the way a reply does, so it is threaded with it, but its attachments are
+// left out. Send it with Reply.Send.
+func (e Email) Forward(from *mail.Address, to []*mail.Address, body string) (Reply, error) {
+	text, err := e.TextBody()
+	if err != nil {
+		return Reply{}, fmt.Errorf("unable to forward email: %w", err)
+	}
+	id, err := newMessageID(from)
+	if err != nil {
+		return Reply{}, err
+	}
+
+	var refs []string
+	if e.Message != nil {
+		refs = parseMessageIDs(e.Message.Header.Get("References"))
+	}
+	if original, err := messageID(e); err == nil {
+		refs = append(refs, original)
+	}
+
+	var forward strings.Builder
+	forward.WriteString(body)
+	forward.WriteString("\n\n---------- Forwarded message ---------\n")
+	if e.From != nil {
+		forward.WriteString("From: " + e.From.String() + "\n")
+	}
+	if !e.Date.IsZero() {
+		forward.WriteString("Date: " + e.Date.Format(time.RFC1123Z) + "\n")
+	}
+	forward.WriteString("Subject: " + e.Subject + "\n")
+	if len(e.To) > 0 {
+		forward.WriteString("To: " + formatAddresses(e.To) + "\n")
+	}
+	forward.WriteString("\n" + text)
+
+	return Reply{
+		From:       from,
+		To:         to,
+		Subject:    forwardSubject(e.Subject),
+		MessageID:  id,
+		References: refs,
+		Body:       forward.String(),
+	}, nil
+}
+
+// Send delivers the reply with the sender, e.g. an SMTPSender.
+func (r Reply) Send(sender Sender) error {
+	if err := sender.Send(r.From.Address, r.Recipients(), r.Bytes()); err != nil {
+		return fmt.Errorf("unable to send %s: %w", r.MessageID, err)
+	}
+	return nil
+}
+
+// attribution is the line introducing the quoted text of the email.
+func (e Email) attribution() string {
+	who := "someone"
+	if e.From != nil {
+		who = e.From.String()
+	}
+	if e.Date.IsZero() {
+		return who + " wrote:"
+	}
+	return "On " + e.Date.Format("Mon, 2 Jan 2006 at 15:04") + ", " + who + " wrote:"
+}
+
+// forwardSubject prefixes the subject with "Fwd: " unless it already is.
+func forwardSubject(subject string) string {
+	lower := strings.ToLower(subject)
+	if strings.HasPrefix(lower, "fwd:") || strings.HasPrefix(lower, "fw:") {
+		return subject
+	}
+	return "Fwd: " + subject
+}
+
+func formatAddresses(addrs []*mail.Address) string {
+	formatted := make([]string, len(addrs))
+	for i, addr := range addrs {
+		formatted[i] = addr.String()
+	}
+	return strings.Join(formatted, ", ")
+}
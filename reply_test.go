@@ -0,0 +1,70 @@
+package eazye
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+type recordingSender struct {
+	from string
+	to   []string
+	msg  []byte
+}
+
+func (s *recordingSender) Send(from string, to []string, msg []byte) error {
+	s.from, s.to, s.msg = from, to, msg
+	return nil
+}
+
+func replyEmail(t *testing.T) Email {
+	return rawEmail(t, "From: Jane <jane@example.com>\r\nTo: help@example.com\r\n"+
+		"Subject: Printer\r\nDate: Mon, 11 Aug 2014 22:14:16 +0000\r\nMessage-Id: <2@example.com>\r\n"+
+		"References: <1@example.com>\r\nContent-Type: text/plain\r\n\r\nIt is broken.\r\n\r\n> old quote\r\n")
+}
+
+func TestEmailReply(t *testing.T) {
+	from := &mail.Address{Address: "help@example.com"}
+	reply, err := replyEmail(t).Reply(from, "On it.")
+	if err != nil {
+		t.Fatalf("Reply() returned an error: %s", err)
+	}
+	want := "On it.\n\nOn Mon, 11 Aug 2014 at 22:14, \"Jane\" <jane@example.com> wrote:\n> It is broken.\n>\n>> old quote\n"
+	if reply.Body != want {
+		t.Errorf("Reply() got body %q, want %q", reply.Body, want)
+	}
+
+	sender := &recordingSender{}
+	if err = reply.Send(sender); err != nil {
+		t.Fatalf("Send() returned an error: %s", err)
+	}
+	if sender.from != "help@example.com" || len(sender.to) != 1 || sender.to[0] != "jane@example.com" {
+		t.Errorf("Send() sent from %q to %v", sender.from, sender.to)
+	}
+	if !strings.Contains(string(sender.msg), "In-Reply-To: <2@example.com>\r\n") {
+		t.Errorf("Send() sent an unthreaded message:\n%s", sender.msg)
+	}
+}
+
+func TestEmailForward(t *testing.T) {
+	from := &mail.Address{Address: "help@example.com"}
+	to := []*mail.Address{{Address: "it@example.com"}}
+	forward, err := replyEmail(t).Forward(from, to, "FYI")
+	if err != nil {
+		t.Fatalf("Forward() returned an error: %s", err)
+	}
+	if forward.Subject != "Fwd: Printer" {
+		t.Errorf("Forward() got subject %q, want %q", forward.Subject, "Fwd: Printer")
+	}
+	if forward.InReplyTo != "" || strings.Join(forward.References, " ") != "<1@example.com> <2@example.com>" {
+		t.Errorf("Forward() is not threaded: %+v", forward)
+	}
+	if !strings.Contains(forward.Body, "Subject: Printer\n") || !strings.Contains(forward.Body, "It is broken.") {
+		t.Errorf("Forward() got body %q, want the forwarded email in it", forward.Body)
+	}
+
+	msg := string(forward.Bytes())
+	if strings.Contains(msg, "In-Reply-To") {
+		t.Errorf("Bytes() of a forward has an In-Reply-To header:\n%s", msg)
+	}
+}
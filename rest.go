@@ -0,0 +1,78 @@
+package eazye
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// restDo sends a request to a REST API, with the payload as JSON if it is not
+// nil, and returns the body of a successful response.
+func restDo(ctx context.Context, client *http.Client, token func(ctx context.Context) (string, error), method, target string, payload any) ([]byte, error) {
+	accessToken, err := token(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unable to get token: %w", ErrAuthFailed, err)
+	}
+
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, restError(resp.StatusCode, data)
+	}
+	return data, nil
+}
+
+// restError turns an error response into an error, matching ErrAuthFailed
+// or ErrEmailNotFound where it makes sense. Both Graph and Gmail describe the
+// error in the same kind of JSON object.
+func restError(status int, body []byte) error {
+	var e struct {
+		Error struct {
+			Code    any    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	msg := http.StatusText(status)
+	if json.Unmarshal(body, &e) == nil && e.Error.Message != "" {
+		msg = fmt.Sprintf("%v: %s", e.Error.Code, e.Error.Message)
+	}
+
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: %s", ErrAuthFailed, msg)
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrEmailNotFound, msg)
+	}
+	return fmt.Errorf("%d %s", status, msg)
+}
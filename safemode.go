@@ -0,0 +1,18 @@
+package eazye
+
+import "errors"
+
+// ErrReadOnlyMode is returned by anything that would modify the mailbox when
+// the Client is in safe mode.
+var ErrReadOnlyMode = errors.New("mailbox is in safe mode")
+
+// SetSafeMode is a functional option to set the SafeMode attr. In safe mode
+// the folder is opened read-only, emails are fetched without setting \Seen and
+// everything that would modify the mailbox (deleting, moving or copying,
+// changing flags or tags, expunging, creating folders) returns
+// ErrReadOnlyMode, whatever the other options and arguments say. Sessions opened with WithFolder inherit it.
+func SetSafeMode(safe bool) Option {
+	return func(c *Client) {
+		c.SafeMode = safe
+	}
+}
@@ -0,0 +1,41 @@
+package eazye
+
+import (
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+func TestSafeMode(t *testing.T) {
+	c := &Client{Imap: &imap.Client{}}
+	SetSafeMode(true)(c)
+	email := Email{ID: uint32(1)}
+
+	if err := c.DeleteEmail(email); err != ErrReadOnlyMode {
+		t.Errorf("DeleteEmail() got %v, want ErrReadOnlyMode", err)
+	}
+	if err := c.SetAsRead(email); err != ErrReadOnlyMode {
+		t.Errorf("SetAsRead() got %v, want ErrReadOnlyMode", err)
+	}
+	if err := c.Tag(email, "done"); err != ErrReadOnlyMode {
+		t.Errorf("Tag() got %v, want ErrReadOnlyMode", err)
+	}
+	if err := c.Expunge(); err != ErrReadOnlyMode {
+		t.Errorf("Expunge() got %v, want ErrReadOnlyMode", err)
+	}
+	if err := c.DeleteAndExpunge(email); err != ErrReadOnlyMode {
+		t.Errorf("DeleteAndExpunge() got %v, want ErrReadOnlyMode", err)
+	}
+	if err := c.Move(email, "Archive"); err != ErrReadOnlyMode {
+		t.Errorf("Move() got %v, want ErrReadOnlyMode", err)
+	}
+	if err := c.Copy(email, "Archive"); err != ErrReadOnlyMode {
+		t.Errorf("Copy() got %v, want ErrReadOnlyMode", err)
+	}
+	if err := c.CreateFolder("Archive"); err != ErrReadOnlyMode {
+		t.Errorf("CreateFolder() got %v, want ErrReadOnlyMode", err)
+	}
+	if _, err := c.DeduplicateFolder("INBOX", false); err != ErrReadOnlyMode {
+		t.Errorf("DeduplicateFolder() got %v, want ErrReadOnlyMode", err)
+	}
+}
@@ -0,0 +1,259 @@
+package eazye
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	// droppedTags are removed along with everything in them. So are SVG and
+	// MathML, whose elements can animate attributes into script URLs.
+	droppedTags = map[string]bool{
+		"script": true, "noscript": true, "iframe": true, "frame": true, "frameset": true,
+		"object": true, "embed": true, "applet": true, "base": true, "link": true,
+		"portal": true, "template": true, "svg": true, "math": true,
+	}
+	// safeTags are the elements kept, any other is replaced by its content.
+	safeTags = map[string]bool{
+		"html": true, "head": true, "body": true, "title": true, "meta": true, "style": true,
+		"div": true, "span": true, "p": true, "br": true, "hr": true, "wbr": true, "center": true,
+		"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+		"a": true, "img": true, "font": true, "blockquote": true, "pre": true, "code": true,
+		"b": true, "i": true, "u": true, "s": true, "strong": true, "em": true, "small": true, "big": true,
+		"sub": true, "sup": true, "strike": true, "del": true, "ins": true, "mark": true, "q": true,
+		"cite": true, "abbr": true, "acronym": true, "address": true, "kbd": true, "samp": true,
+		"tt": true, "var": true, "bdi": true, "bdo": true, "time": true,
+		"table": true, "caption": true, "colgroup": true, "col": true, "thead": true, "tbody": true,
+		"tfoot": true, "tr": true, "td": true, "th": true,
+		"ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true,
+		"section": true, "article": true, "header": true, "footer": true, "nav": true, "main": true,
+		"aside": true, "figure": true, "figcaption": true, "details": true, "summary": true,
+		"form": true, "input": true, "textarea": true, "select": true, "option": true, "optgroup": true,
+		"button": true, "label": true, "fieldset": true, "legend": true,
+	}
+	// safeAttrNames are the attributes kept, along with aria-* ones.
+	safeAttrNames = map[string]bool{
+		"id": true, "class": true, "style": true, "title": true, "lang": true, "dir": true, "role": true,
+		"align": true, "valign": true, "width": true, "height": true, "bgcolor": true, "color": true,
+		"background": true, "border": true, "cellpadding": true, "cellspacing": true, "colspan": true,
+		"rowspan": true, "nowrap": true, "span": true, "scope": true, "headers": true, "summary": true,
+		"face": true, "size": true, "hspace": true, "vspace": true, "clear": true,
+		"href": true, "src": true, "alt": true, "name": true, "target": true, "rel": true, "cite": true,
+		"datetime": true, "start": true, "reversed": true, "type": true, "value": true,
+		"action": true, "method": true, "for": true, "placeholder": true, "disabled": true,
+		"checked": true, "selected": true, "readonly": true, "multiple": true, "maxlength": true,
+		"rows": true, "cols": true, "label": true, "open": true, "charset": true, "content": true,
+	}
+	// urlAttrs are the attributes holding URLs, which must not run scripts.
+	urlAttrs = map[string]bool{"href": true, "src": true, "action": true, "background": true, "cite": true}
+	// unsafeCSS are the CSS constructs that run scripts or load other CSS.
+	unsafeCSS = []string{"expression(", "javascript:", "vbscript:", "behavior:", "-moz-binding", "@import"}
+
+	cssComment   = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	cssSeparator = regexp.MustCompile(`[;{}]`)
+)
+
+// SanitizeHTML returns an HTML body made safe to display in a web page. Only
+// the elements and attributes of common email markup are kept, less script
+// URLs, external form actions, meta refreshes and CSS that runs scripts or
+// imports more CSS. Scripts, frames, plugins, SVG and comments are removed
+// along with their content, other elements are replaced by their content.
+// The email is still laid out as it was meant to be, remote images included.
+func SanitizeHTML(body io.Reader) (string, error) {
+	doc, err := html.Parse(body)
+	if err != nil {
+		return "", err
+	}
+	sanitize(doc)
+
+	var b strings.Builder
+	if err = html.Render(&b, doc); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// SanitizedHTML returns the HTML body of the email, see SanitizeHTML, or ""
+// if it has none.
+func (e Email) SanitizedHTML() (string, error) {
+	html, _, err := e.bodies()
+	if err != nil || len(html) == 0 {
+		return "", err
+	}
+	return SanitizeHTML(bytes.NewReader(html))
+}
+
+// sanitize removes everything unsafe under n.
+func sanitize(n *html.Node) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		switch {
+		case child.Type == html.CommentNode, child.Type == html.ElementNode && droppedElement(child):
+			n.RemoveChild(child)
+		case child.Type == html.ElementNode && !safeTags[child.Data]:
+			sanitize(child)
+			for c := child.FirstChild; c != nil; c = child.FirstChild {
+				child.RemoveChild(c)
+				n.InsertBefore(c, child)
+			}
+			n.RemoveChild(child)
+		case child.Type == html.ElementNode && child.Data == "style" && !sanitizeStyle(child):
+			n.RemoveChild(child)
+		case child.Type == html.ElementNode:
+			child.Attr = safeAttrs(child)
+			sanitize(child)
+		}
+		child = next
+	}
+}
+
+// sanitizeStyle sanitizes the style sheet of a style element and tells
+// whether it can be kept. Its text is rendered as is, so it must not be able
+// to close the element, e.g. with a "</style" put together by removing a
+// comment.
+func sanitizeStyle(n *html.Node) bool {
+	for text := n.FirstChild; text != nil; text = text.NextSibling {
+		if text.Type != html.TextNode {
+			continue
+		}
+		if text.Data = sanitizeCSS(text.Data); strings.Contains(text.Data, "<") {
+			return false
+		}
+	}
+	return true
+}
+
+// droppedElement tells whether the element is removed along with its
+// content, see droppedTags. Meta elements are removed if they refresh or
+// redirect the page.
+func droppedElement(n *html.Node) bool {
+	if n.Data == "meta" {
+		return attr(n, "http-equiv") != ""
+	}
+	return droppedTags[n.Data] || n.Namespace != ""
+}
+
+// safeAttrs returns the attributes of n that are in safeAttrNames, less
+// script URLs, external form actions and unsafe CSS.
+func safeAttrs(n *html.Node) []html.Attribute {
+	var attrs []html.Attribute
+	for _, a := range n.Attr {
+		key := strings.ToLower(a.Key)
+		switch {
+		case a.Namespace != "", !safeAttrNames[key] && !strings.HasPrefix(key, "aria-"):
+			continue
+		case urlAttrs[key] && !safeURL(key, a.Val):
+			continue
+		case key == "style":
+			if a.Val = sanitizeCSS(a.Val); strings.TrimSpace(a.Val) == "" {
+				continue
+			}
+		}
+		attrs = append(attrs, a)
+	}
+	return attrs
+}
+
+// safeURL tells whether the URL of the attribute can be kept: it does not
+// run a script, only images are inlined as data, and forms are not sent to
+// other sites.
+func safeURL(key, val string) bool {
+	// browsers ignore the control characters and spaces script URLs are
+	// hidden with
+	val = strings.ToLower(strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, val))
+
+	scheme, _, found := strings.Cut(val, ":")
+	if !found || strings.ContainsAny(scheme, "/?#") {
+		// relative URLs
+		return !strings.HasPrefix(val, "//") || (key != "action" && key != "formaction")
+	}
+	switch scheme {
+	case "javascript", "vbscript":
+		return false
+	case "data":
+		return key == "src" && strings.HasPrefix(val, "data:image/") && !strings.HasPrefix(val, "data:image/svg")
+	}
+	return key != "action" && key != "formaction"
+}
+
+// sanitizeCSS removes the declarations and rules of a style sheet or
+// attribute that use unsafeCSS, along with all comments.
+func sanitizeCSS(css string) string {
+	css = cssComment.ReplaceAllString(css, "")
+
+	var b strings.Builder
+	start := 0
+	for _, loc := range cssSeparator.FindAllStringIndex(css, -1) {
+		b.WriteString(safeCSS(css[start:loc[0]]))
+		b.WriteString(css[loc[0]:loc[1]])
+		start = loc[1]
+	}
+	b.WriteString(safeCSS(css[start:]))
+	return b.String()
+}
+
+// safeCSS returns the part of a style sheet between separators, or "" if it
+// uses unsafeCSS. CSS escapes and whitespace, which can hide it, are undone
+// for the check.
+func safeCSS(part string) string {
+	normalized := strings.ToLower(strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, cssUnescape(part)))
+	for _, unsafe := range unsafeCSS {
+		if strings.Contains(normalized, unsafe) {
+			return ""
+		}
+	}
+	return part
+}
+
+// cssUnescape replaces the CSS escapes in s, a backslash followed by up to 6
+// hex digits and an optional space, or by any other character, with the
+// characters they stand for.
+func cssUnescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		j := i + 1
+		for j < len(s) && j < i+7 && isHex(s[j]) {
+			j++
+		}
+		if j == i+1 {
+			// not hex, the character itself
+			b.WriteByte(s[j])
+			i = j
+			continue
+		}
+		code, _ := strconv.ParseUint(s[i+1:j], 16, 32)
+		b.WriteRune(rune(code))
+		if j < len(s) && (s[j] == ' ' || s[j] == '\t' || s[j] == '\n') {
+			j++
+		}
+		i = j - 1
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
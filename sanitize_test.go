@@ -0,0 +1,107 @@
+package eazye
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	body := `<html><head><style>@import url(https://evil.example.com/x.css); p { color: red; width: expression(alert(1)) }</style>
+<script>alert(1)</script><meta http-equiv="refresh" content="0;url=https://evil.example.com"></head>
+<body onload="alert(1)"><!-- note --><table width="600"><tr><td style="padding: 4px; background: url(javascript:alert(1))">
+<a href="javascript:alert(1)" onclick="alert(1)">x</a><a href="https://example.com">y</a>
+<img src="https://example.com/logo.png"><iframe src="https://example.com"></iframe>
+<form action="https://evil.example.com/login"><input name="pwd"></form></td></tr></table></body></html>`
+
+	got, err := SanitizeHTML(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("SanitizeHTML() returned an error: %s", err)
+	}
+
+	for _, removed := range []string{"evil.example.com", "alert", "script", "onload", "onclick", "note", "iframe"} {
+		if strings.Contains(got, removed) {
+			t.Errorf("SanitizeHTML() got %q, want it without %q", got, removed)
+		}
+	}
+	for _, kept := range []string{"p { color: red;", `<table width="600">`, `style="padding: 4px;`, `<a>x</a>`, `<a href="https://example.com">y</a>`, `<img src="https://example.com/logo.png"/>`, `<form><input name="pwd"/></form>`} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("SanitizeHTML() got %q, want it with %q", got, kept)
+		}
+	}
+}
+
+func TestSanitizeHTMLAllowlist(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`<svg><a><animate attributeName=href values=javascript:alert(1)/><text>x</text></a></svg>`, `<body></body>`},
+		{`<math><mi xlink:href="javascript:alert(1)">x</mi></math>`, `<body></body>`},
+		{`<p><o:p>kept</o:p><blink>text</blink></p>`, `<body><p>kepttext</p></body>`},
+		{`<div data-x="1" formaction="javascript:alert(1)" align="center">x</div>`, `<body><div align="center">x</div></body>`},
+		{`<td><marquee><a href="https://example.com" ping="https://evil.example.com">y</a></marquee></td>`, `<body><a href="https://example.com">y</a></body>`},
+		{`<span aria-label="l">z</span>`, `<body><span aria-label="l">z</span></body>`},
+		{`<style>a{} </sty/**/le><img src=x onerror=alert(1)></style><p>hi</p>`, `<body><p>hi</p></body>`},
+	}
+
+	for _, tt := range tests {
+		got, err := SanitizeHTML(strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("SanitizeHTML(%q) returned an error: %s", tt.body, err)
+		}
+		if got = strings.TrimPrefix(got, "<html><head></head>"); got != tt.want+"</html>" {
+			t.Errorf("SanitizeHTML(%q) got %q, want %q", tt.body, got, tt.want+"</html>")
+		}
+	}
+}
+
+func TestSanitizeCSS(t *testing.T) {
+	tests := []struct {
+		css  string
+		want string
+	}{
+		{"color: red; padding: 0", "color: red; padding: 0"},
+		{"color: red; width: expression(alert(1))", "color: red;"},
+		{"width: EXPRESSION (alert(1)); color: red", "; color: red"},
+		{`width: expr\65 ssion(alert(1))`, ""},
+		{`background: url("java\script:alert(1)")`, ""},
+		{"width: expr/**/ession(alert(1))", ""},
+		{"behavior: url(x.htc)", ""},
+		{"@import 'x.css'; a { color: blue }", "; a { color: blue }"},
+		{"a { -moz-binding: url(x.xml#x) } b { color: red }", "a {} b { color: red }"},
+	}
+
+	for _, tt := range tests {
+		if got := sanitizeCSS(tt.css); got != tt.want {
+			t.Errorf("sanitizeCSS(%q) got %q, want %q", tt.css, got, tt.want)
+		}
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	tests := []struct {
+		key, val string
+		want     bool
+	}{
+		{"href", "https://example.com", true},
+		{"href", "mailto:bob@example.com", true},
+		{"href", "/relative/path:x", true},
+		{"href", "javascript:alert(1)", false},
+		{"href", " JaVa\tScRiPt:alert(1)", false},
+		{"href", "vbscript:msgbox(1)", false},
+		{"src", "data:image/png;base64,AAAA", true},
+		{"src", "data:image/svg+xml;base64,AAAA", false},
+		{"href", "data:text/html;base64,AAAA", false},
+		{"action", "https://evil.example.com/login", false},
+		{"action", "//evil.example.com/login", false},
+		{"action", "/login", true},
+		{"formaction", "https://evil.example.com/login", false},
+		{"src", "//example.com/logo.png", true},
+	}
+
+	for _, tt := range tests {
+		if got := safeURL(tt.key, tt.val); got != tt.want {
+			t.Errorf("safeURL(%q, %q) got %v, want %v", tt.key, tt.val, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package eazye
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// SaveOption is a functional option of SaveAttachments.
+type SaveOption func(*saveOptions)
+
+type saveOptions struct {
+	maxSize int
+	types   []string
+}
+
+// MaxAttachmentSize is a SaveOption to skip attachments over size bytes,
+// once decoded.
+func MaxAttachmentSize(size int) SaveOption {
+	return func(o *saveOptions) {
+		o.maxSize = size
+	}
+}
+
+// AttachmentTypes is a SaveOption to only save attachments of the given media
+// types, e.g. "application/pdf", or "image/*" for all images.
+func AttachmentTypes(types ...string) SaveOption {
+	return func(o *saveOptions) {
+		o.types = append(o.types, types...)
+	}
+}
+
+// accepts tells whether the attachment is to be saved.
+func (o saveOptions) accepts(attachment Attachment) bool {
+	if o.maxSize > 0 && attachment.Size > o.maxSize {
+		return false
+	}
+	if len(o.types) == 0 {
+		return true
+	}
+	mediaType := strings.ToLower(attachment.ContentType)
+	for _, t := range o.types {
+		if t = strings.ToLower(t); t == mediaType || strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, t[:len(t)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// SaveAttachments writes the decoded attachments of the email to files in
+// dir, creating it if need be, and returns their paths. The file names are
+// those of the attachments made safe, see SafeFilename, with " (1)", " (2)"
+// and so on added to the name of any that already exist, which are never
+// overwritten. The files are only readable by the user.
+func (e Email) SaveAttachments(dir string, options ...SaveOption) ([]string, error) {
+	var o saveOptions
+	for _, option := range options {
+		option(&o)
+	}
+
+	attachments, err := e.Attachments()
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create directory: %w", err)
+	}
+
+	var paths []string
+	for _, attachment := range attachments {
+		if !o.accepts(attachment) {
+			continue
+		}
+		name := SafeFilename(attachment.Filename, attachment.ContentType)
+		saved, err := saveFile(dir, name, attachment.Content)
+		if err != nil {
+			return paths, fmt.Errorf("unable to save %s: %w", name, err)
+		}
+		paths = append(paths, saved)
+	}
+	return paths, nil
+}
+
+// saveFile writes the content to a new file in dir named name, or name with a
+// number added if that one exists, and returns its path.
+func saveFile(dir, name string, content io.Reader) (string, error) {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 0; ; i++ {
+		file := filepath.Join(dir, name)
+		if i > 0 {
+			file = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+		}
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if _, err = io.Copy(f, content); err != nil {
+			f.Close()
+			os.Remove(file)
+			return "", err
+		}
+		return file, f.Close()
+	}
+}
+
+// maxFilename is the length in bytes file names are cut to, which most file
+// systems allow.
+const maxFilename = 200
+
+// SafeFilename returns the file name of an attachment made safe to create a
+// file with: directories, control characters and those not allowed on
+// Windows are dropped, as are leading dots so that it is not hidden, and long
+// names are cut, keeping the extension. Empty names become "attachment" with
+// an extension for the media type.
+func SafeFilename(name, mediaType string) string {
+	// the name of the file in any path, Windows ones included
+	name = name[strings.LastIndexAny(name, `/\`)+1:]
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(`<>:"|?*`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+	// Windows drops trailing dots and spaces
+	name = strings.TrimRight(name, ". ")
+
+	if name == "" {
+		name = "attachment"
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			name += exts[0]
+		}
+	}
+	if stem, _, _ := strings.Cut(strings.ToUpper(name), "."); reservedFilenames[stem] {
+		name = "_" + name
+	}
+
+	if len(name) > maxFilename {
+		ext := path.Ext(name)
+		if len(ext) > maxFilename/2 {
+			ext = ""
+		}
+		name = strings.ToValidUTF8(name[:maxFilename-len(ext)], "") + ext
+	}
+	return name
+}
+
+// reservedFilenames are the names of devices on Windows, which can't be used
+// as file names, with any extension.
+var reservedFilenames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
@@ -0,0 +1,83 @@
+package eazye
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+func TestSaveAttachments(t *testing.T) {
+	body := "--xyz\r\nContent-Type: text/plain\r\n\r\nhello\r\n" +
+		"--xyz\r\nContent-Type: text/plain\r\nContent-Disposition: attachment; filename=\"../../notes.txt\"\r\n\r\nnot this\r\n" +
+		"--xyz\r\nContent-Type: image/png\r\nContent-Disposition: attachment; filename=logo.png\r\nContent-Transfer-Encoding: base64\r\n\r\niVBORw0KGgo=\r\n" +
+		"--xyz\r\nContent-Type: application/pdf\r\nContent-Disposition: attachment\r\nContent-Transfer-Encoding: base64\r\n\r\nJVBERi0xLjQK\r\n" +
+		"--xyz--\r\n"
+	email, err := newEmail(imap.FieldMap{
+		"RFC822.HEADER": []byte(multipartHeader),
+		"BODY[]":        []byte(multipartHeader + body),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		options []SaveOption
+		want    []string
+	}{
+		{"all", nil, []string{"notes.txt", "logo.png", "attachment.pdf"}},
+		{"again", nil, []string{"notes (1).txt", "logo (1).png", "attachment (1).pdf"}},
+		{"types", []SaveOption{AttachmentTypes("image/*", "application/pdf")}, []string{"logo (2).png", "attachment (2).pdf"}},
+		{"size", []SaveOption{MaxAttachmentSize(8)}, []string{"notes (2).txt", "logo (3).png"}},
+	}
+
+	dir := filepath.Join(t.TempDir(), "attachments")
+	for _, tt := range tests {
+		paths, err := email.SaveAttachments(dir, tt.options...)
+		if err != nil {
+			t.Fatalf("SaveAttachments() %s returned an error: %s", tt.name, err)
+		}
+		var got []string
+		for _, path := range paths {
+			got = append(got, strings.TrimPrefix(path, dir+string(filepath.Separator)))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SaveAttachments() %s got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "notes.txt"))
+	if err != nil || string(content) != "not this" {
+		t.Errorf("SaveAttachments() wrote %q, %v, want %q", content, err, "not this")
+	}
+}
+
+func TestSafeFilename(t *testing.T) {
+	long := strings.Repeat("a", 300) + ".pdf"
+	tests := []struct {
+		name, mediaType string
+		want            string
+	}{
+		{"report.pdf", "application/pdf", "report.pdf"},
+		{"../../etc/passwd", "text/plain", "passwd"},
+		{`C:\Users\bob\report.pdf`, "application/pdf", "report.pdf"},
+		{"in<voice>?.pdf", "application/pdf", "invoice.pdf"},
+		{"tab\there.txt", "text/plain", "tabhere.txt"},
+		{".bashrc", "text/plain", "bashrc"},
+		{"name. ", "text/plain", "name"},
+		{"..", "application/pdf", "attachment.pdf"},
+		{"", "application/x-unknown", "attachment"},
+		{"con.txt", "text/plain", "_con.txt"},
+		{long, "application/pdf", strings.Repeat("a", maxFilename-4) + ".pdf"},
+	}
+
+	for _, tt := range tests {
+		if got := SafeFilename(tt.name, tt.mediaType); got != tt.want {
+			t.Errorf("SafeFilename(%q, %q) got %q, want %q", tt.name, tt.mediaType, got, tt.want)
+		}
+	}
+}
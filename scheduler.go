@@ -0,0 +1,315 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Schedule tells when a job runs next.
+type Schedule interface {
+	// Next returns the first time after the given one the job is due.
+	Next(after time.Time) time.Time
+}
+
+// Every returns a Schedule running a job every d, starting d after the
+// Scheduler does. d must be positive, the Scheduler refuses the job
+// otherwise.
+func Every(d time.Duration) Schedule {
+	return every(d)
+}
+
+type every time.Duration
+
+func (e every) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(e))
+}
+
+// Cron is a Schedule in the usual five field crontab format: minute, hour,
+// day of month, month and day of week (0 is Sunday). Fields take *, single
+// values, ranges such as 1-5, lists such as 1,15 and steps such as */10 or
+// 0-30/5. Like cron, if both the day of month and the day of week are
+// restricted a day matching either one will do.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	anyDOM, anyDOW                bool
+}
+
+// ParseCron parses a crontab schedule such as "30 2 * * 1-5".
+func ParseCron(spec string) (*Cron, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("bad cron schedule %q: want 5 fields, got %d", spec, len(fields))
+	}
+
+	var (
+		cron Cron
+		err  error
+	)
+	bounds := []struct {
+		field    *uint64
+		min, max int
+	}{
+		{&cron.minute, 0, 59},
+		{&cron.hour, 0, 23},
+		{&cron.dom, 1, 31},
+		{&cron.month, 1, 12},
+		{&cron.dow, 0, 7},
+	}
+	for i, b := range bounds {
+		if *b.field, err = parseCronField(fields[i], b.min, b.max); err != nil {
+			return nil, fmt.Errorf("bad cron schedule %q: %w", spec, err)
+		}
+	}
+	// 7 is Sunday too
+	if cron.dow&(1<<7) != 0 {
+		cron.dow |= 1
+	}
+	cron.anyDOM = fields[2] == "*"
+	cron.anyDOW = fields[4] == "*"
+	return &cron, nil
+}
+
+// parseCronField returns the values of a cron field as a bit set.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("bad value %q", part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("bad value %q", part)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// Next returns the first minute after the given time matching the schedule,
+// in the location of the given time.
+func (c *Cron) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	// any schedule matches within a few years, leap days included
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			// Truncate works on absolute time, which is off by the half
+			// hour in zones like Asia/Kolkata
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.anyDOM && c.anyDOW:
+		return true
+	case c.anyDOM:
+		return dow
+	case c.anyDOW:
+		return dom
+	}
+	return dom || dow
+}
+
+// Job is a recurring task run by a Scheduler, such as a cleanup, export or
+// sync, against each of its Clients in turn. A Job without Clients is run
+// with a nil one.
+type Job struct {
+	// Name identifies the job in the metrics, it must be unique.
+	Name     string
+	Schedule Schedule
+	Clients  []*Client
+	Run      func(ctx context.Context, c *Client) error
+}
+
+// JobMetrics tracks the runs of a Job.
+type JobMetrics struct {
+	Runs     int
+	Failures int
+	// Skipped counts the runs missed because the previous one had not
+	// finished yet.
+	Skipped      int
+	LastRun      time.Time
+	LastDuration time.Duration
+	// LastErr is the error of the last run, nil if it succeeded.
+	LastErr error
+	NextRun time.Time
+}
+
+// Scheduler runs Jobs on their schedules, for running eazye as a mailbox
+// maintenance daemon. A job is never run again while its last run is still
+// going.
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    []*scheduledJob
+	wake    chan struct{}
+	running sync.WaitGroup
+}
+
+type scheduledJob struct {
+	Job
+	busy    bool
+	metrics JobMetrics
+}
+
+// NewScheduler returns a Scheduler without any jobs.
+func NewScheduler() *Scheduler {
+	return &Scheduler{wake: make(chan struct{}, 1)}
+}
+
+// Add registers the job, it can be called while the Scheduler is running.
+// A schedule that is due again right away, such as Every(0), is refused.
+func (s *Scheduler) Add(job Job) error {
+	if job.Schedule == nil || job.Run == nil {
+		return errors.New("job needs a schedule and a run function")
+	}
+	now := time.Now()
+	next := job.Schedule.Next(now)
+	if !next.IsZero() && !next.After(now) {
+		return fmt.Errorf("job %q is due again right away", job.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.Name == job.Name {
+			return fmt.Errorf("job %q already exists", job.Name)
+		}
+	}
+	j := &scheduledJob{Job: job}
+	j.metrics.NextRun = next
+	s.jobs = append(s.jobs, j)
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run runs the jobs as they come due until the context is done, then waits
+// for the runs still going, which get the same context, to finish.
+func (s *Scheduler) Run(ctx context.Context) error {
+	defer s.running.Wait()
+	for {
+		next := s.startDue(ctx, time.Now())
+
+		wait := time.Hour
+		if !next.IsZero() {
+			wait = time.Until(next)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-s.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// Metrics returns the metrics of every job by name.
+func (s *Scheduler) Metrics() map[string]JobMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	metrics := make(map[string]JobMetrics, len(s.jobs))
+	for _, j := range s.jobs {
+		metrics[j.Name] = j.metrics
+	}
+	return metrics
+}
+
+// startDue starts the jobs due by now and returns when the next one is due.
+func (s *Scheduler) startDue(ctx context.Context, now time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next time.Time
+	for _, j := range s.jobs {
+		if !j.metrics.NextRun.IsZero() && !j.metrics.NextRun.After(now) {
+			if j.busy {
+				j.metrics.Skipped++
+			} else {
+				j.busy = true
+				s.running.Add(1)
+				go s.run(ctx, j)
+			}
+			j.metrics.NextRun = j.Schedule.Next(now)
+		}
+		if !j.metrics.NextRun.IsZero() && (next.IsZero() || j.metrics.NextRun.Before(next)) {
+			next = j.metrics.NextRun
+		}
+	}
+	return next
+}
+
+func (s *Scheduler) run(ctx context.Context, j *scheduledJob) {
+	defer s.running.Done()
+
+	start := time.Now()
+	var errs []error
+	for _, c := range j.Clients {
+		if err := j.Run(ctx, c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(j.Clients) == 0 {
+		if err := j.Run(ctx, nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.busy = false
+	j.metrics.Runs++
+	j.metrics.LastRun = start
+	j.metrics.LastDuration = time.Since(start)
+	j.metrics.LastErr = errors.Join(errs...)
+	if j.metrics.LastErr != nil {
+		j.metrics.Failures++
+	}
+}
@@ -0,0 +1,116 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// a Wednesday
+	from := time.Date(2024, 1, 10, 10, 17, 30, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 10, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 10, 10, 30, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2024, 1, 11, 2, 30, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2024, 1, 11, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// either the 1st or a Friday
+		{"0 0 1 * 5", time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC)},
+		{"0,45 10 10 1 *", time.Date(2024, 1, 10, 10, 45, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		cron, err := ParseCron(test.spec)
+		if err != nil {
+			t.Errorf("ParseCron(%q) returned an error: %s", test.spec, err)
+			continue
+		}
+		if got := cron.Next(from); !got.Equal(test.want) {
+			t.Errorf("ParseCron(%q).Next() got %s, want %s", test.spec, got, test.want)
+		}
+	}
+
+	// Asia/Kolkata, half an hour off the hours in UTC
+	kolkata := time.FixedZone("IST", 5*60*60+30*60)
+	cron, err := ParseCron("0 9 * * *")
+	if err != nil {
+		t.Fatalf("ParseCron() returned an error: %s", err)
+	}
+	want := time.Date(2024, 1, 11, 9, 0, 0, 0, kolkata)
+	if got := cron.Next(from.In(kolkata)); !got.Equal(want) {
+		t.Errorf("ParseCron(\"0 9 * * *\").Next() in Asia/Kolkata got %s, want %s", got, want)
+	}
+
+	for _, spec := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q) did not return an error", spec)
+		}
+	}
+}
+
+func TestSchedulerOverlap(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	s := NewScheduler()
+	err := s.Add(Job{
+		Name:     "slow",
+		Schedule: Every(time.Minute),
+		Run: func(ctx context.Context, c *Client) error {
+			started <- struct{}{}
+			<-release
+			return errors.New("boom")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Add(Job{Name: "slow", Schedule: Every(time.Second), Run: func(context.Context, *Client) error { return nil }}); err == nil {
+		t.Error("Add() of a duplicate name did not return an error")
+	}
+
+	// drive the clock by hand: the run is due, then due again while busy
+	now := time.Now()
+	s.startDue(context.Background(), now.Add(time.Minute))
+	<-started
+	s.startDue(context.Background(), now.Add(2*time.Minute))
+	close(release)
+	s.running.Wait()
+
+	m := s.Metrics()["slow"]
+	if m.Runs != 1 || m.Skipped != 1 || m.Failures != 1 || m.LastErr == nil {
+		t.Errorf("Metrics() got %+v, want a failed run and a skipped one", m)
+	}
+	if want := now.Add(3 * time.Minute); !m.NextRun.Equal(want) {
+		t.Errorf("Metrics() got next run %s, want %s", m.NextRun, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err = s.Run(ctx); err != context.Canceled {
+		t.Errorf("Run() got %v, want %s", err, context.Canceled)
+	}
+}
+
+func TestSchedulerAdd(t *testing.T) {
+	run := func(context.Context, *Client) error { return nil }
+	tests := []struct {
+		schedule Schedule
+		wantErr  bool
+	}{
+		{Every(time.Minute), false},
+		{Every(0), true},
+		{Every(-time.Second), true},
+		{nil, true},
+	}
+	for i, tt := range tests {
+		err := NewScheduler().Add(Job{Name: "job", Schedule: tt.schedule, Run: run})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Add() of schedule %d got %v, want error %v", i, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package eazye
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mxk/go-imap/imap"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SetMaxMessageSize is a functional option to set the MaxMessageSize attr.
+func SetMaxMessageSize(size uint32) Option {
+	return func(c *Client) {
+		c.MaxMessageSize = size
+	}
+}
+
+// skipLarge asks for the sizes of the emails and passes the ones over
+// MaxMessageSize along as skipped, with only their headers. It returns the
+// UIDs of the rest and whether to carry on.
+func (c *Client) skipLarge(ctx context.Context, uids []uint32, responses chan Response, fail func(error) bool) ([]uint32, bool) {
+	sizes, err := c.sizes(ctx, uids)
+	if err != nil {
+		send(ctx, responses, Response{Err: fmt.Errorf("unable to fetch sizes: %w", err)})
+		return nil, false
+	}
+	small, large := splitBySize(uids, sizes, c.MaxMessageSize)
+	if len(large) == 0 {
+		return small, true
+	}
+
+	batch := &imap.SeqSet{}
+	batch.AddNum(large...)
+	items := []string{"INTERNALDATE", "UID", "RFC822.HEADER", "FLAGS", "RFC822.SIZE"}
+	fetchCtx, span := c.startSpan(ctx, "eazye.Fetch", attribute.Int("imap.emails", len(large)))
+	cmd, err := c.do(fetchCtx, func() (*imap.Command, error) {
+		return c.uidFetch(batch, items...)
+	})
+	endSpan(span, err)
+	if err != nil {
+		send(ctx, responses, Response{Err: fmt.Errorf("unable to perform uid fetch: %w", err)})
+		return nil, false
+	}
+
+	for _, msgData := range cmd.Data {
+		info := c.messageInfo(msgData)
+		if _, ok := info.Attrs["RFC822.HEADER"]; !ok {
+			continue
+		}
+		c.track(info)
+		email, err := c.parse(ctx, info.Attrs)
+		if err != nil {
+			if fail(c.emailError(OpParse, info.UID, err)) {
+				continue
+			}
+			return nil, false
+		}
+		if !send(ctx, responses, Response{Email: email, Skipped: true}) {
+			return nil, false
+		}
+	}
+	return small, true
+}
+
+// sizes fetches the RFC822.SIZE of the emails, by UID.
+func (c *Client) sizes(ctx context.Context, uids []uint32) (map[uint32]uint32, error) {
+	seq := &imap.SeqSet{}
+	seq.AddNum(uids...)
+	cmd, err := c.do(ctx, func() (*imap.Command, error) {
+		return c.uidFetch(seq, "UID", "RFC822.SIZE")
+	})
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[uint32]uint32, len(uids))
+	for _, rsp := range cmd.Data {
+		info := c.messageInfo(rsp)
+		if size, ok := info.Attrs["RFC822.SIZE"]; ok {
+			sizes[info.UID] = imap.AsNumber(size)
+		}
+	}
+	return sizes, nil
+}
+
+// splitBySize splits the UIDs into those of emails up to max bytes and those
+// over it. Emails of unknown size are taken to be small.
+func splitBySize(uids []uint32, sizes map[uint32]uint32, max uint32) (small, large []uint32) {
+	for _, uid := range uids {
+		if sizes[uid] > max {
+			large = append(large, uid)
+		} else {
+			small = append(small, uid)
+		}
+	}
+	return small, large
+}
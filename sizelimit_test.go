@@ -0,0 +1,26 @@
+package eazye
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitBySize(t *testing.T) {
+	sizes := map[uint32]uint32{1: 100, 2: 5000, 3: 1000, 4: 1001}
+	tests := []struct {
+		uids      []uint32
+		max       uint32
+		wantSmall []uint32
+		wantLarge []uint32
+	}{
+		{[]uint32{1, 2, 3, 4}, 1000, []uint32{1, 3}, []uint32{2, 4}},
+		{[]uint32{1, 3}, 1000, []uint32{1, 3}, nil},
+		{[]uint32{2, 5}, 10, []uint32{5}, []uint32{2}},
+	}
+	for _, test := range tests {
+		small, large := splitBySize(test.uids, sizes, test.max)
+		if !reflect.DeepEqual(small, test.wantSmall) || !reflect.DeepEqual(large, test.wantLarge) {
+			t.Errorf("splitBySize(%v, %d) got %v, %v, want %v, %v", test.uids, test.max, small, large, test.wantSmall, test.wantLarge)
+		}
+	}
+}
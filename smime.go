@@ -0,0 +1,246 @@
+package eazye
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+
+	"github.com/smallstep/pkcs7"
+)
+
+// maxSMIMELayers is how many layers of signing and encryption Open goes
+// through, emails are signed and then encrypted at most.
+const maxSMIMELayers = 4
+
+// SMIME opens S/MIME emails (RFC 8551): decrypts the encrypted ones and
+// verifies the signature of the signed ones.
+type SMIME struct {
+	// Certificate and Key are those of the recipient, to decrypt emails
+	// encrypted for it. Signed emails can be opened without them.
+	Certificate *x509.Certificate
+	Key         crypto.PrivateKey
+	// Roots are the CAs signers must have a certificate from, the system
+	// ones if nil.
+	Roots *x509.CertPool
+}
+
+// SMIMEStatus tells what Open found an email to be.
+type SMIMEStatus struct {
+	Encrypted bool
+	Signed    bool
+	// Signer is the certificate of the signer of a signed email.
+	Signer *x509.Certificate
+}
+
+// Open returns the message inside an S/MIME email as an Email of its own,
+// with the header of the S/MIME one but for its Content fields, decrypting
+// it and verifying its signature as need be. The signer must have a
+// certificate for the From address of the email. An email that fails to
+// verify returns an error matching ErrBadSignature. Emails that are not
+// S/MIME are returned as they are.
+func (s SMIME) Open(email Email) (Email, SMIMEStatus, error) {
+	var status SMIMEStatus
+	from := email.From
+	for layer := 0; layer < maxSMIMELayers && email.Message != nil; layer++ {
+		mediaType, params, err := mime.ParseMediaType(email.Message.Header.Get("Content-Type"))
+		if err != nil {
+			return email, status, nil
+		}
+		raw, err := rawMessage(email)
+		if err != nil {
+			return email, status, err
+		}
+		header, body := splitMessage(raw)
+
+		var entity []byte
+		switch {
+		case mediaType == "multipart/signed" && isPKCS7(params["protocol"]):
+			if entity, status.Signer, err = s.verifyDetached(body, params["boundary"]); err != nil {
+				return email, status, err
+			}
+			status.Signed = true
+		case isPKCS7(mediaType):
+			data, err := io.ReadAll(decodeTransfer(email.Message.Header.Get("Content-Transfer-Encoding"), bytes.NewReader(body)))
+			if err != nil {
+				return email, status, fmt.Errorf("unable to decode S/MIME content: %w", err)
+			}
+			p7, err := pkcs7.Parse(data)
+			if err != nil {
+				return email, status, fmt.Errorf("unable to parse S/MIME content: %w", err)
+			}
+			if strings.EqualFold(params["smime-type"], "signed-data") {
+				if status.Signer, err = s.verify(p7); err != nil {
+					return email, status, err
+				}
+				entity, status.Signed = p7.Content, true
+			} else {
+				if entity, err = s.decrypt(p7); err != nil {
+					return email, status, err
+				}
+				status.Encrypted = true
+			}
+		default:
+			return email, status, nil
+		}
+		if status.Signed && !signedBy(status.Signer, from) {
+			return email, status, fmt.Errorf("%w: signer is not the sender %v", ErrBadSignature, from)
+		}
+
+		inner, err := parseRaw(email.ID, innerMessage(header, entity))
+		if err != nil {
+			return email, status, fmt.Errorf("unable to parse S/MIME content: %w", err)
+		}
+		inner.InternalDate, inner.Flags, inner.Size = email.InternalDate, email.Flags, email.Size
+		inner.GmailLabels, inner.GmailThreadID, inner.GmailMessageID = email.GmailLabels, email.GmailThreadID, email.GmailMessageID
+		email = inner
+	}
+	return email, status, nil
+}
+
+// decrypt returns the content of an enveloped-data.
+func (s SMIME) decrypt(p7 *pkcs7.PKCS7) ([]byte, error) {
+	if s.Certificate == nil || s.Key == nil {
+		return nil, errors.New("unable to decrypt email: no certificate and key to decrypt with")
+	}
+	content, err := p7.Decrypt(s.Certificate, s.Key)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt email: %w", err)
+	}
+	return content, nil
+}
+
+// verify checks the signature of a signed-data, and the certificate of its
+// signer against the Roots.
+func (s SMIME) verify(p7 *pkcs7.PKCS7) (*x509.Certificate, error) {
+	roots := s.Roots
+	if roots == nil {
+		var err error
+		if roots, err = x509.SystemCertPool(); err != nil {
+			return nil, fmt.Errorf("unable to load system roots: %w", err)
+		}
+	}
+	if err := p7.VerifyWithChain(roots); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadSignature, err)
+	}
+	return p7.GetOnlySigner(), nil
+}
+
+// signedBy tells whether the certificate is for the address, which is up to
+// its subject alternative names.
+func signedBy(cert *x509.Certificate, from *mail.Address) bool {
+	if cert == nil || from == nil {
+		return false
+	}
+	for _, address := range cert.EmailAddresses {
+		if strings.EqualFold(address, from.Address) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyDetached verifies a multipart/signed body and returns the entity it
+// signs.
+func (s SMIME) verifyDetached(body []byte, boundary string) ([]byte, *x509.Certificate, error) {
+	entity, err := signedEntity(body, boundary)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mr := multipart.NewReader(bytes.NewReader(body), boundary)
+	var signature []byte
+	for i := 0; i < 2; i++ {
+		part, err := mr.NextPart()
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to read signature: %w", err)
+		}
+		if i == 1 {
+			signature, err = io.ReadAll(decodeTransfer(part.Header.Get("Content-Transfer-Encoding"), part))
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to read signature: %w", err)
+			}
+		}
+	}
+
+	p7, err := pkcs7.Parse(signature)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrBadSignature, err)
+	}
+	// the signature is over the entity in canonical form
+	p7.Content = toCRLF(entity)
+	signer, err := s.verify(p7)
+	return entity, signer, err
+}
+
+// signedEntity returns the first part of a multipart/signed body exactly as
+// it is, headers included, which is what the signature is over.
+func signedEntity(body []byte, boundary string) ([]byte, error) {
+	delimiter := []byte("--" + boundary)
+	start := bytes.Index(body, delimiter)
+	if start < 0 || (start > 0 && body[start-1] != '\n') {
+		return nil, errors.New("unable to find signed content")
+	}
+	lineEnd := bytes.IndexByte(body[start:], '\n')
+	if lineEnd < 0 {
+		return nil, errors.New("unable to find signed content")
+	}
+	start += lineEnd + 1
+
+	end := bytes.Index(body[start:], append([]byte("\n"), delimiter...))
+	if end < 0 {
+		return nil, errors.New("unable to find end of signed content")
+	}
+	// the line break before the delimiter belongs to it
+	return bytes.TrimSuffix(body[start:start+end], []byte("\r")), nil
+}
+
+// isPKCS7 tells whether the media type is one of S/MIME.
+func isPKCS7(mediaType string) bool {
+	switch strings.ToLower(mediaType) {
+	case "application/pkcs7-mime", "application/x-pkcs7-mime", "application/pkcs7-signature", "application/x-pkcs7-signature":
+		return true
+	}
+	return false
+}
+
+// splitMessage splits a raw message into its header, blank line included,
+// and body.
+func splitMessage(raw []byte) (header, body []byte) {
+	crlf, lf := bytes.Index(raw, []byte("\r\n\r\n")), bytes.Index(raw, []byte("\n\n"))
+	switch {
+	case crlf >= 0 && (lf < 0 || crlf < lf):
+		return raw[:crlf+4], raw[crlf+4:]
+	case lf >= 0:
+		return raw[:lf+2], raw[lf+2:]
+	}
+	return raw, nil
+}
+
+// innerMessage puts the fields of the outer header, but for the Content ones
+// that describe the S/MIME content, in front of the inner entity.
+func innerMessage(header, entity []byte) []byte {
+	var raw bytes.Buffer
+	skip := false
+	for _, line := range bytes.SplitAfter(header, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		// folded lines go with the field they continue
+		if line[0] != ' ' && line[0] != '\t' {
+			skip = bytes.HasPrefix(bytes.ToLower(line), []byte("content-"))
+		}
+		if !skip {
+			raw.Write(bytes.TrimRight(line, "\r\n"))
+			raw.WriteString("\r\n")
+		}
+	}
+	raw.Write(entity)
+	return raw.Bytes()
+}
@@ -0,0 +1,151 @@
+package eazye
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/smallstep/pkcs7"
+)
+
+func smimeCertificate(t *testing.T, name string) (*x509.Certificate, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		EmailAddresses:        []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageEmailProtection},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func smimeEmail(t *testing.T, contentType string, body []byte) Email {
+	return rawEmail(t, "From: jane@example.com\r\nTo: joe@example.com\r\nSubject: Secret\r\n"+
+		"MIME-Version: 1.0\r\nContent-Type: "+contentType+"\r\n"+
+		"Content-Transfer-Encoding: base64\r\n\r\n"+base64.StdEncoding.EncodeToString(body)+"\r\n")
+}
+
+const smimeEntity = "Content-Type: text/plain\r\n\r\nMeet at noon.\r\n"
+
+func TestSMIMEOpen(t *testing.T) {
+	cert, key := smimeCertificate(t, "jane@example.com")
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	other, _ := smimeCertificate(t, "eve@example.com")
+	otherRoots := x509.NewCertPool()
+	otherRoots.AddCert(other)
+
+	encrypted, err := pkcs7.Encrypt([]byte(smimeEntity), []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd, err := pkcs7.NewSignedData([]byte(smimeEntity))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = sd.AddSigner(cert, key, pkcs7.SignerInfoConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	signed, err := sd.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	detached, err := pkcs7.NewSignedData([]byte(smimeEntity))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = detached.AddSigner(cert, key, pkcs7.SignerInfoConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	detached.Detach()
+	signature, err := detached.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	multipartSigned := rawEmail(t, "From: jane@example.com\r\nSubject: Secret\r\nMIME-Version: 1.0\r\n"+
+		"Content-Type: multipart/signed; protocol=\"application/pkcs7-signature\"; micalg=sha-256; boundary=b\r\n\r\n"+
+		"--b\r\n"+smimeEntity+"\r\n--b\r\nContent-Type: application/pkcs7-signature\r\nContent-Transfer-Encoding: base64\r\n\r\n"+
+		base64.StdEncoding.EncodeToString(signature)+"\r\n--b--\r\n")
+
+	forged := multipartSigned
+	forged.From = &mail.Address{Address: "joe@example.com"}
+
+	tests := []struct {
+		name   string
+		smime  SMIME
+		email  Email
+		want   SMIMEStatus
+		wantOK bool
+	}{
+		{"encrypted", SMIME{Certificate: cert, Key: key}, smimeEmail(t, "application/pkcs7-mime; smime-type=enveloped-data", encrypted), SMIMEStatus{Encrypted: true}, true},
+		{"signed", SMIME{Roots: roots}, smimeEmail(t, "application/pkcs7-mime; smime-type=signed-data", signed), SMIMEStatus{Signed: true, Signer: cert}, true},
+		{"multipart signed", SMIME{Roots: roots}, multipartSigned, SMIMEStatus{Signed: true, Signer: cert}, true},
+		{"untrusted signer", SMIME{Roots: otherRoots}, multipartSigned, SMIMEStatus{}, false},
+		{"signer not the sender", SMIME{Roots: roots}, forged, SMIMEStatus{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, status, err := test.smime.Open(test.email)
+			if !test.wantOK {
+				if !errors.Is(err, ErrBadSignature) {
+					t.Errorf("Open() got error %v, want ErrBadSignature", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open() returned an error: %s", err)
+			}
+			if status.Encrypted != test.want.Encrypted || status.Signed != test.want.Signed || !status.Signer.Equal(test.want.Signer) {
+				t.Errorf("Open() got status %+v, want %+v", status, test.want)
+			}
+			if strings.TrimSpace(string(got.Text)) != "Meet at noon." {
+				t.Errorf("Open() got text %q, want %q", got.Text, "Meet at noon.")
+			}
+			if got.Subject != "Secret" {
+				t.Errorf("Open() got subject %q, want %q", got.Subject, "Secret")
+			}
+		})
+	}
+}
+
+func TestSMIMEOpenPlain(t *testing.T) {
+	email := replyEmail(t)
+	got, status, err := SMIME{}.Open(email)
+	if err != nil {
+		t.Fatalf("Open() returned an error: %s", err)
+	}
+	if status != (SMIMEStatus{}) || string(got.Text) != string(email.Text) {
+		t.Errorf("Open() got %+v, want the email as it is", status)
+	}
+}
+
+func TestSMIMEOpenNoKey(t *testing.T) {
+	email := smimeEmail(t, "application/pkcs7-mime; smime-type=enveloped-data", []byte("junk"))
+	if _, _, err := (SMIME{}).Open(email); err == nil {
+		t.Error("Open() got no error, want one")
+	}
+}
@@ -0,0 +1,156 @@
+package eazye
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// SpecialFolders holds the names of the folders with a special use, empty
+// for those the server does not have.
+type SpecialFolders struct {
+	All     string
+	Archive string
+	Drafts  string
+	Flagged string
+	Junk    string
+	Sent    string
+	Trash   string
+}
+
+// specialUses tells how to recognise each special folder: by its SPECIAL-USE
+// attribute (RFC 6154), or the XLIST one used by older Gmail servers, and
+// failing that by the usual names.
+var specialUses = []struct {
+	attrs  []string
+	names  []string
+	folder func(*SpecialFolders) *string
+}{
+	{
+		attrs:  []string{`\All`, `\AllMail`},
+		names:  []string{"[Gmail]/All Mail", "[Google Mail]/All Mail"},
+		folder: func(s *SpecialFolders) *string { return &s.All },
+	},
+	{
+		attrs:  []string{`\Archive`},
+		names:  []string{"Archive", "Archives", "INBOX.Archive", "INBOX/Archive"},
+		folder: func(s *SpecialFolders) *string { return &s.Archive },
+	},
+	{
+		attrs:  []string{`\Drafts`},
+		names:  []string{"Drafts", "INBOX.Drafts", "INBOX/Drafts", "[Gmail]/Drafts", "[Google Mail]/Drafts"},
+		folder: func(s *SpecialFolders) *string { return &s.Drafts },
+	},
+	{
+		attrs:  []string{`\Flagged`, `\Starred`},
+		names:  []string{"[Gmail]/Starred", "[Google Mail]/Starred"},
+		folder: func(s *SpecialFolders) *string { return &s.Flagged },
+	},
+	{
+		attrs: []string{`\Junk`, `\Spam`},
+		names: []string{"Junk", "Spam", "Junk E-mail", "Junk Email", "Bulk Mail", "INBOX.Junk", "INBOX.Spam",
+			"INBOX/Junk", "INBOX/Spam", "[Gmail]/Spam", "[Google Mail]/Spam"},
+		folder: func(s *SpecialFolders) *string { return &s.Junk },
+	},
+	{
+		attrs: []string{`\Sent`},
+		names: []string{"Sent", "Sent Items", "Sent Messages", "Sent Mail", "INBOX.Sent", "INBOX/Sent",
+			"[Gmail]/Sent Mail", "[Google Mail]/Sent Mail"},
+		folder: func(s *SpecialFolders) *string { return &s.Sent },
+	},
+	{
+		attrs: []string{`\Trash`},
+		names: []string{"Trash", "INBOX.Trash", "INBOX/Trash", "Deleted Items", "Deleted Messages",
+			"[Gmail]/Trash", "[Gmail]/Bin", "[Google Mail]/Trash", "[Google Mail]/Bin"},
+		folder: func(s *SpecialFolders) *string { return &s.Trash },
+	},
+}
+
+// SpecialFolders finds the folders with a special use, such as Sent or Trash,
+// whatever their names on the server and in the user's language. Servers
+// with SPECIAL-USE (RFC 6154), or XLIST, mark them. For the others the usual
+// names are looked for. They are looked up once and remembered.
+func (c *Client) SpecialFolders() (SpecialFolders, error) {
+	return c.SpecialFoldersContext(context.Background())
+}
+
+// SpecialFoldersContext is SpecialFolders with a context.
+func (c *Client) SpecialFoldersContext(ctx context.Context) (SpecialFolders, error) {
+	if c.special != nil {
+		return *c.special, nil
+	}
+
+	var folders []Folder
+	var err error
+	if c.caps("XLIST") && !c.caps("SPECIAL-USE") {
+		folders, err = c.xlist(ctx)
+	} else {
+		folders, err = c.ListFoldersContext(ctx)
+	}
+	if err != nil {
+		return SpecialFolders{}, err
+	}
+
+	special := findSpecial(folders)
+	c.special = &special
+	return special, nil
+}
+
+// findSpecial picks the folders with a special use out of the folders.
+func findSpecial(folders []Folder) SpecialFolders {
+	var special SpecialFolders
+	for _, use := range specialUses {
+		folder := use.folder(&special)
+	attrs:
+		for _, f := range folders {
+			for _, attr := range use.attrs {
+				if f.HasAttr(attr) {
+					*folder = f.Name
+					break attrs
+				}
+			}
+		}
+		if *folder != "" {
+			continue
+		}
+	names:
+		for _, name := range use.names {
+			for _, f := range folders {
+				if strings.EqualFold(f.Name, name) && f.Selectable() {
+					*folder = f.Name
+					break names
+				}
+			}
+		}
+	}
+	return special
+}
+
+// xlist lists the folders with the XLIST command, which unlike LIST has the
+// special use attributes on older Gmail servers.
+func (c *Client) xlist(ctx context.Context) ([]Folder, error) {
+	cmd, err := c.do(ctx, func() (*imap.Command, error) {
+		return c.server().Send("XLIST", c.server().Quote(""), c.server().Quote("*"))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list folders: %w", err)
+	}
+
+	var folders []Folder
+	for _, rsp := range cmd.Data {
+		// * XLIST (\HasNoChildren \Sent) "/" "[Gmail]/Sent Mail"
+		if rsp.Label != "XLIST" || len(rsp.Fields) < 4 {
+			continue
+		}
+		folder := Folder{Name: decodeMailbox(imap.AsString(rsp.Fields[3])), Delimiter: imap.AsString(rsp.Fields[2])}
+		for _, attr := range imap.AsList(rsp.Fields[1]) {
+			folder.Attrs = append(folder.Attrs, imap.AsAtom(attr))
+		}
+		sort.Strings(folder.Attrs)
+		folders = append(folders, folder)
+	}
+	return folders, nil
+}
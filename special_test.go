@@ -0,0 +1,30 @@
+package eazye
+
+import "testing"
+
+func TestFindSpecial(t *testing.T) {
+	tests := []struct {
+		folders []Folder
+		want    SpecialFolders
+	}{
+		{
+			[]Folder{{Name: "INBOX"}, {Name: "Papierkorb", Attrs: []string{`\Trash`}}, {Name: "Trash"},
+				{Name: "Gesendet", Attrs: []string{`\HasNoChildren`, `\Sent`}}},
+			SpecialFolders{Sent: "Gesendet", Trash: "Papierkorb"},
+		},
+		{
+			[]Folder{{Name: "[Gmail]", Attrs: []string{`\Noselect`}}, {Name: "[Gmail]/All Mail", Attrs: []string{`\AllMail`}},
+				{Name: "[Gmail]/Spam", Attrs: []string{`\Spam`}}, {Name: "[Gmail]/Bin"}},
+			SpecialFolders{All: "[Gmail]/All Mail", Junk: "[Gmail]/Spam", Trash: "[Gmail]/Bin"},
+		},
+		{
+			[]Folder{{Name: "INBOX.trash"}, {Name: "INBOX.Sent"}, {Name: "Drafts", Attrs: []string{`\Noselect`}}},
+			SpecialFolders{Sent: "INBOX.Sent", Trash: "INBOX.trash"},
+		},
+	}
+	for _, test := range tests {
+		if got := findSpecial(test.folders); got != test.want {
+			t.Errorf("findSpecial(%v) got %+v, want %+v", test.folders, got, test.want)
+		}
+	}
+}
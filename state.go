@@ -0,0 +1,168 @@
+package eazye
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// State is what a Client knows about its folder, saved with SaveState so the
+// next run, e.g. a short-lived CLI invocation or a serverless function, can
+// pick up where it left off without looking through the folder again.
+type State struct {
+	Folder      string `json:"folder"`
+	UIDValidity uint32 `json:"uid_validity"`
+	// HighestUID is the highest UID of the emails fetched so far.
+	HighestUID uint32 `json:"highest_uid"`
+	// HighestModSeq is the highest mod-sequence (RFC 7162) of the emails
+	// fetched so far, 0 if the server does not support CONDSTORE.
+	HighestModSeq uint64 `json:"highest_modseq"`
+}
+
+// ErrStateExpired is returned by LoadState if the folder was reset since the
+// state was saved, so its UIDs no longer mean anything.
+var ErrStateExpired = errors.New("uid validity changed since the state was saved")
+
+// SaveState returns the state of the Client, call it once the emails fetched
+// have been handled.
+func (c *Client) SaveState() State {
+	return State{
+		Folder:        c.Folder,
+		UIDValidity:   c.uidValidity(),
+		HighestUID:    c.highestUID,
+		HighestModSeq: c.highestModSeq,
+	}
+}
+
+// LoadState picks up from a state saved by SaveState, selecting its folder
+// if the Client is on a different one. If the folder was reset in between it
+// returns ErrStateExpired and the Client starts from scratch.
+func (c *Client) LoadState(state State) error {
+	if state.Folder != "" && state.Folder != c.Folder {
+		if err := c.SelectFolder(state.Folder, c.ReadOnly); err != nil {
+			return err
+		}
+	}
+	c.highestUID, c.highestModSeq = 0, 0
+	if state.UIDValidity != c.uidValidity() {
+		return ErrStateExpired
+	}
+	c.highestUID, c.highestModSeq = state.HighestUID, state.HighestModSeq
+	return nil
+}
+
+// ReadState reads a state saved with WriteFile.
+func ReadState(path string) (State, error) {
+	var state State
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+// WriteFile saves the state as JSON in the file at the given path.
+func (s State) WriteFile(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// GetIncremental will pull the emails above the highest UID fetched so far,
+// which is every email in the folder for a fresh Client, see LoadState.
+func (c *Client) GetIncremental(markAsRead, delete bool) ([]Email, error) {
+	return c.GetIncrementalContext(context.Background(), markAsRead, delete)
+}
+
+// GetIncrementalContext is GetIncremental with a context.
+func (c *Client) GetIncrementalContext(ctx context.Context, markAsRead, delete bool) ([]Email, error) {
+	responses, err := c.GenerateIncrementalContext(ctx, markAsRead, delete)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.collect(ctx, responses)
+}
+
+// GenerateIncremental will find the emails above the highest UID fetched so
+// far and pass them along to the responses channel.
+func (c *Client) GenerateIncremental(markAsRead, delete bool) (chan Response, error) {
+	return c.GenerateIncrementalContext(context.Background(), markAsRead, delete)
+}
+
+// GenerateIncrementalContext is GenerateIncremental with a context. See
+// generateMail for how the context is handled.
+func (c *Client) GenerateIncrementalContext(ctx context.Context, markAsRead, delete bool) (chan Response, error) {
+	return c.generateIncremental(ctx, markAsRead, delete, false), nil
+}
+
+// generateIncremental is GenerateIncrementalContext, fetching the emails with
+// BODY.PEEK[] if peek is set, see generateUIDs.
+func (c *Client) generateIncremental(ctx context.Context, markAsRead, delete, peek bool) chan Response {
+	responses := make(chan Response, c.bufferSize())
+
+	go func() {
+		defer close(responses)
+
+		cmd, err := c.findEmails(ctx, c.incrementalQuery())
+		if err != nil {
+			send(ctx, responses, Response{Err: err})
+			return
+		}
+		// n:* always matches the last email, even if its UID is below n
+		var uids []uint32
+		for _, uid := range searchResults(cmd) {
+			if uid > c.highestUID {
+				uids = append(uids, uid)
+			}
+		}
+		c.getEmails(ctx, uids, markAsRead, delete, peek, responses)
+	}()
+
+	return responses
+}
+
+// incrementalQuery matches the emails above the highest UID, or above the
+// highest sequence number for servers without UID commands.
+func (c *Client) incrementalQuery() Query {
+	above := fmt.Sprintf("%d:*", c.highestUID+1)
+	if c.SequenceNumbers {
+		return Query{keys: []imap.Field{above}}
+	}
+	return Query{keys: []imap.Field{"UID", above}}
+}
+
+// track records the UID and mod-sequence of a fetched email in the state.
+func (c *Client) track(info *imap.MessageInfo) {
+	if info.UID > c.highestUID {
+		c.highestUID = info.UID
+	}
+	if modSeq := modSeq(info.Attrs["MODSEQ"]); modSeq > c.highestModSeq {
+		c.highestModSeq = modSeq
+	}
+}
+
+// modSeq returns the value of a MODSEQ fetch item, a parenthesized 63-bit
+// number which go-imap hands over as an atom when it does not fit a uint32.
+func modSeq(f imap.Field) uint64 {
+	list := imap.AsList(f)
+	if len(list) != 1 {
+		return 0
+	}
+	switch v := list[0].(type) {
+	case uint32:
+		return uint64(v)
+	case string:
+		n, _ := strconv.ParseUint(v, 10, 64)
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,63 @@
+package eazye
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+func TestStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	want := State{Folder: "INBOX", UIDValidity: 7, HighestUID: 42, HighestModSeq: 1 << 40}
+	if err := want.WriteFile(path); err != nil {
+		t.Fatalf("WriteFile() returned an error: %s", err)
+	}
+	got, err := ReadState(path)
+	if err != nil {
+		t.Fatalf("ReadState() returned an error: %s", err)
+	}
+	if got != want {
+		t.Errorf("ReadState() got %+v, want %+v", got, want)
+	}
+}
+
+func TestSaveAndLoadState(t *testing.T) {
+	c := &Client{Folder: "INBOX"}
+	c.track(&imap.MessageInfo{UID: 12, Attrs: imap.FieldMap{"MODSEQ": []imap.Field{uint32(300)}}})
+	c.track(&imap.MessageInfo{UID: 9, Attrs: imap.FieldMap{"MODSEQ": []imap.Field{"12345678901"}}})
+
+	state := c.SaveState()
+	want := State{Folder: "INBOX", HighestUID: 12, HighestModSeq: 12345678901}
+	if state != want {
+		t.Fatalf("SaveState() got %+v, want %+v", state, want)
+	}
+
+	fresh := &Client{Folder: "INBOX"}
+	if err := fresh.LoadState(state); err != nil {
+		t.Fatalf("LoadState() returned an error: %s", err)
+	}
+	if got := fresh.SaveState(); got != want {
+		t.Errorf("SaveState() after LoadState() got %+v, want %+v", got, want)
+	}
+
+	state.UIDValidity = 8
+	if err := fresh.LoadState(state); !errors.Is(err, ErrStateExpired) {
+		t.Errorf("LoadState() with another UIDVALIDITY got %v, want ErrStateExpired", err)
+	}
+	if got := fresh.SaveState(); got.HighestUID != 0 || got.HighestModSeq != 0 {
+		t.Errorf("SaveState() after an expired state got %+v, want it reset", got)
+	}
+}
+
+func TestIncrementalQuery(t *testing.T) {
+	c := &Client{highestUID: 41}
+	if got := c.incrementalQuery().fields(); len(got) != 2 || got[0] != "UID" || got[1] != "42:*" {
+		t.Errorf("incrementalQuery() got %v, want [UID 42:*]", got)
+	}
+	c.SequenceNumbers = true
+	if got := c.incrementalQuery().fields(); len(got) != 1 || got[0] != "42:*" {
+		t.Errorf("incrementalQuery() with sequence numbers got %v, want [42:*]", got)
+	}
+}
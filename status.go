@@ -0,0 +1,51 @@
+package eazye
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// FolderStatus holds the counts of a folder, as returned by Status.
+type FolderStatus struct {
+	Name     string
+	Messages uint32
+	Unseen   uint32
+	Recent   uint32
+	// UIDNext is the UID the next email in the folder will get.
+	UIDNext     uint32
+	UIDValidity uint32
+}
+
+// Status will return the counts of the folder with the STATUS command, which
+// does not select it, so it is cheap to call for many folders.
+func (c *Client) Status(folder string) (FolderStatus, error) {
+	return c.StatusContext(context.Background(), folder)
+}
+
+// StatusContext is Status with a context.
+func (c *Client) StatusContext(ctx context.Context, folder string) (FolderStatus, error) {
+	cmd, err := c.do(ctx, func() (*imap.Command, error) {
+		return c.server().Status(folder, "MESSAGES", "UNSEEN", "RECENT", "UIDNEXT", "UIDVALIDITY")
+	})
+	if err != nil {
+		return FolderStatus{}, fmt.Errorf("unable to get status of %s: %w", folder, err)
+	}
+
+	for _, rsp := range cmd.Data {
+		status := rsp.MailboxStatus()
+		if status == nil {
+			continue
+		}
+		return FolderStatus{
+			Name:        folder,
+			Messages:    status.Messages,
+			Unseen:      status.Unseen,
+			Recent:      status.Recent,
+			UIDNext:     status.UIDNext,
+			UIDValidity: status.UIDValidity,
+		}, nil
+	}
+	return FolderStatus{}, fmt.Errorf("%w: %s", ErrFolderNotFound, folder)
+}
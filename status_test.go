@@ -0,0 +1,41 @@
+package eazye
+
+import "testing"
+
+func TestStatus(t *testing.T) {
+	srv := testServer(t)
+	srv.AddFolder("Archive")
+	srv.AddMessage("Archive", []byte("Subject: one\r\n\r\nx\r\n"))
+	srv.AddMessage("Archive", []byte("Subject: two\r\n\r\nx\r\n"), `\Seen`)
+	c := testClient(t, srv)
+	srv.ResetCommands()
+
+	tests := []struct {
+		folder  string
+		want    FolderStatus
+		wantErr bool
+	}{
+		{"Archive", FolderStatus{Name: "Archive", Messages: 2, Unseen: 1, UIDNext: 3}, false},
+		{"INBOX", FolderStatus{Name: "INBOX", UIDNext: 1}, false},
+		{"Missing", FolderStatus{}, true},
+	}
+	for _, tt := range tests {
+		got, err := c.Status(tt.folder)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("Status(%q) got error %v, want error %v", tt.folder, err, tt.wantErr)
+		}
+		if err == nil && got.UIDValidity == 0 {
+			t.Errorf("Status(%q) got no UIDVALIDITY", tt.folder)
+		}
+		got.UIDValidity = 0
+		if got != tt.want {
+			t.Errorf("Status(%q) got %+v, want %+v", tt.folder, got, tt.want)
+		}
+	}
+	if hasCommand(srv, "SELECT") || hasCommand(srv, "EXAMINE") {
+		t.Errorf("Status() selected a folder: %q", srv.Commands())
+	}
+	if c.Folder != "INBOX" {
+		t.Errorf("Status() changed the folder to %q", c.Folder)
+	}
+}
@@ -0,0 +1,79 @@
+package eazye
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mxk/go-imap/imap"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// streamChunkSize is how much of the message StreamByUID fetches at a time,
+// a variable so tests can go through several chunks.
+var streamChunkSize = 1 << 20
+
+// StreamByUID writes the full message with the given UID, headers included,
+// to w without ever holding more than a small chunk of it in memory, e.g. for
+// the emails skipped for being over MaxMessageSize. The email is not marked
+// as read. It returns the number of bytes written.
+func (c *Client) StreamByUID(uid uint32, w io.Writer) (int64, error) {
+	return c.StreamByUIDContext(context.Background(), uid, w)
+}
+
+// StreamByUIDContext is StreamByUID with a context.
+func (c *Client) StreamByUIDContext(ctx context.Context, uid uint32, w io.Writer) (int64, error) {
+	ctx, span := c.startSpan(ctx, "eazye.Fetch", attribute.Int("imap.emails", 1))
+	written, err := c.stream(ctx, uid, w)
+	endSpan(span, err)
+	return written, err
+}
+
+func (c *Client) stream(ctx context.Context, uid uint32, w io.Writer) (int64, error) {
+	seq := &imap.SeqSet{}
+	seq.AddNum(uid)
+	var written int64
+	for {
+		item := fmt.Sprintf("BODY.PEEK[]<%d.%d>", written, streamChunkSize)
+		cmd, err := c.do(ctx, func() (*imap.Command, error) {
+			return c.uidFetch(seq, "UID", item)
+		})
+		if err != nil {
+			return written, fmt.Errorf("unable to perform uid fetch: %w", err)
+		}
+
+		chunk, ok := c.partialBody(cmd, uid)
+		if !ok {
+			if written == 0 {
+				return 0, ErrEmailNotFound
+			}
+			return written, nil
+		}
+		n, err := w.Write(chunk)
+		written += int64(n)
+		if err != nil {
+			return written, fmt.Errorf("unable to write email %d: %w", uid, err)
+		}
+		if len(chunk) < streamChunkSize {
+			return written, nil
+		}
+	}
+}
+
+// partialBody finds the chunk of the message sent for a partial fetch, as
+// BODY[]<origin>, among the responses.
+func (c *Client) partialBody(cmd *imap.Command, uid uint32) ([]byte, bool) {
+	for _, msgData := range cmd.Data {
+		info := c.messageInfo(msgData)
+		if info == nil || info.UID != uid {
+			continue
+		}
+		for key, value := range info.Attrs {
+			if strings.HasPrefix(key, "BODY[]") {
+				return imap.AsBytes(value), true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,55 @@
+package eazye
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestStreamByUID(t *testing.T) {
+	defer func(size int) { streamChunkSize = size }(streamChunkSize)
+	streamChunkSize = 16
+
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"within a chunk", "Subject: a\r\n\r\nx\r\n"},
+		{"several chunks", "Subject: several\r\n\r\n" + strings.Repeat("x", 40) + "\r\n"},
+		// 3 chunks of 16, the last fetch comes back empty
+		{"exact chunks", "Subject: exact\r\n\r\n" + strings.Repeat("x", 28) + "\r\n"},
+	}
+	for _, test := range tests {
+		srv := testServer(t)
+		uid := srv.AddMessage("INBOX", []byte(test.raw))
+		c := testClient(t, srv)
+
+		var buf bytes.Buffer
+		n, err := c.StreamByUID(uid, &buf)
+		if err != nil {
+			t.Errorf("%s: StreamByUID() returned an error: %s", test.name, err)
+			continue
+		}
+		if buf.String() != test.raw || n != int64(len(test.raw)) {
+			t.Errorf("%s: StreamByUID() got %d bytes %q, want %q", test.name, n, buf.String(), test.raw)
+		}
+		if got := serverHasFlag(srv, "INBOX", uid, `\Seen`); got {
+			t.Errorf("%s: StreamByUID() marked the email as read", test.name)
+		}
+	}
+}
+
+func TestStreamByUIDMissing(t *testing.T) {
+	srv := testServer(t)
+	srv.AddMessage("INBOX", []byte("Subject: a\r\n\r\nx\r\n"))
+	c := testClient(t, srv)
+
+	var buf bytes.Buffer
+	if _, err := c.StreamByUID(42, &buf); !errors.Is(err, ErrEmailNotFound) {
+		t.Errorf("StreamByUID() of a missing UID got %v, want %s", err, ErrEmailNotFound)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("StreamByUID() of a missing UID wrote %q", buf.String())
+	}
+}
@@ -0,0 +1,76 @@
+package eazye
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// attributionPattern matches the line introducing a quoted email, in the
+	// languages mail clients most often write it in.
+	attributionPattern = regexp.MustCompile(`^(?i:On\s.+\swrote|Le\s.+\sa\s+écrit|Am\s.+\sschrieb.*|El\s.+\sescribió|Il\s.+\sha\s+scritto|Op\s.+\sschreef.*|Em\s.+\sescreveu)\s*:$`)
+	// originalPattern matches the separators Outlook and others put above
+	// the email they quote in full.
+	originalPattern = regexp.MustCompile(`^(?i:-{2,}\s*Original Message\s*-{2,}|_{20,}|-{2,}\s*Reply message\s*-{2,})$`)
+	// sentFromPattern matches the signatures mobile mail apps add.
+	sentFromPattern = regexp.MustCompile(`^(?i:Sent from my \w+.*|Sent from (?:Mail|Outlook|Yahoo Mail) for .+|Get Outlook for .+)$`)
+)
+
+// StripQuotes returns the text of a reply without the email it replies to:
+// the "On ... wrote:" line and everything below it, the quoted email below an
+// "-----Original Message-----" separator and any other "> " quoted lines, so
+// only the new content is left.
+func StripQuotes(text string) string {
+	lines := bodyLines(text)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if originalPattern.MatchString(trimmed) || isAttribution(lines, i) {
+			lines = lines[:i]
+			break
+		}
+	}
+
+	var kept []string
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimLeft(line, " \t"), ">") {
+			kept = append(kept, line)
+		}
+	}
+	return joinLines(kept)
+}
+
+// StripSignature returns the text without the signature at the bottom: the
+// part below the "-- " delimiter (RFC 3676), or a "Sent from my phone" line
+// added by mobile mail apps.
+func StripSignature(text string) string {
+	lines := bodyLines(text)
+	for i, line := range lines {
+		if line == "-- " || line == "--" || sentFromPattern.MatchString(strings.TrimSpace(line)) {
+			lines = lines[:i]
+			break
+		}
+	}
+	return joinLines(lines)
+}
+
+// isAttribution tells whether the line at i introduces a quoted email, also
+// when it was wrapped over two lines as Gmail does with long names.
+func isAttribution(lines []string, i int) bool {
+	line := strings.TrimSpace(lines[i])
+	if attributionPattern.MatchString(line) {
+		return true
+	}
+	return i+1 < len(lines) && attributionPattern.MatchString(line+" "+strings.TrimSpace(lines[i+1]))
+}
+
+// bodyLines splits the text into lines as they are, whatever the line
+// endings.
+func bodyLines(text string) []string {
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
+
+// joinLines joins the lines back together without the blank lines left at
+// the end.
+func joinLines(lines []string) string {
+	return strings.TrimRight(strings.Join(lines, "\n"), " \t\n") + "\n"
+}
@@ -0,0 +1,39 @@
+package eazye
+
+import "testing"
+
+func TestStripQuotes(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Sounds good.\n\nOn Mon, 11 Aug 2014 at 22:14, Jane <jane@example.com> wrote:\n> Lunch?\n", "Sounds good.\n"},
+		{"Yes!\r\n\r\nOn Mon, Aug 11, 2014 at 10:14 PM, Jane Doe <\r\njane@example.com> wrote:\r\n> Lunch?\r\n", "Yes!\n"},
+		{"> Lunch at noon?\nSure.\n> Or one?\nNoon is better.\n", "Sure.\nNoon is better.\n"},
+		{"Ok\n\n-----Original Message-----\nFrom: Jane\nSubject: Lunch\n", "Ok\n"},
+		{"Oui.\n\nLe lun. 11 août 2014 à 22:14, Jane <jane@example.com> a écrit :\n> Déjeuner ?\n", "Oui.\n"},
+		{"Nothing quoted here.\n", "Nothing quoted here.\n"},
+	}
+	for _, test := range tests {
+		if got := StripQuotes(test.text); got != test.want {
+			t.Errorf("StripQuotes(%q) got %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestStripSignature(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Thanks\n\n-- \nJane Doe\nACME Corp\n", "Thanks\n"},
+		{"Thanks\n\nSent from my iPhone\n", "Thanks\n"},
+		{"Thanks\n--\nJane\n", "Thanks\n"},
+		{"a -- b\n", "a -- b\n"},
+	}
+	for _, test := range tests {
+		if got := StripSignature(test.text); got != test.want {
+			t.Errorf("StripSignature(%q) got %q, want %q", test.text, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package eazye
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// SyncStore persists the State of every folder synced with SyncNew between
+// runs. Implementations backed by a database only need to store a State per
+// folder name.
+type SyncStore interface {
+	// Load returns the saved state of the folder, or the zero State if there
+	// is none.
+	Load(folder string) (State, error)
+	Save(state State) error
+}
+
+// FileSyncStore is a SyncStore that keeps the state of each folder as JSON in
+// a file of its own, in the directory at the given path.
+type FileSyncStore string
+
+// Load reads the state of the folder, a missing file is not an error.
+func (f FileSyncStore) Load(folder string) (State, error) {
+	state, err := ReadState(f.path(folder))
+	if os.IsNotExist(err) {
+		return State{}, nil
+	}
+	return state, err
+}
+
+// Save writes the state to the file of its folder.
+func (f FileSyncStore) Save(state State) error {
+	if err := os.MkdirAll(string(f), 0o700); err != nil {
+		return err
+	}
+	return state.WriteFile(f.path(state.Folder))
+}
+
+func (f FileSyncStore) path(folder string) string {
+	return filepath.Join(string(f), url.PathEscape(folder)+".json")
+}
+
+// SQLSyncStore is a SyncStore that keeps the state of each folder in a table
+// of a SQLite database, opened with any database/sql driver for it.
+type SQLSyncStore struct {
+	DB *sql.DB
+}
+
+// NewSQLSyncStore initializes a SQLSyncStore on the database, creating its
+// eazye_sync_state table if it does not exist yet.
+func NewSQLSyncStore(db *sql.DB) (*SQLSyncStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS eazye_sync_state (
+	folder TEXT PRIMARY KEY,
+	uid_validity INTEGER NOT NULL,
+	highest_uid INTEGER NOT NULL,
+	highest_modseq INTEGER NOT NULL
+)`)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create sync state table: %w", err)
+	}
+	return &SQLSyncStore{DB: db}, nil
+}
+
+// Load reads the state of the folder, a missing row is not an error.
+func (s *SQLSyncStore) Load(folder string) (State, error) {
+	state := State{Folder: folder}
+	var modSeq int64
+	err := s.DB.QueryRow(`SELECT uid_validity, highest_uid, highest_modseq FROM eazye_sync_state WHERE folder = ?`, folder).
+		Scan(&state.UIDValidity, &state.HighestUID, &modSeq)
+	if errors.Is(err, sql.ErrNoRows) {
+		return State{}, nil
+	}
+	if err != nil {
+		return State{}, fmt.Errorf("unable to load sync state: %w", err)
+	}
+	state.HighestModSeq = uint64(modSeq)
+	return state, nil
+}
+
+// Save writes the state to the row of its folder.
+func (s *SQLSyncStore) Save(state State) error {
+	_, err := s.DB.Exec(`INSERT INTO eazye_sync_state (folder, uid_validity, highest_uid, highest_modseq) VALUES (?, ?, ?, ?)
+ON CONFLICT (folder) DO UPDATE SET uid_validity = excluded.uid_validity, highest_uid = excluded.highest_uid, highest_modseq = excluded.highest_modseq`,
+		state.Folder, int64(state.UIDValidity), int64(state.HighestUID), int64(state.HighestModSeq))
+	if err != nil {
+		return fmt.Errorf("unable to save sync state: %w", err)
+	}
+	return nil
+}
+
+// ErrNoSyncStore is returned by SyncNew if the Client has no SyncStore.
+var ErrNoSyncStore = errors.New("no sync store")
+
+// SetSyncStore is a functional option to set the SyncStore attr.
+func SetSyncStore(store SyncStore) Option {
+	return func(c *Client) {
+		c.SyncStore = store
+	}
+}
+
+// SyncNew will pull the emails added to the folder since the last call, for
+// this or any earlier run sharing the SyncStore. The first call, and the
+// first one after the folder's UIDVALIDITY changed, returns every email in
+// the folder. The emails are left unread. The state is only saved if all of
+// the emails were fetched, otherwise the next call returns them again.
+func (c *Client) SyncNew() ([]Email, error) {
+	return c.SyncNewContext(context.Background())
+}
+
+// SyncNewContext is SyncNew with a context.
+func (c *Client) SyncNewContext(ctx context.Context) ([]Email, error) {
+	if c.SyncStore == nil {
+		return nil, ErrNoSyncStore
+	}
+
+	state, err := c.SyncStore.Load(c.Folder)
+	if err != nil {
+		return nil, err
+	}
+	// the state is for this folder, never switch away from it
+	state.Folder = c.Folder
+	// an expired state leaves the Client starting from scratch, which is the
+	// full resync we want
+	if err = c.LoadState(state); err != nil && !errors.Is(err, ErrStateExpired) {
+		return nil, err
+	}
+
+	emails, err := c.collect(ctx, c.generateIncremental(ctx, false, false, true))
+	if err != nil {
+		return emails, err
+	}
+	return emails, c.SyncStore.Save(c.SaveState())
+}
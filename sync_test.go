@@ -0,0 +1,148 @@
+package eazye
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFileSyncStore(t *testing.T) {
+	store := FileSyncStore(t.TempDir())
+
+	state, err := store.Load("INBOX")
+	if err != nil {
+		t.Fatalf("Load() of a new folder returned an error: %s", err)
+	}
+	if state != (State{}) {
+		t.Errorf("Load() of a new folder got %+v, want the zero State", state)
+	}
+
+	inbox := State{Folder: "INBOX", UIDValidity: 7, HighestUID: 42}
+	sent := State{Folder: "[Gmail]/Sent Mail", UIDValidity: 3, HighestUID: 9}
+	for _, s := range []State{inbox, sent} {
+		if err = store.Save(s); err != nil {
+			t.Fatalf("Save() returned an error: %s", err)
+		}
+	}
+
+	for _, want := range []State{inbox, sent} {
+		got, err := store.Load(want.Folder)
+		if err != nil {
+			t.Fatalf("Load() returned an error: %s", err)
+		}
+		if got != want {
+			t.Errorf("Load(%q) got %+v, want %+v", want.Folder, got, want)
+		}
+	}
+}
+
+func TestSyncNewWithoutStore(t *testing.T) {
+	if _, err := (&Client{}).SyncNew(); err != ErrNoSyncStore {
+		t.Errorf("SyncNew() without a store got %v, want ErrNoSyncStore", err)
+	}
+}
+
+func TestSQLSyncStore(t *testing.T) {
+	store, err := NewSQLSyncStore(sql.OpenDB(&fakeSQL{rows: map[string][]driver.Value{}}))
+	if err != nil {
+		t.Fatalf("NewSQLSyncStore() returned an error: %s", err)
+	}
+
+	state, err := store.Load("INBOX")
+	if err != nil {
+		t.Fatalf("Load() of a new folder returned an error: %s", err)
+	}
+	if state != (State{}) {
+		t.Errorf("Load() of a new folder got %+v, want the zero State", state)
+	}
+
+	want := State{Folder: "INBOX", UIDValidity: 7, HighestUID: 42, HighestModSeq: 1 << 40}
+	for _, s := range []State{{Folder: "INBOX", UIDValidity: 7, HighestUID: 1}, want} {
+		if err = store.Save(s); err != nil {
+			t.Fatalf("Save() returned an error: %s", err)
+		}
+	}
+	if got, err := store.Load("INBOX"); err != nil || got != want {
+		t.Errorf("Load() got %+v, %v, want %+v", got, err, want)
+	}
+}
+
+func TestSyncNew(t *testing.T) {
+	srv := testServer(t)
+	srv.AddMessage("INBOX", []byte("Subject: one\r\n\r\n1\r\n"), `\Seen`)
+	srv.AddMessage("INBOX", []byte("Subject: two\r\n\r\n2\r\n"))
+
+	c := testClient(t, srv, SetSyncStore(FileSyncStore(t.TempDir())))
+	emails, err := c.SyncNew()
+	if err != nil || len(emails) != 2 {
+		t.Fatalf("SyncNew() got %d emails, %v, want 2", len(emails), err)
+	}
+	srv.AddMessage("INBOX", []byte("Subject: three\r\n\r\n3\r\n"))
+	emails, err = c.SyncNew()
+	if err != nil || len(emails) != 1 || emails[0].Subject != "three" {
+		t.Fatalf("SyncNew() got %d emails, %v, want three", len(emails), err)
+	}
+
+	if !serverHasFlag(srv, "INBOX", 1, `\Seen`) || serverHasFlag(srv, "INBOX", 2, `\Seen`) {
+		t.Errorf("SyncNew() changed the flags: %+v", srv.Messages("INBOX"))
+	}
+	if hasCommand(srv, "UID STORE") {
+		t.Errorf("SyncNew() stored flags: %q", srv.Commands())
+	}
+}
+
+// fakeSQL is a database/sql driver keeping the rows of eazye_sync_state in
+// memory, by folder. It only understands the statements of SQLSyncStore.
+type fakeSQL struct {
+	rows map[string][]driver.Value
+}
+
+func (f *fakeSQL) Connect(context.Context) (driver.Conn, error) { return f, nil }
+func (f *fakeSQL) Driver() driver.Driver                        { return nil }
+func (f *fakeSQL) Prepare(query string) (driver.Stmt, error)    { return fakeStmt{f, query}, nil }
+func (f *fakeSQL) Close() error                                 { return nil }
+func (f *fakeSQL) Begin() (driver.Tx, error)                    { return nil, driver.ErrSkip }
+
+type fakeStmt struct {
+	db    *fakeSQL
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if strings.HasPrefix(s.query, "INSERT") {
+		s.db.rows[args[0].(string)] = args[1:]
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows := &fakeRows{}
+	if row, ok := s.db.rows[args[0].(string)]; ok {
+		rows.rows = append(rows.rows, row)
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"uid_validity", "highest_uid", "highest_modseq"}
+}
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
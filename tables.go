@@ -0,0 +1,120 @@
+package eazye
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ExtractTables returns the tables of an HTML body as rows of cells, in the
+// order they appear. Each cell holds its visible text with the whitespace
+// collapsed. Nested tables are returned as tables of their own and left out
+// of the text of the cell they are in. Rows without any cells are dropped.
+func ExtractTables(body io.Reader) ([][][]string, error) {
+	doc, err := html.Parse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		tables [][][]string
+		walk   func(n *html.Node)
+	)
+	walk = func(n *html.Node) {
+		if isElement(n, "table") {
+			tables = append(tables, tableRows(n))
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	return tables, nil
+}
+
+// Tables returns the tables of the HTML body of the email, see ExtractTables.
+func (e Email) Tables() ([][][]string, error) {
+	html, _, err := e.bodies()
+	if err != nil || len(html) == 0 {
+		return nil, err
+	}
+	return ExtractTables(bytes.NewReader(html))
+}
+
+// tableRows collects the rows of the table, skipping those of any tables
+// nested in it.
+func tableRows(table *html.Node) [][]string {
+	var (
+		rows [][]string
+		walk func(n *html.Node)
+	)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			switch {
+			case isElement(child, "table"):
+				continue
+			case isElement(child, "tr"):
+				if row := rowCells(child); len(row) > 0 {
+					rows = append(rows, row)
+				}
+			default:
+				walk(child)
+			}
+		}
+	}
+	walk(table)
+	return rows
+}
+
+// rowCells returns the text of the td and th cells of a row.
+func rowCells(tr *html.Node) []string {
+	var cells []string
+	for child := tr.FirstChild; child != nil; child = child.NextSibling {
+		if isElement(child, "td") || isElement(child, "th") {
+			cells = append(cells, cellText(child))
+		}
+	}
+	return cells
+}
+
+// cellText returns the visible text of the cell, leaving out nested tables
+// and the tags VisibleText skips.
+func cellText(cell *html.Node) string {
+	var (
+		words []string
+		walk  func(n *html.Node)
+	)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			switch child.Type {
+			case html.TextNode:
+				words = append(words, strings.Fields(child.Data)...)
+			case html.ElementNode:
+				if child.Data == "table" || isNonVisible(child.Data) {
+					continue
+				}
+				walk(child)
+			}
+		}
+	}
+	walk(cell)
+	return strings.Join(words, " ")
+}
+
+// isElement tells whether the node is an element with the given tag name.
+func isElement(n *html.Node, tag string) bool {
+	return n.Type == html.ElementNode && n.Data == tag
+}
+
+// isNonVisible tells whether the tag is one of nonVisibleTags.
+func isNonVisible(tag string) bool {
+	for _, nvTag := range nonVisibleTags {
+		if tag == string(nvTag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package eazye
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractTables(t *testing.T) {
+	body := `<html><head><style>td { color: red; }</style></head><body>
+<p>Your order</p>
+<table>
+  <thead><tr><th>Item</th><th>Price</th></tr></thead>
+  <tbody>
+    <tr><td>Coffee
+        beans</td><td>$12.00</td></tr>
+    <tr><td>Mug<br>(blue)</td><td><b>$8.50</b></td></tr>
+    <tr></tr>
+    <tr><td colspan="2"><table><tr><td>Shipping</td><td>free</td></tr></table>Total $20.50</td></tr>
+  </tbody>
+</table>
+</body></html>`
+
+	tables, err := ExtractTables(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("ExtractTables() returned an error: %s", err)
+	}
+
+	want := [][][]string{
+		{
+			{"Item", "Price"},
+			{"Coffee beans", "$12.00"},
+			{"Mug (blue)", "$8.50"},
+			{"Total $20.50"},
+		},
+		{
+			{"Shipping", "free"},
+		},
+	}
+	if !reflect.DeepEqual(tables, want) {
+		t.Errorf("ExtractTables() got %q, want %q", tables, want)
+	}
+}
+
+func TestExtractTablesNone(t *testing.T) {
+	tables, err := ExtractTables(strings.NewReader("<p>no tables here</p>"))
+	if err != nil {
+		t.Fatalf("ExtractTables() returned an error: %s", err)
+	}
+	if len(tables) != 0 {
+		t.Errorf("ExtractTables() got %q, want no tables", tables)
+	}
+}
@@ -0,0 +1,238 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// TagMode is the mechanism used to tag emails.
+type TagMode int
+
+const (
+	// TagAuto picks the best mechanism the server supports.
+	TagAuto TagMode = iota
+	// TagLabels uses Gmail labels (X-GM-LABELS).
+	TagLabels
+	// TagKeywords uses IMAP keywords, i.e. custom flags.
+	TagKeywords
+	// TagFolders copies the email into a folder named after the tag. It is
+	// the last resort for servers without keyword support and by far the
+	// slowest when it comes to untagging and listing tags.
+	TagFolders
+)
+
+// SetTagMode is a functional option to set the TagMode attr.
+func SetTagMode(mode TagMode) Option {
+	return func(c *Client) {
+		c.TagMode = mode
+	}
+}
+
+// tagMode resolves TagAuto to the mechanism the server supports.
+func (c *Client) tagMode() TagMode {
+	if c.TagMode != TagAuto {
+		return c.TagMode
+	}
+	if c.caps("X-GM-EXT-1") {
+		return TagLabels
+	}
+	if mailbox := c.mailbox(); mailbox != nil && mailbox.PermFlags[`\*`] {
+		return TagKeywords
+	}
+	return TagFolders
+}
+
+// Tag adds the tag to the email.
+func (c *Client) Tag(email Email, tag string) error {
+	if c.SafeMode {
+		return ErrReadOnlyMode
+	}
+	seq := &imap.SeqSet{}
+	seq.AddNum(imap.AsNumber(email.ID))
+
+	var err error
+	switch c.tagMode() {
+	case TagLabels:
+		_, err = c.wait(context.Background())(c.uidStore(seq, "+X-GM-LABELS", []imap.Field{c.server().Quote(encodeMailbox(tag))}))
+	case TagKeywords:
+		if !validKeyword(tag) {
+			return fmt.Errorf("invalid keyword: %q", tag)
+		}
+		_, err = c.wait(context.Background())(c.uidStore(seq, "+FLAGS", tag))
+	default:
+		// the folder most likely exists already, in that case this fails
+		c.wait(context.Background())(c.server().Create(tag))
+		_, err = c.wait(context.Background())(c.uidCopy(seq, tag))
+	}
+	if err != nil {
+		return fmt.Errorf("unable to tag email: %w", err)
+	}
+	return nil
+}
+
+// Untag removes the tag from the email.
+func (c *Client) Untag(email Email, tag string) error {
+	if c.SafeMode {
+		return ErrReadOnlyMode
+	}
+	seq := &imap.SeqSet{}
+	seq.AddNum(imap.AsNumber(email.ID))
+
+	var err error
+	switch c.tagMode() {
+	case TagLabels:
+		_, err = c.wait(context.Background())(c.uidStore(seq, "-X-GM-LABELS", []imap.Field{c.server().Quote(encodeMailbox(tag))}))
+	case TagKeywords:
+		if !validKeyword(tag) {
+			return fmt.Errorf("invalid keyword: %q", tag)
+		}
+		_, err = c.wait(context.Background())(c.uidStore(seq, "-FLAGS", tag))
+	default:
+		err = c.untagFolder(email, tag)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to untag email: %w", err)
+	}
+	return nil
+}
+
+// ListTags returns the tags of the email, sorted.
+func (c *Client) ListTags(email Email) ([]string, error) {
+	mode := c.tagMode()
+	if mode == TagFolders {
+		return c.listTagFolders(email)
+	}
+
+	seq := &imap.SeqSet{}
+	seq.AddNum(imap.AsNumber(email.ID))
+	item := "FLAGS"
+	if mode == TagLabels {
+		item = "X-GM-LABELS"
+	}
+
+	cmd, err := c.wait(context.Background())(c.uidFetch(seq, "UID", item))
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch tags: %w", err)
+	}
+
+	var tags []string
+	for _, rsp := range cmd.Data {
+		info := c.messageInfo(rsp)
+		if info.UID != imap.AsNumber(email.ID) {
+			continue
+		}
+		if mode == TagLabels {
+			for _, label := range imap.AsList(info.Attrs["X-GM-LABELS"]) {
+				tags = append(tags, decodeMailbox(imap.AsString(label)))
+			}
+			continue
+		}
+		for flag := range info.Flags {
+			// system flags are not tags
+			if !strings.HasPrefix(flag, `\`) {
+				tags = append(tags, flag)
+			}
+		}
+	}
+
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// untagFolder removes the copy of the email from the tag folder. The copy is
+// found by its Message-ID on a separate session, so the selected folder of c
+// is left untouched. Without UIDPLUS it is only flagged as deleted.
+func (c *Client) untagFolder(email Email, tag string) error {
+	id, err := messageID(email)
+	if err != nil {
+		return err
+	}
+
+	session, err := c.WithFolder(tag)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	uids, err := session.findMessageID(id)
+	if err != nil || len(uids) == 0 {
+		return err
+	}
+
+	seq := &imap.SeqSet{}
+	seq.AddNum(uids...)
+	if _, err = session.wait(context.Background())(session.uidStore(seq, "+FLAGS", `\Deleted`)); err != nil {
+		return err
+	}
+	// a plain EXPUNGE would remove whatever else is flagged as deleted in the
+	// tag folder, so without UIDPLUS the copy is left flagged
+	if !session.caps("UIDPLUS") || session.SequenceNumbers {
+		return nil
+	}
+	return session.expunge(context.Background(), seq)
+}
+
+// listTagFolders looks for copies of the email in every other folder.
+func (c *Client) listTagFolders(email Email) ([]string, error) {
+	id, err := messageID(email)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	err = c.WalkFolders(func(session *Client, folder Folder) error {
+		if session.Folder == c.Folder {
+			return nil
+		}
+		uids, err := session.findMessageID(id)
+		if err != nil {
+			return err
+		}
+		if len(uids) > 0 {
+			tags = append(tags, folder.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// findMessageID returns the UIDs of the emails with the given Message-ID in
+// the selected folder.
+func (c *Client) findMessageID(id string) ([]uint32, error) {
+	cmd, err := c.wait(context.Background())(c.uidSearch("HEADER", "Message-ID", c.server().Quote(id)))
+	if err != nil {
+		return nil, fmt.Errorf("uid search failed: %w", err)
+	}
+	return searchResults(cmd), nil
+}
+
+func messageID(email Email) (string, error) {
+	if email.Message == nil || email.Message.Header.Get("Message-Id") == "" {
+		return "", errors.New("email has no Message-ID")
+	}
+	return email.Message.Header.Get("Message-Id"), nil
+}
+
+// validKeyword reports whether the tag can be used as an IMAP keyword, which
+// has to be a plain atom that is not a system flag.
+func validKeyword(tag string) bool {
+	if tag == "" || strings.HasPrefix(tag, `\`) {
+		return false
+	}
+	for _, r := range tag {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`(){%*"]\`, r) {
+			return false
+		}
+	}
+	return true
+}
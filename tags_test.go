@@ -0,0 +1,50 @@
+package eazye
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidKeyword(t *testing.T) {
+	tests := []struct {
+		given string
+		want  bool
+	}{
+		{"invoice", true},
+		{"$Forwarded", true},
+		{"to-do", true},
+		{"", false},
+		{`\Seen`, false},
+		{"two words", false},
+		{"(paren)", false},
+		{"naïve", false},
+	}
+
+	for _, test := range tests {
+		if got := validKeyword(test.given); got != test.want {
+			t.Errorf("validKeyword(%q) got %t, want %t", test.given, got, test.want)
+		}
+	}
+}
+
+func TestUntagFolderKeepsOtherDeletedEmails(t *testing.T) {
+	const raw = "Message-ID: <1@example.com>\r\nSubject: hi\r\n\r\nhi\r\n"
+	srv := testServer(t)
+	srv.AddMessage("INBOX", []byte(raw))
+	srv.AddFolder("work")
+	srv.AddMessage("work", []byte("Subject: other\r\n\r\nx\r\n"), `\Deleted`)
+
+	c := testClient(t, srv, SetTagMode(TagFolders))
+	email := rawEmail(t, raw)
+	if err := c.Tag(email, "work"); err != nil {
+		t.Fatalf("Tag() returned an error: %s", err)
+	}
+	if err := c.Untag(email, "work"); err != nil {
+		t.Fatalf("Untag() returned an error: %s", err)
+	}
+
+	msgs := srv.Messages("work")
+	if len(msgs) != 1 || !strings.Contains(string(msgs[0].Raw), "Subject: other") {
+		t.Errorf("Untag() left %+v in the tag folder, want only the other deleted email", msgs)
+	}
+}
@@ -0,0 +1,369 @@
+package eazye
+
+import (
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Conversation is a message of a thread and the replies to it, as put
+// together by a Threader.
+type Conversation struct {
+	// MessageID is the Message-ID of the message, or one made up for emails
+	// without one.
+	MessageID string
+	// Email is the message, nil for messages that were replied to but are
+	// not among those threaded.
+	Email *Email
+	// Subject is the normalized subject of the thread, see ThreadSubject.
+	// It is only set on the roots returned by Threads.
+	Subject  string
+	Parent   *Conversation
+	Children []*Conversation
+}
+
+// Date returns the date of the message, or of its earliest reply if the
+// message is missing.
+func (c *Conversation) Date() time.Time {
+	if c.Email != nil {
+		return c.Email.Date
+	}
+	var date time.Time
+	for _, child := range c.Children {
+		if d := child.Date(); !d.IsZero() && (date.IsZero() || d.Before(date)) {
+			date = d
+		}
+	}
+	return date
+}
+
+// Walk calls fn for the message and every reply under it, depth first. The
+// depth of the message is 0.
+func (c *Conversation) Walk(fn func(c *Conversation, depth int)) {
+	var walk func(c *Conversation, depth int)
+	walk = func(c *Conversation, depth int) {
+		fn(c, depth)
+		for _, child := range c.Children {
+			walk(child, depth+1)
+		}
+	}
+	walk(c, 0)
+}
+
+// Emails returns the emails of the conversation, depth first.
+func (c *Conversation) Emails() []Email {
+	var emails []Email
+	c.Walk(func(c *Conversation, _ int) {
+		if c.Email != nil {
+			emails = append(emails, *c.Email)
+		}
+	})
+	return emails
+}
+
+// Threader puts emails together into conversations by their Message-ID,
+// References and In-Reply-To headers, then by subject for those the headers
+// don't tie together, following the algorithm of Jamie Zawinski
+// (https://www.jwz.org/doc/threading.html).
+type Threader struct {
+	emails []Email
+}
+
+// NewThreader initializes an empty Threader.
+func NewThreader() *Threader {
+	return &Threader{}
+}
+
+// ThreadEmails returns the conversations of the emails, see Threader.
+func ThreadEmails(emails []Email) []*Conversation {
+	return (&Threader{emails: emails}).Threads()
+}
+
+// Add adds an email to thread.
+func (t *Threader) Add(email Email) {
+	t.emails = append(t.emails, email)
+}
+
+// AddHeader adds a message known only by its header, such as one fetched
+// with its headers only.
+func (t *Threader) AddHeader(header mail.Header) {
+	email := Email{
+		Message:   &mail.Message{Header: header},
+		Subject:   parseSubject(header.Get("Subject")),
+		MessageID: header.Get("Message-Id"),
+	}
+	email.Date, _ = header.Date()
+	t.Add(email)
+}
+
+// Threads returns the conversations of the emails added so far, oldest
+// first, each with its replies oldest first. Messages that were replied to
+// but not added are left out, unless they tie several replies together.
+func (t *Threader) Threads() []*Conversation {
+	g := &threadGraph{ids: map[string]*Conversation{}}
+	for i := range t.emails {
+		g.add(&t.emails[i])
+	}
+
+	var roots []*Conversation
+	for _, c := range g.order {
+		if c.Parent == nil {
+			roots = append(roots, c)
+		}
+	}
+	roots = pruneEmpty(roots, true)
+	roots = groupBySubject(roots)
+
+	for _, root := range roots {
+		root.Subject = ThreadSubject(root.subject())
+		root.Walk(func(c *Conversation, _ int) {
+			sortConversations(c.Children)
+		})
+	}
+	sortConversations(roots)
+	return roots
+}
+
+// threadGraph links the messages by their Message-IDs.
+type threadGraph struct {
+	ids   map[string]*Conversation
+	order []*Conversation
+}
+
+// add links the email to the messages it references.
+func (g *threadGraph) add(email *Email) {
+	var refs []string
+	if email.Message != nil {
+		refs = parseMessageIDs(email.Message.Header.Get("References"))
+		// In-Reply-To may hold other things than the message replied to,
+		// only its first message id is of use
+		if inReplyTo := parseMessageIDs(email.Message.Header.Get("In-Reply-To")); len(inReplyTo) > 0 &&
+			(len(refs) == 0 || refs[len(refs)-1] != inReplyTo[0]) {
+			refs = append(refs, inReplyTo[0])
+		}
+	}
+
+	var id string
+	if ids := parseMessageIDs(email.MessageID); len(ids) > 0 {
+		id = ids[0]
+	}
+	c := g.ids[id]
+	if id == "" || (c != nil && c.Email != nil) {
+		// no Message-ID, or the same as another email's
+		id = "<" + strconv.Itoa(len(g.order)) + "@eazye.invalid>"
+	}
+	c = g.conversation(id)
+	c.Email = email
+
+	// link the references together, each replying to the one before, unless
+	// another email already told otherwise
+	var parent *Conversation
+	for _, ref := range refs {
+		ref := g.conversation(ref)
+		if parent != nil && ref.Parent == nil && !ref.ancestorOf(parent) {
+			parent.adopt(ref)
+		}
+		parent = ref
+	}
+
+	// the email's own References have the last word on its parent
+	if parent != nil && c.ancestorOf(parent) {
+		parent = nil
+	}
+	if c.Parent != nil {
+		c.Parent.remove(c)
+	}
+	if parent != nil {
+		parent.adopt(c)
+	}
+}
+
+// conversation returns the Conversation of the Message-ID, adding an empty
+// one if it is new.
+func (g *threadGraph) conversation(id string) *Conversation {
+	c, ok := g.ids[id]
+	if !ok {
+		c = &Conversation{MessageID: id}
+		g.ids[id] = c
+		g.order = append(g.order, c)
+	}
+	return c
+}
+
+// pruneEmpty removes the messages that were never added and have no replies,
+// and replaces those that were with their replies. At the root an empty
+// message with several replies is kept, as it is the only thing tying them
+// together.
+func pruneEmpty(convs []*Conversation, root bool) []*Conversation {
+	var pruned []*Conversation
+	for _, c := range convs {
+		c.Children = pruneEmpty(c.Children, false)
+		if c.Email != nil || (root && len(c.Children) > 1) {
+			pruned = append(pruned, c)
+			continue
+		}
+		for _, child := range c.Children {
+			child.Parent = c.Parent
+		}
+		pruned = append(pruned, c.Children...)
+	}
+	return pruned
+}
+
+// groupBySubject gathers the roots with the same subject into a single
+// conversation, for the replies by clients that don't set References.
+func groupBySubject(roots []*Conversation) []*Conversation {
+	subjects := map[string]*Conversation{}
+	for _, root := range roots {
+		subject := ThreadSubject(root.subject())
+		if subject == "" {
+			continue
+		}
+		// an empty message is the best to gather others under, then the
+		// original message rather than a reply to it
+		other, ok := subjects[subject]
+		if !ok || (root.Email == nil && other.Email != nil) ||
+			(other.Email != nil && root.Email != nil && isReplySubject(other.subject()) && !isReplySubject(root.subject())) {
+			subjects[subject] = root
+		}
+	}
+
+	var grouped []*Conversation
+	for _, root := range roots {
+		subject := ThreadSubject(root.subject())
+		other, ok := subjects[subject]
+		if !ok || subject == "" || other == root {
+			grouped = append(grouped, root)
+			continue
+		}
+
+		switch {
+		case other.Email == nil && root.Email == nil:
+			for len(root.Children) > 0 {
+				other.adopt(root.Children[0])
+			}
+		case other.Email == nil:
+			other.adopt(root)
+		case !isReplySubject(other.subject()) && isReplySubject(root.subject()):
+			other.adopt(root)
+		default:
+			// neither is the original, they become siblings under an empty
+			// message taking the place of other
+			group := &Conversation{}
+			for i, c := range grouped {
+				if c == other {
+					grouped[i] = group
+				}
+			}
+			group.adopt(other)
+			group.adopt(root)
+			subjects[subject] = group
+			if group.Parent == nil && !containsConversation(grouped, group) {
+				grouped = append(grouped, group)
+			}
+		}
+	}
+	return grouped
+}
+
+// containsConversation tells whether c is one of convs.
+func containsConversation(convs []*Conversation, c *Conversation) bool {
+	for _, other := range convs {
+		if other == c {
+			return true
+		}
+	}
+	return false
+}
+
+// subject returns the subject of the message, or of its first reply if the
+// message is missing.
+func (c *Conversation) subject() string {
+	if c.Email != nil {
+		return c.Email.Subject
+	}
+	for _, child := range c.Children {
+		if subject := child.subject(); subject != "" {
+			return subject
+		}
+	}
+	return ""
+}
+
+// ancestorOf tells whether c is other or one of the messages it replies to.
+func (c *Conversation) ancestorOf(other *Conversation) bool {
+	for ; other != nil; other = other.Parent {
+		if other == c {
+			return true
+		}
+	}
+	return false
+}
+
+// adopt makes child a reply to c.
+func (c *Conversation) adopt(child *Conversation) {
+	if child.Parent != nil {
+		child.Parent.remove(child)
+	}
+	child.Parent = c
+	c.Children = append(c.Children, child)
+}
+
+// remove forgets child is a reply to c.
+func (c *Conversation) remove(child *Conversation) {
+	for i, other := range c.Children {
+		if other == child {
+			c.Children = append(c.Children[:i], c.Children[i+1:]...)
+			break
+		}
+	}
+	child.Parent = nil
+}
+
+// sortConversations sorts the conversations by date, oldest first.
+func sortConversations(convs []*Conversation) {
+	sort.SliceStable(convs, func(i, j int) bool {
+		return convs[i].Date().Before(convs[j].Date())
+	})
+}
+
+// subjectPrefix matches a reply or forward prefix, or a [list] tag, at the
+// start of a subject.
+var subjectPrefix = regexp.MustCompile(`(?i)^\s*(?:(?:re|fwd?|aw|sv|antw)(?:\[\d+\])?\s*:|\[[^\]]*\])\s*`)
+
+// ThreadSubject normalizes a subject for threading: it strips the reply and
+// forward prefixes and the mailing list tags, collapses the whitespace and
+// lowercases it.
+func ThreadSubject(subject string) string {
+	return strings.ToLower(strings.Join(strings.Fields(baseSubject(subject)), " "))
+}
+
+// baseSubject strips the reply and forward prefixes and the mailing list tags
+// of a subject.
+func baseSubject(subject string) string {
+	for {
+		stripped := subjectPrefix.ReplaceAllString(subject, "")
+		if stripped == subject {
+			return strings.TrimSpace(subject)
+		}
+		subject = stripped
+	}
+}
+
+// isReplySubject tells whether the subject has a reply or forward prefix.
+func isReplySubject(subject string) bool {
+	subject = strings.TrimSpace(subject)
+	for {
+		loc := subjectPrefix.FindStringIndex(subject)
+		if loc == nil {
+			return false
+		}
+		if !strings.HasPrefix(subject, "[") {
+			return true
+		}
+		subject = subject[loc[1]:]
+	}
+}
@@ -0,0 +1,128 @@
+package eazye
+
+import (
+	"strings"
+	"testing"
+)
+
+// threadOutline renders the conversations as one line per message, indented
+// by depth, with "-" for missing messages.
+func threadOutline(convs []*Conversation) string {
+	var b strings.Builder
+	for _, conv := range convs {
+		conv.Walk(func(c *Conversation, depth int) {
+			b.WriteString(strings.Repeat("  ", depth))
+			if c.Email == nil {
+				b.WriteString("-")
+			} else {
+				b.WriteString(c.Email.Subject)
+			}
+			b.WriteString("\n")
+		})
+	}
+	return b.String()
+}
+
+func TestThreadEmails(t *testing.T) {
+	tests := []struct {
+		name string
+		raws []string
+		want string
+	}{
+		{
+			"references",
+			[]string{
+				"Message-Id: <2@x>\r\nIn-Reply-To: <1@x>\r\nSubject: Re: Lunch\r\nDate: Tue, 2 Jan 2024 10:00:00 +0000\r\n\r\n",
+				"Message-Id: <1@x>\r\nSubject: Lunch\r\nDate: Mon, 1 Jan 2024 10:00:00 +0000\r\n\r\n",
+				"Message-Id: <3@x>\r\nReferences: <1@x> <2@x>\r\nSubject: Re: Re: Lunch\r\nDate: Wed, 3 Jan 2024 10:00:00 +0000\r\n\r\n",
+				"Message-Id: <4@x>\r\nSubject: Other\r\nDate: Sun, 31 Dec 2023 10:00:00 +0000\r\n\r\n",
+			},
+			"Other\nLunch\n  Re: Lunch\n    Re: Re: Lunch\n",
+		},
+		{
+			"missing parent with one reply",
+			[]string{
+				"Message-Id: <2@x>\r\nReferences: <1@x>\r\nSubject: Re: Lunch\r\n\r\n",
+			},
+			"Re: Lunch\n",
+		},
+		{
+			"missing parent with several replies",
+			[]string{
+				"Message-Id: <2@x>\r\nReferences: <1@x>\r\nSubject: Re: Lunch\r\nDate: Tue, 2 Jan 2024 10:00:00 +0000\r\n\r\n",
+				"Message-Id: <3@x>\r\nReferences: <1@x>\r\nSubject: Re: Lunch?\r\nDate: Wed, 3 Jan 2024 10:00:00 +0000\r\n\r\n",
+			},
+			"-\n  Re: Lunch\n  Re: Lunch?\n",
+		},
+		{
+			"missing parent in the middle",
+			[]string{
+				"Message-Id: <1@x>\r\nSubject: Lunch\r\n\r\n",
+				"Message-Id: <3@x>\r\nReferences: <1@x> <2@x>\r\nSubject: Re: Lunch\r\n\r\n",
+			},
+			"Lunch\n  Re: Lunch\n",
+		},
+		{
+			"by subject",
+			[]string{
+				"Message-Id: <1@x>\r\nSubject: [team] Lunch\r\nDate: Mon, 1 Jan 2024 10:00:00 +0000\r\n\r\n",
+				"Message-Id: <2@x>\r\nSubject: RE: [team] lunch\r\nDate: Tue, 2 Jan 2024 10:00:00 +0000\r\n\r\n",
+			},
+			"[team] Lunch\n  RE: [team] lunch\n",
+		},
+		{
+			"by subject without the original",
+			[]string{
+				"Message-Id: <2@x>\r\nSubject: Re: Lunch\r\nDate: Tue, 2 Jan 2024 10:00:00 +0000\r\n\r\n",
+				"Message-Id: <3@x>\r\nSubject: Fwd: Lunch\r\nDate: Wed, 3 Jan 2024 10:00:00 +0000\r\n\r\n",
+			},
+			"-\n  Re: Lunch\n  Fwd: Lunch\n",
+		},
+		{
+			"loop",
+			[]string{
+				"Message-Id: <1@x>\r\nReferences: <2@x>\r\nSubject: A\r\n\r\n",
+				"Message-Id: <2@x>\r\nReferences: <1@x>\r\nSubject: B\r\n\r\n",
+			},
+			"B\n  A\n",
+		},
+		{
+			"duplicate Message-ID",
+			[]string{
+				"Message-Id: <1@x>\r\nSubject: A\r\nDate: Mon, 1 Jan 2024 10:00:00 +0000\r\n\r\n",
+				"Message-Id: <1@x>\r\nSubject: B\r\nDate: Tue, 2 Jan 2024 10:00:00 +0000\r\n\r\n",
+			},
+			"A\nB\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			threader := NewThreader()
+			for _, raw := range test.raws {
+				threader.AddHeader(rawEmail(t, raw).Message.Header)
+			}
+			if got := threadOutline(threader.Threads()); got != test.want {
+				t.Errorf("Threads() got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestThreadSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    string
+	}{
+		{"Lunch", "lunch"},
+		{"Re: Lunch", "lunch"},
+		{"RE: Fwd: re[2]: Lunch", "lunch"},
+		{"[team] Re: [team]  Lunch  today", "lunch today"},
+		{"AW: Lunch", "lunch"},
+		{"Recap", "recap"},
+	}
+	for _, test := range tests {
+		if got := ThreadSubject(test.subject); got != test.want {
+			t.Errorf("ThreadSubject(%q) got %q, want %q", test.subject, got, test.want)
+		}
+	}
+}
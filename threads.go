@@ -0,0 +1,275 @@
+package eazye
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ThreadStore maps the Message-IDs of emails to the IDs of the threads they
+// belong to in another system, such as a ticket or a comment thread.
+type ThreadStore interface {
+	// Lookup returns the thread the Message-ID belongs to, "" if unknown.
+	Lookup(messageID string) (string, error)
+	// Link records that the Message-ID belongs to the thread.
+	Link(messageID, threadID string) error
+	// Messages returns the Message-IDs of the thread in the order they were
+	// linked.
+	Messages(threadID string) ([]string, error)
+}
+
+// MemoryThreadStore is a ThreadStore that keeps everything in memory.
+type MemoryThreadStore struct {
+	mu       sync.Mutex
+	threads  map[string]string
+	messages map[string][]string
+}
+
+// NewMemoryThreadStore initializes an empty MemoryThreadStore.
+func NewMemoryThreadStore() *MemoryThreadStore {
+	return &MemoryThreadStore{
+		threads:  map[string]string{},
+		messages: map[string][]string{},
+	}
+}
+
+// Lookup returns the thread the Message-ID belongs to.
+func (s *MemoryThreadStore) Lookup(messageID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.threads[messageID], nil
+}
+
+// Link records that the Message-ID belongs to the thread.
+func (s *MemoryThreadStore) Link(messageID, threadID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.threads[messageID] == threadID {
+		return nil
+	}
+	s.threads[messageID] = threadID
+	s.messages[threadID] = append(s.messages[threadID], messageID)
+	return nil
+}
+
+// Messages returns the Message-IDs of the thread.
+func (s *MemoryThreadStore) Messages(threadID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.messages[threadID]...), nil
+}
+
+// ThreadBridge keeps email threads and the threads of another system in sync,
+// for bots that turn emails into comments and comments back into emails.
+type ThreadBridge struct {
+	Store ThreadStore
+}
+
+// NewThreadBridge initializes a ThreadBridge on top of the store.
+func NewThreadBridge(store ThreadStore) *ThreadBridge {
+	return &ThreadBridge{Store: store}
+}
+
+// Thread returns the thread the email belongs to, looking at its own
+// Message-ID first and then at the ones it replies to, newest first. It is
+// "" if none of them are known. The email is linked to the thread found so
+// replies to it are found as well.
+func (b *ThreadBridge) Thread(email Email) (string, error) {
+	if email.Message == nil {
+		return "", errors.New("email has no message")
+	}
+	header := email.Message.Header
+
+	candidates := parseMessageIDs(header.Get("Message-Id"))
+	var own string
+	if len(candidates) > 0 {
+		own = candidates[0]
+	}
+	refs := append(parseMessageIDs(header.Get("References")), parseMessageIDs(header.Get("In-Reply-To"))...)
+	for i := len(refs) - 1; i >= 0; i-- {
+		candidates = append(candidates, refs[i])
+	}
+
+	for i, id := range candidates {
+		thread, err := b.Store.Lookup(id)
+		if err != nil {
+			return "", err
+		}
+		if thread == "" {
+			continue
+		}
+		if i > 0 && own != "" {
+			err = b.Store.Link(own, thread)
+		}
+		return thread, err
+	}
+	return "", nil
+}
+
+// Link records that the email starts, or belongs to, the thread.
+func (b *ThreadBridge) Link(email Email, threadID string) error {
+	id, err := messageID(email)
+	if err != nil {
+		return err
+	}
+	return b.Store.Link(id, threadID)
+}
+
+// Reply builds an email replying to the latest message of the thread, so it
+// shows up in the same conversation for everyone on it. The reply itself is
+// linked to the thread as well.
+func (b *ThreadBridge) Reply(threadID string, from *mail.Address, to []*mail.Address, subject, body string) (Reply, error) {
+	refs, err := b.Store.Messages(threadID)
+	if err != nil {
+		return Reply{}, err
+	}
+	if len(refs) == 0 {
+		return Reply{}, fmt.Errorf("unknown thread: %s", threadID)
+	}
+
+	reply, err := newReply(from, to, subject, body, refs)
+	if err != nil {
+		return reply, err
+	}
+	return reply, b.Store.Link(reply.MessageID, threadID)
+}
+
+// Reply is an outgoing email threaded onto an existing conversation.
+type Reply struct {
+	From       *mail.Address
+	To         []*mail.Address
+	Subject    string
+	MessageID  string
+	InReplyTo  string
+	References []string
+	// Body is the plain text of the reply.
+	Body string
+}
+
+// NewReply builds a reply to the email, addressed to its Reply-To, or From
+// if not set.
+func NewReply(email Email, from *mail.Address, body string) (Reply, error) {
+	id, err := messageID(email)
+	if err != nil {
+		return Reply{}, err
+	}
+	header := email.Message.Header
+
+	to, err := header.AddressList("Reply-To")
+	if err != nil {
+		if to, err = header.AddressList("From"); err != nil {
+			return Reply{}, fmt.Errorf("unable to find who to reply to: %w", err)
+		}
+	}
+
+	subject := DecodeHeader(header.Get("Subject"))
+
+	refs := parseMessageIDs(header.Get("References"))
+	if len(refs) == 0 {
+		refs = parseMessageIDs(header.Get("In-Reply-To"))
+	}
+	refs = append(refs, id)
+
+	return newReply(from, to, replySubject(subject), body, refs)
+}
+
+func newReply(from *mail.Address, to []*mail.Address, subject, body string, refs []string) (Reply, error) {
+	id, err := newMessageID(from)
+	if err != nil {
+		return Reply{}, err
+	}
+	return Reply{
+		From:       from,
+		To:         to,
+		Subject:    subject,
+		MessageID:  id,
+		InReplyTo:  refs[len(refs)-1],
+		References: refs,
+		Body:       body,
+	}, nil
+}
+
+// Recipients returns the addresses to deliver the reply to, as expected by a
+// Sender.
+func (r Reply) Recipients() []string {
+	rcpts := make([]string, len(r.To))
+	for i, addr := range r.To {
+		rcpts[i] = addr.Address
+	}
+	return rcpts
+}
+
+// Bytes renders the reply as a MIME message dated now.
+func (r Reply) Bytes() []byte {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+
+	header("From", r.From.String())
+	header("To", formatAddresses(r.To))
+	header("Subject", mime.QEncoding.Encode("utf-8", r.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-Id", r.MessageID)
+	if r.InReplyTo != "" {
+		header("In-Reply-To", r.InReplyTo)
+	}
+	if len(r.References) > 0 {
+		header("References", strings.Join(r.References, " "))
+	}
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	qp.Write([]byte(r.Body))
+	qp.Close()
+	return buf.Bytes()
+}
+
+// replySubject prefixes the base subject with a single "Re: ".
+func replySubject(subject string) string {
+	return "Re: " + baseSubject(subject)
+}
+
+// parseMessageIDs returns the <...> message ids of a Message-ID, In-Reply-To
+// or References header, in order.
+func parseMessageIDs(value string) []string {
+	var ids []string
+	for {
+		start := strings.IndexByte(value, '<')
+		if start < 0 {
+			return ids
+		}
+		end := strings.IndexByte(value[start:], '>')
+		if end < 0 {
+			return ids
+		}
+		ids = append(ids, value[start:start+end+1])
+		value = value[start+end+1:]
+	}
+}
+
+// newMessageID returns a random Message-ID in the domain of the address.
+func newMessageID(from *mail.Address) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	domain := "localhost"
+	if from != nil {
+		if i := strings.LastIndexByte(from.Address, '@'); i >= 0 && i < len(from.Address)-1 {
+			domain = from.Address[i+1:]
+		}
+	}
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">", nil
+}
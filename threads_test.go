@@ -0,0 +1,94 @@
+package eazye
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func TestThreadBridge(t *testing.T) {
+	bridge := NewThreadBridge(NewMemoryThreadStore())
+
+	first := rawEmail(t, "Message-Id: <1@example.com>\r\nSubject: Broken printer\r\n\r\n")
+	if err := bridge.Link(first, "TICKET-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a reply to the first email that only references it
+	second := rawEmail(t, "Message-Id: <2@example.com>\r\nIn-Reply-To: <1@example.com>\r\n"+
+		"References: <0@example.com> <1@example.com>\r\n\r\n")
+	thread, err := bridge.Thread(second)
+	if err != nil || thread != "TICKET-1" {
+		t.Fatalf("Thread() got %q, %v, want TICKET-1", thread, err)
+	}
+
+	unknown := rawEmail(t, "Message-Id: <9@example.com>\r\n\r\n")
+	if thread, _ = bridge.Thread(unknown); thread != "" {
+		t.Errorf("Thread() for an unknown email got %q, want nothing", thread)
+	}
+
+	from := &mail.Address{Name: "Helpdesk", Address: "help@example.com"}
+	to := []*mail.Address{{Address: "jane@example.com"}}
+	reply, err := bridge.Reply("TICKET-1", from, to, "Re: Broken printer", "Fixed it.")
+	if err != nil {
+		t.Fatalf("Reply() returned an error: %s", err)
+	}
+	if reply.InReplyTo != "<2@example.com>" || strings.Join(reply.References, " ") != "<1@example.com> <2@example.com>" {
+		t.Errorf("Reply() is not threaded onto the latest message: %+v", reply)
+	}
+	if !strings.HasSuffix(reply.MessageID, "@example.com>") {
+		t.Errorf("Reply() got Message-ID %q, want one in the sender's domain", reply.MessageID)
+	}
+	if thread, _ = bridge.Store.Lookup(reply.MessageID); thread != "TICKET-1" {
+		t.Errorf("Reply() did not link the reply to the thread")
+	}
+
+	if _, err = bridge.Reply("TICKET-2", from, to, "hi", "hi"); err == nil {
+		t.Error("Reply() to an unknown thread did not return an error")
+	}
+}
+
+func TestNewReply(t *testing.T) {
+	email := rawEmail(t, "From: Jane <jane@example.com>\r\nReply-To: support@example.com\r\n"+
+		"Subject: =?utf-8?q?Caf=C3=A9?=\r\nMessage-Id: <2@example.com>\r\nReferences: <1@example.com>\r\n\r\n")
+
+	reply, err := NewReply(email, &mail.Address{Address: "bot@example.com"}, "Thanks!")
+	if err != nil {
+		t.Fatalf("NewReply() returned an error: %s", err)
+	}
+	if reply.Subject != "Re: Café" {
+		t.Errorf("NewReply() got subject %q, want %q", reply.Subject, "Re: Café")
+	}
+	if got := reply.Recipients(); len(got) != 1 || got[0] != "support@example.com" {
+		t.Errorf("NewReply() got recipients %v, want the Reply-To address", got)
+	}
+	if reply.InReplyTo != "<2@example.com>" || len(reply.References) != 2 {
+		t.Errorf("NewReply() is not threaded: %+v", reply)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(reply.Bytes())))
+	if err != nil {
+		t.Fatalf("Bytes() is not a valid message: %s", err)
+	}
+	if msg.Header.Get("In-Reply-To") != "<2@example.com>" || msg.Header.Get("References") != "<1@example.com> <2@example.com>" {
+		t.Errorf("Bytes() got unexpected headers: %v", msg.Header)
+	}
+}
+
+func TestReplySubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    string
+	}{
+		{"Lunch", "Re: Lunch"},
+		{"Re: Lunch", "Re: Lunch"},
+		{"RE: Fwd: re[2]: Lunch", "Re: Lunch"},
+		{"[team] AW: Lunch", "Re: Lunch"},
+		{"Recap", "Re: Recap"},
+	}
+	for _, test := range tests {
+		if got := replySubject(test.subject); got != test.want {
+			t.Errorf("replySubject(%q) got %q, want %q", test.subject, got, test.want)
+		}
+	}
+}
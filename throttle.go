@@ -0,0 +1,79 @@
+package eazye
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// Throttle limits how fast data is read from the server, e.g. so a huge
+// archive pull does not run into the bandwidth limits of the provider while
+// production pollers share the same account. A Throttle can be shared by
+// several Clients to limit them all together, and its rate changed at any
+// time, such as around a single query.
+type Throttle struct {
+	mu        sync.Mutex
+	rate      float64
+	allowance float64
+	last      time.Time
+}
+
+// NewThrottle returns a Throttle allowing bytesPerSecond, with bursts of up
+// to a second's worth.
+func NewThrottle(bytesPerSecond int) *Throttle {
+	return &Throttle{rate: float64(bytesPerSecond)}
+}
+
+// SetThrottle is a functional option to set the Throttle attr.
+func SetThrottle(throttle *Throttle) Option {
+	return func(c *Client) {
+		c.Throttle = throttle
+	}
+}
+
+// SetRate changes the allowed bytes per second, 0 or less lifts the limit.
+func (t *Throttle) SetRate(bytesPerSecond int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rate = float64(bytesPerSecond)
+	if t.allowance > t.rate {
+		t.allowance = t.rate
+	}
+}
+
+// delay takes n bytes out of the allowance and returns how long to wait for
+// them to be covered.
+func (t *Throttle) delay(n int) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rate <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	t.allowance += now.Sub(t.last).Seconds() * t.rate
+	if t.allowance > t.rate {
+		t.allowance = t.rate
+	}
+	t.last = now
+
+	t.allowance -= float64(n)
+	if t.allowance >= 0 {
+		return 0
+	}
+	return time.Duration(-t.allowance / t.rate * float64(time.Second))
+}
+
+// throttledConn is a connection whose reads are limited by a Throttle.
+type throttledConn struct {
+	net.Conn
+	throttle *Throttle
+}
+
+func (c *throttledConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		time.Sleep(c.throttle.delay(n))
+	}
+	return n, err
+}
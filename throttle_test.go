@@ -0,0 +1,23 @@
+package eazye
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottleDelay(t *testing.T) {
+	throttle := NewThrottle(1000)
+
+	// a second's worth goes through right away
+	if d := throttle.delay(1000); d != 0 {
+		t.Errorf("delay() of the burst got %s, want 0", d)
+	}
+	if d := throttle.delay(500); d < 400*time.Millisecond || d > 500*time.Millisecond {
+		t.Errorf("delay() past the burst got %s, want about 500ms", d)
+	}
+
+	throttle.SetRate(0)
+	if d := throttle.delay(1 << 20); d != 0 {
+		t.Errorf("delay() without a limit got %s, want 0", d)
+	}
+}
@@ -0,0 +1,43 @@
+package eazye
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation name of the spans eazye starts.
+const tracerName = "github.com/sluceno/eazye"
+
+// SetTracerProvider is a functional option to set the TracerProvider attr.
+func SetTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *Client) {
+		c.TracerProvider = provider
+	}
+}
+
+// startSpan starts a span as a child of the one in ctx, if any, tagged with
+// the server and folder.
+func (c *Client) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	provider := c.TracerProvider
+	if provider == nil {
+		provider = noop.NewTracerProvider()
+	}
+	attrs = append([]attribute.KeyValue{
+		attribute.String("imap.host", c.host),
+		attribute.String("imap.folder", c.Folder),
+	}, attrs...)
+	return provider.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends the span, marking it as failed if err is not nil.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
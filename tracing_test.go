@@ -0,0 +1,67 @@
+package eazye
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordingProvider remembers the spans started and whether they failed.
+type recordingProvider struct {
+	noop.TracerProvider
+	spans []*recordingSpan
+}
+
+func (p *recordingProvider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{provider: p}
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	provider *recordingProvider
+}
+
+func (t recordingTracer) Start(ctx context.Context, name string, _ ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &recordingSpan{name: name}
+	t.provider.spans = append(t.provider.spans, span)
+	return ctx, span
+}
+
+type recordingSpan struct {
+	noop.Span
+	name   string
+	failed bool
+	ended  bool
+}
+
+func (s *recordingSpan) SetStatus(code codes.Code, _ string) { s.failed = code == codes.Error }
+func (s *recordingSpan) End(...trace.SpanEndOption)          { s.ended = true }
+
+func TestParseSpan(t *testing.T) {
+	provider := &recordingProvider{}
+	c := &Client{}
+	SetTracerProvider(provider)(c)
+
+	if _, err := c.parse(context.Background(), imap.FieldMap{"RFC822.HEADER": []byte("Subject: hi\r\n")}); err != nil {
+		t.Fatalf("parse() returned an error: %s", err)
+	}
+	if _, err := c.parse(context.Background(), imap.FieldMap{"RFC822.HEADER": []byte("not a header\r\n")}); err == nil {
+		t.Fatal("parse() of a broken header returned no error")
+	}
+
+	if len(provider.spans) != 2 {
+		t.Fatalf("parse() started %d spans, want 2", len(provider.spans))
+	}
+	for i, span := range provider.spans {
+		if span.name != "eazye.Parse" || !span.ended {
+			t.Errorf("span %d got %q ended %v, want eazye.Parse ended", i, span.name, span.ended)
+		}
+		if span.failed != (i == 1) {
+			t.Errorf("span %d failed %v, want %v", i, span.failed, i == 1)
+		}
+	}
+}
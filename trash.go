@@ -0,0 +1,52 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrTrashSequenceNumbers is returned when deleting with DeleteToTrash set
+// along with SequenceNumbers: every move renumbers the emails after it, so
+// the next ones would move the wrong emails.
+var ErrTrashSequenceNumbers = errors.New("DeleteToTrash can not be used with SequenceNumbers")
+
+// SetDeleteToTrash is a functional option to set the DeleteToTrash attr.
+func SetDeleteToTrash(toTrash bool) Option {
+	return func(c *Client) {
+		c.DeleteToTrash = toTrash
+	}
+}
+
+// Trash returns the name of the trash folder, see SpecialFolders.
+func (c *Client) Trash() (string, error) {
+	return c.TrashContext(context.Background())
+}
+
+// TrashContext is Trash with a context.
+func (c *Client) TrashContext(ctx context.Context) (string, error) {
+	special, err := c.SpecialFoldersContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	if special.Trash == "" {
+		return "", fmt.Errorf("%w: no trash folder", ErrFolderNotFound)
+	}
+	return special.Trash, nil
+}
+
+// moveToTrash moves the email to the trash folder, or flags it as deleted if
+// it already is in there.
+func (c *Client) moveToTrash(ctx context.Context, email Email) error {
+	if c.SequenceNumbers {
+		return ErrTrashSequenceNumbers
+	}
+	trash, err := c.TrashContext(ctx)
+	if err != nil {
+		return err
+	}
+	if c.Folder == trash {
+		return c.alterEmail(ctx, email, "\\DELETED", true)
+	}
+	return c.MoveContext(ctx, email, trash)
+}
@@ -0,0 +1,31 @@
+package eazye
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDeleteToTrashSafeMode(t *testing.T) {
+	c := &Client{DeleteToTrash: true, SafeMode: true, special: &SpecialFolders{Trash: "Trash"}}
+	if err := c.DeleteEmail(Email{ID: uint32(1)}); !errors.Is(err, ErrReadOnlyMode) {
+		t.Errorf("DeleteEmail() got %v, want %s", err, ErrReadOnlyMode)
+	}
+}
+
+func TestTrashMissing(t *testing.T) {
+	c := &Client{special: &SpecialFolders{Sent: "Sent"}}
+	if _, err := c.Trash(); !errors.Is(err, ErrFolderNotFound) {
+		t.Errorf("Trash() got %v, want %s", err, ErrFolderNotFound)
+	}
+}
+
+func TestDeleteToTrashSequenceNumbers(t *testing.T) {
+	c := &Client{DeleteToTrash: true, SequenceNumbers: true, special: &SpecialFolders{Trash: "Trash"}}
+	if err := c.DeleteEmail(Email{ID: uint32(1)}); !errors.Is(err, ErrTrashSequenceNumbers) {
+		t.Errorf("DeleteEmail() got %v, want %s", err, ErrTrashSequenceNumbers)
+	}
+	if _, err := c.collect(context.Background(), c.generateUIDs(context.Background(), []uint32{1, 2}, false, true, false)); !errors.Is(err, ErrTrashSequenceNumbers) {
+		t.Errorf("generateUIDs() deleting got %v, want %s", err, ErrTrashSequenceNumbers)
+	}
+}
@@ -0,0 +1,54 @@
+package eazye
+
+// TextOption is a functional option of VisibleText.
+type TextOption func(*textOptions)
+
+// textOptions holds the HTML elements VisibleText skips the text of, and
+// those it keeps the text of even in a skipped one.
+type textOptions struct {
+	skip map[string]bool
+	keep map[string]bool
+}
+
+// SkipTags is a TextOption to also skip the text of the given HTML elements,
+// e.g. "nav" and "footer", on top of those skipped by default: style,
+// script, head and the like.
+func SkipTags(tags ...string) TextOption {
+	return func(o *textOptions) {
+		for _, tag := range tags {
+			o.skip[tag] = true
+			delete(o.keep, tag)
+		}
+	}
+}
+
+// KeepTags is a TextOption to keep the text of the given HTML elements, even
+// if they are skipped by default or in a skipped element, e.g. "title" in
+// the head.
+func KeepTags(tags ...string) TextOption {
+	return func(o *textOptions) {
+		for _, tag := range tags {
+			o.keep[tag] = true
+			delete(o.skip, tag)
+		}
+	}
+}
+
+// newTextOptions returns the default options with the given ones applied.
+func newTextOptions(options []TextOption) *textOptions {
+	o := &textOptions{skip: map[string]bool{}, keep: map[string]bool{}}
+	for _, tag := range nonVisibleTags {
+		o.skip[string(tag)] = true
+	}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// voidTags are the HTML elements that have no end tag, which VisibleText
+// must not wait for.
+var voidTags = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
@@ -0,0 +1,53 @@
+package eazye
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVisibleTextOptions(t *testing.T) {
+	body := `<html><head><title>Title</title><style>p {}</style><meta charset="utf-8"></head>
+<body><nav>Menu</nav><p>Body</p><footer>Unsubscribe</footer></body></html>`
+
+	tests := []struct {
+		name    string
+		options []TextOption
+		want    []string
+	}{
+		{"default", nil, []string{"Menu", "Body", "Unsubscribe"}},
+		{"skip", []TextOption{SkipTags("nav", "footer")}, []string{"Body"}},
+		{"keep", []TextOption{KeepTags("title")}, []string{"Title", "Menu", "Body", "Unsubscribe"}},
+		{"keep skipped", []TextOption{SkipTags("nav"), KeepTags("nav", "style")}, []string{"p {}", "Menu", "Body", "Unsubscribe"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := VisibleText(strings.NewReader(body), tt.options...)
+			if err != nil {
+				t.Fatalf("VisibleText() returned an error: %s", err)
+			}
+			var got []string
+			for _, block := range text {
+				got = append(got, string(block))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("VisibleText() got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTextOptions(t *testing.T) {
+	o := newTextOptions([]TextOption{SkipTags("nav", "title"), KeepTags("title", "style")})
+	for tag, want := range map[string]bool{"head": true, "script": true, "nav": true, "title": false, "style": false, "p": false} {
+		if got := o.skip[tag]; got != want {
+			t.Errorf("newTextOptions() skip[%q] got %v, want %v", tag, got, want)
+		}
+	}
+	for tag, want := range map[string]bool{"title": true, "style": true, "nav": false} {
+		if got := o.keep[tag]; got != want {
+			t.Errorf("newTextOptions() keep[%q] got %v, want %v", tag, got, want)
+		}
+	}
+}
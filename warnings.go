@@ -0,0 +1,93 @@
+package eazye
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+)
+
+// checkMessage looks for problems in a raw message that do not prevent it
+// from being parsed but that callers may want to know about.
+func checkMessage(raw []byte) []error {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return []error{fmt.Errorf("unable to read message: %w", err)}
+	}
+
+	return append(checkHeader(msg.Header), checkPart(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)...)
+}
+
+// checkHeader looks for problems in the header of a message, which is all
+// there is to check for emails fetched without their body.
+func checkHeader(header mail.Header) []error {
+	if _, err := header.Date(); err != nil {
+		return []error{fmt.Errorf("bad date header: %w", err)}
+	}
+	return nil
+}
+
+// checkPart verifies a MIME part (and its children for multiparts) can be
+// fully read and decoded.
+func checkPart(contentType, encoding string, body io.Reader) []error {
+	if contentType == "" {
+		return nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return []error{fmt.Errorf("bad content type %q: %w", contentType, err)}
+	}
+
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		if strings.EqualFold(encoding, "base64") {
+			_, err = io.Copy(io.Discard, base64.NewDecoder(base64.StdEncoding, stripSpace(body)))
+			if err != nil {
+				return []error{fmt.Errorf("undecodable %s part: %w", mediaType, err)}
+			}
+		}
+		return nil
+	}
+
+	boundary := params["boundary"]
+	if boundary == "" {
+		return []error{fmt.Errorf("%s body has no boundary", mediaType)}
+	}
+
+	var warnings []error
+	mr := multipart.NewReader(body, boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			warnings = append(warnings, fmt.Errorf("truncated MIME body: %w", err))
+			break
+		}
+		// quoted-printable parts are decoded by the multipart reader itself
+		partWarnings := checkPart(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+		warnings = append(warnings, partWarnings...)
+		if _, err = io.Copy(io.Discard, part); err != nil {
+			// no telling where the next part starts after this
+			warnings = append(warnings, fmt.Errorf("undecodable MIME part: %w", err))
+			break
+		}
+	}
+
+	return warnings
+}
+
+// stripSpace removes the line breaks and other whitespace base64 bodies are
+// wrapped with.
+func stripSpace(r io.Reader) io.Reader {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return r
+	}
+	return bytes.NewReader(bytes.Join(bytes.Fields(data), nil))
+}
@@ -0,0 +1,67 @@
+package eazye
+
+import (
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+func TestCheckMessage(t *testing.T) {
+	tests := []struct {
+		name  string
+		given string
+		want  int
+	}{
+		{
+			"clean message",
+			"Date: Tue, 12 Aug 2014 13:49:54 -0400\r\nContent-Type: text/plain\r\n\r\nhello\r\n",
+			0,
+		},
+		{
+			"bad date",
+			"Date: yesterday\r\nContent-Type: text/plain\r\n\r\nhello\r\n",
+			1,
+		},
+		{
+			"truncated multipart",
+			"Date: Tue, 12 Aug 2014 13:49:54 -0400\r\nContent-Type: multipart/alternative; boundary=xyz\r\n\r\n--xyz\r\nContent-Type: text/plain\r\n\r\nhello\r\n",
+			1,
+		},
+		{
+			"undecodable base64",
+			"Date: Tue, 12 Aug 2014 13:49:54 -0400\r\nContent-Type: text/plain\r\nContent-Transfer-Encoding: base64\r\n\r\n!!not base64!!\r\n",
+			1,
+		},
+	}
+
+	for _, test := range tests {
+		got := checkMessage([]byte(test.given))
+		if len(got) != test.want {
+			t.Errorf("checkMessage(%s) got %d warnings %v, want %d", test.name, len(got), got, test.want)
+		}
+	}
+
+	if got := checkMessage([]byte(multipartEmail)); len(got) != 0 {
+		t.Errorf("checkMessage(multipartEmail) got unexpected warnings: %v", got)
+	}
+}
+
+func TestNewEmailDateWarning(t *testing.T) {
+	const header = "Date: yesterday\r\nSubject: hi\r\n\r\n"
+	tests := []struct {
+		name   string
+		fields imap.FieldMap
+	}{
+		{"full", imap.FieldMap{"RFC822.HEADER": []byte(header), "BODY[]": []byte(header + "hello\r\n")}},
+		{"header only", imap.FieldMap{"RFC822.HEADER": []byte(header)}},
+	}
+	for _, test := range tests {
+		email, err := newEmail(test.fields)
+		if err != nil {
+			t.Fatalf("newEmail(%s) returned an error: %s", test.name, err)
+		}
+		if len(email.Warnings) != 1 {
+			t.Errorf("newEmail(%s) got warnings %v, want the bad date", test.name, email.Warnings)
+		}
+	}
+}
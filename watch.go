@@ -0,0 +1,166 @@
+package eazye
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+// WatchEvent is passed along by Watch when new emails arrive in the folder.
+type WatchEvent struct {
+	// UIDs of the new emails, in ascending order.
+	UIDs []uint32
+	Err  error
+}
+
+// WatchPollInterval is how often Watch checks for new emails on servers
+// without IDLE.
+var WatchPollInterval = time.Minute
+
+// idleTimeout is how long to IDLE for before starting over, RFC 2177 asks
+// clients to do so at least every 29 minutes.
+const idleTimeout = 25 * time.Minute
+
+// Watch will pass along an event every time new emails arrive in the folder
+// until the context is done. It uses IMAP IDLE (RFC 2177) to be told about
+// new emails right away, falling back to checking every WatchPollInterval if
+// the server does not support it.
+//
+// The channel is closed after an error is passed along. The Client can not
+// be used for anything else while it is being watched, use WithFolder for a
+// separate session instead.
+func (c *Client) Watch(ctx context.Context) (chan WatchEvent, error) {
+	next, err := c.uidNext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan WatchEvent, c.bufferSize())
+	go func() {
+		defer close(events)
+
+		for {
+			if c.caps("IDLE") {
+				err = c.idle(ctx)
+			} else {
+				err = sleep(ctx, WatchPollInterval)
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if c.dropped(ctx, err) {
+				// reconnect as the RetryPolicy allows and catch up
+				_, err = c.do(ctx, func() (*imap.Command, error) {
+					return c.server().Noop()
+				})
+			}
+
+			var uids []uint32
+			if err == nil {
+				uids, next, err = c.newSince(ctx, next)
+			}
+			if err != nil {
+				sendEvent(ctx, events, WatchEvent{Err: err})
+				return
+			}
+			if len(uids) > 0 && !sendEvent(ctx, events, WatchEvent{UIDs: uids}) {
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// sendEvent passes the event along unless the context is done first.
+func sendEvent(ctx context.Context, events chan WatchEvent, event WatchEvent) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// idle waits in IDLE until the server reports new emails, idleTimeout passes
+// or the context is done.
+func (c *Client) idle(ctx context.Context) error {
+	if _, err := c.server().Idle(); err != nil {
+		return fmt.Errorf("unable to idle: %w", err)
+	}
+
+	c.server().ClearUnilateral()
+	deadline := time.Now().Add(idleTimeout)
+	for !hasExists(c.server().Unilateral()) && time.Now().Before(deadline) && ctx.Err() == nil {
+		// wake up every second to check on the context
+		if err := c.server().Recv(time.Second); err != nil && err != imap.ErrTimeout {
+			return err
+		}
+	}
+	c.server().ClearUnilateral()
+
+	_, err := c.wait(ctx)(c.server().IdleTerm())
+	return err
+}
+
+// sleep waits for d or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// hasExists reports whether the server sent an EXISTS response, which it does
+// when new emails arrive.
+func hasExists(data []*imap.Response) bool {
+	for _, rsp := range data {
+		if rsp.Label == "EXISTS" {
+			return true
+		}
+	}
+	return false
+}
+
+// uidNext returns the UID the next email in the folder will get.
+func (c *Client) uidNext(ctx context.Context) (uint32, error) {
+	if mailbox := c.mailbox(); mailbox != nil && mailbox.UIDNext > 0 {
+		return mailbox.UIDNext, nil
+	}
+
+	// the server did not say, find the highest UID in use
+	_, next, err := c.newSince(ctx, 1)
+	return next, err
+}
+
+// newSince finds the emails with a UID of at least next, returning them along
+// with the new value of next.
+func (c *Client) newSince(ctx context.Context, next uint32) ([]uint32, uint32, error) {
+	cmd, err := c.do(ctx, func() (*imap.Command, error) {
+		return c.uidSearch("UID", fmt.Sprintf("%d:*", next))
+	})
+	if err != nil {
+		return nil, next, fmt.Errorf("uid search failed: %w", err)
+	}
+
+	// n:* always matches the last email, even if its UID is below n
+	var uids []uint32
+	for _, uid := range searchResults(cmd) {
+		if uid < next {
+			continue
+		}
+		uids = append(uids, uid)
+	}
+	sort.Slice(uids, func(i, j int) bool {
+		return uids[i] < uids[j]
+	})
+	if len(uids) > 0 {
+		next = uids[len(uids)-1] + 1
+	}
+	return uids, next, nil
+}
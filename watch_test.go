@@ -0,0 +1,16 @@
+package eazye
+
+import (
+	"testing"
+
+	"github.com/mxk/go-imap/imap"
+)
+
+func TestHasExists(t *testing.T) {
+	if hasExists([]*imap.Response{{Label: "EXPUNGE"}, {Label: "FETCH"}}) {
+		t.Error("hasExists() without EXISTS got true")
+	}
+	if !hasExists([]*imap.Response{{Label: "FETCH"}, {Label: "EXISTS"}}) {
+		t.Error("hasExists() with EXISTS got false")
+	}
+}